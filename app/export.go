@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/iqdf/benjerry-service/common/config"
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/product/catalog"
+
+	productUC "github.com/iqdf/benjerry-service/product/service"
+)
+
+// runExport streams every product of product repository of
+// --storage into output file (or stdout when file is "-")
+func runExport(command Command) {
+	var output io.Writer = os.Stdout
+
+	if command.Output != "-" {
+		file, err := os.Create(command.Output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "export: unable to create output:", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		output = file
+	}
+
+	buffered := bufio.NewWriter(output)
+	writer, err := catalog.NewWriter(buffered, command.Format, command.Delimiter)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "export:", err)
+		os.Exit(1)
+	}
+
+	appconfig := config.Get(config.BENJERRY, command.Host, command.Port)
+	store := newCommandStorage("export", command.Storage, appconfig)
+	defer store.close()
+
+	productService := productUC.NewProductService(string(appconfig.AppName), store.productRepo, store.revisionRepo, nil, nil)
+
+	var count int
+	err = productService.ExportProducts(context.Background(), strings.ToLower(command.Region), func(product domain.Product) error {
+		count++
+		return writer.Write(product)
+	})
+
+	if err == nil {
+		err = writer.Close()
+	}
+	if err == nil {
+		err = buffered.Flush()
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "export: aborted:", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "exported: %d\n", count)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/iqdf/benjerry-service/common/config"
+	"github.com/iqdf/benjerry-service/product/catalog"
+
+	productUC "github.com/iqdf/benjerry-service/product/service"
+)
+
+// runImport loads catalog file (or stdin when file is "-") into
+// product repository of --storage and prints import summary
+func runImport(command Command) {
+	if err := importCatalog(command); err != nil {
+		fmt.Fprintln(os.Stderr, "import:", err)
+		os.Exit(1)
+	}
+}
+
+// importCatalog imports catalog of command, so that catalog file
+// and storage are closed by the time runImport exits on failure
+func importCatalog(command Command) error {
+	var input io.Reader = os.Stdin
+
+	if command.File != "-" {
+		file, err := os.Open(command.File)
+		if err != nil {
+			return fmt.Errorf("unable to open catalog: %v", err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	// imports rely on unique indexes created by migrations
+	appconfig := config.Get(config.BENJERRY, command.Host, command.Port)
+	store := newCommandStorage("import", command.Storage, appconfig)
+	defer store.close()
+
+	productService := productUC.NewProductService(
+		string(appconfig.AppName),
+		store.productRepo,
+		store.revisionRepo,
+		store.productIDs,
+		store.transactor,
+	)
+
+	importer := catalog.NewImporter(productService, command.BatchSize, command.DryRun, os.Stdout)
+	summary, err := importer.Import(context.Background(), input)
+
+	if command.DryRun {
+		fmt.Println("Dry run, no product was written.")
+	}
+	fmt.Printf("created: %d, updated: %d, skipped: %d, failed: %d\n",
+		summary.Created, summary.Updated, summary.Skipped, summary.Failed)
+
+	if err != nil {
+		return fmt.Errorf("aborted: %v", err)
+	}
+	return nil
+}
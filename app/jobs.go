@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Intervals of background jobs
+const (
+	trashPurgeInterval    = time.Hour
+	scheduleApplyInterval = time.Minute
+)
+
+// runJob runs job right away and then every interval until ctx is
+// done. Job returns the number of products it has taken care of
+func runJob(ctx context.Context, name string, interval time.Duration, job func(context.Context) (int, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		count, err := job(ctx)
+
+		if err != nil {
+			log.Printf("%s: %s\n", name, err)
+		} else if count > 0 {
+			log.Printf("%s: %d products\n", name, count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -10,7 +10,6 @@ import (
 	"time"
 
 	"github.com/docopt/docopt-go"
-	"github.com/gomodule/redigo/redis"
 	"github.com/gorilla/mux"
 	"github.com/justinas/alice"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -19,15 +18,18 @@ import (
 	// "github.com/iqdf/benjerry-service/config"
 	"github.com/iqdf/benjerry-service/common/auth"
 	"github.com/iqdf/benjerry-service/common/config"
+	"github.com/iqdf/benjerry-service/common/locale"
 	"github.com/iqdf/benjerry-service/common/middleware"
 	"github.com/iqdf/benjerry-service/domain"
 
+	priceHTTP "github.com/iqdf/benjerry-service/pricing/delivery/http"
+
 	productHTTP "github.com/iqdf/benjerry-service/product/delivery/http"
 	productMongo "github.com/iqdf/benjerry-service/product/repository/mongo"
 
 	userHTTP "github.com/iqdf/benjerry-service/user/delivery/http"
-	userMongo "github.com/iqdf/benjerry-service/user/repository/mongo"
 
+	priceUC "github.com/iqdf/benjerry-service/pricing/service"
 	productUC "github.com/iqdf/benjerry-service/product/service"
 	userUC "github.com/iqdf/benjerry-service/user/service"
 )
@@ -35,20 +37,46 @@ import (
 const version = "1.0.0"
 const usage string = `Ben Jerry Service.
 Usage:
-	app run [--port=<port>] [--host=<host>]
+	app run [--port=<port>] [--host=<host>] [--storage=<storage>] [--migrations=<mode>]
+	app migrate (up|down|status) [--storage=<storage>]
+	app import <file> [--dry-run] [--batch-size=<size>] [--storage=<storage>]
+	app export [--format=<format>] [--delimiter=<delim>] [--output=<file>] [--region=<region>] [--storage=<storage>]
 	app -h | --help
 	app --version
 Options:
-	-h --help          Show this screen.
-	--port=<port>      Set port where instance run.
-	--host=<host>      Set hostname where instance run.`
+	-h --help             Show this screen.
+	--port=<port>         Set port where instance run.
+	--host=<host>         Set hostname where instance run.
+	--storage=<storage>   Set storage of data: mongo, memory, sqlite or postgres [default: mongo].
+	--migrations=<mode>   Set migrations pending on start: apply them, or require none [default: apply].
+	--dry-run             Validate and report records without writing them.
+	--batch-size=<size>   Set number of records upserted at once [default: 100].
+	--format=<format>     Set export format: json, ndjson or csv [default: json].
+	--delimiter=<delim>   Set delimiter joining list values in csv [default: |].
+	--output=<file>       Set file to export into, "-" for stdout [default: -].
+	--region=<region>     Only export products sold in region, with its overrides.`
 
 // Command ...
 type Command struct {
-	Run     bool
-	Port    string `docopt:"--port"`
-	Host    string `docopt:"--host"`
-	Version bool
+	Run        bool
+	Import     bool
+	Export     bool
+	Migrate    bool
+	Up         bool
+	Down       bool
+	Status     bool
+	Port       string `docopt:"--port"`
+	Host       string `docopt:"--host"`
+	Storage    string `docopt:"--storage"`
+	Migrations string `docopt:"--migrations"`
+	File       string `docopt:"<file>"`
+	DryRun     bool   `docopt:"--dry-run"`
+	BatchSize  int    `docopt:"--batch-size"`
+	Format     string `docopt:"--format"`
+	Delimiter  string `docopt:"--delimiter"`
+	Output     string `docopt:"--output"`
+	Region     string `docopt:"--region"`
+	Version    bool
 }
 
 // parseCommand ...
@@ -63,64 +91,81 @@ func parseCommand() Command {
 }
 
 func main() {
+	command := parseCommand()
+
+	switch {
+	case command.Run:
+		runServer(command)
+	case command.Import:
+		runImport(command)
+	case command.Export:
+		runExport(command)
+	case command.Migrate:
+		runMigrate(command)
+	case command.Version:
+		fmt.Printf("ben&jerry %s \n", version)
+	}
+}
+
+// connectMongo connects to database configured in
+// app config and panics when it is unreachable
+func connectMongo(appconfig config.AppConfig) *mongo.Client {
+	ctx, cancelMongo := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelMongo()
+
+	mongoOpt := options.Client().ApplyURI(appconfig.DatabaseURI)
+	dbConn, err := mongo.Connect(ctx, mongoOpt)
+
+	if err != nil {
+		panic("unable to connect to mongodb: " + err.Error())
+	}
+	return dbConn
+}
+
+// newProductIDGenerator creates generator of
+// productIds configured in app config
+func newProductIDGenerator(appconfig config.AppConfig, dbConn *mongo.Client) domain.ProductIDGenerator {
+	if appconfig.ProductIDs == config.RANDOM {
+		return productUC.NewRandomIDGenerator(appconfig.ProductIDDigits)
+	}
+	return productMongo.NewProductSequenceRepo(dbConn, appconfig.DatabaseName)
+}
+
+// runServer serves the REST API until interrupted
+func runServer(command Command) {
 	var (
-		err     error
-		command Command
 		// config        config.Config
-		dbConn      *mongo.Client
-		productRepo domain.ProductRepository
-		userRepo    domain.UserRepository
-
 		productService domain.ProductService
+		priceService   domain.PriceService
 		userService    domain.UserService
 		authService    *auth.Service
 
 		rootRouter    *mux.Router
 		productRouter *mux.Router
+		priceRouter   *mux.Router
 		userRouter    *mux.Router
 	)
 
-	command = parseCommand()
-
-	if !command.Run {
-		if command.Version {
-			fmt.Printf("ben&jerry %s \n", version)
-		}
-		return
-	}
-
 	appconfig := config.Get(config.BENJERRY, command.Host, command.Port)
 	config.PrintConfig(appconfig)
 
 	appname := string(appconfig.AppName)
 
-	// Setup database connection here ...
-	ctx, cancelMongo := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancelMongo()
-
-	mongoOpt := options.Client().ApplyURI(appconfig.DatabaseURI)
-	dbConn, err = mongo.Connect(ctx, mongoOpt)
-
-	if err != nil {
-		panic("unable to connect to mongodb: " + err.Error())
-	}
-
-	ctx, cancelRedis := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancelRedis()
-
-	redisConn, err := redis.DialURL(appconfig.RedisURI)
-	if err != nil {
-		panic("unable to connect to redis: " + err.Error())
-	}
-
 	// Setup repositories here ...
-	productRepo = productMongo.NewProductRepo(dbConn, appconfig.DatabaseName) // benjerry
-	userRepo = userMongo.NewUserRepo(dbConn, appconfig.DatabaseName)
+	store := newStorage(command.Storage, command.Migrations, appconfig)
+	store = withProductCache(store, appconfig)
 
 	// Instantiate services here ...
-	productService = productUC.NewProductService(productRepo)
-	userService = userUC.NewUserService(appname, userRepo)
-	authService = auth.NewAuthService(redisConn)
+	productService = productUC.NewProductService(
+		appname,
+		store.productRepo,
+		store.revisionRepo,
+		store.productIDs,
+		store.transactor,
+	)
+	priceService = priceUC.NewPriceService(appname, store.priceRepo, productService)
+	userService = userUC.NewUserService(appname, store.userRepo)
+	authService = auth.NewAuthService(newTokenStore(command.Storage, appconfig))
 
 	// Setup Middleware here ....
 	authMiddleware := middleware.AuthMiddleWare(authService)
@@ -130,11 +175,18 @@ func main() {
 	// Register routings here ...
 	rootRouter = mux.NewRouter()
 	productRouter = rootRouter.PathPrefix("/api/products").Subrouter()
+	priceRouter = rootRouter.PathPrefix("/api/prices").Subrouter()
 	userRouter = rootRouter.PathPrefix("/api/users").Subrouter()
 
 	sessionExpiry := 480 * time.Second
-	productHTTP.NewProductHandler(productService).Routes(productRouter, middlewareChain)
+	locales := locale.Negotiator{Default: appconfig.DefaultLocale, Fallback: appconfig.LocaleFallback}
+	productHTTP.NewProductHandler(productService, locales).Routes(productRouter, middlewareChain)
+	priceHTTP.NewPriceHandler(priceService).Routes(priceRouter, middlewareChain)
 	userHTTP.NewUserHandler(userService, authService, sessionExpiry).Routes(userRouter)
+	if store.productCache != nil {
+		rootRouter.Handle("/debug/product-cache", middlewareChain.Then(productCacheStatsHandler(store.productCache))).
+			Methods("GET").Name("DEBUG_PRODUCT_CACHE_GET")
+	}
 
 	server := &http.Server{
 		Addr:         appconfig.AppAddress(),
@@ -151,6 +203,17 @@ func main() {
 		}
 	}()
 
+	// Run background jobs until shutdown
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	if appconfig.TrashRetention > 0 {
+		go runJob(jobsCtx, "purge trash", trashPurgeInterval, func(ctx context.Context) (int, error) {
+			return productService.PurgeTrash(ctx, time.Now().Add(-appconfig.TrashRetention))
+		})
+	}
+	go runJob(jobsCtx, "apply scheduled statuses", scheduleApplyInterval, func(ctx context.Context) (int, error) {
+		return productService.ApplyScheduledStatuses(ctx, time.Now())
+	})
+
 	// Handle shutdowns when quit via SIGINT (Ctrl+C)
 	// Note: SIGKILL, SIGQUIT or SIGTERM (Ctrl+/) will not be caught
 	c := make(chan os.Signal, 1)
@@ -162,6 +225,7 @@ func main() {
 	ctx, cancelRun := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancelRun()
 
+	stopJobs()
 	server.Shutdown(ctx)
 
 	log.Println("Shutting Down...")
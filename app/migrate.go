@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/iqdf/benjerry-service/common/config"
+	mongoHelper "github.com/iqdf/benjerry-service/common/repository/mongo"
+	sqlHelper "github.com/iqdf/benjerry-service/common/repository/sql"
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// Modes of --migrations, telling what the server
+// does with migrations pending on start
+const (
+	applyMigrations   = "apply"
+	requireMigrations = "require"
+)
+
+// migrationStatus tells whether a migration
+// of either database has been applied
+type migrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+// migrator migrates the database of a storage
+type migrator interface {
+	Up(ctx context.Context) (int, error)
+	Down(ctx context.Context) (migrationStatus, error)
+	Statuses(ctx context.Context) ([]migrationStatus, error)
+}
+
+// mongoMigrator migrates mongo by Go migrations
+type mongoMigrator struct {
+	*mongoHelper.Migrator
+}
+
+func (migrator mongoMigrator) Down(ctx context.Context) (migrationStatus, error) {
+	migration, err := migrator.Migrator.Down(ctx)
+	return migrationStatus{Version: migration.Version, Name: migration.Name}, err
+}
+
+func (migrator mongoMigrator) Statuses(ctx context.Context) ([]migrationStatus, error) {
+	statuses, err := migrator.Migrator.Statuses(ctx)
+
+	migrationStatuses := make([]migrationStatus, 0, len(statuses))
+	for _, status := range statuses {
+		migrationStatuses = append(migrationStatuses, migrationStatus{
+			Version:   status.Version,
+			Name:      status.Name,
+			AppliedAt: status.AppliedAt,
+		})
+	}
+	return migrationStatuses, err
+}
+
+// sqlMigrator migrates SQL databases, whose
+// migrations are applied but never reverted
+type sqlMigrator struct {
+	db *sqlHelper.DB
+}
+
+func (migrator sqlMigrator) Up(ctx context.Context) (int, error) {
+	return migrator.db.Migrate(ctx)
+}
+
+func (migrator sqlMigrator) Down(ctx context.Context) (migrationStatus, error) {
+	return migrationStatus{}, errors.New(string(migrator.db.Dialect) + " migrations cannot be reverted")
+}
+
+func (migrator sqlMigrator) Statuses(ctx context.Context) ([]migrationStatus, error) {
+	statuses, err := migrator.db.MigrationStatuses(ctx)
+
+	migrationStatuses := make([]migrationStatus, 0, len(statuses))
+	for _, status := range statuses {
+		migrationStatuses = append(migrationStatuses, migrationStatus{
+			Version:   status.Version,
+			Name:      status.Name,
+			AppliedAt: status.AppliedAt,
+		})
+	}
+	return migrationStatuses, err
+}
+
+// migrateOnStart applies pending migrations, or given mode
+// require, exits when any migration is pending so that the
+// server never runs on a database it does not expect.
+// Panics when the database fails to migrate
+func migrateOnStart(mode string, storageName string, migrator migrator) {
+	ctx := context.Background()
+
+	switch mode {
+	case applyMigrations:
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			panic("unable to migrate " + storageName + ": " + err.Error())
+		}
+		if applied > 0 {
+			fmt.Printf("Applied %d migrations of %s\n", applied, storageName)
+		}
+
+	case requireMigrations:
+		statuses, err := migrator.Statuses(ctx)
+		if err != nil {
+			panic("unable to read migrations of " + storageName + ": " + err.Error())
+		}
+		if pending := countPending(statuses); pending > 0 {
+			fmt.Fprintf(os.Stderr, "run: %d migrations of %s are pending, see app migrate up\n", pending, storageName)
+			os.Exit(1)
+		}
+
+	default:
+		fmt.Fprintln(os.Stderr, "run: migrations must be apply or require:", mode)
+		os.Exit(1)
+	}
+}
+
+// countPending counts migrations not applied yet
+func countPending(statuses []migrationStatus) int {
+	var pending int
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending
+}
+
+// runMigrate applies pending migrations, reverts the latest applied
+// migration or lists migrations of the database of --storage
+func runMigrate(command Command) {
+	ctx := context.Background()
+	appconfig := config.Get(config.BENJERRY, command.Host, command.Port)
+
+	var migrator migrator
+	switch command.Storage {
+	case mongoStorage:
+		dbConn := connectMongo(appconfig)
+		defer dbConn.Disconnect(ctx)
+		migrator = mongoMigrator{mongoHelper.NewMigrator(dbConn, appconfig.DatabaseName)}
+
+	case sqliteStorage, postgresStorage:
+		db, err := sqlHelper.Open(sqlHelper.Dialect(command.Storage), appconfig.DatabaseDSN)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "migrate: unable to connect to "+command.Storage+":", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+		migrator = sqlMigrator{db}
+
+	default:
+		fmt.Fprintln(os.Stderr, "migrate: storage must be mongo, sqlite or postgres:", command.Storage)
+		os.Exit(1)
+	}
+
+	var err error
+	switch {
+	case command.Up:
+		var applied int
+		if applied, err = migrator.Up(ctx); err == nil {
+			fmt.Printf("Applied %d migrations\n", applied)
+		}
+
+	case command.Down:
+		var reverted migrationStatus
+		switch reverted, err = migrator.Down(ctx); err {
+		case nil:
+			fmt.Printf("Reverted migration %d: %s\n", reverted.Version, reverted.Name)
+		case domain.ErrResourceNotFound:
+			fmt.Println("No migration to revert")
+			err = nil
+		}
+
+	case command.Status:
+		var statuses []migrationStatus
+		if statuses, err = migrator.Statuses(ctx); err == nil {
+			printMigrations(statuses)
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "migrate:", err)
+		os.Exit(1)
+	}
+}
+
+// printMigrations lists migrations in order and when they were applied
+func printMigrations(statuses []migrationStatus) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "VERSION\tNAME\tAPPLIED AT")
+
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	writer.Flush()
+	fmt.Printf("%d pending\n", countPending(statuses))
+}
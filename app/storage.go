@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/iqdf/benjerry-service/common/auth"
+	"github.com/iqdf/benjerry-service/common/config"
+	mongoHelper "github.com/iqdf/benjerry-service/common/repository/mongo"
+	sqlHelper "github.com/iqdf/benjerry-service/common/repository/sql"
+	"github.com/iqdf/benjerry-service/domain"
+
+	priceMemory "github.com/iqdf/benjerry-service/pricing/repository/memory"
+	priceMongo "github.com/iqdf/benjerry-service/pricing/repository/mongo"
+	priceSQL "github.com/iqdf/benjerry-service/pricing/repository/sql"
+	productMemory "github.com/iqdf/benjerry-service/product/repository/memory"
+	productMongo "github.com/iqdf/benjerry-service/product/repository/mongo"
+	productRedis "github.com/iqdf/benjerry-service/product/repository/redis"
+	productSQL "github.com/iqdf/benjerry-service/product/repository/sql"
+	productUC "github.com/iqdf/benjerry-service/product/service"
+	userMemory "github.com/iqdf/benjerry-service/user/repository/memory"
+	userMongo "github.com/iqdf/benjerry-service/user/repository/mongo"
+	userSQL "github.com/iqdf/benjerry-service/user/repository/sql"
+)
+
+// Storages the server runs on, see --storage
+const (
+	mongoStorage    = "mongo"
+	memoryStorage   = "memory"
+	sqliteStorage   = "sqlite"
+	postgresStorage = "postgres"
+)
+
+// storage is the repositories of the server and its commands
+type storage struct {
+	productRepo  domain.ProductRepository
+	revisionRepo domain.ProductRevisionRepository
+	priceRepo    domain.PriceRepository
+	userRepo     domain.UserRepository
+	productIDs   domain.ProductIDGenerator
+	transactor   domain.Transactor
+
+	// close disconnects from the database
+	close func()
+
+	// productCache caches productRepo, nil unless PRODUCT_CACHE is on
+	productCache *productRedis.ProductCacheRepo
+}
+
+// newStorage creates storage named by --storage, whose pending
+// migrations are handled by mode of --migrations. Exits when
+// the name is unknown
+func newStorage(name string, migrations string, appconfig config.AppConfig) storage {
+	switch name {
+	case mongoStorage:
+		return newMongoStorage(migrations, appconfig)
+	case memoryStorage:
+		return newMemoryStorage(appconfig)
+	case sqliteStorage:
+		return newSQLStorage(sqlHelper.SQLite, migrations, appconfig)
+	case postgresStorage:
+		return newSQLStorage(sqlHelper.Postgres, migrations, appconfig)
+	}
+
+	fmt.Fprintln(os.Stderr, "storage must be mongo, memory, sqlite or postgres:", name)
+	os.Exit(1)
+	return storage{}
+}
+
+// newCommandStorage creates storage named by --storage for commands
+// that run apart from the server, such as import, applying pending
+// migrations first. Exits on memory storage, which is lost once the
+// command is done and is not seen by the server
+func newCommandStorage(command string, name string, appconfig config.AppConfig) storage {
+	if name == memoryStorage {
+		fmt.Fprintln(os.Stderr, command+": storage must be mongo, sqlite or postgres:", name)
+		os.Exit(1)
+	}
+	return newStorage(name, applyMigrations, appconfig)
+}
+
+// newTokenStore creates store of session tokens of storage named
+// by --storage. Mongo and postgres shared by instances keep them in
+// redis, single-node memory and sqlite keep them in process memory.
+// Panics when redis is unreachable
+func newTokenStore(name string, appconfig config.AppConfig) auth.TokenStore {
+	if name != mongoStorage && name != postgresStorage {
+		return auth.NewMemoryTokenStore()
+	}
+
+	redisConn, err := redis.DialURL(appconfig.RedisURI)
+	if err != nil {
+		panic("unable to connect to redis: " + err.Error())
+	}
+	return auth.NewRedisTokenStore(redisConn)
+}
+
+// newMongoStorage keeps data in mongo, panics when it is unreachable
+func newMongoStorage(migrations string, appconfig config.AppConfig) storage {
+	dbConn := connectMongo(appconfig)
+	migrateOnStart(migrations, mongoStorage, mongoMigrator{mongoHelper.NewMigrator(dbConn, appconfig.DatabaseName)})
+
+	return storage{
+		productRepo:  productMongo.NewProductRepo(dbConn, appconfig.DatabaseName), // benjerry
+		revisionRepo: productMongo.NewProductRevisionRepo(dbConn, appconfig.DatabaseName),
+		priceRepo:    priceMongo.NewPriceRepo(dbConn, appconfig.DatabaseName),
+		userRepo:     userMongo.NewUserRepo(dbConn, appconfig.DatabaseName),
+		productIDs:   newProductIDGenerator(appconfig, dbConn),
+		transactor:   mongoHelper.NewTransactor(dbConn),
+		close:        func() { dbConn.Disconnect(context.Background()) },
+	}
+}
+
+// withProductCache caches products of storage in redis when
+// PRODUCT_CACHE is on, see productCacheStatsHandler for its
+// hits and misses
+func withProductCache(store storage, appconfig config.AppConfig) storage {
+	if !appconfig.ProductCache {
+		return store
+	}
+
+	pool := &redis.Pool{
+		MaxIdle:     16,
+		IdleTimeout: 4 * time.Minute,
+		Dial:        func() (redis.Conn, error) { return redis.DialURL(appconfig.RedisURI) },
+	}
+	cache := productRedis.NewProductCacheRepo(
+		store.productRepo,
+		pool,
+		appconfig.ProductCacheTTL,
+		appconfig.ProductCacheNotFoundTTL,
+	)
+
+	store.productRepo = cache
+	store.productCache = cache
+	if store.transactor != nil {
+		store.transactor = cache.Transactor(store.transactor)
+	}
+	return store
+}
+
+// productCacheStatsHandler provides handler func that counts hits and misses of cache
+// [GET] /debug/product-cache
+func productCacheStatsHandler(cache *productRedis.ProductCacheRepo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(cache.Stats())
+	}
+}
+
+// newMemoryStorage keeps data in process memory, which
+// starts empty and is lost on shutdown. Atomic batches need
+// transactions, which memory storage does not support
+func newMemoryStorage(appconfig config.AppConfig) storage {
+	productRepo := productMemory.NewProductRepo()
+
+	var productIDs domain.ProductIDGenerator = productMemory.NewProductSequenceRepo(productRepo)
+	if appconfig.ProductIDs == config.RANDOM {
+		productIDs = productUC.NewRandomIDGenerator(appconfig.ProductIDDigits)
+	}
+
+	return storage{
+		productRepo:  productRepo,
+		revisionRepo: productMemory.NewProductRevisionRepo(),
+		priceRepo:    priceMemory.NewPriceRepo(),
+		userRepo:     userMemory.NewUserRepo(),
+		productIDs:   productIDs,
+		close:        func() {},
+	}
+}
+
+// newSQLStorage keeps data in the SQL database at DB_URI, whose
+// pending migrations are handled first. Panics when the
+// database is unreachable or the sequence of productIds
+// cannot be seeded
+func newSQLStorage(dialect sqlHelper.Dialect, migrations string, appconfig config.AppConfig) storage {
+	db, err := sqlHelper.Open(dialect, appconfig.DatabaseDSN)
+	if err != nil {
+		panic("unable to connect to " + string(dialect) + ": " + err.Error())
+	}
+	migrateOnStart(migrations, string(dialect), sqlMigrator{db})
+
+	var productIDs domain.ProductIDGenerator = productUC.NewRandomIDGenerator(appconfig.ProductIDDigits)
+	if appconfig.ProductIDs != config.RANDOM {
+		productIDs, err = productSQL.NewProductSequenceRepo(context.Background(), db)
+		if err != nil {
+			panic("unable to seed productId sequence: " + err.Error())
+		}
+	}
+
+	return storage{
+		productRepo:  productSQL.NewProductRepo(db),
+		revisionRepo: productSQL.NewProductRevisionRepo(db),
+		priceRepo:    priceSQL.NewPriceRepo(db),
+		userRepo:     userSQL.NewUserRepo(db),
+		productIDs:   productIDs,
+		transactor:   db,
+		close:        func() { db.Close() },
+	}
+}
@@ -0,0 +1,19 @@
+package auth
+
+import "context"
+
+// contextKey to get authentication from context
+type contextKey string
+
+const authKey contextKey = "authentication"
+
+// NewContext returns a copy of ctx carrying authentication
+func NewContext(ctx context.Context, auth Authentication) context.Context {
+	return context.WithValue(ctx, authKey, auth)
+}
+
+// FromContext returns authentication carried by ctx, if any
+func FromContext(ctx context.Context) (Authentication, bool) {
+	auth, ok := ctx.Value(authKey).(Authentication)
+	return auth, ok
+}
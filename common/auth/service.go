@@ -2,9 +2,8 @@ package auth
 
 import (
 	"encoding/json"
-	"strconv"
+	"time"
 
-	"github.com/gomodule/redigo/redis"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -16,13 +15,13 @@ type CreateTokenData struct {
 
 // Service ...
 type Service struct {
-	cache  redis.Conn
+	store TokenStore
 }
 
 // NewAuthService ...
-func NewAuthService(redisConn redis.Conn) *Service {
+func NewAuthService(store TokenStore) *Service {
 	return &Service{
-		cache:  redisConn,
+		store: store,
 	}
 }
 
@@ -30,9 +29,9 @@ func NewAuthService(redisConn redis.Conn) *Service {
 func (service *Service) CreateToken(data CreateTokenData) (string, error) {
 	token := uuid.NewV4().String()
 
-	expiry := strconv.Itoa(data.ExpirationTime)
+	expiry := time.Duration(data.ExpirationTime) * time.Second
 	value, _ := json.Marshal(&data.Authentication)
-	_, err := service.cache.Do("SETEX", token, expiry, string(value))
+	err := service.store.Set(token, value, expiry)
 
 	if err != nil {
 		return "", err
@@ -44,14 +43,13 @@ func (service *Service) CreateToken(data CreateTokenData) (string, error) {
 func (service *Service) VerifyToken(token string) (Authentication, bool, error) {
 	var auth Authentication
 
-	response, err := service.cache.Do("GET", token)
-	if response == nil {
-		// verify token is stored in cache
-		// empty string means token is not found
-		return Authentication{}, false, nil
+	value, found, err := service.store.Get(token)
+	if !found {
+		// verify token is stored in store,
+		// expired tokens are not found
+		return Authentication{}, false, err
 	}
 
-	value, _ := redis.Bytes(response, err)
 	err = json.Unmarshal(value, &auth)
 
 	if err != nil {
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// TokenStore keeps session tokens until they expire. Get tells
+// whether token is stored, expired tokens are never found
+type TokenStore interface {
+	Set(token string, value []byte, expiry time.Duration) error
+	Get(token string) ([]byte, bool, error)
+}
+
+// RedisTokenStore keeps tokens in redis, which expires them
+type RedisTokenStore struct {
+	conn redis.Conn
+}
+
+// NewRedisTokenStore creates token store over redis connection
+func NewRedisTokenStore(conn redis.Conn) *RedisTokenStore {
+	return &RedisTokenStore{conn: conn}
+}
+
+// Set stores value of token for expiry, in whole seconds
+func (store *RedisTokenStore) Set(token string, value []byte, expiry time.Duration) error {
+	seconds := strconv.Itoa(int(expiry / time.Second))
+	_, err := store.conn.Do("SETEX", token, seconds, string(value))
+	return err
+}
+
+// Get reads value of token, nil response
+// means token is not found
+func (store *RedisTokenStore) Get(token string) ([]byte, bool, error) {
+	response, err := store.conn.Do("GET", token)
+	if response == nil {
+		return nil, false, nil
+	}
+
+	value, err := redis.Bytes(response, err)
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// memoryToken is a value stored until expiresAt
+type memoryToken struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// MemoryTokenStore keeps tokens in process memory, e.g. to run without
+// redis. Expired tokens are dropped as they are read, and all of them
+// whenever a token is stored, so that memory does not grow unbounded
+type MemoryTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]memoryToken
+	now    func() time.Time
+}
+
+// NewMemoryTokenStore creates an empty in-memory token store
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{
+		tokens: make(map[string]memoryToken),
+		now:    time.Now,
+	}
+}
+
+// Set stores copy of value of token for expiry
+func (store *MemoryTokenStore) Set(token string, value []byte, expiry time.Duration) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := store.now()
+	for stored, t := range store.tokens {
+		if !now.Before(t.expiresAt) {
+			delete(store.tokens, stored)
+		}
+	}
+
+	store.tokens[token] = memoryToken{
+		value:     append([]byte(nil), value...),
+		expiresAt: now.Add(expiry),
+	}
+	return nil
+}
+
+// Get reads copy of value of token unless it has expired
+func (store *MemoryTokenStore) Get(token string) ([]byte, bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	t, ok := store.tokens[token]
+	if !ok {
+		return nil, false, nil
+	}
+
+	if !store.now().Before(t.expiresAt) {
+		delete(store.tokens, token)
+		return nil, false, nil
+	}
+	return append([]byte(nil), t.value...), true, nil
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryTokenStore(t *testing.T) {
+	now := time.Date(2020, 7, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryTokenStore()
+	store.now = func() time.Time { return now }
+
+	t.Run("MemoryTokenStore-found", func(t *testing.T) {
+		err := store.Set("token-1", []byte(`{"username":"ben"}`), 10*time.Second)
+		assert.NoError(t, err)
+
+		value, found, err := store.Get("token-1")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, `{"username":"ben"}`, string(value))
+	})
+
+	t.Run("MemoryTokenStore-not-found", func(t *testing.T) {
+		value, found, err := store.Get("token-unknown")
+		assert.NoError(t, err)
+		assert.False(t, found)
+		assert.Nil(t, value)
+	})
+
+	t.Run("MemoryTokenStore-expired", func(t *testing.T) {
+		store.Set("token-2", []byte("value"), 10*time.Second)
+
+		now = now.Add(9 * time.Second)
+		_, found, _ := store.Get("token-2")
+		assert.True(t, found)
+
+		now = now.Add(time.Second)
+		_, found, _ = store.Get("token-2")
+		assert.False(t, found)
+	})
+
+	t.Run("MemoryTokenStore-evicted", func(t *testing.T) {
+		store.Set("token-3", []byte("value"), time.Second)
+		now = now.Add(time.Second)
+		store.Set("token-4", []byte("value"), time.Second)
+
+		store.mu.Lock()
+		_, stored := store.tokens["token-3"]
+		store.mu.Unlock()
+		assert.False(t, stored)
+	})
+
+	t.Run("MemoryTokenStore-concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				store.Set("token-5", []byte("value"), time.Minute)
+				store.Get("token-5")
+			}()
+		}
+		wg.Wait()
+
+		_, found, _ := store.Get("token-5")
+		assert.True(t, found)
+	})
+}
+
+func TestServiceVerifyToken(t *testing.T) {
+	service := NewAuthService(NewMemoryTokenStore())
+	authentication := Authentication{ID: "ben"}
+
+	token, err := service.CreateToken(CreateTokenData{Authentication: authentication, ExpirationTime: 60})
+	assert.NoError(t, err)
+
+	verified, ok, err := service.VerifyToken(token)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, authentication, verified)
+
+	_, ok, err = service.VerifyToken("unknown")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
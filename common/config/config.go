@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/iqdf/benjerry-service/common/locale"
 )
 
 // AppConfig serves standard App Configuration
@@ -19,10 +23,35 @@ type AppConfig struct {
 	// Running Environment
 	EnvironmentMode EnvIdentifier
 
-	// Database Configuration
+	// Database Configuration. SQL storages open
+	// DatabaseDSN, which is DB_URI as it is given
 	DatabaseURI  string
 	DatabaseName string
+	DatabaseDSN  string
 	RedisURI     string
+
+	// Trashed products are purged after retention,
+	// zero retention keeps them until purged by hand
+	TrashRetention time.Duration
+
+	// Product text is written in default locale, translations
+	// are picked from fallback locales, in order, when none of
+	// the locales requested is available
+	DefaultLocale  string
+	LocaleFallback []string
+
+	// Created products are allocated productIds by ProductIDs
+	// generator, either a sequence in the database or random
+	// ids of ProductIDDigits digits
+	ProductIDs      ProductIDGenerator
+	ProductIDDigits int
+
+	// Products read by productId are cached in redis for
+	// ProductCacheTTL when ProductCache is on, products that
+	// are not found for the shorter ProductCacheNotFoundTTL
+	ProductCache            bool
+	ProductCacheTTL         time.Duration
+	ProductCacheNotFoundTTL time.Duration
 }
 
 // AppAddress returns address of hosted app
@@ -37,6 +66,10 @@ type (
 	// EnvIdentifier tags current running environment
 	// see constant below
 	EnvIdentifier string
+
+	// ProductIDGenerator names generator of
+	// productIds, see constant below
+	ProductIDGenerator string
 )
 
 // Enum for deployment related
@@ -48,12 +81,29 @@ const (
 	DEVELOPMENT EnvIdentifier = "development"
 	STAGING     EnvIdentifier = "staging"
 	PRODUCTION  EnvIdentifier = "production"
+
+	// Generators of productIds
+	SEQUENCE ProductIDGenerator = "sequence"
+	RANDOM   ProductIDGenerator = "random"
+)
+
+// defaultProductIDDigits keeps random productIds rare to
+// collide in catalogs of up to a few thousand products
+const defaultProductIDDigits = 9
+
+// Products are cached for minutes, products that are not found for
+// seconds. Products written around the cache, e.g. by app import,
+// are not invalidated and show up once their cache entry expires
+const (
+	defaultProductCacheTTL         = 5 * time.Minute
+	defaultProductCacheNotFoundTTL = 10 * time.Second
 )
 
 // Get application configurations which
 // are passed by environment variables
 func Get(appID AppIdentifier, host string, port string) AppConfig {
 	dbURI := os.Getenv("DB_URI")
+	dsn := dbURI
 	uri, err := url.Parse(dbURI)
 
 	if err != nil {
@@ -68,6 +118,80 @@ func Get(appID AppIdentifier, host string, port string) AppConfig {
 		redisURI = "redis://localhost:6379"
 	}
 
+	trashRetention := 30 * 24 * time.Hour
+	if value := os.Getenv("TRASH_RETENTION"); len(value) > 0 {
+		trashRetention, err = time.ParseDuration(value)
+		if err != nil {
+			fmt.Println("warning: got invalid trash retention:", err)
+		}
+	}
+
+	defaultLocale := "en"
+	if value := os.Getenv("DEFAULT_LOCALE"); len(value) > 0 {
+		if tag, ok := locale.Canonical(value); ok {
+			defaultLocale = tag
+		} else {
+			fmt.Println("warning: got invalid default locale:", value)
+		}
+	}
+
+	var localeFallback []string
+	for _, value := range strings.Split(os.Getenv("LOCALE_FALLBACK"), ",") {
+		if len(strings.TrimSpace(value)) == 0 {
+			continue
+		}
+		if tag, ok := locale.Canonical(value); ok {
+			localeFallback = append(localeFallback, tag)
+		} else {
+			fmt.Println("warning: got invalid fallback locale:", value)
+		}
+	}
+
+	productIDs := ProductIDGenerator(os.Getenv("PRODUCT_ID_GENERATOR"))
+	switch productIDs {
+	case SEQUENCE, RANDOM:
+	case "":
+		productIDs = SEQUENCE
+	default:
+		fmt.Println("warning: got invalid product id generator:", productIDs)
+		productIDs = SEQUENCE
+	}
+
+	productIDDigits := defaultProductIDDigits
+	if value := os.Getenv("PRODUCT_ID_DIGITS"); len(value) > 0 {
+		productIDDigits, err = strconv.Atoi(value)
+		if err != nil || productIDDigits < 3 || productIDDigits > 18 {
+			fmt.Println("warning: got invalid product id digits:", value)
+			productIDDigits = defaultProductIDDigits
+		}
+	}
+
+	var productCache bool
+	if value := os.Getenv("PRODUCT_CACHE"); len(value) > 0 {
+		productCache, err = strconv.ParseBool(value)
+		if err != nil {
+			fmt.Println("warning: got invalid product cache switch:", value)
+		}
+	}
+
+	productCacheTTL := defaultProductCacheTTL
+	if value := os.Getenv("PRODUCT_CACHE_TTL"); len(value) > 0 {
+		productCacheTTL, err = time.ParseDuration(value)
+		if err != nil || productCacheTTL <= 0 {
+			fmt.Println("warning: got invalid product cache ttl:", value)
+			productCacheTTL = defaultProductCacheTTL
+		}
+	}
+
+	productCacheNotFoundTTL := defaultProductCacheNotFoundTTL
+	if value := os.Getenv("PRODUCT_CACHE_NOT_FOUND_TTL"); len(value) > 0 {
+		productCacheNotFoundTTL, err = time.ParseDuration(value)
+		if err != nil || productCacheNotFoundTTL <= 0 {
+			fmt.Println("warning: got invalid product cache not found ttl:", value)
+			productCacheNotFoundTTL = defaultProductCacheNotFoundTTL
+		}
+	}
+
 	env := EnvIdentifier(os.Getenv("ENV_MODE"))
 	if len(env) == 0 {
 		env = DEVELOPMENT
@@ -80,7 +204,17 @@ func Get(appID AppIdentifier, host string, port string) AppConfig {
 		EnvironmentMode: env,
 		DatabaseURI:     dbURI,
 		DatabaseName:    dbName,
+		DatabaseDSN:     dsn,
 		RedisURI:        redisURI,
+		TrashRetention:  trashRetention,
+		DefaultLocale:   defaultLocale,
+		LocaleFallback:  localeFallback,
+		ProductIDs:      productIDs,
+		ProductIDDigits: productIDDigits,
+
+		ProductCache:            productCache,
+		ProductCacheTTL:         productCacheTTL,
+		ProductCacheNotFoundTTL: productCacheNotFoundTTL,
 	}
 }
 
@@ -98,6 +232,13 @@ func PrintConfig(config AppConfig) {
 	fmt.Printf(format, "Database URI", config.DatabaseURI)
 	fmt.Printf(format, "Database Name", config.DatabaseName)
 	fmt.Printf(format, "Redis URI", config.RedisURI)
+	fmt.Printf(format, "Trash Retention", config.TrashRetention)
+	fmt.Printf(format, "Default Locale", config.DefaultLocale)
+	fmt.Printf(format, "Locale Fallback", strings.Join(config.LocaleFallback, ","))
+	fmt.Printf(format, "Product IDs", config.ProductIDs)
+	if config.ProductCache {
+		fmt.Printf(format, "Product Cache", config.ProductCacheTTL)
+	}
 
 	fmt.Println("-----------------------------------------")
 }
@@ -0,0 +1,42 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+)
+
+// MergePatch applies JSON Merge Patch (RFC 7396) onto document.
+// Members set to null in the patch are removed from the document
+func MergePatch(document, patch []byte) ([]byte, error) {
+	var target, patchValue interface{}
+
+	if err := json.Unmarshal(document, &target); err != nil {
+		return nil, ErrInvalidDocument
+	}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, ErrInvalidPatch
+	}
+
+	return json.Marshal(mergeValue(target, patchValue))
+}
+
+// mergeValue implements MergePatch pseudo code of RFC 7396 section 2
+func mergeValue(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergeValue(targetObject[name], value)
+	}
+	return targetObject
+}
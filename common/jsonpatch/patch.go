@@ -0,0 +1,292 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidDocument is returned when patched document is not JSON
+	ErrInvalidDocument = errors.New("jsonpatch: document is not valid JSON")
+
+	// ErrInvalidPatch is returned when patch is malformed
+	ErrInvalidPatch = errors.New("jsonpatch: patch is malformed")
+
+	// ErrPathNotFound is returned when operation refers to missing location
+	ErrPathNotFound = errors.New("jsonpatch: path does not exist in document")
+
+	// ErrTestFailed is returned when a test operation does not match
+	ErrTestFailed = errors.New("jsonpatch: test operation failed")
+)
+
+// Operation is a single JSON Patch (RFC 6902) operation
+type Operation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from,omitempty"`
+	Value *json.RawMessage `json:"value,omitempty"`
+}
+
+// Paths lists the paths that the operation reads or writes
+func (operation Operation) Paths() []string {
+	if operation.From != "" {
+		return []string{operation.Path, operation.From}
+	}
+	return []string{operation.Path}
+}
+
+// DecodePatch parses JSON Patch document into its operations
+func DecodePatch(patch []byte) ([]Operation, error) {
+	var operations []Operation
+
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, ErrInvalidPatch
+	}
+	return operations, nil
+}
+
+// ApplyPatch applies JSON Patch (RFC 6902) operations onto document.
+// Operations are applied in order and patching stops at the first
+// failed operation, in which case the document is left unchanged
+func ApplyPatch(document []byte, operations []Operation) ([]byte, error) {
+	var target interface{}
+
+	if err := json.Unmarshal(document, &target); err != nil {
+		return nil, ErrInvalidDocument
+	}
+
+	for _, operation := range operations {
+		var err error
+		if target, err = apply(target, operation); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(target)
+}
+
+func apply(target interface{}, operation Operation) (interface{}, error) {
+	path, err := parsePointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operation.Op {
+	case "add", "replace", "test":
+		if operation.Value == nil {
+			return nil, ErrInvalidPatch
+		}
+		var value interface{}
+		if err = json.Unmarshal(*operation.Value, &value); err != nil {
+			return nil, ErrInvalidPatch
+		}
+
+		switch operation.Op {
+		case "add":
+			return add(target, path, value)
+		case "replace":
+			if target, _, err = remove(target, path); err != nil {
+				return nil, err
+			}
+			return add(target, path, value)
+		}
+
+		current, err := get(target, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, ErrTestFailed
+		}
+		return target, nil
+
+	case "remove":
+		target, _, err = remove(target, path)
+		return target, err
+
+	case "move", "copy":
+		from, err := parsePointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+
+		var value interface{}
+		if operation.Op == "move" {
+			if isPrefix(from, path) && len(from) < len(path) {
+				// a location cannot be moved into one of its children
+				return nil, ErrInvalidPatch
+			}
+			target, value, err = remove(target, from)
+		} else {
+			value, err = get(target, from)
+			value = deepCopy(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return add(target, path, value)
+	}
+	return nil, ErrInvalidPatch
+}
+
+// parsePointer splits JSON Pointer (RFC 6901) into unescaped tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, ErrInvalidPatch
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		token = strings.Replace(token, "~1", "/", -1)
+		tokens[i] = strings.Replace(token, "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+func get(target interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := target.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, ErrPathNotFound
+			}
+			target = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			target = node[index]
+		default:
+			return nil, ErrPathNotFound
+		}
+	}
+	return target, nil
+}
+
+// add sets value at path and returns the modified target,
+// array elements are inserted before the given index
+func add(target interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(target, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+		return target, nil
+	case []interface{}:
+		index := len(node)
+		if last != "-" {
+			if index, err = arrayIndex(last, len(node)); err != nil {
+				return nil, err
+			}
+		}
+		node = append(node, nil)
+		copy(node[index+1:], node[index:])
+		node[index] = value
+		return replaceParent(target, path[:len(path)-1], node)
+	}
+	return nil, ErrPathNotFound
+}
+
+// remove deletes value at path and returns
+// the modified target and the removed value
+func remove(target interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, target, nil
+	}
+
+	parent, err := get(target, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		value, ok := node[last]
+		if !ok {
+			return nil, nil, ErrPathNotFound
+		}
+		delete(node, last)
+		return target, value, nil
+	case []interface{}:
+		index, err := arrayIndex(last, len(node)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := node[index]
+		node = append(node[:index:index], node[index+1:]...)
+		target, err = replaceParent(target, path[:len(path)-1], node)
+		return target, value, err
+	}
+	return nil, nil, ErrPathNotFound
+}
+
+// replaceParent stores resized array back into its parent
+func replaceParent(target interface{}, path []string, array []interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return array, nil
+	}
+
+	grandparent, err := get(target, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	last := path[len(path)-1]
+	switch node := grandparent.(type) {
+	case map[string]interface{}:
+		node[last] = array
+	case []interface{}:
+		index, _ := arrayIndex(last, len(node)-1)
+		node[index] = array
+	}
+	return target, nil
+}
+
+// arrayIndex parses array index token which must be within [0, max]
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, ErrPathNotFound
+	}
+
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > max {
+		return 0, ErrPathNotFound
+	}
+	return index, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func deepCopy(value interface{}) interface{} {
+	encoded, _ := json.Marshal(value)
+
+	var copied interface{}
+	json.Unmarshal(encoded, &copied)
+	return copied
+}
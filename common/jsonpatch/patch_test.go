@@ -0,0 +1,117 @@
+package jsonpatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+	document := `{"title": "Goodbye!", "author": {"givenName": "John", "familyName": "Doe"}, "tags": ["example", "sample"], "content": "This will be unchanged"}`
+	patch := `{"title": "Hello!", "phoneNumber": "+01-123-456-7890", "author": {"familyName": null}, "tags": ["example"]}`
+	expected := `{"title": "Hello!", "author": {"givenName": "John"}, "tags": ["example"], "content": "This will be unchanged", "phoneNumber": "+01-123-456-7890"}`
+
+	patched, err := MergePatch([]byte(document), []byte(patch))
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, expected, string(patched))
+}
+
+func TestApplyPatch(t *testing.T) {
+	testCases := []struct {
+		name     string
+		document string
+		patch    string
+		expected string
+		err      error
+	}{
+		{
+			name:     "add-object-member",
+			document: `{"foo": "bar"}`,
+			patch:    `[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			expected: `{"baz": "qux", "foo": "bar"}`,
+		},
+		{
+			name:     "add-array-element",
+			document: `{"foo": ["bar", "baz"]}`,
+			patch:    `[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+			expected: `{"foo": ["bar", "qux", "baz"]}`,
+		},
+		{
+			name:     "append-array-element",
+			document: `{"foo": ["bar"]}`,
+			patch:    `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+			expected: `{"foo": ["bar", ["abc", "def"]]}`,
+		},
+		{
+			name:     "remove-array-element",
+			document: `{"foo": ["bar", "qux", "baz"]}`,
+			patch:    `[{"op": "remove", "path": "/foo/1"}]`,
+			expected: `{"foo": ["bar", "baz"]}`,
+		},
+		{
+			name:     "replace-value",
+			document: `{"baz": "qux", "foo": "bar"}`,
+			patch:    `[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+			expected: `{"baz": "boo", "foo": "bar"}`,
+		},
+		{
+			name:     "move-array-element",
+			document: `{"foo": ["all", "grass", "cows", "eat"]}`,
+			patch:    `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			expected: `{"foo": ["all", "cows", "eat", "grass"]}`,
+		},
+		{
+			name:     "copy-value",
+			document: `{"foo": {"bar": [1]}}`,
+			patch:    `[{"op": "copy", "from": "/foo/bar", "path": "/baz"}, {"op": "add", "path": "/baz/-", "value": 2}]`,
+			expected: `{"foo": {"bar": [1]}, "baz": [1, 2]}`,
+		},
+		{
+			name:     "escaped-pointer",
+			document: `{"a/b": 1, "m~n": 2}`,
+			patch:    `[{"op": "test", "path": "/a~1b", "value": 1}, {"op": "remove", "path": "/m~0n"}]`,
+			expected: `{"a/b": 1}`,
+		},
+		{
+			name:     "test-failed",
+			document: `{"baz": "qux"}`,
+			patch:    `[{"op": "test", "path": "/baz", "value": "bar"}]`,
+			err:      ErrTestFailed,
+		},
+		{
+			name:     "remove-missing",
+			document: `{"foo": ["bar"]}`,
+			patch:    `[{"op": "remove", "path": "/foo/1"}]`,
+			err:      ErrPathNotFound,
+		},
+		{
+			name:     "add-to-missing-parent",
+			document: `{"foo": "bar"}`,
+			patch:    `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+			err:      ErrPathNotFound,
+		},
+		{
+			name:     "unknown-op",
+			document: `{"foo": "bar"}`,
+			patch:    `[{"op": "upsert", "path": "/foo", "value": "qux"}]`,
+			err:      ErrInvalidPatch,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			operations, err := DecodePatch([]byte(testCase.patch))
+			assert.NoError(t, err)
+
+			patched, err := ApplyPatch([]byte(testCase.document), operations)
+			if testCase.err != nil {
+				assert.Equal(t, testCase.err, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.JSONEq(t, testCase.expected, string(patched))
+		})
+	}
+}
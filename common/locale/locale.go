@@ -0,0 +1,145 @@
+package locale
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Canonical formats a BCP 47 language tag such as "en-us" into its
+// canonical case, e.g. "en-US", and tells whether the tag is well formed.
+// Language is lower case, script title case and region upper case
+func Canonical(tag string) (string, bool) {
+	subtags := strings.Split(strings.TrimSpace(tag), "-")
+
+	if !isLanguage(subtags[0]) {
+		return "", false
+	}
+
+	for i, subtag := range subtags {
+		if len(subtag) == 0 || len(subtag) > 8 || !isAlphaNum(subtag) {
+			return "", false
+		}
+
+		switch {
+		case i == 0:
+			subtags[i] = strings.ToLower(subtag)
+		case len(subtag) == 4 && isAlpha(subtag):
+			subtags[i] = strings.ToUpper(subtag[:1]) + strings.ToLower(subtag[1:])
+		case len(subtag) == 2 && isAlpha(subtag):
+			subtags[i] = strings.ToUpper(subtag)
+		default:
+			subtags[i] = strings.ToLower(subtag)
+		}
+	}
+	return strings.Join(subtags, "-"), true
+}
+
+// Parent strips the last subtag of tag, e.g. "zh-Hant-TW" is
+// parent of "zh-Hant". Tags without subtags have no parent
+func Parent(tag string) (string, bool) {
+	index := strings.LastIndex(tag, "-")
+	if index < 0 {
+		return "", false
+	}
+	return tag[:index], true
+}
+
+// ParseAcceptLanguage lists canonical tags of Accept-Language header,
+// most preferred first. Wildcards, malformed tags and tags
+// weighted q=0 are left out
+func ParseAcceptLanguage(header string) []string {
+	type weightedTag struct {
+		tag    string
+		weight float64
+	}
+
+	var weighted []weightedTag
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		tag, ok := Canonical(params[0])
+
+		if !ok {
+			continue
+		}
+
+		weight := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			if value, err := strconv.ParseFloat(param[2:], 64); err == nil {
+				weight = value
+			}
+		}
+
+		if weight > 0 {
+			weighted = append(weighted, weightedTag{tag: tag, weight: weight})
+		}
+	}
+
+	sort.SliceStable(weighted, func(i, j int) bool {
+		return weighted[i].weight > weighted[j].weight
+	})
+
+	tags := make([]string, 0, len(weighted))
+	for _, item := range weighted {
+		tags = append(tags, item.tag)
+	}
+	return tags
+}
+
+// Negotiator picks locale of response among the available ones
+type Negotiator struct {
+	// Default is the locale of untranslated content,
+	// which is always available
+	Default string
+
+	// Fallback lists locales tried in order
+	// when none of the requested ones is available
+	Fallback []string
+}
+
+// Resolve picks the first available locale, trying every requested
+// locale and then its parents, e.g. "fr-CA" then "fr", then the fallback
+// chain. Default locale is picked when there is nothing else available
+func (negotiator Negotiator) Resolve(requested []string, available func(locale string) bool) string {
+	var candidates []string
+	for _, tag := range requested {
+		for ok := true; ok; tag, ok = Parent(tag) {
+			candidates = append(candidates, tag)
+		}
+	}
+	candidates = append(candidates, negotiator.Fallback...)
+
+	for _, candidate := range candidates {
+		if candidate == negotiator.Default || available(candidate) {
+			return candidate
+		}
+	}
+	return negotiator.Default
+}
+
+// isLanguage tells whether subtag is a primary language subtag
+func isLanguage(subtag string) bool {
+	return len(subtag) >= 2 && len(subtag) <= 8 && isAlpha(subtag)
+}
+
+func isAlpha(value string) bool {
+	for _, char := range value {
+		if (char < 'a' || char > 'z') && (char < 'A' || char > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphaNum(value string) bool {
+	for _, char := range value {
+		if (char < '0' || char > '9') && !isAlpha(string(char)) {
+			return false
+		}
+	}
+	return true
+}
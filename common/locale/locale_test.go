@@ -0,0 +1,72 @@
+package locale
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonical(t *testing.T) {
+	testCases := []struct {
+		tag      string
+		expected string
+		ok       bool
+	}{
+		{tag: "en", expected: "en", ok: true},
+		{tag: "EN-us", expected: "en-US", ok: true},
+		{tag: "zh-hant-tw", expected: "zh-Hant-TW", ok: true},
+		{tag: "es-419", expected: "es-419", ok: true},
+		{tag: "", ok: false},
+		{tag: "*", ok: false},
+		{tag: "e", ok: false},
+		{tag: "en--US", ok: false},
+		{tag: "en_US", ok: false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.tag, func(t *testing.T) {
+			canonical, ok := Canonical(testCase.tag)
+
+			assert.Equal(t, testCase.ok, ok)
+			assert.Equal(t, testCase.expected, canonical)
+		})
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	header := "fr-CA;q=0.8, de;q=0, en-gb, *;q=0.5, fr;q=0.7, x_y"
+
+	assert.Equal(t, []string{"en-GB", "fr-CA", "fr"}, ParseAcceptLanguage(header))
+	assert.Empty(t, ParseAcceptLanguage(""))
+}
+
+func TestResolve(t *testing.T) {
+	translated := map[string]bool{"fr": true, "de-CH": true, "nl": true}
+	available := func(locale string) bool { return translated[locale] }
+
+	negotiator := Negotiator{Default: "en", Fallback: []string{"nl"}}
+
+	testCases := []struct {
+		name      string
+		requested []string
+		expected  string
+	}{
+		{name: "exact", requested: []string{"de-CH"}, expected: "de-CH"},
+		{name: "parent", requested: []string{"fr-CA"}, expected: "fr"},
+		{name: "preference-order", requested: []string{"es", "fr", "de-CH"}, expected: "fr"},
+		{name: "default-requested", requested: []string{"en-US", "fr"}, expected: "en"},
+		{name: "fallback", requested: []string{"de-AT"}, expected: "nl"},
+		{name: "nothing-requested", expected: "nl"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, negotiator.Resolve(testCase.requested, available))
+		})
+	}
+
+	t.Run("default", func(t *testing.T) {
+		negotiator := Negotiator{Default: "en"}
+		assert.Equal(t, "en", negotiator.Resolve([]string{"ja"}, available))
+	})
+}
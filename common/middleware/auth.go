@@ -13,11 +13,6 @@ import (
 	"github.com/iqdf/benjerry-service/common/consts/role"
 )
 
-// AuthKey to get authentication context from request
-type AuthKey string
-
-const authKey AuthKey = "authentication"
-
 // AuthMiddleWare ...
 func AuthMiddleWare(service *auth.Service) alice.Constructor {
 	verifyAuthenticated := func(next http.Handler) http.Handler {
@@ -37,7 +32,7 @@ func AuthMiddleWare(service *auth.Service) alice.Constructor {
 			sessionToken := cookie.Value
 
 			// Retrieve credential from cache and verify
-			auth, verified, err := service.VerifyToken(sessionToken)
+			authentication, verified, err := service.VerifyToken(sessionToken)
 
 			if !verified {
 				w.WriteHeader(http.StatusUnauthorized)
@@ -46,7 +41,7 @@ func AuthMiddleWare(service *auth.Service) alice.Constructor {
 			}
 
 			fmt.Println("inserting auth to context")
-			ctx := context.WithValue(r.Context(), authKey, auth)
+			ctx := auth.NewContext(r.Context(), authentication)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -67,18 +62,14 @@ func RoleMiddleWare(appName string) alice.Constructor {
 		return role.Unauthorized
 	}
 
-	verifyFromContext := func(ctx context.Context, k AuthKey, requiredRole string) bool {
+	verifyFromContext := func(ctx context.Context, requiredRole string) bool {
 		if requiredRole == role.Unauthorized {
 			return false
 		}
 
-		if v := ctx.Value(authKey); v == nil {
-			// authorization context isn't set
-			fmt.Println("ctx auth not set?", v)
-			return false
-		} else if auth, ok := v.(auth.Authentication); !ok {
+		if auth, ok := auth.FromContext(ctx); !ok {
 			// authorization context isn't set
-			fmt.Println("cannot cast auth object")
+			fmt.Println("ctx auth not set?")
 			return false
 		} else if len(auth.Authorizations) > 0 {
 			// check authorization roles here
@@ -106,7 +97,7 @@ func RoleMiddleWare(appName string) alice.Constructor {
 			role = getRequiredRole(routeName)
 
 			fmt.Println(role, routeName)
-			if verifyFromContext(ctx, authKey, role) == false {
+			if verifyFromContext(ctx, role) == false {
 				w.WriteHeader(http.StatusForbidden)
 				w.Write([]byte("Operation not permitted"))
 				return // important!
@@ -0,0 +1,259 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+const (
+	migrationCollectionName     = "schema_migrations"
+	migrationLockCollectionName = "schema_migrations_lock"
+	migrationLockID             = "migrate"
+)
+
+// migrationLockExpiry frees the lock of an instance that stopped
+// while migrating, its owner renews it every third of the expiry
+// while migrating. migrationLockPoll is how often instances waiting
+// for the lock try to take it
+var (
+	migrationLockExpiry = 10 * time.Minute
+	migrationLockPoll   = time.Second
+)
+
+// Migration is a single change of the database, applied once in
+// order of Version and recorded in schema_migrations collection.
+// Mongo cannot change indexes within transactions, so Up and Down
+// must be safe to run again after failing halfway
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+	Down    func(ctx context.Context, db *mongo.Database) error
+}
+
+// MigrationStatus tells whether a migration has been applied
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// migrationModel records an applied migration
+type migrationModel struct {
+	Version   int64     `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"appliedAt"`
+}
+
+// Migrator applies and reverts migrations of a database. Instances
+// migrating the same database at once take turns by a lock document,
+// which expires in case its owner stopped before releasing it
+type Migrator struct {
+	db         *mongo.Database
+	migrations []Migration
+}
+
+// NewMigrator creates migrator of database dbName
+func NewMigrator(client *mongo.Client, dbName string) *Migrator {
+	return &Migrator{
+		db:         client.Database(dbName),
+		migrations: migrations,
+	}
+}
+
+// Up applies pending migrations in order and returns
+// the number of migrations applied
+func (migrator *Migrator) Up(ctx context.Context) (int, error) {
+	ctx, unlock, err := migrator.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	appliedAt, err := migrator.appliedAt(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var applied int
+	collection := migrator.db.Collection(migrationCollectionName)
+	for _, migration := range migrator.migrations {
+		if _, ok := appliedAt[migration.Version]; ok {
+			continue
+		}
+
+		if err = migration.Up(ctx, migrator.db); err != nil {
+			return applied, err
+		}
+
+		_, err = collection.InsertOne(ctx, migrationModel{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return applied, TranslateError(err)
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+// Down reverts the latest applied migration and returns it.
+// It is not found when no migration has been applied
+func (migrator *Migrator) Down(ctx context.Context) (Migration, error) {
+	ctx, unlock, err := migrator.lock(ctx)
+	if err != nil {
+		return Migration{}, err
+	}
+	defer unlock()
+
+	var model migrationModel
+	collection := migrator.db.Collection(migrationCollectionName)
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})
+
+	err = collection.FindOne(ctx, bson.M{}, findOptions).Decode(&model)
+	if err != nil {
+		return Migration{}, TranslateError(err)
+	}
+
+	migration, ok := migrator.migration(model.Version)
+	if !ok {
+		return Migration{}, fmt.Errorf("migration %d %q is unknown to this version of the service", model.Version, model.Name)
+	}
+
+	if err = migration.Down(ctx, migrator.db); err != nil {
+		return migration, err
+	}
+
+	_, err = collection.DeleteOne(ctx, bson.M{"_id": migration.Version})
+	return migration, TranslateError(err)
+}
+
+// Statuses lists every migration and when it was applied
+func (migrator *Migrator) Statuses(ctx context.Context) ([]MigrationStatus, error) {
+	appliedAt, err := migrator.appliedAt(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrator.migrations))
+	for _, migration := range migrator.migrations {
+		status := MigrationStatus{Migration: migration}
+		if at, ok := appliedAt[migration.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// migration finds migration of version
+func (migrator *Migrator) migration(version int64) (Migration, bool) {
+	for _, migration := range migrator.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// appliedAt maps version of applied migrations to when they were applied
+func (migrator *Migrator) appliedAt(ctx context.Context) (map[int64]time.Time, error) {
+	var models []migrationModel
+
+	collection := migrator.db.Collection(migrationCollectionName)
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, TranslateError(err)
+	}
+
+	if err = cursor.All(ctx, &models); err != nil {
+		return nil, TranslateError(err)
+	}
+
+	appliedAt := make(map[int64]time.Time, len(models))
+	for _, model := range models {
+		appliedAt[model.Version] = model.AppliedAt.UTC()
+	}
+	return appliedAt, nil
+}
+
+// lock waits until it takes the migration lock and returns the
+// context to migrate in, along with the function releasing the lock.
+// The lock is taken by a single upsert, which either inserts the lock
+// document, or takes it over once it expired. While another instance
+// holds the lock, the upsert inserts a second lock document of the
+// same id, which the _id index rejects
+func (migrator *Migrator) lock(ctx context.Context) (context.Context, func(), error) {
+	owner := primitive.NewObjectID()
+	collection := migrator.db.Collection(migrationLockCollectionName)
+
+	for {
+		now := time.Now().UTC()
+		_, err := collection.UpdateOne(ctx,
+			bson.M{"_id": migrationLockID, "expiresAt": bson.M{"$lt": now}},
+			bson.M{"$set": bson.M{"owner": owner, "lockedAt": now, "expiresAt": now.Add(migrationLockExpiry)}},
+			options.Update().SetUpsert(true),
+		)
+
+		if err == nil {
+			lockCtx, cancel := context.WithCancel(ctx)
+			renewed := make(chan struct{})
+			go func() {
+				defer close(renewed)
+				migrator.renewLock(lockCtx, cancel, owner)
+			}()
+
+			return lockCtx, func() {
+				cancel()
+				<-renewed
+				collection.DeleteOne(context.Background(), bson.M{"_id": migrationLockID, "owner": owner})
+			}, nil
+		}
+
+		if err = TranslateError(err); err != domain.ErrConflict {
+			return nil, nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(migrationLockPoll):
+		}
+	}
+}
+
+// renewLock extends the lock of owner until ctx is done, so that
+// migrations running longer than migrationLockExpiry keep the lock.
+// Once the lock was taken over by another instance, migrating is
+// cancelled rather than carried on by two instances at once
+func (migrator *Migrator) renewLock(ctx context.Context, cancel context.CancelFunc, owner primitive.ObjectID) {
+	collection := migrator.db.Collection(migrationLockCollectionName)
+	ticker := time.NewTicker(migrationLockExpiry / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		result, err := collection.UpdateOne(ctx,
+			bson.M{"_id": migrationLockID, "owner": owner},
+			bson.M{"$set": bson.M{"expiresAt": time.Now().UTC().Add(migrationLockExpiry)}},
+		)
+		if err == nil && result.MatchedCount == 0 {
+			cancel()
+			return
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// connectTestDB connects to mongo of MONGO_TEST_URI and names a
+// database of the test, which is dropped once the test is done.
+// Tests are skipped when no mongo is given
+func connectTestDB(t *testing.T) (*mongo.Client, string) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dbName := fmt.Sprint("benjerry_test_", time.Now().UnixNano())
+	t.Cleanup(func() {
+		client.Database(dbName).Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	return client, dbName
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.TODO()
+	client, dbName := connectTestDB(t)
+	migrator := NewMigrator(client, dbName)
+
+	t.Run("Up", func(t *testing.T) {
+		statuses, err := migrator.Statuses(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, statuses, len(migrations))
+		assert.Nil(t, statuses[0].AppliedAt)
+
+		applied, err := migrator.Up(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, len(migrations), applied)
+
+		// applied migrations are not applied again
+		applied, err = migrator.Up(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, applied)
+
+		statuses, _ = migrator.Statuses(ctx)
+		for _, status := range statuses {
+			assert.NotNil(t, status.AppliedAt, status.Name)
+		}
+	})
+
+	t.Run("Down", func(t *testing.T) {
+		latest := migrations[len(migrations)-1]
+
+		reverted, err := migrator.Down(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, latest.Version, reverted.Version)
+
+		statuses, _ := migrator.Statuses(ctx)
+		assert.Nil(t, statuses[len(statuses)-1].AppliedAt)
+		assert.NotNil(t, statuses[0].AppliedAt)
+
+		applied, err := migrator.Up(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, applied)
+	})
+
+	t.Run("Down-none-applied", func(t *testing.T) {
+		for range migrations {
+			_, err := migrator.Down(ctx)
+			assert.NoError(t, err)
+		}
+
+		_, err := migrator.Down(ctx)
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+}
+
+func TestSeedProductIDCounter(t *testing.T) {
+	ctx := context.TODO()
+	client, dbName := connectTestDB(t)
+	db := client.Database(dbName)
+
+	// productIds given before the sequence existed,
+	// including those of trashed products
+	_, err := db.Collection("IceCream").InsertMany(ctx, []interface{}{
+		bson.M{"productId": "646"},
+		bson.M{"productId": "1984", "deletedAt": time.Now()},
+		bson.M{"productId": "cherry-garcia"},
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, seedProductIDCounter(ctx, db))
+
+	var counter struct {
+		Value int64 `bson:"value"`
+	}
+	assert.NoError(t, db.Collection("Counter").FindOne(ctx, bson.M{"_id": "productId"}).Decode(&counter))
+	assert.Equal(t, int64(1984), counter.Value)
+
+	// counter ahead of stored productIds is not moved back
+	_, err = db.Collection("IceCream").DeleteMany(ctx, bson.M{})
+	assert.NoError(t, err)
+	assert.NoError(t, seedProductIDCounter(ctx, db))
+	assert.NoError(t, db.Collection("Counter").FindOne(ctx, bson.M{"_id": "productId"}).Decode(&counter))
+	assert.Equal(t, int64(1984), counter.Value)
+}
+
+func TestMigratorLock(t *testing.T) {
+	ctx := context.TODO()
+	client, dbName := connectTestDB(t)
+
+	// locks expire sooner than migrations run,
+	// so that only renewed locks are kept
+	defaultExpiry, defaultPoll := migrationLockExpiry, migrationLockPoll
+	migrationLockExpiry, migrationLockPoll = 300*time.Millisecond, 10*time.Millisecond
+	defer func() { migrationLockExpiry, migrationLockPoll = defaultExpiry, defaultPoll }()
+
+	t.Run("Up-concurrent", func(t *testing.T) {
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			applied int
+		)
+
+		// instances take turns, so that every
+		// migration is applied by one of them
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				count, err := NewMigrator(client, dbName).Up(ctx)
+				assert.NoError(t, err)
+
+				mu.Lock()
+				applied += count
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, len(migrations), applied)
+	})
+
+	t.Run("Lock-held", func(t *testing.T) {
+		migrator := NewMigrator(client, dbName)
+		_, unlock, err := migrator.lock(ctx)
+		assert.NoError(t, err)
+
+		waitCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		_, err = NewMigrator(client, dbName).Down(waitCtx)
+		assert.Equal(t, context.DeadlineExceeded, err)
+
+		unlock()
+		_, unlock, err = migrator.lock(ctx)
+		assert.NoError(t, err)
+		unlock()
+	})
+
+	t.Run("Lock-renewed", func(t *testing.T) {
+		// lock is kept while migrating longer than its expiry
+		_, unlock, err := NewMigrator(client, dbName).lock(ctx)
+		assert.NoError(t, err)
+		defer unlock()
+		time.Sleep(3 * migrationLockExpiry)
+
+		waitCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		_, err = NewMigrator(client, dbName).Down(waitCtx)
+		assert.Equal(t, context.DeadlineExceeded, err)
+	})
+
+	t.Run("Lock-lost", func(t *testing.T) {
+		lockCtx, unlock, err := NewMigrator(client, dbName).lock(ctx)
+		assert.NoError(t, err)
+		defer unlock()
+
+		// migrating is cancelled once another
+		// instance took over the lock
+		collection := client.Database(dbName).Collection(migrationLockCollectionName)
+		_, err = collection.UpdateOne(ctx,
+			bson.M{"_id": migrationLockID},
+			bson.M{"$set": bson.M{"owner": primitive.NewObjectID()}},
+		)
+		assert.NoError(t, err)
+
+		select {
+		case <-lockCtx.Done():
+		case <-time.After(2 * time.Second):
+			t.Error("migrating went on after the lock was taken over")
+		}
+		_, err = collection.DeleteOne(ctx, bson.M{"_id": migrationLockID})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Lock-expired", func(t *testing.T) {
+		// lock of an instance that stopped while migrating
+		// is no longer renewed, and taken over once it expires
+		stoppedCtx, stop := context.WithCancel(ctx)
+		_, _, err := NewMigrator(client, dbName).lock(stoppedCtx)
+		assert.NoError(t, err)
+		stop()
+
+		waitCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		_, err = NewMigrator(client, dbName).Up(waitCtx)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,228 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+)
+
+// migrations create and evolve indexes and documents, in order of
+// version. Indexes are named as repositories created them before
+// migrations existed, so that databases created back then migrate
+// without conflicts, and product_gtins and product_slug are the names
+// repositories tell duplicate GTINs and slugs by. Applied migrations
+// must never change, changes are appended as new migrations
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create product indexes",
+		Up: createIndexes("IceCream",
+			// unique productIds, trashed products keep
+			// theirs until they are purged
+			mongo.IndexModel{
+				Keys:    bsonx.Doc{{Key: "productId", Value: bsonx.Int32(1)}},
+				Options: options.Index().SetName("productId_1").SetUnique(true),
+			},
+			// unique SKU of variants across products, sparse
+			// so that products without variants pass
+			mongo.IndexModel{
+				Keys:    bsonx.Doc{{Key: "variants.sku", Value: bsonx.Int32(1)}},
+				Options: options.Index().SetName("variants.sku_1").SetUnique(true).SetSparse(true),
+			},
+			// unique GTINs across products, sparse so
+			// that products without GTINs pass
+			mongo.IndexModel{
+				Keys:    bsonx.Doc{{Key: "gtins", Value: bsonx.Int32(1)}},
+				Options: options.Index().SetName("product_gtins").SetUnique(true).SetSparse(true),
+			},
+			// unique slugs across products, sparse so
+			// that products stored before slugs pass
+			mongo.IndexModel{
+				Keys:    bsonx.Doc{{Key: "slug", Value: bsonx.Int32(1)}},
+				Options: options.Index().SetName("product_slug").SetUnique(true).SetSparse(true),
+			},
+			// previous slugs of renamed products
+			mongo.IndexModel{
+				Keys:    bsonx.Doc{{Key: "previousSlugs", Value: bsonx.Int32(1)}},
+				Options: options.Index().SetName("previousSlugs_1"),
+			},
+			// products sold in a region
+			mongo.IndexModel{
+				Keys:    bsonx.Doc{{Key: "regions", Value: bsonx.Int32(1)}},
+				Options: options.Index().SetName("regions_1"),
+			},
+			// weighted full-text search, matches in
+			// name rank higher than matches in story
+			mongo.IndexModel{
+				Keys: bsonx.Doc{
+					{Key: "name", Value: bsonx.String("text")},
+					{Key: "description", Value: bsonx.String("text")},
+					{Key: "story", Value: bsonx.String("text")},
+					{Key: "ingredients", Value: bsonx.String("text")},
+				},
+				Options: options.Index().
+					SetName("product_text").
+					SetWeights(bson.M{"name": 10, "description": 5, "ingredients": 3, "story": 1}),
+			},
+		),
+		Down: dropIndexes("IceCream",
+			"productId_1", "variants.sku_1", "product_gtins", "product_slug",
+			"previousSlugs_1", "regions_1", "product_text",
+		),
+	},
+	{
+		Version: 2,
+		Name:    "create product revision indexes",
+		Up: createIndexes("IceCreamRevision",
+			// each revision number is taken only once
+			mongo.IndexModel{
+				Keys: bsonx.Doc{
+					{Key: "productId", Value: bsonx.Int32(1)},
+					{Key: "revision", Value: bsonx.Int32(1)},
+				},
+				Options: options.Index().SetName("productId_1_revision_1").SetUnique(true),
+			},
+		),
+		Down: dropIndexes("IceCreamRevision", "productId_1_revision_1"),
+	},
+	{
+		Version: 3,
+		Name:    "create user indexes",
+		Up: createIndexes("User",
+			mongo.IndexModel{
+				Keys:    bsonx.Doc{{Key: "username", Value: bsonx.Int32(1)}},
+				Options: options.Index().SetName("username_1").SetUnique(true),
+			},
+		),
+		Down: dropIndexes("User", "username_1"),
+	},
+	{
+		Version: 4,
+		Name:    "create price indexes",
+		Up: createIndexes("IceCreamPrice",
+			// at most one price of a product, or of its variant, in
+			// a region and currency takes effect at a time
+			mongo.IndexModel{
+				Keys: bsonx.Doc{
+					{Key: "productId", Value: bsonx.Int32(1)},
+					{Key: "region", Value: bsonx.Int32(1)},
+					{Key: "currency", Value: bsonx.Int32(1)},
+					{Key: "sku", Value: bsonx.Int32(1)},
+					{Key: "valid_from", Value: bsonx.Int32(-1)},
+				},
+				Options: options.Index().SetName("productId_1_region_1_currency_1_sku_1_valid_from_-1").SetUnique(true),
+			},
+		),
+		Down: dropIndexes("IceCreamPrice", "productId_1_region_1_currency_1_sku_1_valid_from_-1"),
+	},
+	{
+		Version: 5,
+		Name:    "create product trash index",
+		Up: createIndexes("IceCream",
+			// trashed products by deletion time, sparse so
+			// that products not in trash are left out
+			mongo.IndexModel{
+				Keys:    bsonx.Doc{{Key: "deletedAt", Value: bsonx.Int32(1)}},
+				Options: options.Index().SetName("deletedAt_1").SetSparse(true),
+			},
+		),
+		Down: dropIndexes("IceCream", "deletedAt_1"),
+	},
+	{
+		Version: 6,
+		Name:    "create retired product index",
+		Up: createIndexes("IceCream",
+			// graveyard pages, latest retired first
+			mongo.IndexModel{
+				Keys: bsonx.Doc{
+					{Key: "status", Value: bsonx.Int32(1)},
+					{Key: "retiredAt", Value: bsonx.Int32(-1)},
+					{Key: "productId", Value: bsonx.Int32(-1)},
+				},
+				Options: options.Index().SetName("status_1_retiredAt_-1_productId_-1"),
+			},
+		),
+		Down: dropIndexes("IceCream", "status_1_retiredAt_-1_productId_-1"),
+	},
+	{
+		Version: 7,
+		Name:    "seed product id counter",
+		Up:      seedProductIDCounter,
+		// the counter is kept, so that productIds
+		// allocated from it are never allocated again
+		Down: func(context.Context, *mongo.Database) error { return nil },
+	},
+}
+
+// minProductID keeps productIds allocated by the
+// sequence within the minimum length of 3 digits
+const minProductID = 100
+
+// seedProductIDCounter seeds the sequence of productIds with the greatest
+// numeric productId, including those of products in trash, so that ids
+// given by clients before the sequence existed are not allocated again.
+// $max never moves the counter back once it is ahead
+func seedProductIDCounter(ctx context.Context, db *mongo.Database) error {
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"productId": bson.M{"$regex": "^[0-9]{1,18}$"}}},
+		bson.M{"$group": bson.M{"_id": nil, "max": bson.M{"$max": bson.M{"$toLong": "$productId"}}}},
+	}
+
+	cursor, err := db.Collection("IceCream").Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+
+	var results []struct {
+		Max int64 `bson:"max"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return err
+	}
+
+	maxID := int64(minProductID - 1)
+	if len(results) > 0 && results[0].Max > maxID {
+		maxID = results[0].Max
+	}
+
+	_, err = db.Collection("Counter").UpdateOne(ctx,
+		bson.M{"_id": "productId"},
+		bson.M{"$max": bson.M{"value": maxID}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// createIndexes migrates collection up by creating indexes, which
+// mongo leaves as they are when they already exist
+func createIndexes(collectionName string, indexes ...mongo.IndexModel) func(context.Context, *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection(collectionName).Indexes().CreateMany(ctx, indexes)
+		return err
+	}
+}
+
+// dropIndexes migrates collection down by dropping indexes of
+// names, those that do not exist (anymore) are skipped
+func dropIndexes(collectionName string, names ...string) func(context.Context, *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		indexes := db.Collection(collectionName).Indexes()
+		for _, name := range names {
+			_, err := indexes.DropOne(ctx, name)
+			if err != nil && !isNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// isNotFound tells whether command failed on a missing index or
+// collection, which mongo reports as IndexNotFound and NamespaceNotFound
+func isNotFound(err error) bool {
+	commandErr, ok := err.(mongo.CommandError)
+	return ok && (commandErr.Code == 27 || commandErr.Code == 26)
+}
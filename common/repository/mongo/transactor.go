@@ -0,0 +1,72 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// Transactor runs functions within multi-document transactions,
+// which take a replica set or a sharded cluster
+type Transactor struct {
+	client *mongo.Client
+}
+
+// NewTransactor creates transactor over sessions of client
+func NewTransactor(client *mongo.Client) *Transactor {
+	return &Transactor{client: client}
+}
+
+// WithinTransaction runs fn within a transaction, which is committed
+// when fn succeeds and aborted otherwise. Repositories take part in the
+// transaction through the session carried by ctx given to fn. Errors of
+// fn are returned as they are, other errors are translated
+func (transactor *Transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	supported, err := transactor.supportsTransactions(ctx)
+	if err != nil {
+		return TranslateError(err)
+	}
+
+	if !supported {
+		return domain.ErrTransactionUnsupported
+	}
+
+	var fnErr error
+	err = transactor.client.UseSession(ctx, func(sessionCtx mongo.SessionContext) error {
+		_, err := sessionCtx.WithTransaction(sessionCtx, func(txCtx mongo.SessionContext) (interface{}, error) {
+			fnErr = fn(txCtx)
+			return nil, fnErr
+		})
+		return err
+	})
+
+	switch {
+	case err == nil:
+		return nil
+	case fnErr != nil:
+		return fnErr
+	}
+	return TranslateError(err)
+}
+
+// supportsTransactions tells whether the deployment is a
+// replica set or a sharded cluster, standalone servers
+// reject transactions
+func (transactor *Transactor) supportsTransactions(ctx context.Context) (bool, error) {
+	var reply struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+
+	err := transactor.client.Database("admin").
+		RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).
+		Decode(&reply)
+
+	if err != nil {
+		return false, err
+	}
+	return reply.SetName != "" || reply.Msg == "isdbgrid", nil
+}
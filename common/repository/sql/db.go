@@ -0,0 +1,129 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
+
+	// postgres driver, registered as "postgres"
+	_ "github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Dialect of SQL spoken by the database, see constants below
+type Dialect string
+
+// Enum for supported SQL databases
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// sqliteDriver names sqlite driver whose connections
+// enforce foreign keys and match regular expressions
+const sqliteDriver = "sqlite3_benjerry"
+
+func init() {
+	sql.Register(sqliteDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if _, err := conn.Exec("PRAGMA foreign_keys = ON", nil); err != nil {
+				return err
+			}
+
+			// sqlite parses "x REGEXP y" but leaves
+			// regexp(y, x) to be defined by application
+			return conn.RegisterFunc("regexp", func(pattern, text string) (bool, error) {
+				return regexp.MatchString(pattern, text)
+			}, true)
+		},
+	})
+}
+
+// Querier runs queries either on the database or within a transaction
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// DB is a database of dialect, whose queries are written
+// with ? placeholders whatever placeholders dialect takes
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+}
+
+// Open opens database of dialect at dsn, e.g. file:benjerry.db
+// for sqlite or postgres://localhost/benjerry for postgres
+func Open(dialect Dialect, dsn string) (*DB, error) {
+	driver := string(dialect)
+	if dialect == SQLite {
+		driver = sqliteDriver
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// sqlite locks the whole database while writing, a single
+	// connection serializes writers rather than failing them
+	if dialect == SQLite {
+		db.SetMaxOpenConns(1)
+	}
+
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &DB{DB: db, Dialect: dialect}, nil
+}
+
+// Rebind rewrites ? placeholders of query into
+// the placeholders of the database dialect
+func (db *DB) Rebind(query string) string {
+	if db.Dialect != Postgres {
+		return query
+	}
+
+	var (
+		rebound strings.Builder
+		n       int
+	)
+	for _, r := range query {
+		if r == '?' {
+			n++
+			rebound.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		rebound.WriteRune(r)
+	}
+	return rebound.String()
+}
+
+// MatchesWords is a condition matching column against any of the
+// words as a whole word, ignoring case. Words are regular expressions,
+// the pattern is the single argument of the condition
+func (db *DB) MatchesWords(column string, words []string) (string, string) {
+	alternatives := strings.Join(words, "|")
+	if db.Dialect == Postgres {
+		return column + ` ~* ?`, `\y(` + alternatives + `)\y`
+	}
+	return column + ` REGEXP ?`, `(?i)\b(` + alternatives + `)\b`
+}
+
+// ForUpdate locks rows selected within a transaction until it ends,
+// sqlite locks the whole database on write and takes no locking clause
+func (db *DB) ForUpdate() string {
+	if db.Dialect == Postgres {
+		return " FOR UPDATE"
+	}
+	return ""
+}
+
+// Placeholders lists n comma separated ? placeholders, e.g. for IN
+func Placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,102 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func openTestDB(t *testing.T) (*DB, func()) {
+	dir, err := ioutil.TempDir("", "benjerry")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := Open(SQLite, "file:"+filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.TODO()
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	statuses, err := db.MigrationStatuses(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, statuses, len(migrations))
+	assert.Nil(t, statuses[0].AppliedAt)
+
+	applied, err := db.Migrate(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, len(migrations), applied)
+
+	// applied migrations are not applied again
+	applied, err = db.Migrate(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, applied)
+
+	statuses, _ = db.MigrationStatuses(ctx)
+	for _, status := range statuses {
+		assert.NotNil(t, status.AppliedAt, status.Name)
+	}
+}
+
+func TestWithinTransaction(t *testing.T) {
+	ctx := context.TODO()
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+	db.Migrate(ctx)
+
+	insert := func(ctx context.Context, username string) error {
+		_, err := db.Conn(ctx).ExecContext(ctx, "INSERT INTO users (username, hash_password) VALUES (?, '')", username)
+		return TranslateError(err)
+	}
+	count := func() (n int) {
+		db.QueryRow("SELECT COUNT(*) FROM users").Scan(&n)
+		return n
+	}
+
+	t.Run("Rollback-on-error", func(t *testing.T) {
+		failed := errors.New("failed")
+		err := db.WithinTransaction(ctx, func(ctx context.Context) error {
+			insert(ctx, "ben")
+			return failed
+		})
+		assert.Equal(t, failed, err)
+		assert.Equal(t, 0, count())
+	})
+
+	t.Run("Savepoint-rolls-back-nested", func(t *testing.T) {
+		err := db.WithinTransaction(ctx, func(ctx context.Context) error {
+			insert(ctx, "ben")
+			nested := db.WithinTransaction(ctx, func(ctx context.Context) error {
+				insert(ctx, "jerry")
+				return insert(ctx, "ben")
+			})
+			assert.Equal(t, domain.ErrConflict, nested)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count())
+	})
+}
+
+func TestRebind(t *testing.T) {
+	postgres := &DB{Dialect: Postgres}
+	assert.Equal(t, "a = $1 AND b IN ($2, $3)", postgres.Rebind("a = ? AND b IN ("+Placeholders(2)+")"))
+
+	sqlite := &DB{Dialect: SQLite}
+	assert.Equal(t, "a = ?", sqlite.Rebind("a = ?"))
+}
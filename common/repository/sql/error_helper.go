@@ -0,0 +1,51 @@
+package sql
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// postgresUniqueViolation is the SQLSTATE of unique constraint violations
+const postgresUniqueViolation = "23505"
+
+// ViolatedUnique names the unique constraint violated by dbError, as
+// "table.column" for sqlite and by constraint name for postgres. Empty
+// when dbError is not a unique constraint violation
+func ViolatedUnique(dbError error) string {
+	switch err := dbError.(type) {
+	case sqlite3.Error:
+		if err.ExtendedCode == sqlite3.ErrConstraintUnique || err.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+			// e.g. UNIQUE constraint failed: products.slug
+			message := err.Error()
+			return strings.TrimSpace(message[strings.LastIndex(message, ":")+1:])
+		}
+	case *pq.Error:
+		if err.Code == postgresUniqueViolation {
+			return err.Constraint
+		}
+	}
+	return ""
+}
+
+// TranslateError converts SQL DB error into
+// approriate application errors
+func TranslateError(dbError error) error {
+	if dbError == nil {
+		return nil
+	}
+
+	switch {
+	case dbError == sql.ErrNoRows:
+		return domain.ErrResourceNotFound
+
+	case ViolatedUnique(dbError) != "":
+		return domain.ErrConflict
+	}
+
+	return domain.ErrInternalServerError
+}
@@ -0,0 +1,135 @@
+package sql
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// migrationLockID keys the postgres advisory lock held while
+// migrating, so that instances started together migrate in turn
+const migrationLockID = 7246
+
+// Migration is a single change of the schema, applied once in
+// order of Version and recorded in schema_migrations table
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+}
+
+// MigrationStatus tells whether a migration has been applied
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// statements of migration in the database dialect
+func (db *DB) statements(migration Migration) string {
+	serial := "INTEGER PRIMARY KEY AUTOINCREMENT"
+	timestamp := "TIMESTAMP"
+	if db.Dialect == Postgres {
+		serial = "BIGSERIAL PRIMARY KEY"
+		timestamp = "TIMESTAMPTZ"
+	}
+
+	return strings.NewReplacer("{serial}", serial, "{timestamp}", timestamp).Replace(migration.Up)
+}
+
+// Migrate applies pending migrations in order, each within its own
+// transaction, and returns the number of migrations applied
+func (db *DB) Migrate(ctx context.Context) (int, error) {
+	if err := db.createMigrationTable(ctx); err != nil {
+		return 0, err
+	}
+
+	var applied int
+	for _, migration := range migrations {
+		var done bool
+		err := db.WithinTransaction(ctx, func(ctx context.Context) error {
+			conn := db.Conn(ctx)
+			if db.Dialect == Postgres {
+				if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockID); err != nil {
+					return err
+				}
+			}
+
+			var count int
+			row := conn.QueryRowContext(ctx, db.Rebind("SELECT COUNT(*) FROM schema_migrations WHERE version = ?"), migration.Version)
+			if err := row.Scan(&count); err != nil || count > 0 {
+				done = count > 0
+				return err
+			}
+
+			if _, err := conn.ExecContext(ctx, db.statements(migration)); err != nil {
+				return err
+			}
+
+			_, err := conn.ExecContext(ctx,
+				db.Rebind("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"),
+				migration.Version, migration.Name, time.Now().UTC(),
+			)
+			return err
+		})
+
+		if err != nil {
+			return applied, err
+		}
+		if !done {
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+// MigrationStatuses lists every migration and when it was applied
+func (db *DB) MigrationStatuses(ctx context.Context) ([]MigrationStatus, error) {
+	if err := db.createMigrationTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appliedAt := make(map[int64]time.Time)
+	for rows.Next() {
+		var (
+			version int64
+			at      time.Time
+		)
+		if err = rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		appliedAt[version] = at.UTC()
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		status := MigrationStatus{Migration: migration}
+		if at, ok := appliedAt[migration.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (db *DB) createMigrationTable(ctx context.Context) error {
+	timestamp := "TIMESTAMP"
+	if db.Dialect == Postgres {
+		timestamp = "TIMESTAMPTZ"
+	}
+
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    BIGINT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	applied_at `+timestamp+` NOT NULL
+)`)
+	return err
+}
@@ -0,0 +1,178 @@
+package sql
+
+// migrations create and evolve the schema, in order of version. They are
+// written once for every dialect, {serial} and {timestamp} standing for
+// the column types that differ between dialects. Applied migrations must
+// never change, schema changes are appended as new migrations
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create products",
+		Up: `
+CREATE TABLE products (
+	product_id            TEXT PRIMARY KEY,
+	version               BIGINT NOT NULL,
+	name                  TEXT NOT NULL DEFAULT '',
+	slug                  TEXT,
+	image_closed_url      TEXT NOT NULL DEFAULT '',
+	image_open_url        TEXT NOT NULL DEFAULT '',
+	description           TEXT NOT NULL DEFAULT '',
+	story                 TEXT NOT NULL DEFAULT '',
+	allergy_info          TEXT NOT NULL DEFAULT '',
+	dietary_certification TEXT NOT NULL DEFAULT '',
+	parsed_ingredients    TEXT,
+	allergen_check        TEXT,
+	nutrition             TEXT,
+	region_overrides      TEXT,
+	translations          TEXT,
+	status                TEXT NOT NULL DEFAULT '',
+	retired_at            {timestamp},
+	epitaph               TEXT NOT NULL DEFAULT '',
+	scheduled_status      TEXT NOT NULL DEFAULT '',
+	scheduled_at          {timestamp},
+	deleted_at            {timestamp},
+	deleted_by            TEXT NOT NULL DEFAULT '',
+	CONSTRAINT product_slug UNIQUE (slug)
+);
+CREATE INDEX product_name ON products (name, product_id);
+CREATE INDEX product_scheduled_at ON products (scheduled_at);
+CREATE INDEX product_deleted_at ON products (deleted_at);
+
+CREATE TABLE ingredients (
+	ingredient_id {serial},
+	name          TEXT NOT NULL,
+	CONSTRAINT ingredient_name UNIQUE (name)
+);
+
+CREATE TABLE product_ingredients (
+	product_id    TEXT NOT NULL REFERENCES products (product_id) ON DELETE CASCADE,
+	ordinal       INTEGER NOT NULL,
+	ingredient_id BIGINT NOT NULL REFERENCES ingredients (ingredient_id),
+	PRIMARY KEY (product_id, ordinal)
+);
+CREATE INDEX product_ingredient ON product_ingredients (ingredient_id);
+
+CREATE TABLE sourcing_values (
+	sourcing_value_id {serial},
+	value             TEXT NOT NULL,
+	CONSTRAINT sourcing_value UNIQUE (value)
+);
+
+CREATE TABLE product_sourcing_values (
+	product_id        TEXT NOT NULL REFERENCES products (product_id) ON DELETE CASCADE,
+	ordinal           INTEGER NOT NULL,
+	sourcing_value_id BIGINT NOT NULL REFERENCES sourcing_values (sourcing_value_id),
+	PRIMARY KEY (product_id, ordinal)
+);
+CREATE INDEX product_sourcing_value ON product_sourcing_values (sourcing_value_id);
+
+CREATE TABLE product_previous_slugs (
+	product_id TEXT NOT NULL REFERENCES products (product_id) ON DELETE CASCADE,
+	ordinal    INTEGER NOT NULL,
+	slug       TEXT NOT NULL,
+	PRIMARY KEY (product_id, ordinal)
+);
+CREATE INDEX product_previous_slug ON product_previous_slugs (slug);
+
+CREATE TABLE product_regions (
+	product_id TEXT NOT NULL REFERENCES products (product_id) ON DELETE CASCADE,
+	ordinal    INTEGER NOT NULL,
+	region     TEXT NOT NULL,
+	PRIMARY KEY (product_id, ordinal)
+);
+CREATE INDEX product_region ON product_regions (region);
+
+CREATE TABLE product_gtins (
+	gtin       TEXT PRIMARY KEY,
+	product_id TEXT NOT NULL REFERENCES products (product_id) ON DELETE CASCADE,
+	ordinal    INTEGER NOT NULL
+);
+CREATE INDEX product_gtin_product ON product_gtins (product_id);
+
+CREATE TABLE product_allergens (
+	product_id  TEXT NOT NULL REFERENCES products (product_id) ON DELETE CASCADE,
+	may_contain INTEGER NOT NULL,
+	ordinal     INTEGER NOT NULL,
+	allergen    TEXT NOT NULL,
+	PRIMARY KEY (product_id, may_contain, ordinal)
+);
+CREATE INDEX product_allergen ON product_allergens (allergen);
+
+CREATE TABLE product_variants (
+	sku              TEXT PRIMARY KEY,
+	product_id       TEXT NOT NULL REFERENCES products (product_id) ON DELETE CASCADE,
+	ordinal          INTEGER NOT NULL,
+	format           TEXT NOT NULL,
+	size             TEXT NOT NULL DEFAULT '',
+	image_closed_url TEXT NOT NULL DEFAULT '',
+	image_open_url   TEXT NOT NULL DEFAULT '',
+	ingredients      TEXT
+);
+CREATE INDEX product_variant_product ON product_variants (product_id);
+`,
+	},
+	{
+		Version: 2,
+		Name:    "create product revisions and counters",
+		Up: `
+CREATE TABLE product_revisions (
+	product_id    TEXT NOT NULL,
+	revision      BIGINT NOT NULL,
+	action        TEXT NOT NULL,
+	author        TEXT NOT NULL,
+	changed_at    {timestamp} NOT NULL,
+	changes       TEXT NOT NULL,
+	product       TEXT NOT NULL,
+	restored_from BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY (product_id, revision)
+);
+
+CREATE TABLE counters (
+	name  TEXT PRIMARY KEY,
+	value BIGINT NOT NULL
+);
+`,
+	},
+	{
+		Version: 3,
+		Name:    "create users",
+		Up: `
+CREATE TABLE users (
+	username      TEXT PRIMARY KEY,
+	hash_password TEXT NOT NULL
+);
+
+CREATE TABLE user_authorizations (
+	username TEXT NOT NULL REFERENCES users (username) ON DELETE CASCADE,
+	ordinal  INTEGER NOT NULL,
+	app_name TEXT NOT NULL,
+	role     TEXT NOT NULL,
+	PRIMARY KEY (username, ordinal)
+);
+`,
+	},
+	{
+		Version: 4,
+		Name:    "create prices",
+		Up: `
+CREATE TABLE prices (
+	price_id   {serial},
+	product_id TEXT NOT NULL,
+	sku        TEXT NOT NULL DEFAULT '',
+	region     TEXT NOT NULL,
+	amount     BIGINT NOT NULL,
+	currency   TEXT NOT NULL,
+	valid_from {timestamp} NOT NULL,
+	valid_to   {timestamp},
+	CONSTRAINT price_effective UNIQUE (product_id, region, currency, sku, valid_from)
+);
+`,
+	},
+	{
+		Version: 5,
+		Name:    "create retired product index",
+		Up: `
+CREATE INDEX product_retired_at ON products (status, retired_at, product_id);
+`,
+	},
+}
@@ -0,0 +1,67 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+)
+
+// txKey keys transaction carried by context
+type txKey struct{}
+
+// transaction is a database transaction carried by context,
+// savepoints of which nest transactions within it
+type transaction struct {
+	tx         *sql.Tx
+	savepoints int
+}
+
+// Conn gives the transaction carried by ctx, so that repositories
+// take part in it, or the database when there is none
+func (db *DB) Conn(ctx context.Context) Querier {
+	if current, ok := ctx.Value(txKey{}).(*transaction); ok {
+		return current.tx
+	}
+	return db.DB
+}
+
+// WithinTransaction runs fn within a transaction, which is committed
+// when fn succeeds and rolled back otherwise. Repositories take part in
+// the transaction through ctx given to fn. Within a transaction already
+// carried by ctx, fn runs within a savepoint instead, so that its writes
+// are rolled back without aborting the enclosing transaction. Errors of
+// fn are returned as they are, other errors are translated
+func (db *DB) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if current, ok := ctx.Value(txKey{}).(*transaction); ok {
+		return current.withinSavepoint(ctx, fn)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return TranslateError(err)
+	}
+
+	if err = fn(context.WithValue(ctx, txKey{}, &transaction{tx: tx})); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return TranslateError(tx.Commit())
+}
+
+// withinSavepoint runs fn within a savepoint of the transaction
+func (current *transaction) withinSavepoint(ctx context.Context, fn func(ctx context.Context) error) error {
+	current.savepoints++
+	savepoint := "sp" + strconv.Itoa(current.savepoints)
+
+	if _, err := current.tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return TranslateError(err)
+	}
+
+	if err := fn(ctx); err != nil {
+		current.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint)
+		return err
+	}
+
+	_, err := current.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint)
+	return TranslateError(err)
+}
@@ -22,6 +22,23 @@ var (
 
 	// message for checking string is a file path
 	FileValidateMessage = "{0} must be a valid unix file path"
+
+	// message for checking string is a region code
+	RegionValidateMessage = "{0} must be a region code such as us or uk"
+
+	// message for checking string is a barcode number
+	GTINValidateMessage = "{0} must be a GTIN-8, GTIN-12, GTIN-13 or GTIN-14 with valid check digit"
+
+	// message for checking string is a currency code
+	CurrencyValidateMessage = "{0} must be an ISO 4217 currency code such as USD or GBP"
+
+	// message for checking string is a known allergen
+	AllergenValidateMessage = "{0} must be a known allergen such as milk or peanuts"
+
+	// message for checking string is a quantity of a dimension, at most tag param
+	MassValidateMessage   = "{0} must be a mass such as 12 g or 150 mg, at most {1}"
+	VolumeValidateMessage = "{0} must be a volume such as 158 ml or 2/3 cup, at most {1}"
+	EnergyValidateMessage = "{0} must be an energy such as 250 kcal or 1046 kJ, at most {1}"
 )
 
 // setupRegisteredTranslations registers validation field
@@ -38,16 +55,25 @@ func setupRegisteredTranslations(validate *validator.Validate, trans ut.Translat
 	registerTranslation(validate, trans, "ascii", ASCIIValidateMessage)
 
 	registerTranslation(validate, trans, "file", FileValidateMessage)
+
+	registerTranslation(validate, trans, "region", RegionValidateMessage)
+	registerTranslation(validate, trans, "gtin", GTINValidateMessage)
+	registerTranslation(validate, trans, "currency", CurrencyValidateMessage)
+	registerTranslation(validate, trans, "allergen", AllergenValidateMessage)
+	registerTranslation(validate, trans, "mass", MassValidateMessage)
+	registerTranslation(validate, trans, "volume", VolumeValidateMessage)
+	registerTranslation(validate, trans, "energy", EnergyValidateMessage)
 }
 
-// registerTranslation is a helper to register translated field error
+// registerTranslation is a helper to register translated field error,
+// message may refer to field as {0} and to tag param as {1}
 func registerTranslation(v *validator.Validate, trans ut.Translator, tag string, message string) {
 	_ = v.RegisterTranslation(tag, trans,
 		func(ut ut.Translator) error {
 			return ut.Add(tag, message, true)
 		},
 		func(ut ut.Translator, fe validator.FieldError) string {
-			t, _ := ut.T(tag, fe.Field())
+			t, _ := ut.T(tag, fe.Field(), fe.Param())
 			return t
 		},
 	)
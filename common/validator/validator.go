@@ -10,6 +10,8 @@ import (
 	ut "github.com/go-playground/universal-translator"
 	"gopkg.in/go-playground/validator.v9"
 	en_translations "gopkg.in/go-playground/validator.v9/translations/en"
+
+	"github.com/iqdf/benjerry-service/domain"
 )
 
 // ValidationError ...
@@ -96,10 +98,54 @@ func init() {
 		log.Fatal("error in registering validation translator:", err)
 	}
 
+	setupCustomValidations(validate)
 	setupRegisteredTranslations(validate, trans)
 	isInit = true
 }
 
+// setupCustomValidations registers validation
+// tags of domain rules, e.g. validate:"region",
+// validate:"gtin", validate:"currency" or validate:"allergen", and
+// of quantities with their maximum, e.g. validate:"mass=100g"
+func setupCustomValidations(validate *validator.Validate) {
+	_ = validate.RegisterValidation("region", func(fl validator.FieldLevel) bool {
+		return domain.ValidRegion(fl.Field().String())
+	})
+
+	_ = validate.RegisterValidation("gtin", func(fl validator.FieldLevel) bool {
+		return domain.ValidGTIN(fl.Field().String())
+	})
+
+	_ = validate.RegisterValidation("currency", func(fl validator.FieldLevel) bool {
+		return domain.ValidCurrency(fl.Field().String())
+	})
+
+	_ = validate.RegisterValidation("allergen", func(fl validator.FieldLevel) bool {
+		return domain.ValidAllergen(fl.Field().String())
+	})
+
+	_ = validate.RegisterValidation("mass", quantityValidation(domain.DimensionMass))
+	_ = validate.RegisterValidation("volume", quantityValidation(domain.DimensionVolume))
+	_ = validate.RegisterValidation("energy", quantityValidation(domain.DimensionEnergy))
+}
+
+// quantityValidation validates quantities of dimension such as "98 g",
+// which are at most the quantity given as tag param, if any
+func quantityValidation(dimension domain.Dimension) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		quantity, err := domain.ParseQuantity(fl.Field().String())
+		if err != nil || quantity.Dimension() != dimension {
+			return false
+		}
+
+		if fl.Param() == "" {
+			return true
+		}
+		max, err := domain.ParseQuantity(fl.Param())
+		return err == nil && quantity.Base() <= max.Base()
+	}
+}
+
 // ValidateStruct ,,,
 func ValidateStruct(s interface{}) error {
 	err := validate.Struct(s)
@@ -141,3 +187,16 @@ func DecodeAndValidateJSON(field io.Reader, s interface{}) error {
 	}
 	return ValidateStruct(s)
 }
+
+// DecodeStrictAndValidateJSON is like DecodeAndValidateJSON
+// but also rejects JSON fields that are not in the struct
+func DecodeStrictAndValidateJSON(field io.Reader, s interface{}) error {
+	decoder := json.NewDecoder(field)
+	decoder.DisallowUnknownFields()
+
+	unmarshalErr := decoder.Decode(s)
+	if unmarshalErr != nil {
+		return NewValidationError(unmarshalErr)
+	}
+	return ValidateStruct(s)
+}
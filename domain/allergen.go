@@ -0,0 +1,90 @@
+package domain
+
+// KnownAllergens is the vocabulary of allergens
+// which products can be filtered and counted by
+var KnownAllergens = []string{
+	"milk",
+	"eggs",
+	"peanuts",
+	"tree nuts",
+	"soy",
+	"wheat",
+	"fish",
+	"shellfish",
+	"sesame",
+}
+
+// ValidAllergen tells whether allergen is in KnownAllergens
+func ValidAllergen(allergen string) bool {
+	for _, known := range KnownAllergens {
+		if known == allergen {
+			return true
+		}
+	}
+	return false
+}
+
+// Allergens declares allergens of product in the vocabulary of
+// KnownAllergens. Contains lists allergens that are ingredients of
+// product, MayContain those that may be present through cross contact
+type Allergens struct {
+	Contains   []string
+	MayContain []string
+}
+
+// IsEmpty tells whether no allergens are declared
+func (allergens Allergens) IsEmpty() bool {
+	return len(allergens.Contains) == 0 && len(allergens.MayContain) == 0
+}
+
+// Includes tells whether product contains or may contain allergen
+func (allergens Allergens) Includes(allergen string) bool {
+	return hasAllergen(allergens.Contains, allergen) || hasAllergen(allergens.MayContain, allergen)
+}
+
+// Normalized lists allergens in the order of KnownAllergens
+// without duplicates. Allergens that are contained are
+// left out of MayContain
+func (allergens Allergens) Normalized() Allergens {
+	var normalized Allergens
+	for _, known := range KnownAllergens {
+		switch {
+		case hasAllergen(allergens.Contains, known):
+			normalized.Contains = append(normalized.Contains, known)
+		case hasAllergen(allergens.MayContain, known):
+			normalized.MayContain = append(normalized.MayContain, known)
+		}
+	}
+	return normalized
+}
+
+// AllergenCheck is the disagreement between allergens declared as
+// contained and allergens detected in ingredients. Undeclared are
+// detected but not declared as contained, Undetected are declared
+// as contained but not detected
+type AllergenCheck struct {
+	Undeclared []string
+	Undetected []string
+}
+
+// Agrees tells whether declared and detected allergens are the same
+func (check AllergenCheck) Agrees() bool {
+	return len(check.Undeclared) == 0 && len(check.Undetected) == 0
+}
+
+// AllergenProposal is the allergens detected in ingredients of
+// product next to the declared ones and their disagreement
+type AllergenProposal struct {
+	Declared Allergens
+	Detected []string
+	Check    AllergenCheck
+}
+
+func hasAllergen(allergens []string, allergen string) bool {
+	for _, listed := range allergens {
+		if listed == allergen {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package domain
+
+import "strings"
+
+// gtinLength is the length of GTIN-14, the longest GTIN
+const gtinLength = 14
+
+// ValidGTIN tells whether gtin is a GTIN-8, GTIN-12 (UPC-A),
+// GTIN-13 (EAN-13) or GTIN-14 with a correct check digit
+func ValidGTIN(gtin string) bool {
+	switch len(gtin) {
+	case 8, 12, 13, 14:
+	default:
+		return false
+	}
+
+	// digits are weighted 3 and 1 alternately from the
+	// right, starting with the digit next to check digit
+	sum := 0
+	for i := len(gtin) - 1; i >= 0; i-- {
+		digit := int(gtin[i] - '0')
+		if digit < 0 || digit > 9 {
+			return false
+		}
+		if (len(gtin)-1-i)%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
+
+// NormalizeGTIN pads gtin with leading zeros into GTIN-14, so
+// that a UPC-A and its EAN-13 form, e.g. 012345678905 and
+// 0012345678905, are the same GTIN
+func NormalizeGTIN(gtin string) string {
+	if len(gtin) >= gtinLength {
+		return gtin
+	}
+	return strings.Repeat("0", gtinLength-len(gtin)) + gtin
+}
+
+// NormalizeGTINs normalizes every GTIN of gtins, dropping duplicates
+func NormalizeGTINs(gtins []string) []string {
+	if len(gtins) == 0 {
+		return nil
+	}
+
+	normalized := make([]string, 0, len(gtins))
+	seen := make(map[string]bool, len(gtins))
+	for _, gtin := range gtins {
+		gtin = NormalizeGTIN(gtin)
+		if !seen[gtin] {
+			seen[gtin] = true
+			normalized = append(normalized, gtin)
+		}
+	}
+	return normalized
+}
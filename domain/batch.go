@@ -0,0 +1,28 @@
+package domain
+
+import "context"
+
+// MaxBatchSize bounds items of a single batch of products
+const MaxBatchSize = 100
+
+// ProductKey identifies product at version, or at any version if zero
+type ProductKey struct {
+	ProductID string
+	Version   int64
+}
+
+// BatchItemResult is the outcome of a single item of a batch, in the
+// order of items. Product is the product got or created, Err is nil
+// when the item succeeded
+type BatchItemResult struct {
+	ProductID string
+	Product   Product
+	Err       error
+}
+
+// Transactor runs fn within a transaction, so that writes fn makes
+// through repositories given its ctx are either all committed or,
+// when fn fails, none is
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
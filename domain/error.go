@@ -12,6 +12,33 @@ var (
 	// ErrConflict will throw if the current action already exists
 	ErrConflict = errors.New("Conflicting state, item with same productId exists")
 
+	// ErrDuplicateSKU will throw if a variant with the same sku already exists
+	ErrDuplicateSKU = errors.New("Conflicting state, variant with same sku exists")
+
+	// ErrDuplicateGTIN will throw if a product with the same gtin already exists
+	ErrDuplicateGTIN = errors.New("Conflicting state, product with same gtin exists")
+
+	// ErrDuplicateSlug will throw if a product with the same slug already exists
+	ErrDuplicateSlug = errors.New("Conflicting state, product with same slug exists")
+
+	// ErrDuplicatePrice will throw if a price taking effect at the same time already exists
+	ErrDuplicatePrice = errors.New("Conflicting state, price with same start exists")
+
+	// ErrPreconditionFailed will throw if the item was modified since the expected version
+	ErrPreconditionFailed = errors.New("Precondition failed, item has been modified")
+
+	// ErrInvalidTransition will throw if the item cannot move to the requested status
+	ErrInvalidTransition = errors.New("Invalid status transition")
+
+	// ErrInvalidNutrition will throw if nutrients of nutrition facts are out of range
+	ErrInvalidNutrition = errors.New("Invalid nutrition facts, nutrients out of range or exceed their totals")
+
+	// ErrBatchAborted will throw for items of an atomic batch that were rolled back because another item failed
+	ErrBatchAborted = errors.New("Batch aborted, another item of the atomic batch failed")
+
+	// ErrTransactionUnsupported will throw if the database cannot run transactions
+	ErrTransactionUnsupported = errors.New("Transactions are not supported by the database")
+
 	// ErrBadParamInput will throw if the given request input is not valid
 	ErrBadParamInput = errors.New("Bad or invalid input")
 
@@ -21,3 +48,13 @@ var (
 	// ErrAuthFail ...
 	ErrAuthFail = errors.New("Authentication fail for no matching credential")
 )
+
+// IsConflict tells whether err is a conflict with another
+// item, e.g. a taken productId, SKU, GTIN or slug
+func IsConflict(err error) bool {
+	switch err {
+	case ErrConflict, ErrDuplicateSKU, ErrDuplicateGTIN, ErrDuplicateSlug:
+		return true
+	}
+	return false
+}
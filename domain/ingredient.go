@@ -0,0 +1,38 @@
+package domain
+
+import "strings"
+
+// Ingredient is an ingredient of product parsed from its label text,
+// e.g. "chocolate chips (sugar, cocoa (processed with alkali))" is
+// chocolate chips made of sugar and of cocoa qualified as processed
+// with alkali. Qualifiers describe the ingredient itself, while
+// Ingredients lists the sub-ingredients it is made of
+type Ingredient struct {
+	Name        string
+	Qualifiers  []string
+	Ingredients []Ingredient
+}
+
+// String formats ingredient in its normalized label text, qualifiers
+// and sub-ingredients each in their own parentheses, e.g.
+// "cocoa (processed with alkali)" or "chips (sugar, milk (skim milk))"
+func (ingredient Ingredient) String() string {
+	text := ingredient.Name
+	if len(ingredient.Qualifiers) > 0 {
+		text += " (" + strings.Join(ingredient.Qualifiers, ", ") + ")"
+	}
+	if len(ingredient.Ingredients) > 0 {
+		text += " (" + FormatIngredients(ingredient.Ingredients) + ")"
+	}
+	return text
+}
+
+// FormatIngredients formats ingredients in their
+// normalized label text, separated by commas
+func FormatIngredients(ingredients []Ingredient) string {
+	texts := make([]string, 0, len(ingredients))
+	for _, ingredient := range ingredients {
+		texts = append(texts, ingredient.String())
+	}
+	return strings.Join(texts, ", ")
+}
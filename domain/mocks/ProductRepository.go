@@ -4,6 +4,7 @@ package mocks
 
 import (
 	context "context"
+	time "time"
 
 	domain "github.com/iqdf/benjerry-service/domain"
 	mock "github.com/stretchr/testify/mock"
@@ -14,6 +15,27 @@ type ProductRepository struct {
 	mock.Mock
 }
 
+// CountFacets provides a mock function with given fields: ctx, filter
+func (_m *ProductRepository) CountFacets(ctx context.Context, filter domain.ProductFilter) (domain.ProductFacets, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 domain.ProductFacets
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ProductFilter) domain.ProductFacets); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		r0 = ret.Get(0).(domain.ProductFacets)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ProductFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Create provides a mock function with given fields: ctx, product
 func (_m *ProductRepository) Create(ctx context.Context, product domain.Product) error {
 	ret := _m.Called(ctx, product)
@@ -28,13 +50,13 @@ func (_m *ProductRepository) Create(ctx context.Context, product domain.Product)
 	return r0
 }
 
-// Delete provides a mock function with given fields: ctx, productID
-func (_m *ProductRepository) Delete(ctx context.Context, productID string) error {
-	ret := _m.Called(ctx, productID)
+// Delete provides a mock function with given fields: ctx, productID, version, deletedBy
+func (_m *ProductRepository) Delete(ctx context.Context, productID string, version int64, deletedBy string) error {
+	ret := _m.Called(ctx, productID, version, deletedBy)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, productID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64, string) error); ok {
+		r0 = rf(ctx, productID, version, deletedBy)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -42,6 +64,96 @@ func (_m *ProductRepository) Delete(ctx context.Context, productID string) error
 	return r0
 }
 
+// Fetch provides a mock function with given fields: ctx, query
+func (_m *ProductRepository) Fetch(ctx context.Context, query domain.ProductQuery) (domain.ProductPage, error) {
+	ret := _m.Called(ctx, query)
+
+	var r0 domain.ProductPage
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ProductQuery) domain.ProductPage); ok {
+		r0 = rf(ctx, query)
+	} else {
+		r0 = ret.Get(0).(domain.ProductPage)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ProductQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FetchScheduled provides a mock function with given fields: ctx, before
+func (_m *ProductRepository) FetchScheduled(ctx context.Context, before time.Time) ([]domain.Product, error) {
+	ret := _m.Called(ctx, before)
+
+	var r0 []domain.Product
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) []domain.Product); ok {
+		r0 = rf(ctx, before)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Product)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FetchTrash provides a mock function with given fields: ctx
+func (_m *ProductRepository) FetchTrash(ctx context.Context) ([]domain.TrashedProduct, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []domain.TrashedProduct
+	if rf, ok := ret.Get(0).(func(context.Context) []domain.TrashedProduct); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.TrashedProduct)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FetchTrashBefore provides a mock function with given fields: ctx, deletedBefore, limit
+func (_m *ProductRepository) FetchTrashBefore(ctx context.Context, deletedBefore time.Time, limit int) ([]domain.TrashedProduct, error) {
+	ret := _m.Called(ctx, deletedBefore, limit)
+
+	var r0 []domain.TrashedProduct
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) []domain.TrashedProduct); ok {
+		r0 = rf(ctx, deletedBefore, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.TrashedProduct)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int) error); ok {
+		r1 = rf(ctx, deletedBefore, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Get provides a mock function with given fields: ctx, productID
 func (_m *ProductRepository) Get(ctx context.Context, productID string) (domain.Product, error) {
 	ret := _m.Called(ctx, productID)
@@ -63,6 +175,169 @@ func (_m *ProductRepository) Get(ctx context.Context, productID string) (domain.
 	return r0, r1
 }
 
+// GetByGTIN provides a mock function with given fields: ctx, gtin
+func (_m *ProductRepository) GetByGTIN(ctx context.Context, gtin string) (domain.Product, error) {
+	ret := _m.Called(ctx, gtin)
+
+	var r0 domain.Product
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.Product); ok {
+		r0 = rf(ctx, gtin)
+	} else {
+		r0 = ret.Get(0).(domain.Product)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, gtin)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBySKU provides a mock function with given fields: ctx, sku
+func (_m *ProductRepository) GetBySKU(ctx context.Context, sku string) (domain.Product, error) {
+	ret := _m.Called(ctx, sku)
+
+	var r0 domain.Product
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.Product); ok {
+		r0 = rf(ctx, sku)
+	} else {
+		r0 = ret.Get(0).(domain.Product)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, sku)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBySlug provides a mock function with given fields: ctx, slug
+func (_m *ProductRepository) GetBySlug(ctx context.Context, slug string) (domain.Product, error) {
+	ret := _m.Called(ctx, slug)
+
+	var r0 domain.Product
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.Product); ok {
+		r0 = rf(ctx, slug)
+	} else {
+		r0 = ret.Get(0).(domain.Product)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, slug)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTrashed provides a mock function with given fields: ctx, productID
+func (_m *ProductRepository) GetTrashed(ctx context.Context, productID string) (domain.TrashedProduct, error) {
+	ret := _m.Called(ctx, productID)
+
+	var r0 domain.TrashedProduct
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.TrashedProduct); ok {
+		r0 = rf(ctx, productID)
+	} else {
+		r0 = ret.Get(0).(domain.TrashedProduct)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, productID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Purge provides a mock function with given fields: ctx, productID
+func (_m *ProductRepository) Purge(ctx context.Context, productID string) error {
+	ret := _m.Called(ctx, productID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, productID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Replace provides a mock function with given fields: ctx, productID, product
+func (_m *ProductRepository) Replace(ctx context.Context, productID string, product domain.Product) error {
+	ret := _m.Called(ctx, productID, product)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.Product) error); ok {
+		r0 = rf(ctx, productID, product)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Restore provides a mock function with given fields: ctx, productID
+func (_m *ProductRepository) Restore(ctx context.Context, productID string) error {
+	ret := _m.Called(ctx, productID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, productID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Search provides a mock function with given fields: ctx, text, limit, filter
+func (_m *ProductRepository) Search(ctx context.Context, text string, limit int, filter domain.ProductFilter) ([]domain.ProductSearchResult, error) {
+	ret := _m.Called(ctx, text, limit, filter)
+
+	var r0 []domain.ProductSearchResult
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, domain.ProductFilter) []domain.ProductSearchResult); ok {
+		r0 = rf(ctx, text, limit, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.ProductSearchResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, domain.ProductFilter) error); ok {
+		r1 = rf(ctx, text, limit, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Stream provides a mock function with given fields: ctx, filter, fn
+func (_m *ProductRepository) Stream(ctx context.Context, filter domain.ProductFilter, fn func(domain.Product) error) error {
+	ret := _m.Called(ctx, filter, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ProductFilter, func(domain.Product) error) error); ok {
+		r0 = rf(ctx, filter, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Update provides a mock function with given fields: ctx, productID, product
 func (_m *ProductRepository) Update(ctx context.Context, productID string, product domain.Product) error {
 	ret := _m.Called(ctx, productID, product)
@@ -76,3 +351,66 @@ func (_m *ProductRepository) Update(ctx context.Context, productID string, produ
 
 	return r0
 }
+
+// UpdateStatus provides a mock function with given fields: ctx, productID, product
+func (_m *ProductRepository) UpdateStatus(ctx context.Context, productID string, product domain.Product) error {
+	ret := _m.Called(ctx, productID, product)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.Product) error); ok {
+		r0 = rf(ctx, productID, product)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateTranslations provides a mock function with given fields: ctx, productID, product
+func (_m *ProductRepository) UpdateTranslations(ctx context.Context, productID string, product domain.Product) error {
+	ret := _m.Called(ctx, productID, product)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.Product) error); ok {
+		r0 = rf(ctx, productID, product)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateVariants provides a mock function with given fields: ctx, productID, product
+func (_m *ProductRepository) UpdateVariants(ctx context.Context, productID string, product domain.Product) error {
+	ret := _m.Called(ctx, productID, product)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.Product) error); ok {
+		r0 = rf(ctx, productID, product)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Upsert provides a mock function with given fields: ctx, products
+func (_m *ProductRepository) Upsert(ctx context.Context, products []domain.Product) (domain.UpsertResult, error) {
+	ret := _m.Called(ctx, products)
+
+	var r0 domain.UpsertResult
+	if rf, ok := ret.Get(0).(func(context.Context, []domain.Product) domain.UpsertResult); ok {
+		r0 = rf(ctx, products)
+	} else {
+		r0 = ret.Get(0).(domain.UpsertResult)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []domain.Product) error); ok {
+		r1 = rf(ctx, products)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
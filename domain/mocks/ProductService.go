@@ -4,6 +4,7 @@ package mocks
 
 import (
 	context "context"
+	time "time"
 
 	domain "github.com/iqdf/benjerry-service/domain"
 	mock "github.com/stretchr/testify/mock"
@@ -14,13 +15,196 @@ type ProductService struct {
 	mock.Mock
 }
 
+// AddVariant provides a mock function with given fields: ctx, productID, variant, version
+func (_m *ProductService) AddVariant(ctx context.Context, productID string, variant domain.ProductVariant, version int64) error {
+	ret := _m.Called(ctx, productID, variant, version)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.ProductVariant, int64) error); ok {
+		r0 = rf(ctx, productID, variant, version)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ApplyScheduledStatuses provides a mock function with given fields: ctx, now
+func (_m *ProductService) ApplyScheduledStatuses(ctx context.Context, now time.Time) (int, error) {
+	ret := _m.Called(ctx, now)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int); ok {
+		r0 = rf(ctx, now)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BatchCreateProducts provides a mock function with given fields: ctx, products, atomic
+func (_m *ProductService) BatchCreateProducts(ctx context.Context, products []domain.Product, atomic bool) ([]domain.BatchItemResult, error) {
+	ret := _m.Called(ctx, products, atomic)
+
+	var r0 []domain.BatchItemResult
+	if rf, ok := ret.Get(0).(func(context.Context, []domain.Product, bool) []domain.BatchItemResult); ok {
+		r0 = rf(ctx, products, atomic)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.BatchItemResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []domain.Product, bool) error); ok {
+		r1 = rf(ctx, products, atomic)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BatchDeleteProducts provides a mock function with given fields: ctx, keys, atomic
+func (_m *ProductService) BatchDeleteProducts(ctx context.Context, keys []domain.ProductKey, atomic bool) ([]domain.BatchItemResult, error) {
+	ret := _m.Called(ctx, keys, atomic)
+
+	var r0 []domain.BatchItemResult
+	if rf, ok := ret.Get(0).(func(context.Context, []domain.ProductKey, bool) []domain.BatchItemResult); ok {
+		r0 = rf(ctx, keys, atomic)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.BatchItemResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []domain.ProductKey, bool) error); ok {
+		r1 = rf(ctx, keys, atomic)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BatchGetProducts provides a mock function with given fields: ctx, productIDs
+func (_m *ProductService) BatchGetProducts(ctx context.Context, productIDs []string) ([]domain.BatchItemResult, error) {
+	ret := _m.Called(ctx, productIDs)
+
+	var r0 []domain.BatchItemResult
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []domain.BatchItemResult); ok {
+		r0 = rf(ctx, productIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.BatchItemResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, productIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BatchUpdateProducts provides a mock function with given fields: ctx, products, atomic
+func (_m *ProductService) BatchUpdateProducts(ctx context.Context, products []domain.Product, atomic bool) ([]domain.BatchItemResult, error) {
+	ret := _m.Called(ctx, products, atomic)
+
+	var r0 []domain.BatchItemResult
+	if rf, ok := ret.Get(0).(func(context.Context, []domain.Product, bool) []domain.BatchItemResult); ok {
+		r0 = rf(ctx, products, atomic)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.BatchItemResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []domain.Product, bool) error); ok {
+		r1 = rf(ctx, products, atomic)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ChangeProductStatus provides a mock function with given fields: ctx, productID, change, version
+func (_m *ProductService) ChangeProductStatus(ctx context.Context, productID string, change domain.StatusChange, version int64) error {
+	ret := _m.Called(ctx, productID, change, version)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.StatusChange, int64) error); ok {
+		r0 = rf(ctx, productID, change, version)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ComputeNutrition provides a mock function with given fields: ctx, productID, quantity, sku
+func (_m *ProductService) ComputeNutrition(ctx context.Context, productID string, quantity domain.Quantity, sku string) (domain.NutritionServing, error) {
+	ret := _m.Called(ctx, productID, quantity, sku)
+
+	var r0 domain.NutritionServing
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.Quantity, string) domain.NutritionServing); ok {
+		r0 = rf(ctx, productID, quantity, sku)
+	} else {
+		r0 = ret.Get(0).(domain.NutritionServing)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, domain.Quantity, string) error); ok {
+		r1 = rf(ctx, productID, quantity, sku)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateProduct provides a mock function with given fields: ctx, product
-func (_m *ProductService) CreateProduct(ctx context.Context, product domain.Product) error {
+func (_m *ProductService) CreateProduct(ctx context.Context, product domain.Product) (domain.Product, error) {
 	ret := _m.Called(ctx, product)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, domain.Product) error); ok {
+	var r0 domain.Product
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Product) domain.Product); ok {
 		r0 = rf(ctx, product)
+	} else {
+		r0 = ret.Get(0).(domain.Product)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Product) error); ok {
+		r1 = rf(ctx, product)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteProduct provides a mock function with given fields: ctx, productID, version
+func (_m *ProductService) DeleteProduct(ctx context.Context, productID string, version int64) error {
+	ret := _m.Called(ctx, productID, version)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) error); ok {
+		r0 = rf(ctx, productID, version)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -28,13 +212,41 @@ func (_m *ProductService) CreateProduct(ctx context.Context, product domain.Prod
 	return r0
 }
 
-// DeleteProduct provides a mock function with given fields: ctx, productID
-func (_m *ProductService) DeleteProduct(ctx context.Context, productID string) error {
-	ret := _m.Called(ctx, productID)
+// DeleteTranslation provides a mock function with given fields: ctx, productID, locale, version
+func (_m *ProductService) DeleteTranslation(ctx context.Context, productID string, locale string, version int64) error {
+	ret := _m.Called(ctx, productID, locale, version)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, productID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) error); ok {
+		r0 = rf(ctx, productID, locale, version)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteVariant provides a mock function with given fields: ctx, productID, sku, version
+func (_m *ProductService) DeleteVariant(ctx context.Context, productID string, sku string, version int64) error {
+	ret := _m.Called(ctx, productID, sku, version)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) error); ok {
+		r0 = rf(ctx, productID, sku, version)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ExportProducts provides a mock function with given fields: ctx, region, fn
+func (_m *ProductService) ExportProducts(ctx context.Context, region string, fn func(domain.Product) error) error {
+	ret := _m.Called(ctx, region, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, func(domain.Product) error) error); ok {
+		r0 = rf(ctx, region, fn)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -42,6 +254,94 @@ func (_m *ProductService) DeleteProduct(ctx context.Context, productID string) e
 	return r0
 }
 
+// FetchProducts provides a mock function with given fields: ctx, query
+func (_m *ProductService) FetchProducts(ctx context.Context, query domain.ProductQuery) (domain.ProductPage, error) {
+	ret := _m.Called(ctx, query)
+
+	var r0 domain.ProductPage
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ProductQuery) domain.ProductPage); ok {
+		r0 = rf(ctx, query)
+	} else {
+		r0 = ret.Get(0).(domain.ProductPage)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ProductQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FetchRetiredProducts provides a mock function with given fields: ctx, query
+func (_m *ProductService) FetchRetiredProducts(ctx context.Context, query domain.ProductQuery) (domain.ProductPage, error) {
+	ret := _m.Called(ctx, query)
+
+	var r0 domain.ProductPage
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ProductQuery) domain.ProductPage); ok {
+		r0 = rf(ctx, query)
+	} else {
+		r0 = ret.Get(0).(domain.ProductPage)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ProductQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FetchRevisions provides a mock function with given fields: ctx, productID
+func (_m *ProductService) FetchRevisions(ctx context.Context, productID string) ([]domain.ProductRevision, error) {
+	ret := _m.Called(ctx, productID)
+
+	var r0 []domain.ProductRevision
+	if rf, ok := ret.Get(0).(func(context.Context, string) []domain.ProductRevision); ok {
+		r0 = rf(ctx, productID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.ProductRevision)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, productID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FetchTrash provides a mock function with given fields: ctx
+func (_m *ProductService) FetchTrash(ctx context.Context) ([]domain.TrashedProduct, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []domain.TrashedProduct
+	if rf, ok := ret.Get(0).(func(context.Context) []domain.TrashedProduct); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.TrashedProduct)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetProduct provides a mock function with given fields: ctx, productID
 func (_m *ProductService) GetProduct(ctx context.Context, productID string) (domain.Product, error) {
 	ret := _m.Called(ctx, productID)
@@ -63,6 +363,225 @@ func (_m *ProductService) GetProduct(ctx context.Context, productID string) (dom
 	return r0, r1
 }
 
+// GetProductByGTIN provides a mock function with given fields: ctx, gtin
+func (_m *ProductService) GetProductByGTIN(ctx context.Context, gtin string) (domain.Product, error) {
+	ret := _m.Called(ctx, gtin)
+
+	var r0 domain.Product
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.Product); ok {
+		r0 = rf(ctx, gtin)
+	} else {
+		r0 = ret.Get(0).(domain.Product)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, gtin)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetProductBySKU provides a mock function with given fields: ctx, sku
+func (_m *ProductService) GetProductBySKU(ctx context.Context, sku string) (domain.Product, error) {
+	ret := _m.Called(ctx, sku)
+
+	var r0 domain.Product
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.Product); ok {
+		r0 = rf(ctx, sku)
+	} else {
+		r0 = ret.Get(0).(domain.Product)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, sku)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetProductBySlug provides a mock function with given fields: ctx, slug
+func (_m *ProductService) GetProductBySlug(ctx context.Context, slug string) (domain.Product, error) {
+	ret := _m.Called(ctx, slug)
+
+	var r0 domain.Product
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.Product); ok {
+		r0 = rf(ctx, slug)
+	} else {
+		r0 = ret.Get(0).(domain.Product)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, slug)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRevision provides a mock function with given fields: ctx, productID, revision
+func (_m *ProductService) GetRevision(ctx context.Context, productID string, revision int64) (domain.ProductRevision, error) {
+	ret := _m.Called(ctx, productID, revision)
+
+	var r0 domain.ProductRevision
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) domain.ProductRevision); ok {
+		r0 = rf(ctx, productID, revision)
+	} else {
+		r0 = ret.Get(0).(domain.ProductRevision)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64) error); ok {
+		r1 = rf(ctx, productID, revision)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ProposeAllergens provides a mock function with given fields: ctx, productID
+func (_m *ProductService) ProposeAllergens(ctx context.Context, productID string) (domain.AllergenProposal, error) {
+	ret := _m.Called(ctx, productID)
+
+	var r0 domain.AllergenProposal
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.AllergenProposal); ok {
+		r0 = rf(ctx, productID)
+	} else {
+		r0 = ret.Get(0).(domain.AllergenProposal)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, productID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PurgeProduct provides a mock function with given fields: ctx, productID
+func (_m *ProductService) PurgeProduct(ctx context.Context, productID string) error {
+	ret := _m.Called(ctx, productID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, productID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PurgeTrash provides a mock function with given fields: ctx, deletedBefore
+func (_m *ProductService) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error) {
+	ret := _m.Called(ctx, deletedBefore)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int); ok {
+		r0 = rf(ctx, deletedBefore)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, deletedBefore)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReplaceProduct provides a mock function with given fields: ctx, productID, product
+func (_m *ProductService) ReplaceProduct(ctx context.Context, productID string, product domain.Product) error {
+	ret := _m.Called(ctx, productID, product)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, domain.Product) error); ok {
+		r0 = rf(ctx, productID, product)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RestoreProduct provides a mock function with given fields: ctx, productID
+func (_m *ProductService) RestoreProduct(ctx context.Context, productID string) error {
+	ret := _m.Called(ctx, productID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, productID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RestoreRevision provides a mock function with given fields: ctx, productID, revision, version
+func (_m *ProductService) RestoreRevision(ctx context.Context, productID string, revision int64, version int64) error {
+	ret := _m.Called(ctx, productID, revision, version)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64, int64) error); ok {
+		r0 = rf(ctx, productID, revision, version)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SearchProducts provides a mock function with given fields: ctx, text, limit, region
+func (_m *ProductService) SearchProducts(ctx context.Context, text string, limit int, region string) ([]domain.ProductSearchResult, error) {
+	ret := _m.Called(ctx, text, limit, region)
+
+	var r0 []domain.ProductSearchResult
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, string) []domain.ProductSearchResult); ok {
+		r0 = rf(ctx, text, limit, region)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.ProductSearchResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, string) error); ok {
+		r1 = rf(ctx, text, limit, region)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetTranslation provides a mock function with given fields: ctx, productID, locale, translation, version
+func (_m *ProductService) SetTranslation(ctx context.Context, productID string, locale string, translation domain.ProductTranslation, version int64) error {
+	ret := _m.Called(ctx, productID, locale, translation, version)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, domain.ProductTranslation, int64) error); ok {
+		r0 = rf(ctx, productID, locale, translation, version)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateProduct provides a mock function with given fields: ctx, productID, product
 func (_m *ProductService) UpdateProduct(ctx context.Context, productID string, product domain.Product) error {
 	ret := _m.Called(ctx, productID, product)
@@ -76,3 +595,38 @@ func (_m *ProductService) UpdateProduct(ctx context.Context, productID string, p
 
 	return r0
 }
+
+// UpdateVariant provides a mock function with given fields: ctx, productID, sku, variant, version
+func (_m *ProductService) UpdateVariant(ctx context.Context, productID string, sku string, variant domain.ProductVariant, version int64) error {
+	ret := _m.Called(ctx, productID, sku, variant, version)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, domain.ProductVariant, int64) error); ok {
+		r0 = rf(ctx, productID, sku, variant, version)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpsertProducts provides a mock function with given fields: ctx, products
+func (_m *ProductService) UpsertProducts(ctx context.Context, products []domain.Product) (domain.UpsertResult, error) {
+	ret := _m.Called(ctx, products)
+
+	var r0 domain.UpsertResult
+	if rf, ok := ret.Get(0).(func(context.Context, []domain.Product) domain.UpsertResult); ok {
+		r0 = rf(ctx, products)
+	} else {
+		r0 = ret.Get(0).(domain.UpsertResult)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []domain.Product) error); ok {
+		r1 = rf(ctx, products)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,176 @@
+package domain
+
+import "math"
+
+// MaxServingSize bounds serving size of nutrition facts in grams
+const MaxServingSize = 1000
+
+// Nutrients is amounts of nutrients in a quantity of product,
+// Calories in kilocalories and every other nutrient in grams
+type Nutrients struct {
+	Calories          float64
+	TotalFat          float64
+	SaturatedFat      float64
+	TransFat          float64
+	Cholesterol       float64
+	Sodium            float64
+	TotalCarbohydrate float64
+	DietaryFiber      float64
+	TotalSugars       float64
+	AddedSugars       float64
+	Protein           float64
+	VitaminD          float64
+	Calcium           float64
+	Iron              float64
+	Potassium         float64
+}
+
+// Scale multiplies every nutrient by factor
+func (nutrients Nutrients) Scale(factor float64) Nutrients {
+	return Nutrients{
+		Calories:          nutrients.Calories * factor,
+		TotalFat:          nutrients.TotalFat * factor,
+		SaturatedFat:      nutrients.SaturatedFat * factor,
+		TransFat:          nutrients.TransFat * factor,
+		Cholesterol:       nutrients.Cholesterol * factor,
+		Sodium:            nutrients.Sodium * factor,
+		TotalCarbohydrate: nutrients.TotalCarbohydrate * factor,
+		DietaryFiber:      nutrients.DietaryFiber * factor,
+		TotalSugars:       nutrients.TotalSugars * factor,
+		AddedSugars:       nutrients.AddedSugars * factor,
+		Protein:           nutrients.Protein * factor,
+		VitaminD:          nutrients.VitaminD * factor,
+		Calcium:           nutrients.Calcium * factor,
+		Iron:              nutrients.Iron * factor,
+		Potassium:         nutrients.Potassium * factor,
+	}
+}
+
+// NutritionFacts is nutrients of product per 100 grams, and its
+// serving size in grams. ServingVolume is the volume of a serving in
+// millilitres, which converts volumes such as containers sold by
+// volume into grams, and ServingLabel its household measure, e.g.
+// "2/3 cup". Products without nutrition facts have no serving size
+type NutritionFacts struct {
+	ServingSize   float64
+	ServingVolume float64
+	ServingLabel  string
+	Per100g       Nutrients
+}
+
+// IsEmpty tells whether product has no nutrition facts
+func (facts NutritionFacts) IsEmpty() bool {
+	return facts.ServingSize == 0
+}
+
+// Grams converts quantity of product into grams. Volumes are
+// converted by the density of a serving, so they are only
+// converted when serving volume is known
+func (facts NutritionFacts) Grams(quantity Quantity) (float64, error) {
+	switch quantity.Dimension() {
+	case DimensionMass:
+		return quantity.Base(), nil
+	case DimensionVolume:
+		if facts.ServingVolume <= 0 {
+			return 0, ErrBadParamInput
+		}
+		return quantity.Base() * facts.ServingSize / facts.ServingVolume, nil
+	default:
+		return 0, ErrBadParamInput
+	}
+}
+
+// Serving computes nutrients in grams of product
+func (facts NutritionFacts) Serving(grams float64) NutritionServing {
+	return NutritionServing{
+		Grams:     grams,
+		Servings:  grams / facts.ServingSize,
+		Nutrients: facts.Per100g.Scale(grams / 100),
+	}
+}
+
+// Check tells whether nutrition facts are consistent: no nutrient is
+// negative or exceeds the nutrient it is part of, nutrients of 100
+// grams weigh at most 100 grams, and serving size is within
+// MaxServingSize. Returns ErrInvalidNutrition otherwise
+func (facts NutritionFacts) Check() error {
+	if facts.IsEmpty() {
+		return nil
+	}
+
+	n := facts.Per100g
+	for _, amount := range []float64{
+		facts.ServingSize, facts.ServingVolume, n.Calories, n.TotalFat, n.SaturatedFat,
+		n.TransFat, n.Cholesterol, n.Sodium, n.TotalCarbohydrate, n.DietaryFiber,
+		n.TotalSugars, n.AddedSugars, n.Protein, n.VitaminD, n.Calcium, n.Iron, n.Potassium,
+	} {
+		if amount < 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+			return ErrInvalidNutrition
+		}
+	}
+
+	// macronutrients and minerals together weigh at most 100 grams,
+	// which allows for rounding of amounts on labels
+	weight := n.TotalFat + n.Cholesterol + n.Sodium + n.TotalCarbohydrate +
+		n.Protein + n.Calcium + n.Iron + n.Potassium
+
+	switch {
+	case facts.ServingSize > MaxServingSize,
+		n.SaturatedFat+n.TransFat > n.TotalFat,
+		n.DietaryFiber+n.TotalSugars > n.TotalCarbohydrate,
+		n.AddedSugars > n.TotalSugars,
+		weight > 100+nutritionTolerance:
+		return ErrInvalidNutrition
+	}
+	return nil
+}
+
+// nutritionTolerance is grams by which nutrients of
+// 100 grams may exceed 100 grams due to rounding
+const nutritionTolerance = 1
+
+// NutritionServing is nutrients in a quantity of product, which is
+// Grams of product or Servings of its serving size
+type NutritionServing struct {
+	Grams     float64
+	Servings  float64
+	Nutrients Nutrients
+}
+
+// ComputeNutrition computes nutrients in quantity of product, or in
+// the container of its variant identified by sku when sku is given,
+// e.g. 465ml. Nutrients of a serving are computed when neither is
+// given. Returns ErrResourceNotFound for products without nutrition
+// facts or without the variant, and ErrBadParamInput for quantities
+// that are not positive masses or volumes convertible into grams
+func (product Product) ComputeNutrition(quantity Quantity, sku string) (NutritionServing, error) {
+	facts := product.Nutrition
+	if facts.IsEmpty() {
+		return NutritionServing{}, ErrResourceNotFound
+	}
+
+	if sku != "" {
+		variant, ok := product.Variant(sku)
+		if !ok {
+			return NutritionServing{}, ErrResourceNotFound
+		}
+
+		var err error
+		if quantity, err = ParseQuantity(variant.Size); err != nil {
+			return NutritionServing{}, ErrBadParamInput
+		}
+	}
+
+	if quantity == (Quantity{}) {
+		return facts.Serving(facts.ServingSize), nil
+	}
+
+	grams, err := facts.Grams(quantity)
+	if err != nil {
+		return NutritionServing{}, err
+	}
+	if grams <= 0 {
+		return NutritionServing{}, ErrBadParamInput
+	}
+	return facts.Serving(grams), nil
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+// SortOrder of listed items, see constants below
+type SortOrder int
+
+// Enum for sorting and paging
+const (
+	Ascending  SortOrder = 1
+	Descending SortOrder = -1
+
+	// Sortable product fields
+	SortByProductID = "productId"
+	SortByName      = "name"
+	SortByRetiredAt = "retiredAt"
+
+	// Page size limits
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// PageCursor marks the last item of a page so that
+// the next page can continue right after it
+type PageCursor struct {
+	SortValue string `json:"v"`
+	ProductID string `json:"id"`
+}
+
+// Encode serialises cursor into an opaque url-safe string
+func (cursor PageCursor) Encode() string {
+	value, _ := json.Marshal(&cursor)
+	return base64.RawURLEncoding.EncodeToString(value)
+}
+
+// DecodePageCursor parses cursor previously created by Encode
+func DecodePageCursor(encoded string) (PageCursor, error) {
+	var cursor PageCursor
+
+	value, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return PageCursor{}, ErrBadParamInput
+	}
+
+	if err = json.Unmarshal(value, &cursor); err != nil || cursor.ProductID == "" {
+		return PageCursor{}, ErrBadParamInput
+	}
+	return cursor, nil
+}
+
+// FormatSortTime formats time as sort value of a page cursor,
+// products without the sorted time sort as the zero time
+func FormatSortTime(t *time.Time) string {
+	if t == nil {
+		return time.Time{}.Format(time.RFC3339Nano)
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// ParseSortTime parses sort value formatted by FormatSortTime
+func ParseSortTime(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, ErrBadParamInput
+	}
+	return t, nil
+}
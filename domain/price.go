@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxPriceAmount bounds amount of a price in minor units
+const MaxPriceAmount int64 = 100000000
+
+// currencyExponents lists ISO 4217 currencies by number of digits
+// of their minor unit, e.g. 2 for cents of USD
+var currencyExponents = map[string]int{
+	"AED": 2, "AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2,
+	"CLP": 0, "CNY": 2, "CZK": 2, "DKK": 2, "EUR": 2, "GBP": 2,
+	"HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "ISK": 0,
+	"JOD": 3, "JPY": 0, "KRW": 0, "KWD": 3, "MXN": 2, "MYR": 2,
+	"NOK": 2, "NZD": 2, "OMR": 3, "PHP": 2, "PLN": 2, "SAR": 2,
+	"SEK": 2, "SGD": 2, "THB": 2, "TND": 3, "TRY": 2, "TWD": 2,
+	"USD": 2, "VND": 0, "ZAR": 2,
+}
+
+// ValidCurrency tells whether currency is
+// a supported ISO 4217 code such as USD or GBP
+func ValidCurrency(currency string) bool {
+	_, ok := currencyExponents[currency]
+	return ok
+}
+
+// Money is an amount in minor units of currency,
+// e.g. 499 GBP is £4.99 while 499 JPY is ¥499
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// Valid tells whether money is a positive amount,
+// at most MaxPriceAmount, of a supported currency
+func (money Money) Valid() bool {
+	return ValidCurrency(money.Currency) && money.Amount > 0 && money.Amount <= MaxPriceAmount
+}
+
+// Decimal formats amount in major units of currency,
+// e.g. "4.99" for 499 GBP and "499" for 499 JPY
+func (money Money) Decimal() string {
+	exponent := currencyExponents[money.Currency]
+	digits := strconv.FormatInt(money.Amount, 10)
+
+	if exponent == 0 {
+		return digits
+	}
+
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+
+	point := len(digits) - exponent
+	return digits[:point] + "." + digits[point:]
+}
+
+// Price is the price of product, or of one of its variants given SKU,
+// in a region from ValidFrom until ValidTo. Open-ended prices have no
+// ValidTo. Prices taking effect later replace earlier ones, so that
+// price changes are scheduled ahead by adding prices
+type Price struct {
+	PriceID   string
+	ProductID string
+	SKU       string
+	Region    string
+	Money     Money
+	ValidFrom time.Time
+	ValidTo   *time.Time
+}
+
+// EffectiveAt tells whether price is valid at time at
+func (price Price) EffectiveAt(at time.Time) bool {
+	if at.Before(price.ValidFrom) {
+		return false
+	}
+	return price.ValidTo == nil || at.Before(*price.ValidTo)
+}
+
+// PriceQuery asks for price of product, or of its variant given SKU,
+// in region at time At. Without currency, prices in every currency
+// are quoted
+type PriceQuery struct {
+	ProductID string
+	SKU       string
+	Region    string
+	Currency  string
+	At        time.Time
+}
+
+// PriceService ...
+type PriceService interface {
+	FetchPrices(ctx context.Context, productID string) ([]Price, error)
+	GetPrice(ctx context.Context, priceID string) (Price, error)
+	CreatePrice(ctx context.Context, price Price) (Price, error)
+	DeletePrice(ctx context.Context, priceID string) error
+	QuotePrices(ctx context.Context, query PriceQuery) ([]Price, error)
+}
+
+// PriceRepository ...
+type PriceRepository interface {
+	Fetch(ctx context.Context, productID string) ([]Price, error)
+	FetchEffective(ctx context.Context, query PriceQuery) ([]Price, error)
+	Get(ctx context.Context, priceID string) (Price, error)
+	Create(ctx context.Context, price Price) (string, error)
+	Delete(ctx context.Context, priceID string) error
+}
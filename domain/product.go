@@ -2,34 +2,210 @@ package domain
 
 import (
 	"context"
+	"time"
 )
 
-// Product domain
+// Product domain. Version is incremented on every write, writes
+// given a non-zero Version only apply to the product at that version.
+// Product is sold in Regions, or everywhere when it lists none, and
+// RegionOverrides maps region to attributes that differ there.
+// Status, retirement and scheduled status make up product lifecycle,
+// which only changes through ChangeProductStatus. Translations maps
+// locale to text of product in that locale, and only changes through
+// SetTranslation and DeleteTranslation. Likewise Variants only change
+// through AddVariant, UpdateVariant and DeleteVariant. ParsedIngredients
+// and AllergenCheck are derived from Ingredients on every write. Slug
+// names product in URLs and is derived from Name on every write,
+// PreviousSlugs are the slugs it had before renames, which keep
+// resolving to the product
 type Product struct {
 	ProductID            string
+	Version              int64
 	Name                 string
+	Slug                 string
+	PreviousSlugs        []string
 	ImageClosedURL       string
 	ImageOpenURL         string
 	Description          string
 	Story                string
 	SourcingValues       *[]string
 	Ingredients          *[]string
+	ParsedIngredients    []Ingredient
 	AllergyInfo          string
 	DietaryCertification string
+	Allergens            Allergens
+	AllergenCheck        AllergenCheck
+	Nutrition            NutritionFacts
+	Regions              []string
+	RegionOverrides      map[string]RegionOverride
+	GTINs                []string
+	Status               ProductStatus
+	RetiredAt            *time.Time
+	Epitaph              string
+	ScheduledStatus      ProductStatus
+	ScheduledAt          *time.Time
+	Translations         map[string]ProductTranslation
+	Variants             []ProductVariant
+}
+
+// ProductQuery describes which page of products to fetch
+// and the order in which products are listed
+type ProductQuery struct {
+	Cursor    string
+	Limit     int
+	SortBy    string
+	SortOrder SortOrder
+	Filter    ProductFilter
+}
+
+// ProductFilter narrows down listed products. Products must have
+// all (or any, see SourcingMatch) of the sourcing values, any of the
+// dietary certifications, none of the excluded allergens, be in
+// any of the statuses and be sold in Region, if given
+type ProductFilter struct {
+	SourcingValues        []string
+	SourcingMatch         MatchMode
+	DietaryCertifications []string
+	ExcludeAllergens      []string
+	Statuses              []ProductStatus
+	Region                string
+}
+
+// MatchMode tells whether all or any of
+// the filtered values must match
+type MatchMode string
+
+// Enum for filter match mode
+const (
+	MatchAll MatchMode = "all"
+	MatchAny MatchMode = "any"
+)
+
+// FacetCount is the number of filtered
+// products having the facet value
+type FacetCount struct {
+	Value string
+	Count int64
+}
+
+// ProductFacets counts filtered products by every
+// value of sourcing, certification and allergen
+type ProductFacets struct {
+	SourcingValues        []FacetCount
+	DietaryCertifications []FacetCount
+	Allergens             []FacetCount
+}
+
+// ProductPage is a single page of fetched products. NextCursor
+// is empty when there are no more products after this page
+type ProductPage struct {
+	Products   []Product
+	NextCursor string
+	TotalCount int64
+	Facets     ProductFacets
+}
+
+// ProductSearchResult is a product matching a full-text search.
+// Highlights maps searched field name to a snippet of its
+// matching text where matched terms are wrapped in <em> tags
+type ProductSearchResult struct {
+	Product    Product
+	Score      float64
+	Highlights map[string]string
+}
+
+// UpsertResult counts upserted products by whether they were
+// created, updated or left unchanged. Products failing on their
+// own, e.g. on a GTIN of another product, are left as they were
+// and listed in Failures, while the rest are written
+type UpsertResult struct {
+	Created   int64
+	Updated   int64
+	Unchanged int64
+	Failures  []UpsertFailure
+}
+
+// UpsertFailure tells why the product at Index
+// of upserted products was not written
+type UpsertFailure struct {
+	Index int
+	Err   error
+}
+
+// TrashedProduct is a soft deleted product, kept in
+// trash until it is either restored or purged
+type TrashedProduct struct {
+	Product   Product
+	DeletedAt time.Time
+	DeletedBy string
 }
 
 // ProductService ...
 type ProductService interface {
+	FetchProducts(ctx context.Context, query ProductQuery) (ProductPage, error)
+	SearchProducts(ctx context.Context, text string, limit int, region string) ([]ProductSearchResult, error)
 	GetProduct(ctx context.Context, productID string) (Product, error)
-	CreateProduct(ctx context.Context, product Product) error
+	GetProductBySKU(ctx context.Context, sku string) (Product, error)
+	GetProductByGTIN(ctx context.Context, gtin string) (Product, error)
+	GetProductBySlug(ctx context.Context, slug string) (Product, error)
+	ProposeAllergens(ctx context.Context, productID string) (AllergenProposal, error)
+	ComputeNutrition(ctx context.Context, productID string, quantity Quantity, sku string) (NutritionServing, error)
+	CreateProduct(ctx context.Context, product Product) (Product, error)
+	BatchGetProducts(ctx context.Context, productIDs []string) ([]BatchItemResult, error)
+	BatchCreateProducts(ctx context.Context, products []Product, atomic bool) ([]BatchItemResult, error)
+	BatchUpdateProducts(ctx context.Context, products []Product, atomic bool) ([]BatchItemResult, error)
+	BatchDeleteProducts(ctx context.Context, keys []ProductKey, atomic bool) ([]BatchItemResult, error)
+	UpsertProducts(ctx context.Context, products []Product) (UpsertResult, error)
+	ExportProducts(ctx context.Context, region string, fn func(Product) error) error
+	FetchRetiredProducts(ctx context.Context, query ProductQuery) (ProductPage, error)
 	UpdateProduct(ctx context.Context, productID string, product Product) error
-	DeleteProduct(ctx context.Context, productID string) error
+	ReplaceProduct(ctx context.Context, productID string, product Product) error
+	DeleteProduct(ctx context.Context, productID string, version int64) error
+	ChangeProductStatus(ctx context.Context, productID string, change StatusChange, version int64) error
+	SetTranslation(ctx context.Context, productID string, locale string, translation ProductTranslation, version int64) error
+	DeleteTranslation(ctx context.Context, productID string, locale string, version int64) error
+	AddVariant(ctx context.Context, productID string, variant ProductVariant, version int64) error
+	UpdateVariant(ctx context.Context, productID string, sku string, variant ProductVariant, version int64) error
+	DeleteVariant(ctx context.Context, productID string, sku string, version int64) error
+	ApplyScheduledStatuses(ctx context.Context, now time.Time) (int, error)
+	FetchRevisions(ctx context.Context, productID string) ([]ProductRevision, error)
+	GetRevision(ctx context.Context, productID string, revision int64) (ProductRevision, error)
+	RestoreRevision(ctx context.Context, productID string, revision int64, version int64) error
+	FetchTrash(ctx context.Context) ([]TrashedProduct, error)
+	RestoreProduct(ctx context.Context, productID string) error
+	PurgeProduct(ctx context.Context, productID string) error
+	PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error)
 }
 
 // ProductRepository ...
 type ProductRepository interface {
+	Fetch(ctx context.Context, query ProductQuery) (ProductPage, error)
+	CountFacets(ctx context.Context, filter ProductFilter) (ProductFacets, error)
+	Search(ctx context.Context, text string, limit int, filter ProductFilter) ([]ProductSearchResult, error)
 	Create(ctx context.Context, product Product) error
+	Upsert(ctx context.Context, products []Product) (UpsertResult, error)
+	Stream(ctx context.Context, filter ProductFilter, fn func(Product) error) error
 	Get(ctx context.Context, productID string) (Product, error)
+	GetBySKU(ctx context.Context, sku string) (Product, error)
+	GetByGTIN(ctx context.Context, gtin string) (Product, error)
+	GetBySlug(ctx context.Context, slug string) (Product, error)
 	Update(ctx context.Context, productID string, product Product) error
-	Delete(ctx context.Context, productID string) error
+	Replace(ctx context.Context, productID string, product Product) error
+	UpdateStatus(ctx context.Context, productID string, product Product) error
+	FetchScheduled(ctx context.Context, before time.Time) ([]Product, error)
+	UpdateTranslations(ctx context.Context, productID string, product Product) error
+	UpdateVariants(ctx context.Context, productID string, product Product) error
+	Delete(ctx context.Context, productID string, version int64, deletedBy string) error
+	FetchTrash(ctx context.Context) ([]TrashedProduct, error)
+	FetchTrashBefore(ctx context.Context, deletedBefore time.Time, limit int) ([]TrashedProduct, error)
+	GetTrashed(ctx context.Context, productID string) (TrashedProduct, error)
+	Restore(ctx context.Context, productID string) error
+	Purge(ctx context.Context, productID string) error
+}
+
+// ProductIDGenerator allocates productIds of created products
+// that are not given one. ProductIds it allocates may still be
+// taken by products given theirs, so they are retried on conflict
+type ProductIDGenerator interface {
+	NextProductID(ctx context.Context) (string, error)
 }
@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Dimension is what a unit measures
+type Dimension string
+
+// Dimensions of units
+const (
+	DimensionMass   Dimension = "mass"
+	DimensionVolume Dimension = "volume"
+	DimensionEnergy Dimension = "energy"
+)
+
+// unit is a unit of dimension and its size in base unit of the
+// dimension, which is gram for mass, millilitre for volume and
+// kilocalorie for energy
+type unit struct {
+	dimension Dimension
+	size      float64
+}
+
+// units maps lower cased unit symbols and names, singular
+// and plural, to their unit. Volumes are US customary ones
+var units = map[string]unit{
+	"mcg":          {DimensionMass, 0.000001},
+	"µg":           {DimensionMass, 0.000001},
+	"mg":           {DimensionMass, 0.001},
+	"g":            {DimensionMass, 1},
+	"kg":           {DimensionMass, 1000},
+	"oz":           {DimensionMass, 28.349523125},
+	"lb":           {DimensionMass, 453.59237},
+	"ml":           {DimensionVolume, 1},
+	"l":            {DimensionVolume, 1000},
+	"fl oz":        {DimensionVolume, 29.5735295625},
+	"cup":          {DimensionVolume, 236.5882365},
+	"cups":         {DimensionVolume, 236.5882365},
+	"pint":         {DimensionVolume, 473.176473},
+	"pints":        {DimensionVolume, 473.176473},
+	"quart":        {DimensionVolume, 946.352946},
+	"quarts":       {DimensionVolume, 946.352946},
+	"kcal":         {DimensionEnergy, 1},
+	"calories":     {DimensionEnergy, 1},
+	"kj":           {DimensionEnergy, 1 / 4.184},
+	"kilojoules":   {DimensionEnergy, 1 / 4.184},
+	"kilocalories": {DimensionEnergy, 1},
+}
+
+// Quantity is an amount in a unit, e.g. 98 g or 2/3 cup
+type Quantity struct {
+	Amount float64
+	Unit   string
+}
+
+// ParseQuantity parses amount followed by unit, e.g. "98g", "465 ml",
+// "2/3 cup" or "1 pint". Amount is a decimal or a fraction, and must
+// not be negative. Unit is matched regardless of case
+func ParseQuantity(text string) (Quantity, error) {
+	text = strings.TrimSpace(text)
+
+	split := strings.IndexFunc(text, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '/'
+	})
+	if split <= 0 {
+		return Quantity{}, ErrBadParamInput
+	}
+
+	amount, err := parseAmount(text[:split])
+	if err != nil {
+		return Quantity{}, err
+	}
+
+	symbol := strings.ToLower(strings.Join(strings.Fields(text[split:]), " "))
+	if _, ok := units[symbol]; !ok {
+		return Quantity{}, ErrBadParamInput
+	}
+	return Quantity{Amount: amount, Unit: symbol}, nil
+}
+
+// parseAmount parses a non-negative decimal or fraction such as 2/3
+func parseAmount(text string) (float64, error) {
+	parts := strings.Split(text, "/")
+	if len(parts) > 2 {
+		return 0, ErrBadParamInput
+	}
+
+	amount, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0, ErrBadParamInput
+	}
+
+	if len(parts) == 2 {
+		divisor, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || divisor == 0 {
+			return 0, ErrBadParamInput
+		}
+		amount /= divisor
+	}
+	return amount, nil
+}
+
+// Dimension tells what unit of quantity measures
+func (quantity Quantity) Dimension() Dimension {
+	return units[quantity.Unit].dimension
+}
+
+// Base converts quantity into base unit of its dimension,
+// i.e. grams, millilitres or kilocalories
+func (quantity Quantity) Base() float64 {
+	return quantity.Amount * units[quantity.Unit].size
+}
+
+// String formats quantity such as "98 g"
+func (quantity Quantity) String() string {
+	return strconv.FormatFloat(quantity.Amount, 'f', -1, 64) + " " + quantity.Unit
+}
@@ -0,0 +1,62 @@
+package domain
+
+// RegionOverride replaces product attributes in one region, e.g. an
+// image of the pint sold there. Empty fields are not overridden
+type RegionOverride struct {
+	ImageClosedURL       string
+	ImageOpenURL         string
+	AllergyInfo          string
+	DietaryCertification string
+}
+
+// ValidRegion tells whether region is a region code,
+// which is two lower case letters such as us or uk
+func ValidRegion(region string) bool {
+	if len(region) != 2 {
+		return false
+	}
+
+	for _, char := range region {
+		if char < 'a' || char > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// SoldIn tells whether product is sold in region. Products
+// not limited to any region are sold in every region
+func (product Product) SoldIn(region string) bool {
+	if len(product.Regions) == 0 {
+		return true
+	}
+
+	for _, soldIn := range product.Regions {
+		if soldIn == region {
+			return true
+		}
+	}
+	return false
+}
+
+// InRegion returns product with overrides of region applied
+func (product Product) InRegion(region string) Product {
+	override, ok := product.RegionOverrides[region]
+	if !ok {
+		return product
+	}
+
+	if override.ImageClosedURL != "" {
+		product.ImageClosedURL = override.ImageClosedURL
+	}
+	if override.ImageOpenURL != "" {
+		product.ImageOpenURL = override.ImageOpenURL
+	}
+	if override.AllergyInfo != "" {
+		product.AllergyInfo = override.AllergyInfo
+	}
+	if override.DietaryCertification != "" {
+		product.DietaryCertification = override.DietaryCertification
+	}
+	return product
+}
@@ -0,0 +1,752 @@
+// Package repotest checks that repositories keep the contracts of
+// domain repository interfaces, so that every storage backend is
+// tested by the same suite rather than by hand-written tests of its own
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// concurrentWriters is the number of goroutines
+// writing the same product at once
+const concurrentWriters = 8
+
+// ProductRepositoryFactory creates an empty product repository.
+// Every subtest of the suite is given a repository of its own,
+// factories release it by registering a cleanup on t
+type ProductRepositoryFactory func(t *testing.T) domain.ProductRepository
+
+// RunProductRepositorySuite checks that repositories created by factory
+// keep the contract of domain.ProductRepository: missing and trashed
+// products are not found, unique productIds, GTINs, slugs and SKUs are
+// conflicts, versioned writes fail on stale versions and concurrent
+// writers neither lose updates nor create a product twice
+func RunProductRepositorySuite(t *testing.T, factory ProductRepositoryFactory) {
+	t.Run("NotFound", func(t *testing.T) { testProductNotFound(t, factory(t)) })
+	t.Run("DuplicateKey", func(t *testing.T) { testProductDuplicateKey(t, factory(t)) })
+	t.Run("Update", func(t *testing.T) { testProductUpdate(t, factory(t)) })
+	t.Run("Upsert", func(t *testing.T) { testProductUpsert(t, factory(t)) })
+	t.Run("Delete", func(t *testing.T) { testProductDelete(t, factory(t)) })
+	t.Run("Fetch", func(t *testing.T) { testProductFetch(t, factory(t)) })
+	t.Run("Facets", func(t *testing.T) { testProductFacets(t, factory(t)) })
+	t.Run("ConcurrentWriters", func(t *testing.T) { testProductConcurrentWriters(t, factory) })
+}
+
+// mockProduct creates a published product whose slug
+// is derived from productID, like services would
+func mockProduct(productID string, name string) domain.Product {
+	ingredients := []string{"cream", "skim milk", "toffee"}
+	sourcing := []string{"Fairtrade"}
+	return domain.Product{
+		ProductID:      productID,
+		Name:           name,
+		Slug:           "slug-" + productID,
+		Description:    "Buttery toffee in vanilla",
+		Story:          "Made with toffee from a local bakery",
+		Ingredients:    &ingredients,
+		SourcingValues: &sourcing,
+		Status:         domain.StatusPublished,
+	}
+}
+
+// mockVariant creates a pint variant of sku
+func mockVariant(sku string) domain.ProductVariant {
+	return domain.ProductVariant{SKU: sku, Format: domain.FormatPint, Size: "465ml"}
+}
+
+func testProductNotFound(t *testing.T, repo domain.ProductRepository) {
+	ctx := context.TODO()
+
+	t.Run("Get-missing", func(t *testing.T) {
+		_, err := repo.Get(ctx, "404")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+
+		_, err = repo.GetBySKU(ctx, "404-pint")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+
+		_, err = repo.GetByGTIN(ctx, "0000000000404")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+
+		_, err = repo.GetBySlug(ctx, "slug-404")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+
+	t.Run("Write-missing", func(t *testing.T) {
+		product := mockProduct("404", "Missing")
+		product.Variants = []domain.ProductVariant{mockVariant("404-pint")}
+
+		assert.Equal(t, domain.ErrResourceNotFound, repo.Update(ctx, "404", product))
+		assert.Equal(t, domain.ErrResourceNotFound, repo.Replace(ctx, "404", product))
+		assert.Equal(t, domain.ErrResourceNotFound, repo.UpdateStatus(ctx, "404", product))
+		assert.Equal(t, domain.ErrResourceNotFound, repo.UpdateTranslations(ctx, "404", product))
+		assert.Equal(t, domain.ErrResourceNotFound, repo.UpdateVariants(ctx, "404", product))
+
+		// versioned writes of missing products are not found
+		// rather than failing on their version
+		product.Version = 3
+		assert.Equal(t, domain.ErrResourceNotFound, repo.Update(ctx, "404", product))
+
+		_, err := repo.Get(ctx, "404")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+
+	t.Run("Get-trashed", func(t *testing.T) {
+		product := mockProduct("646", "Vanilla Toffee Bar Crunch")
+		product.GTINs = []string{"0076840100646"}
+		product.Variants = []domain.ProductVariant{mockVariant("646-pint")}
+		assert.NoError(t, repo.Create(ctx, product))
+		assert.NoError(t, repo.Delete(ctx, "646", 0, "admin"))
+
+		_, err := repo.Get(ctx, "646")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+
+		_, err = repo.GetBySKU(ctx, "646-pint")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+
+		_, err = repo.GetByGTIN(ctx, "0076840100646")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+
+		// slugs of trashed products keep resolving until purged
+		trashed, err := repo.GetBySlug(ctx, "slug-646")
+		assert.NoError(t, err)
+		assert.Equal(t, "646", trashed.ProductID)
+	})
+
+	t.Run("Write-trashed", func(t *testing.T) {
+		product := mockProduct("646", "Renamed")
+		assert.Equal(t, domain.ErrResourceNotFound, repo.Update(ctx, "646", product))
+		assert.Equal(t, domain.ErrResourceNotFound, repo.Replace(ctx, "646", product))
+		assert.Equal(t, domain.ErrResourceNotFound, repo.UpdateStatus(ctx, "646", product))
+	})
+
+	t.Run("Restore-and-purge-missing", func(t *testing.T) {
+		assert.Equal(t, domain.ErrResourceNotFound, repo.Restore(ctx, "404"))
+		assert.Equal(t, domain.ErrResourceNotFound, repo.Purge(ctx, "404"))
+
+		// products not in trash are neither restored nor purged
+		assert.NoError(t, repo.Create(ctx, mockProduct("647", "Cherry Garcia")))
+		assert.Equal(t, domain.ErrResourceNotFound, repo.Restore(ctx, "647"))
+		assert.Equal(t, domain.ErrResourceNotFound, repo.Purge(ctx, "647"))
+
+		_, err := repo.Get(ctx, "647")
+		assert.NoError(t, err)
+	})
+}
+
+func testProductDuplicateKey(t *testing.T, repo domain.ProductRepository) {
+	ctx := context.TODO()
+
+	product := mockProduct("646", "Vanilla Toffee Bar Crunch")
+	product.GTINs = []string{"0076840100646"}
+	product.Variants = []domain.ProductVariant{mockVariant("646-pint")}
+	assert.NoError(t, repo.Create(ctx, product))
+
+	t.Run("Create-duplicate-productId", func(t *testing.T) {
+		err := repo.Create(ctx, mockProduct("646", "Another"))
+		assert.Equal(t, domain.ErrConflict, err)
+
+		stored, err := repo.Get(ctx, "646")
+		assert.NoError(t, err)
+		assert.Equal(t, "Vanilla Toffee Bar Crunch", stored.Name)
+	})
+
+	t.Run("Create-duplicate-gtin", func(t *testing.T) {
+		duplicate := mockProduct("647", "Cherry Garcia")
+		duplicate.GTINs = []string{"0076840100647", "0076840100646"}
+		assert.Equal(t, domain.ErrDuplicateGTIN, repo.Create(ctx, duplicate))
+
+		_, err := repo.Get(ctx, "647")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+
+	t.Run("Create-duplicate-slug", func(t *testing.T) {
+		duplicate := mockProduct("648", "Chunky Monkey")
+		duplicate.Slug = "slug-646"
+		assert.Equal(t, domain.ErrDuplicateSlug, repo.Create(ctx, duplicate))
+
+		_, err := repo.Get(ctx, "648")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+
+	t.Run("Update-duplicate-gtin-and-slug", func(t *testing.T) {
+		assert.NoError(t, repo.Create(ctx, mockProduct("649", "Phish Food")))
+
+		update := domain.Product{GTINs: []string{"0076840100646"}}
+		assert.Equal(t, domain.ErrDuplicateGTIN, repo.Update(ctx, "649", update))
+
+		update = domain.Product{Slug: "slug-646"}
+		assert.Equal(t, domain.ErrDuplicateSlug, repo.Update(ctx, "649", update))
+
+		stored, err := repo.Get(ctx, "649")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), stored.Version)
+		assert.Equal(t, "slug-649", stored.Slug)
+		assert.Empty(t, stored.GTINs)
+	})
+
+	t.Run("UpdateVariants-duplicate-sku", func(t *testing.T) {
+		variants := domain.Product{Variants: []domain.ProductVariant{mockVariant("646-pint")}}
+		assert.Equal(t, domain.ErrDuplicateSKU, repo.UpdateVariants(ctx, "649", variants))
+
+		stored, err := repo.GetBySKU(ctx, "646-pint")
+		assert.NoError(t, err)
+		assert.Equal(t, "646", stored.ProductID)
+	})
+
+	t.Run("Create-trashed-productId", func(t *testing.T) {
+		// productIds and slugs of trashed products stay taken until purged
+		assert.NoError(t, repo.Create(ctx, mockProduct("650", "Americone Dream")))
+		assert.NoError(t, repo.Delete(ctx, "650", 0, "admin"))
+
+		assert.Equal(t, domain.ErrConflict, repo.Create(ctx, mockProduct("650", "Another")))
+
+		duplicate := mockProduct("651", "Half Baked")
+		duplicate.Slug = "slug-650"
+		assert.Equal(t, domain.ErrDuplicateSlug, repo.Create(ctx, duplicate))
+
+		// purged products free both of them
+		assert.NoError(t, repo.Purge(ctx, "650"))
+		assert.NoError(t, repo.Create(ctx, duplicate))
+
+		another := mockProduct("650", "Another")
+		another.Slug = "slug-another"
+		assert.NoError(t, repo.Create(ctx, another))
+	})
+}
+
+func testProductUpdate(t *testing.T, repo domain.ProductRepository) {
+	ctx := context.TODO()
+
+	product := mockProduct("646", "Vanilla Toffee Bar Crunch")
+	product.GTINs = []string{"0076840100646"}
+	assert.NoError(t, repo.Create(ctx, product))
+
+	t.Run("Create-version", func(t *testing.T) {
+		stored, err := repo.Get(ctx, "646")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), stored.Version)
+		assert.Equal(t, product.Name, stored.Name)
+		assert.Equal(t, *product.Ingredients, *stored.Ingredients)
+		assert.Equal(t, domain.StatusPublished, stored.Status)
+	})
+
+	t.Run("Update-partial", func(t *testing.T) {
+		update := domain.Product{Description: "Vanilla with fudge", Version: 1}
+		assert.NoError(t, repo.Update(ctx, "646", update))
+
+		// attributes empty in update are left as they are
+		stored, err := repo.Get(ctx, "646")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), stored.Version)
+		assert.Equal(t, "Vanilla with fudge", stored.Description)
+		assert.Equal(t, product.Name, stored.Name)
+		assert.Equal(t, product.Story, stored.Story)
+		assert.Equal(t, product.GTINs, stored.GTINs)
+		assert.Equal(t, *product.Ingredients, *stored.Ingredients)
+	})
+
+	t.Run("Update-stale-version", func(t *testing.T) {
+		update := domain.Product{Description: "Stale", Version: 1}
+		assert.Equal(t, domain.ErrPreconditionFailed, repo.Update(ctx, "646", update))
+		assert.Equal(t, domain.ErrPreconditionFailed, repo.Replace(ctx, "646", update))
+		assert.Equal(t, domain.ErrPreconditionFailed, repo.UpdateStatus(ctx, "646", update))
+		assert.Equal(t, domain.ErrPreconditionFailed, repo.UpdateTranslations(ctx, "646", update))
+		assert.Equal(t, domain.ErrPreconditionFailed, repo.UpdateVariants(ctx, "646", update))
+
+		stored, err := repo.Get(ctx, "646")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), stored.Version)
+		assert.Equal(t, "Vanilla with fudge", stored.Description)
+	})
+
+	t.Run("Update-unversioned", func(t *testing.T) {
+		update := domain.Product{Story: "Toffee from down the road"}
+		assert.NoError(t, repo.Update(ctx, "646", update))
+
+		stored, err := repo.Get(ctx, "646")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), stored.Version)
+		assert.Equal(t, "Toffee from down the road", stored.Story)
+	})
+
+	t.Run("Update-keeps-lifecycle-translations-and-variants", func(t *testing.T) {
+		status := domain.Product{Status: domain.StatusRetired, Epitaph: "Gone but not forgotten"}
+		assert.NoError(t, repo.UpdateStatus(ctx, "646", status))
+
+		translations := domain.Product{Translations: map[string]domain.ProductTranslation{
+			"fr": {Name: "Croquant Vanille Caramel"},
+		}}
+		assert.NoError(t, repo.UpdateTranslations(ctx, "646", translations))
+
+		variants := domain.Product{Variants: []domain.ProductVariant{mockVariant("646-pint")}}
+		assert.NoError(t, repo.UpdateVariants(ctx, "646", variants))
+
+		// content updates carry none of them
+		update := domain.Product{Name: "Vanilla Toffee Crunch", Status: domain.StatusDraft}
+		assert.NoError(t, repo.Update(ctx, "646", update))
+
+		stored, err := repo.Get(ctx, "646")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), stored.Version)
+		assert.Equal(t, "Vanilla Toffee Crunch", stored.Name)
+		assert.Equal(t, domain.StatusRetired, stored.Status)
+		assert.Equal(t, "Gone but not forgotten", stored.Epitaph)
+		assert.Equal(t, "Croquant Vanille Caramel", stored.Translations["fr"].Name)
+		assert.Len(t, stored.Variants, 1)
+	})
+
+	t.Run("Replace-clears", func(t *testing.T) {
+		replacement := domain.Product{Name: "Vanilla Toffee Crunch", Slug: "slug-646", Version: 7}
+		assert.NoError(t, repo.Replace(ctx, "646", replacement))
+
+		// attributes empty in replacement are cleared,
+		// lifecycle and variants are left as they are
+		stored, err := repo.Get(ctx, "646")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(8), stored.Version)
+		assert.Empty(t, stored.Description)
+		assert.Empty(t, stored.Story)
+		assert.Empty(t, stored.GTINs)
+		assert.Empty(t, stored.Ingredients)
+		assert.Equal(t, domain.StatusRetired, stored.Status)
+		assert.Len(t, stored.Variants, 1)
+
+		// cleared GTINs are free to be taken by other products
+		other := mockProduct("647", "Cherry Garcia")
+		other.GTINs = []string{"0076840100646"}
+		assert.NoError(t, repo.Create(ctx, other))
+	})
+
+	t.Run("Clear-translations-and-variants", func(t *testing.T) {
+		assert.NoError(t, repo.UpdateTranslations(ctx, "646", domain.Product{Version: 8}))
+		assert.NoError(t, repo.UpdateVariants(ctx, "646", domain.Product{Version: 9}))
+
+		stored, err := repo.Get(ctx, "646")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10), stored.Version)
+		assert.Empty(t, stored.Translations)
+		assert.Empty(t, stored.Variants)
+
+		_, err = repo.GetBySKU(ctx, "646-pint")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+}
+
+func testProductUpsert(t *testing.T, repo domain.ProductRepository) {
+	ctx := context.TODO()
+
+	assert.NoError(t, repo.Create(ctx, mockProduct("646", "Vanilla Toffee Bar Crunch")))
+	assert.NoError(t, repo.UpdateStatus(ctx, "646", domain.Product{Status: domain.StatusDraft}))
+
+	t.Run("Upsert-created-and-updated", func(t *testing.T) {
+		existing := mockProduct("646", "Vanilla Toffee Crunch")
+		existing.Status = ""
+		created := mockProduct("647", "Cherry Garcia")
+		created.Status = ""
+
+		result, err := repo.Upsert(ctx, []domain.Product{existing, created})
+		assert.NoError(t, err)
+		assert.Equal(t, domain.UpsertResult{Created: 1, Updated: 1}, result)
+
+		// updated products keep their lifecycle,
+		// created products are published
+		stored, err := repo.Get(ctx, "646")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), stored.Version)
+		assert.Equal(t, "Vanilla Toffee Crunch", stored.Name)
+		assert.Equal(t, domain.StatusDraft, stored.Status)
+
+		stored, err = repo.Get(ctx, "647")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), stored.Version)
+		assert.Equal(t, domain.StatusPublished, stored.Status)
+	})
+
+	t.Run("Upsert-trashed", func(t *testing.T) {
+		assert.NoError(t, repo.Delete(ctx, "647", 0, "admin"))
+
+		result, err := repo.Upsert(ctx, []domain.Product{mockProduct("647", "Cherry Garcia")})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), result.Updated)
+
+		_, err = repo.Get(ctx, "647")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Upsert-conflict-writes-the-rest", func(t *testing.T) {
+		taken := mockProduct("648", "Phish Food")
+		taken.GTINs = []string{"00076840100477"}
+		assert.NoError(t, repo.Create(ctx, taken))
+
+		// products conflicting with others fail on
+		// their own, the rest of the batch is written
+		conflicting := mockProduct("649", "Half Baked")
+		conflicting.GTINs = taken.GTINs
+		result, err := repo.Upsert(ctx, []domain.Product{conflicting, mockProduct("650", "Chunky Monkey")})
+		assert.NoError(t, err)
+		assert.Equal(t, domain.UpsertResult{
+			Created:  1,
+			Failures: []domain.UpsertFailure{{Index: 0, Err: domain.ErrDuplicateGTIN}},
+		}, result)
+
+		_, err = repo.Get(ctx, "649")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+		_, err = repo.Get(ctx, "650")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Upsert-none", func(t *testing.T) {
+		result, err := repo.Upsert(ctx, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.UpsertResult{}, result)
+	})
+}
+
+func testProductDelete(t *testing.T, repo domain.ProductRepository) {
+	ctx := context.TODO()
+
+	assert.NoError(t, repo.Create(ctx, mockProduct("646", "Vanilla Toffee Bar Crunch")))
+	assert.NoError(t, repo.Create(ctx, mockProduct("647", "Cherry Garcia")))
+
+	t.Run("Delete-missing", func(t *testing.T) {
+		assert.Equal(t, domain.ErrResourceNotFound, repo.Delete(ctx, "404", 0, "admin"))
+		assert.Equal(t, domain.ErrResourceNotFound, repo.Delete(ctx, "404", 1, "admin"))
+	})
+
+	t.Run("Delete-stale-version", func(t *testing.T) {
+		assert.Equal(t, domain.ErrPreconditionFailed, repo.Delete(ctx, "646", 2, "admin"))
+
+		_, err := repo.Get(ctx, "646")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Delete-success", func(t *testing.T) {
+		assert.NoError(t, repo.Delete(ctx, "646", 1, "admin"))
+
+		_, err := repo.Get(ctx, "646")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+
+		trash, err := repo.FetchTrash(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, trash, 1)
+		assert.Equal(t, "646", trash[0].Product.ProductID)
+		assert.Equal(t, int64(2), trash[0].Product.Version)
+		assert.Equal(t, "admin", trash[0].DeletedBy)
+		assert.False(t, trash[0].DeletedAt.IsZero())
+	})
+
+	t.Run("Delete-twice", func(t *testing.T) {
+		assert.Equal(t, domain.ErrResourceNotFound, repo.Delete(ctx, "646", 0, "admin"))
+		assert.Equal(t, domain.ErrResourceNotFound, repo.Delete(ctx, "646", 2, "admin"))
+	})
+
+	t.Run("Trash-latest-first", func(t *testing.T) {
+		// databases may keep deletion time in milliseconds
+		time.Sleep(10 * time.Millisecond)
+		assert.NoError(t, repo.Delete(ctx, "647", 0, "editor"))
+
+		trash, err := repo.FetchTrash(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, trash, 2)
+		assert.Equal(t, "647", trash[0].Product.ProductID)
+		assert.Equal(t, "646", trash[1].Product.ProductID)
+	})
+
+	t.Run("Trash-before-earliest-first", func(t *testing.T) {
+		trash, err := repo.FetchTrash(ctx)
+		assert.NoError(t, err)
+		latestDeletedAt := trash[0].DeletedAt
+
+		trash, err = repo.FetchTrashBefore(ctx, latestDeletedAt, 10)
+		assert.NoError(t, err)
+		assert.Len(t, trash, 1)
+		assert.Equal(t, "646", trash[0].Product.ProductID)
+
+		trash, err = repo.FetchTrashBefore(ctx, latestDeletedAt.Add(time.Second), 1)
+		assert.NoError(t, err)
+		assert.Len(t, trash, 1)
+		assert.Equal(t, "646", trash[0].Product.ProductID)
+	})
+
+	t.Run("GetTrashed", func(t *testing.T) {
+		trashed, err := repo.GetTrashed(ctx, "647")
+		assert.NoError(t, err)
+		assert.Equal(t, "647", trashed.Product.ProductID)
+		assert.Equal(t, "editor", trashed.DeletedBy)
+
+		_, err = repo.GetTrashed(ctx, "404")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		assert.NoError(t, repo.Restore(ctx, "646"))
+
+		stored, err := repo.Get(ctx, "646")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), stored.Version)
+		assert.Equal(t, domain.ErrResourceNotFound, repo.Restore(ctx, "646"))
+
+		_, err = repo.GetTrashed(ctx, "646")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		assert.NoError(t, repo.Purge(ctx, "647"))
+		assert.Equal(t, domain.ErrResourceNotFound, repo.Purge(ctx, "647"))
+
+		_, err := repo.GetBySlug(ctx, "slug-647")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+
+		trash, err := repo.FetchTrash(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, trash)
+	})
+}
+
+func testProductFetch(t *testing.T, repo domain.ProductRepository) {
+	ctx := context.TODO()
+
+	for i := 1; i <= 5; i++ {
+		productID := fmt.Sprint(640 + i)
+		assert.NoError(t, repo.Create(ctx, mockProduct(productID, "Flavor "+productID)))
+	}
+	assert.NoError(t, repo.Delete(ctx, "645", 0, "admin"))
+
+	t.Run("Fetch-pages", func(t *testing.T) {
+		query := domain.ProductQuery{Limit: 3, SortBy: domain.SortByProductID, SortOrder: domain.Ascending}
+
+		page, err := repo.Fetch(ctx, query)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), page.TotalCount)
+		assert.Len(t, page.Products, 3)
+		assert.Equal(t, "641", page.Products[0].ProductID)
+		assert.NotEmpty(t, page.NextCursor)
+
+		query.Cursor = page.NextCursor
+		page, err = repo.Fetch(ctx, query)
+		assert.NoError(t, err)
+		assert.Len(t, page.Products, 1)
+		assert.Equal(t, "644", page.Products[0].ProductID)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("Fetch-descending-by-name", func(t *testing.T) {
+		query := domain.ProductQuery{Limit: 10, SortBy: domain.SortByName, SortOrder: domain.Descending}
+
+		page, err := repo.Fetch(ctx, query)
+		assert.NoError(t, err)
+		assert.Len(t, page.Products, 4)
+		assert.Equal(t, "644", page.Products[0].ProductID)
+		assert.Equal(t, "641", page.Products[3].ProductID)
+	})
+
+	t.Run("Fetch-bad-cursor", func(t *testing.T) {
+		query := domain.ProductQuery{Limit: 3, SortBy: domain.SortByProductID, SortOrder: domain.Ascending, Cursor: "!"}
+
+		_, err := repo.Fetch(ctx, query)
+		assert.Equal(t, domain.ErrBadParamInput, err)
+	})
+
+	t.Run("Fetch-latest-retired-first", func(t *testing.T) {
+		retiredAt := map[string]time.Time{
+			"642": time.Date(2020, 7, 1, 12, 0, 0, 0, time.UTC),
+			"643": time.Date(2020, 7, 1, 10, 0, 0, 500*int(time.Millisecond), time.UTC),
+			"644": time.Date(2020, 7, 1, 12, 0, 0, 0, time.UTC),
+		}
+		for productID, at := range retiredAt {
+			at := at
+			stored, err := repo.Get(ctx, productID)
+			assert.NoError(t, err)
+
+			stored.Status = domain.StatusRetired
+			stored.RetiredAt = &at
+			assert.NoError(t, repo.UpdateStatus(ctx, productID, stored))
+		}
+
+		// products retired at once are ordered by productId
+		query := domain.ProductQuery{
+			Limit:     2,
+			SortBy:    domain.SortByRetiredAt,
+			SortOrder: domain.Descending,
+			Filter:    domain.ProductFilter{Statuses: []domain.ProductStatus{domain.StatusRetired}},
+		}
+
+		page, err := repo.Fetch(ctx, query)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), page.TotalCount)
+		assert.Len(t, page.Products, 2)
+		assert.Equal(t, "644", page.Products[0].ProductID)
+		assert.Equal(t, "642", page.Products[1].ProductID)
+		assert.NotEmpty(t, page.NextCursor)
+
+		query.Cursor = page.NextCursor
+		page, err = repo.Fetch(ctx, query)
+		assert.NoError(t, err)
+		assert.Len(t, page.Products, 1)
+		assert.Equal(t, "643", page.Products[0].ProductID)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		var streamed []string
+		err := repo.Stream(ctx, domain.ProductFilter{}, func(product domain.Product) error {
+			streamed = append(streamed, product.ProductID)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"641", "642", "643", "644"}, streamed)
+	})
+}
+
+func testProductFacets(t *testing.T, repo domain.ProductRepository) {
+	ctx := context.TODO()
+
+	for productID, certification := range map[string]string{"651": "Kosher", "652": "Kosher", "653": "Vegan", "654": "Halal"} {
+		product := mockProduct(productID, "Flavor "+productID)
+		product.DietaryCertification = certification
+		product.Allergens = domain.Allergens{Contains: []string{"milk"}}
+		if certification == "Vegan" {
+			*product.SourcingValues = []string{"Organic"}
+			product.Allergens = domain.Allergens{Contains: []string{"peanuts"}}
+		}
+		assert.NoError(t, repo.Create(ctx, product))
+	}
+
+	// each group is counted without its own condition,
+	// other groups are counted within the selection
+	t.Run("Facets-selected-dietary", func(t *testing.T) {
+		facets, err := repo.CountFacets(ctx, domain.ProductFilter{DietaryCertifications: []string{"Kosher"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.FacetCount{
+			{Value: "Kosher", Count: 2},
+			{Value: "Halal", Count: 1},
+			{Value: "Vegan", Count: 1},
+		}, facets.DietaryCertifications)
+		assert.Equal(t, []domain.FacetCount{{Value: "Fairtrade", Count: 2}}, facets.SourcingValues)
+		assert.Equal(t, domain.FacetCount{Value: "milk", Count: 2}, facets.Allergens[0])
+		assert.Equal(t, domain.FacetCount{Value: "peanuts", Count: 0}, facets.Allergens[2])
+	})
+
+	t.Run("Facets-selected-sourcing-and-excluded-allergen", func(t *testing.T) {
+		facets, err := repo.CountFacets(ctx, domain.ProductFilter{
+			SourcingValues:   []string{"Organic"},
+			ExcludeAllergens: []string{"milk"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.FacetCount{{Value: "Vegan", Count: 1}}, facets.DietaryCertifications)
+		assert.Equal(t, []domain.FacetCount{{Value: "Organic", Count: 1}}, facets.SourcingValues)
+		assert.Equal(t, domain.FacetCount{Value: "milk", Count: 0}, facets.Allergens[0])
+		assert.Equal(t, domain.FacetCount{Value: "peanuts", Count: 1}, facets.Allergens[2])
+	})
+}
+
+func testProductConcurrentWriters(t *testing.T, factory ProductRepositoryFactory) {
+	ctx := context.TODO()
+
+	t.Run("Update-unversioned", func(t *testing.T) {
+		repo := factory(t)
+		assert.NoError(t, repo.Create(ctx, mockProduct("646", "Vanilla Toffee Bar Crunch")))
+
+		// no update is lost, every one of them increments version
+		errs := concurrently(func(i int) error {
+			return repo.Update(ctx, "646", domain.Product{Description: fmt.Sprint("Writer ", i)})
+		})
+		for _, err := range errs {
+			assert.NoError(t, err)
+		}
+
+		stored, err := repo.Get(ctx, "646")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(concurrentWriters+1), stored.Version)
+	})
+
+	t.Run("Update-same-version", func(t *testing.T) {
+		repo := factory(t)
+		assert.NoError(t, repo.Create(ctx, mockProduct("646", "Vanilla Toffee Bar Crunch")))
+
+		// exactly one writer of version 1 wins, the others are stale
+		errs := concurrently(func(i int) error {
+			return repo.Update(ctx, "646", domain.Product{Description: fmt.Sprint("Writer ", i), Version: 1})
+		})
+		assert.Equal(t, 1, countSucceeded(t, errs, domain.ErrPreconditionFailed))
+
+		stored, err := repo.Get(ctx, "646")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), stored.Version)
+	})
+
+	t.Run("Create-same-productId", func(t *testing.T) {
+		repo := factory(t)
+
+		errs := concurrently(func(i int) error {
+			product := mockProduct("646", fmt.Sprint("Writer ", i))
+			product.Slug = fmt.Sprint("slug-writer-", i)
+			return repo.Create(ctx, product)
+		})
+		assert.Equal(t, 1, countSucceeded(t, errs, domain.ErrConflict))
+
+		stored, err := repo.Get(ctx, "646")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), stored.Version)
+	})
+
+	t.Run("Delete-same-version", func(t *testing.T) {
+		repo := factory(t)
+		assert.NoError(t, repo.Create(ctx, mockProduct("646", "Vanilla Toffee Bar Crunch")))
+
+		// deletes after the first find the product in trash
+		errs := concurrently(func(i int) error {
+			return repo.Delete(ctx, "646", 1, fmt.Sprint("writer-", i))
+		})
+		assert.Equal(t, 1, countSucceeded(t, errs, domain.ErrResourceNotFound))
+
+		trash, err := repo.FetchTrash(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, trash, 1)
+	})
+}
+
+// concurrently runs write on concurrentWriters goroutines
+// started together and collects the error of every writer
+func concurrently(write func(i int) error) []error {
+	var (
+		start sync.WaitGroup
+		done  sync.WaitGroup
+		errs  = make([]error, concurrentWriters)
+	)
+
+	start.Add(1)
+	for i := 0; i < concurrentWriters; i++ {
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+			start.Wait()
+			errs[i] = write(i)
+		}(i)
+	}
+	start.Done()
+	done.Wait()
+	return errs
+}
+
+// countSucceeded counts writers that succeeded,
+// every other writer must have failed with expected
+func countSucceeded(t *testing.T, errs []error, expected error) int {
+	var succeeded int
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.Equal(t, expected, err)
+	}
+	return succeeded
+}
@@ -0,0 +1,85 @@
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iqdf/benjerry-service/common/auth"
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// UserRepositoryFactory creates an empty user repository.
+// Every subtest of the suite is given a repository of its own,
+// factories release it by registering a cleanup on t
+type UserRepositoryFactory func(t *testing.T) domain.UserRepository
+
+// RunUserRepositorySuite checks that repositories created by factory
+// keep the contract of domain.UserRepository: missing users are not
+// found and usernames are unique, even to concurrent writers
+func RunUserRepositorySuite(t *testing.T, factory UserRepositoryFactory) {
+	t.Run("Create", func(t *testing.T) { testUserCreate(t, factory(t)) })
+	t.Run("ConcurrentWriters", func(t *testing.T) { testUserConcurrentWriters(t, factory(t)) })
+}
+
+func testUserCreate(t *testing.T, repo domain.UserRepository) {
+	ctx := context.TODO()
+
+	user := domain.User{
+		Username:     "ben",
+		HashPassword: "hashed",
+		Authorizations: []auth.Authorization{
+			{AppName: "BenJerry", Role: "admin"},
+			{AppName: "Pricing", Role: "user"},
+		},
+	}
+
+	t.Run("Get-missing", func(t *testing.T) {
+		_, err := repo.Get(ctx, "ben")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+
+	t.Run("Create-success", func(t *testing.T) {
+		assert.NoError(t, repo.Create(ctx, user))
+
+		stored, err := repo.Get(ctx, "ben")
+		assert.NoError(t, err)
+		assert.Equal(t, user, stored)
+	})
+
+	t.Run("Create-duplicate-username", func(t *testing.T) {
+		duplicate := domain.User{Username: "ben", HashPassword: "another"}
+		assert.Equal(t, domain.ErrConflict, repo.Create(ctx, duplicate))
+
+		stored, err := repo.Get(ctx, "ben")
+		assert.NoError(t, err)
+		assert.Equal(t, "hashed", stored.HashPassword)
+	})
+
+	t.Run("Create-another", func(t *testing.T) {
+		another := domain.User{
+			Username:       "jerry",
+			HashPassword:   "hashed",
+			Authorizations: []auth.Authorization{{AppName: "BenJerry", Role: "user"}},
+		}
+		assert.NoError(t, repo.Create(ctx, another))
+
+		stored, err := repo.Get(ctx, "jerry")
+		assert.NoError(t, err)
+		assert.Equal(t, another, stored)
+	})
+}
+
+func testUserConcurrentWriters(t *testing.T, repo domain.UserRepository) {
+	ctx := context.TODO()
+
+	errs := concurrently(func(i int) error {
+		return repo.Create(ctx, domain.User{Username: "ben", HashPassword: fmt.Sprint("writer-", i)})
+	})
+	assert.Equal(t, 1, countSucceeded(t, errs, domain.ErrConflict))
+
+	_, err := repo.Get(ctx, "ben")
+	assert.NoError(t, err)
+}
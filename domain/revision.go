@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// RevisionAction is the kind of change recorded by a revision
+type RevisionAction string
+
+// Enum for revision actions
+const (
+	RevisionCreate  RevisionAction = "create"
+	RevisionUpdate  RevisionAction = "update"
+	RevisionDelete  RevisionAction = "delete"
+	RevisionRestore RevisionAction = "restore"
+
+	// Trashed products are either
+	// undeleted or purged for good
+	RevisionUndelete RevisionAction = "undelete"
+	RevisionPurge    RevisionAction = "purge"
+
+	// SystemAuthor authors changes made outside of
+	// an authenticated request, e.g. catalog import
+	SystemAuthor = "system"
+)
+
+// FieldChange is the value of a single product field
+// before and after a change, nil when the field is empty
+type FieldChange struct {
+	Field string
+	From  interface{}
+	To    interface{}
+}
+
+// ProductRevision is an immutable record of a single change of a
+// product. Revisions are numbered from 1 in the order of changes,
+// Product is a snapshot of the product after the change, or right
+// before it for delete
+type ProductRevision struct {
+	ProductID    string
+	Revision     int64
+	Action       RevisionAction
+	Author       string
+	Timestamp    time.Time
+	Changes      []FieldChange
+	Product      Product
+	RestoredFrom int64
+}
+
+// ProductRevisionRepository ...
+type ProductRevisionRepository interface {
+	Fetch(ctx context.Context, productID string) ([]ProductRevision, error)
+	Get(ctx context.Context, productID string, revision int64) (ProductRevision, error)
+	Create(ctx context.Context, revision ProductRevision) (int64, error)
+}
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+)
+
+// maxSlugLength bounds length of slugs, which are cut at a
+// hyphen so that they do not end in the middle of a word
+const maxSlugLength = 60
+
+// reservedSlugs are paths of product routes, e.g. api/products/trash,
+// which shadow products of the same slug
+var reservedSlugs = map[string]bool{
+	"search":    true,
+	"export":    true,
+	"trash":     true,
+	"graveyard": true,
+}
+
+// Slugify derives URL slug from product name, which is the name lower
+// cased with every run of characters other than ASCII letters and
+// digits replaced by a hyphen, e.g. "Cherry Garcia®" is "cherry-garcia".
+// Slugs are never all digits, so that they are not taken for
+// productIds, and names without letters or digits are slugged "product"
+func Slugify(name string) string {
+	var slug strings.Builder
+	hyphen := false
+
+	for _, char := range strings.ToLower(name) {
+		if (char < 'a' || char > 'z') && (char < '0' || char > '9') {
+			hyphen = true
+			continue
+		}
+
+		if hyphen && slug.Len() > 0 {
+			slug.WriteByte('-')
+		}
+		slug.WriteRune(char)
+		hyphen = false
+	}
+
+	text := slug.String()
+	if len(text) > maxSlugLength {
+		text = text[:maxSlugLength]
+		if cut := strings.LastIndexByte(text, '-'); cut > 0 {
+			text = text[:cut]
+		}
+	}
+
+	switch {
+	case text == "":
+		return "product"
+	case isDigits(text):
+		return "product-" + text
+	}
+	return text
+}
+
+// SlugCandidate is the nth candidate slug for base slug, which is
+// base itself, then base suffixed by a number, e.g. "vanilla-2"
+func SlugCandidate(base string, n int) string {
+	if n <= 1 {
+		return base
+	}
+	return base + "-" + strconv.Itoa(n)
+}
+
+// IsReservedSlug tells whether slug is the path of a product
+// route, which products are never slugged by
+func IsReservedSlug(slug string) bool {
+	return reservedSlugs[slug]
+}
+
+func isDigits(text string) bool {
+	for _, char := range text {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// ProductStatus is the stage of product lifecycle, see constants below
+type ProductStatus string
+
+// Enum for product lifecycle
+const (
+	StatusDraft     ProductStatus = "draft"
+	StatusPublished ProductStatus = "published"
+	StatusRetired   ProductStatus = "retired"
+)
+
+// statusTransitions lists statuses each status may move to.
+// Retired products may be published again for a comeback
+var statusTransitions = map[ProductStatus][]ProductStatus{
+	StatusDraft:     {StatusPublished},
+	StatusPublished: {StatusRetired},
+	StatusRetired:   {StatusPublished},
+}
+
+// Valid tells whether status is a known product status
+func (status ProductStatus) Valid() bool {
+	_, ok := statusTransitions[status]
+	return ok
+}
+
+// CanTransitionTo tells whether product
+// may move from status to next status
+func (status ProductStatus) CanTransitionTo(next ProductStatus) bool {
+	for _, allowed := range statusTransitions[status] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusChange moves product to Status, at a future time
+// if At is set. Epitaph is only kept for retired products
+type StatusChange struct {
+	Status  ProductStatus
+	At      time.Time
+	Epitaph string
+}
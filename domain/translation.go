@@ -0,0 +1,38 @@
+package domain
+
+// ProductTranslation holds text of a product in one locale.
+// Empty fields are not translated and fall back to product text
+type ProductTranslation struct {
+	Name        string
+	Description string
+	Story       string
+	AllergyInfo string
+}
+
+// IsEmpty tells whether translation translates none of the fields
+func (translation ProductTranslation) IsEmpty() bool {
+	return translation == ProductTranslation{}
+}
+
+// Localized returns product with its text translated into
+// locale. Fields that are not translated are kept as they are
+func (product Product) Localized(locale string) Product {
+	translation, ok := product.Translations[locale]
+	if !ok {
+		return product
+	}
+
+	if translation.Name != "" {
+		product.Name = translation.Name
+	}
+	if translation.Description != "" {
+		product.Description = translation.Description
+	}
+	if translation.Story != "" {
+		product.Story = translation.Story
+	}
+	if translation.AllergyInfo != "" {
+		product.AllergyInfo = translation.AllergyInfo
+	}
+	return product
+}
@@ -0,0 +1,34 @@
+package domain
+
+// VariantFormat is the format a flavor is sold in, see constants below
+type VariantFormat string
+
+// Enum for variant format
+const (
+	FormatPint      VariantFormat = "pint"
+	FormatMiniCup   VariantFormat = "mini_cup"
+	FormatScoopShop VariantFormat = "scoop_shop"
+	FormatNonDairy  VariantFormat = "non_dairy"
+)
+
+// ProductVariant is a product sold in one format and size, identified
+// by its SKU which is unique across the catalog. Image and ingredients
+// replace those of the product when given
+type ProductVariant struct {
+	SKU            string
+	Format         VariantFormat
+	Size           string
+	ImageClosedURL string
+	ImageOpenURL   string
+	Ingredients    *[]string
+}
+
+// Variant finds variant of product by its SKU
+func (product Product) Variant(sku string) (ProductVariant, bool) {
+	for _, variant := range product.Variants {
+		if variant.SKU == sku {
+			return variant, true
+		}
+	}
+	return ProductVariant{}, false
+}
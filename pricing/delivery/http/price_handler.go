@@ -0,0 +1,293 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/justinas/alice"
+
+	validatorLib "github.com/iqdf/benjerry-service/common/validator"
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// priceSingleResponse ...
+type priceSingleResponse struct {
+	Data priceResponseData `json:"price"`
+}
+
+// priceListResponse ...
+type priceListResponse struct {
+	Data []priceResponseData `json:"prices"`
+}
+
+type priceResponseData struct {
+	PriceID   string `json:"price_id"`
+	ProductID string `json:"product_id"`
+	SKU       string `json:"sku,omitempty"`
+	Region    string `json:"region"`
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+	Decimal   string `json:"decimal"`
+	ValidFrom int64  `json:"valid_from"`
+	ValidTo   *int64 `json:"valid_to,omitempty"`
+}
+
+type messageError struct {
+	Message string `json:"message"`
+}
+
+// priceCreateRequest takes amount in minor units of
+// currency, e.g. 499 with GBP for £4.99, and times
+// in seconds since epoch
+type priceCreateRequest struct {
+	ProductID string `json:"product_id" validate:"required"`
+	SKU       string `json:"sku" validate:"omitempty,printascii,max=32"`
+	Region    string `json:"region" validate:"required,region"`
+	Amount    int64  `json:"amount" validate:"required,min=1,max=100000000"`
+	Currency  string `json:"currency" validate:"required,currency"`
+	ValidFrom *int64 `json:"valid_from" validate:"omitempty,min=0"`
+	ValidTo   *int64 `json:"valid_to" validate:"omitempty,min=0"`
+}
+
+func newPriceData(price domain.Price) priceResponseData {
+	var validTo *int64
+	if price.ValidTo != nil {
+		seconds := price.ValidTo.Unix()
+		validTo = &seconds
+	}
+
+	return priceResponseData{
+		PriceID:   price.PriceID,
+		ProductID: price.ProductID,
+		SKU:       price.SKU,
+		Region:    price.Region,
+		Amount:    price.Money.Amount,
+		Currency:  price.Money.Currency,
+		Decimal:   price.Money.Decimal(),
+		ValidFrom: price.ValidFrom.Unix(),
+		ValidTo:   validTo,
+	}
+}
+
+func newListResponse(prices []domain.Price) priceListResponse {
+	pricesData := make([]priceResponseData, 0, len(prices))
+	for _, price := range prices {
+		pricesData = append(pricesData, newPriceData(price))
+	}
+	return priceListResponse{Data: pricesData}
+}
+
+func createToPrice(requestData priceCreateRequest) domain.Price {
+	price := domain.Price{
+		ProductID: requestData.ProductID,
+		SKU:       requestData.SKU,
+		Region:    requestData.Region,
+		Money:     domain.Money{Amount: requestData.Amount, Currency: requestData.Currency},
+	}
+
+	if requestData.ValidFrom != nil {
+		price.ValidFrom = time.Unix(*requestData.ValidFrom, 0).UTC()
+	}
+	if requestData.ValidTo != nil {
+		validTo := time.Unix(*requestData.ValidTo, 0).UTC()
+		price.ValidTo = &validTo
+	}
+	return price
+}
+
+// parsePriceQuery reads query of [GET] /api/prices/quote,
+// region is lowercased and currency uppercased
+func parsePriceQuery(r *http.Request) (domain.PriceQuery, error) {
+	values := r.URL.Query()
+
+	query := domain.PriceQuery{
+		ProductID: values.Get("product_id"),
+		SKU:       values.Get("sku"),
+		Region:    strings.ToLower(values.Get("region")),
+		Currency:  strings.ToUpper(values.Get("currency")),
+	}
+
+	if query.ProductID == "" {
+		return query, domain.ErrBadParamInput
+	}
+	if !domain.ValidRegion(query.Region) {
+		return query, domain.ErrBadParamInput
+	}
+	if query.Currency != "" && !domain.ValidCurrency(query.Currency) {
+		return query, domain.ErrBadParamInput
+	}
+
+	if at := values.Get("at"); at != "" {
+		seconds, err := strconv.ParseInt(at, 10, 64)
+		if err != nil || seconds < 0 {
+			return query, domain.ErrBadParamInput
+		}
+		query.At = time.Unix(seconds, 0).UTC()
+	}
+	return query, nil
+}
+
+// PriceHandler ...
+type PriceHandler struct {
+	service domain.PriceService
+}
+
+// NewPriceHandler ...
+func NewPriceHandler(service domain.PriceService) *PriceHandler {
+	return &PriceHandler{service: service}
+}
+
+// Routes register handle func with the path url
+func (handler *PriceHandler) Routes(router *mux.Router, middleware alice.Chain) {
+	fetchHandler := middleware.Then(handler.handleFetchPrices())
+	quoteHandler := middleware.Then(handler.handleQuotePrices())
+	getHandler := middleware.Then(handler.handleGetPrice())
+	createHandler := middleware.Then(handler.handleCreatePrice())
+	deleteHandler := middleware.Then(handler.handleDeletePrice())
+
+	router.Handle("/", fetchHandler).Methods("GET").Name("PRICE_FETCH")
+	router.Handle("/", createHandler).Methods("POST").Name("PRICE_CREATE")
+	router.Handle("/quote", quoteHandler).Methods("GET").Name("PRICE_QUOTE_GET")
+	router.Handle("/{price_id}", getHandler).Methods("GET").Name("PRICE_GET")
+	router.Handle("/{price_id}", deleteHandler).Methods("DELETE").Name("PRICE_DELETE")
+}
+
+// handleFetchPrices provides handler func that lists prices of a product
+// [GET] /api/prices/?product_id=
+func (handler *PriceHandler) handleFetchPrices() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		productID := r.URL.Query().Get("product_id")
+		if productID == "" {
+			writeErrorMessage(w, domain.ErrBadParamInput.Error(), http.StatusBadRequest)
+			return
+		}
+
+		prices, err := handler.service.FetchPrices(r.Context(), productID)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		json.NewEncoder(w).Encode(newListResponse(prices))
+	}
+}
+
+// handleQuotePrices provides handler func that answers price
+// of a product, or of its variant, in region at a time
+// [GET] /api/prices/quote?product_id=&sku=&region=&currency=&at=
+func (handler *PriceHandler) handleQuotePrices() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		query, err := parsePriceQuery(r)
+		if err != nil {
+			writeErrorMessage(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		prices, err := handler.service.QuotePrices(r.Context(), query)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		json.NewEncoder(w).Encode(newListResponse(prices))
+	}
+}
+
+// handleGetPrice provides handler func that gets a price
+// [GET] /api/prices/:price_id
+func (handler *PriceHandler) handleGetPrice() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		price, err := handler.service.GetPrice(r.Context(), params["price_id"])
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		json.NewEncoder(w).Encode(priceSingleResponse{Data: newPriceData(price)})
+	}
+}
+
+// handleCreatePrice provides handler func that adds a price,
+// unknown fields such as decimal amounts are rejected
+// [POST] /api/prices/
+func (handler *PriceHandler) handleCreatePrice() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var priceCreate priceCreateRequest
+		if err := validatorLib.DecodeStrictAndValidateJSON(r.Body, &priceCreate); err != nil {
+			verr, _ := err.(*validatorLib.ValidationError)
+			writeErrorMessage(w, verr.Message(), http.StatusBadRequest)
+			return
+		}
+
+		price, err := handler.service.CreatePrice(r.Context(), createToPrice(priceCreate))
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(priceSingleResponse{Data: newPriceData(price)})
+	}
+}
+
+// handleDeletePrice provides handler func that removes a price
+// [DELETE] /api/prices/:price_id
+func (handler *PriceHandler) handleDeletePrice() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		err := handler.service.DeletePrice(r.Context(), params["price_id"])
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func writeErrorMessage(writer http.ResponseWriter, errMsg string, httpStatus int) {
+	writer.WriteHeader(httpStatus)
+	json.NewEncoder(writer).
+		Encode(messageError{Message: errMsg})
+}
+
+// getResponseStatus inputs error from application
+// and infers the appropriate HTTP status to be returned
+func getResponseStatus(err error) int {
+	switch err {
+	case nil:
+		return http.StatusOK
+	case domain.ErrBadParamInput:
+		return http.StatusBadRequest
+	case domain.ErrResourceNotFound:
+		return http.StatusNotFound
+	case domain.ErrDuplicatePrice:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
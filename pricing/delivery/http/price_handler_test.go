@@ -0,0 +1,192 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/domain/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	contextType = mock.Anything
+	priceType   = mock.AnythingOfType("domain.Price")
+)
+
+func TestCreatePriceSuccess(t *testing.T) {
+	priceService := new(mocks.PriceService)
+
+	validFrom := time.Unix(1596240000, 0).UTC()
+	price := domain.Price{
+		ProductID: "646",
+		Region:    "uk",
+		Money:     domain.Money{Amount: 499, Currency: "GBP"},
+		ValidFrom: validFrom,
+	}
+	created := price
+	created.PriceID = "5f0c"
+
+	priceService.On("CreatePrice", contextType, price).
+		Return(created, nil).
+		Once()
+
+	body := strings.NewReader(`{"product_id": "646", "region": "uk", "amount": 499, "currency": "GBP", "valid_from": 1596240000}`)
+	request, _ := http.NewRequest("POST", "/api/prices/", body)
+	recorder := httptest.NewRecorder()
+
+	priceHandler := NewPriceHandler(priceService)
+	createHandle := priceHandler.handleCreatePrice()
+
+	var priceResponse priceSingleResponse
+
+	createHandle(recorder, request)
+	err := json.NewDecoder(recorder.Body).Decode(&priceResponse)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 201, recorder.Code)
+	assert.Equal(t, "5f0c", priceResponse.Data.PriceID)
+	assert.Equal(t, "4.99", priceResponse.Data.Decimal)
+	priceService.AssertExpectations(t)
+}
+
+func TestCreatePriceInvalid(t *testing.T) {
+	priceService := new(mocks.PriceService)
+
+	bodies := map[string]string{
+		"decimal-amount":   `{"product_id": "646", "region": "uk", "amount": 4.99, "currency": "GBP"}`,
+		"string-amount":    `{"product_id": "646", "region": "uk", "amount": "499", "currency": "GBP"}`,
+		"negative-amount":  `{"product_id": "646", "region": "uk", "amount": -499, "currency": "GBP"}`,
+		"unknown-currency": `{"product_id": "646", "region": "uk", "amount": 499, "currency": "gbp"}`,
+		"unknown-field":    `{"product_id": "646", "region": "uk", "amount": 499, "currency": "GBP", "price": "4.99"}`,
+		"missing-region":   `{"product_id": "646", "amount": 499, "currency": "GBP"}`,
+	}
+
+	for name, body := range bodies {
+		t.Run(name, func(t *testing.T) {
+			request, _ := http.NewRequest("POST", "/api/prices/", strings.NewReader(body))
+			recorder := httptest.NewRecorder()
+
+			priceHandler := NewPriceHandler(priceService)
+			createHandle := priceHandler.handleCreatePrice()
+
+			createHandle(recorder, request)
+			assert.Equal(t, 400, recorder.Code)
+		})
+	}
+	priceService.AssertNotCalled(t, "CreatePrice", contextType, priceType)
+}
+
+func TestCreatePriceDuplicate(t *testing.T) {
+	priceService := new(mocks.PriceService)
+
+	priceService.On("CreatePrice", contextType, priceType).
+		Return(domain.Price{}, domain.ErrDuplicatePrice).
+		Once()
+
+	body := strings.NewReader(`{"product_id": "646", "region": "uk", "amount": 499, "currency": "GBP", "valid_from": 1596240000}`)
+	request, _ := http.NewRequest("POST", "/api/prices/", body)
+	recorder := httptest.NewRecorder()
+
+	priceHandler := NewPriceHandler(priceService)
+	createHandle := priceHandler.handleCreatePrice()
+
+	createHandle(recorder, request)
+	assert.Equal(t, 409, recorder.Code)
+	priceService.AssertExpectations(t)
+}
+
+func TestQuotePricesSuccess(t *testing.T) {
+	priceService := new(mocks.PriceService)
+
+	query := domain.PriceQuery{
+		ProductID: "646",
+		SKU:       "BJ-646-M",
+		Region:    "jp",
+		Currency:  "JPY",
+		At:        time.Unix(1596240000, 0).UTC(),
+	}
+	price := domain.Price{
+		PriceID:   "5f0c",
+		ProductID: "646",
+		SKU:       "BJ-646-M",
+		Region:    "jp",
+		Money:     domain.Money{Amount: 320, Currency: "JPY"},
+		ValidFrom: time.Unix(1593561600, 0).UTC(),
+	}
+
+	priceService.On("QuotePrices", contextType, query).
+		Return([]domain.Price{price}, nil).
+		Once()
+
+	request, _ := http.NewRequest("GET", "/api/prices/quote?product_id=646&sku=BJ-646-M&region=JP&currency=jpy&at=1596240000", nil)
+	recorder := httptest.NewRecorder()
+
+	priceHandler := NewPriceHandler(priceService)
+	quoteHandle := priceHandler.handleQuotePrices()
+
+	var listResponse priceListResponse
+
+	quoteHandle(recorder, request)
+	err := json.NewDecoder(recorder.Body).Decode(&listResponse)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, newListResponse([]domain.Price{price}), listResponse)
+	assert.Equal(t, "320", listResponse.Data[0].Decimal)
+	priceService.AssertExpectations(t)
+}
+
+func TestQuotePricesBadQuery(t *testing.T) {
+	priceService := new(mocks.PriceService)
+
+	for _, url := range []string{
+		"/api/prices/quote?region=uk",
+		"/api/prices/quote?product_id=646",
+		"/api/prices/quote?product_id=646&region=uk&currency=XYZ",
+		"/api/prices/quote?product_id=646&region=uk&at=yesterday",
+	} {
+		t.Run(url, func(t *testing.T) {
+			request, _ := http.NewRequest("GET", url, nil)
+			recorder := httptest.NewRecorder()
+
+			priceHandler := NewPriceHandler(priceService)
+			quoteHandle := priceHandler.handleQuotePrices()
+
+			quoteHandle(recorder, request)
+			assert.Equal(t, 400, recorder.Code)
+		})
+	}
+}
+
+func TestDeletePriceNotFound(t *testing.T) {
+	priceService := new(mocks.PriceService)
+
+	priceService.On("DeletePrice", contextType, "5f0c").
+		Return(domain.ErrResourceNotFound).
+		Once()
+
+	request, _ := http.NewRequest("DELETE", "/api/prices/5f0c", nil)
+	request = mux.SetURLVars(request, map[string]string{"price_id": "5f0c"})
+	recorder := httptest.NewRecorder()
+
+	priceHandler := NewPriceHandler(priceService)
+	deleteHandle := priceHandler.handleDeletePrice()
+
+	deleteHandle(recorder, request)
+	assert.Equal(t, 404, recorder.Code)
+	priceService.AssertExpectations(t)
+}
+
+func TestMoneyDecimal(t *testing.T) {
+	assert.Equal(t, "4.99", domain.Money{Amount: 499, Currency: "GBP"}.Decimal())
+	assert.Equal(t, "0.05", domain.Money{Amount: 5, Currency: "USD"}.Decimal())
+	assert.Equal(t, "1.250", domain.Money{Amount: 1250, Currency: "KWD"}.Decimal())
+	assert.Equal(t, "499", domain.Money{Amount: 499, Currency: "JPY"}.Decimal())
+}
@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// PriceMemoryRepo keeps prices in process memory, e.g. to run without
+// mongo. At most one price of a product, or of its variant, in a region
+// and currency takes effect at a time, like in mongo
+type PriceMemoryRepo struct {
+	mu     sync.RWMutex
+	prices map[string]domain.Price
+	lastID int64
+}
+
+// NewPriceRepo creates an empty in-memory price repository
+func NewPriceRepo() *PriceMemoryRepo {
+	return &PriceMemoryRepo{prices: make(map[string]domain.Price)}
+}
+
+// copyPrice copies price in UTC, so that stored
+// prices share no valid to time with callers
+func copyPrice(price domain.Price) domain.Price {
+	price.ValidFrom = price.ValidFrom.UTC()
+	if price.ValidTo != nil {
+		validTo := price.ValidTo.UTC()
+		price.ValidTo = &validTo
+	}
+	return price
+}
+
+// Fetch queries all prices of a product, latest first
+func (repo *PriceMemoryRepo) Fetch(ctx context.Context, productID string) ([]domain.Price, error) {
+	return repo.find(func(price domain.Price) bool {
+		return price.ProductID == productID
+	}), nil
+}
+
+// FetchEffective queries prices of a product in region effective at
+// time of query, latest first. Given SKU, both prices of the variant
+// and of the product are queried
+func (repo *PriceMemoryRepo) FetchEffective(ctx context.Context, query domain.PriceQuery) ([]domain.Price, error) {
+	return repo.find(func(price domain.Price) bool {
+		return price.ProductID == query.ProductID &&
+			price.Region == query.Region &&
+			(price.SKU == query.SKU || price.SKU == "") &&
+			(query.Currency == "" || price.Money.Currency == query.Currency) &&
+			!price.ValidFrom.After(query.At) &&
+			(price.ValidTo == nil || price.ValidTo.After(query.At))
+	}), nil
+}
+
+func (repo *PriceMemoryRepo) find(match func(price domain.Price) bool) []domain.Price {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	var prices = make([]domain.Price, 0)
+	for _, price := range repo.prices {
+		if match(price) {
+			prices = append(prices, copyPrice(price))
+		}
+	}
+
+	sort.Slice(prices, func(i, j int) bool {
+		if !prices[i].ValidFrom.Equal(prices[j].ValidFrom) {
+			return prices[i].ValidFrom.After(prices[j].ValidFrom)
+		}
+		return prices[i].PriceID < prices[j].PriceID
+	})
+	return prices
+}
+
+// Get queries a single price
+func (repo *PriceMemoryRepo) Get(ctx context.Context, priceID string) (domain.Price, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	price, ok := repo.prices[priceID]
+	if !ok {
+		return domain.Price{}, domain.ErrResourceNotFound
+	}
+	return copyPrice(price), nil
+}
+
+// Create stores a price and returns its ID
+func (repo *PriceMemoryRepo) Create(ctx context.Context, price domain.Price) (string, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	price = copyPrice(price)
+	for _, stored := range repo.prices {
+		if samePriceKey(stored, price) {
+			return "", domain.ErrDuplicatePrice
+		}
+	}
+
+	repo.lastID++
+	price.PriceID = strconv.FormatInt(repo.lastID, 10)
+	repo.prices[price.PriceID] = price
+	return price.PriceID, nil
+}
+
+// samePriceKey tells whether prices are of the same product or variant,
+// region and currency, and take effect at the same time
+func samePriceKey(price domain.Price, other domain.Price) bool {
+	return price.ProductID == other.ProductID &&
+		price.SKU == other.SKU &&
+		price.Region == other.Region &&
+		price.Money.Currency == other.Money.Currency &&
+		price.ValidFrom.Truncate(time.Millisecond).Equal(other.ValidFrom.Truncate(time.Millisecond))
+}
+
+// Delete removes a single price
+func (repo *PriceMemoryRepo) Delete(ctx context.Context, priceID string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.prices[priceID]; !ok {
+		return domain.ErrResourceNotFound
+	}
+	delete(repo.prices, priceID)
+	return nil
+}
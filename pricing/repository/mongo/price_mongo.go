@@ -0,0 +1,172 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	mongoHelper "github.com/iqdf/benjerry-service/common/repository/mongo"
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+const collectionName = "IceCreamPrice" // prices
+
+// PriceModel ...
+type PriceModel struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	ProductID string             `bson:"productId"`
+	SKU       string             `bson:"sku,omitempty"`
+	Region    string             `bson:"region"`
+	Amount    int64              `bson:"amount"`
+	Currency  string             `bson:"currency"`
+	ValidFrom time.Time          `bson:"valid_from"`
+	ValidTo   *time.Time         `bson:"valid_to"`
+}
+
+// PriceMongoRepo ...
+type PriceMongoRepo struct {
+	client *mongo.Client
+	db     *mongo.Database
+}
+
+// modelFromPrice creates new PriceModel and
+// copy data from price entity to price DB model
+func modelFromPrice(price domain.Price) PriceModel {
+	return PriceModel{
+		ProductID: price.ProductID,
+		SKU:       price.SKU,
+		Region:    price.Region,
+		Amount:    price.Money.Amount,
+		Currency:  price.Money.Currency,
+		ValidFrom: price.ValidFrom,
+		ValidTo:   price.ValidTo,
+	}
+}
+
+// Price creates price entity instance and
+// copies data from model into price entity
+func (model *PriceModel) Price() domain.Price {
+	var validTo *time.Time
+	if model.ValidTo != nil {
+		utc := model.ValidTo.UTC()
+		validTo = &utc
+	}
+
+	return domain.Price{
+		PriceID:   model.ID.Hex(),
+		ProductID: model.ProductID,
+		SKU:       model.SKU,
+		Region:    model.Region,
+		Money:     domain.Money{Amount: model.Amount, Currency: model.Currency},
+		ValidFrom: model.ValidFrom.UTC(),
+		ValidTo:   validTo,
+	}
+}
+
+// NewPriceRepo ...
+func NewPriceRepo(client *mongo.Client, dbName string) *PriceMongoRepo {
+	repo := &PriceMongoRepo{
+		client: client,
+		db:     client.Database(dbName),
+	}
+	return repo
+}
+
+// Fetch queries all prices of a product, latest first
+func (repo *PriceMongoRepo) Fetch(ctx context.Context, productID string) ([]domain.Price, error) {
+	return repo.find(ctx, bson.M{"productId": productID})
+}
+
+// FetchEffective queries prices of a product in region effective at
+// time of query, latest first. Given SKU, both prices of the variant
+// and of the product are queried
+func (repo *PriceMongoRepo) FetchEffective(ctx context.Context, query domain.PriceQuery) ([]domain.Price, error) {
+	filter := bson.M{
+		"productId":  query.ProductID,
+		"region":     query.Region,
+		"sku":        bson.M{"$in": bson.A{query.SKU, nil}},
+		"valid_from": bson.M{"$lte": query.At},
+		"$or": bson.A{
+			bson.M{"valid_to": nil},
+			bson.M{"valid_to": bson.M{"$gt": query.At}},
+		},
+	}
+
+	if query.Currency != "" {
+		filter["currency"] = query.Currency
+	}
+	return repo.find(ctx, filter)
+}
+
+func (repo *PriceMongoRepo) find(ctx context.Context, filter bson.M) ([]domain.Price, error) {
+	collection := repo.db.Collection(collectionName)
+	findOptions := options.Find().SetSort(bson.D{{Key: "valid_from", Value: -1}})
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, mongoHelper.TranslateError(err)
+	}
+	defer cursor.Close(ctx)
+
+	var prices = make([]domain.Price, 0)
+	for cursor.Next(ctx) {
+		var model PriceModel
+		if err = cursor.Decode(&model); err != nil {
+			return nil, mongoHelper.TranslateError(err)
+		}
+		prices = append(prices, model.Price())
+	}
+	return prices, mongoHelper.TranslateError(cursor.Err())
+}
+
+// Get queries a single price
+func (repo *PriceMongoRepo) Get(ctx context.Context, priceID string) (domain.Price, error) {
+	id, err := primitive.ObjectIDFromHex(priceID)
+	if err != nil {
+		return domain.Price{}, domain.ErrResourceNotFound
+	}
+
+	var model PriceModel
+	collection := repo.db.Collection(collectionName)
+	err = collection.FindOne(ctx, bson.M{"_id": id}).Decode(&model)
+
+	return model.Price(), mongoHelper.TranslateError(err)
+}
+
+// Create inserts a price and returns its ID
+func (repo *PriceMongoRepo) Create(ctx context.Context, price domain.Price) (string, error) {
+	collection := repo.db.Collection(collectionName)
+	result, err := collection.InsertOne(ctx, modelFromPrice(price))
+
+	if err = mongoHelper.TranslateError(err); err == domain.ErrConflict {
+		return "", domain.ErrDuplicatePrice
+	} else if err != nil {
+		return "", err
+	}
+
+	id, _ := result.InsertedID.(primitive.ObjectID)
+	return id.Hex(), nil
+}
+
+// Delete removes a single price
+func (repo *PriceMongoRepo) Delete(ctx context.Context, priceID string) error {
+	id, err := primitive.ObjectIDFromHex(priceID)
+	if err != nil {
+		return domain.ErrResourceNotFound
+	}
+
+	collection := repo.db.Collection(collectionName)
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": id})
+
+	if err != nil {
+		return mongoHelper.TranslateError(err)
+	}
+	if result.DeletedCount == 0 {
+		return domain.ErrResourceNotFound
+	}
+	return nil
+}
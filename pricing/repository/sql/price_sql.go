@@ -0,0 +1,173 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	sqlHelper "github.com/iqdf/benjerry-service/common/repository/sql"
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// priceColumns are the columns scanned by scanPrice
+const priceColumns = "price_id, product_id, sku, region, amount, currency, valid_from, valid_to"
+
+// PriceSQLRepo stores prices in a SQL database. At most one price of a
+// product, or of its variant, in a region and currency takes effect at
+// a time, like in mongo
+type PriceSQLRepo struct {
+	db *sqlHelper.DB
+}
+
+// NewPriceRepo creates price repository over db, whose
+// schema is migrated by sqlHelper.DB.Migrate
+func NewPriceRepo(db *sqlHelper.DB) *PriceSQLRepo {
+	return &PriceSQLRepo{db: db}
+}
+
+// scanPrice reads price from columns listed in priceColumns
+func scanPrice(rows *sql.Rows) (domain.Price, error) {
+	var (
+		price   domain.Price
+		priceID int64
+		validTo sql.NullTime
+	)
+
+	err := rows.Scan(
+		&priceID, &price.ProductID, &price.SKU, &price.Region,
+		&price.Money.Amount, &price.Money.Currency, &price.ValidFrom, &validTo,
+	)
+	if err != nil {
+		return domain.Price{}, err
+	}
+
+	price.PriceID = strconv.FormatInt(priceID, 10)
+	price.ValidFrom = price.ValidFrom.UTC()
+	if validTo.Valid {
+		utc := validTo.Time.UTC()
+		price.ValidTo = &utc
+	}
+	return price, nil
+}
+
+// Fetch queries all prices of a product, latest first
+func (repo *PriceSQLRepo) Fetch(ctx context.Context, productID string) ([]domain.Price, error) {
+	return repo.find(ctx, "product_id = ?", productID)
+}
+
+// FetchEffective queries prices of a product in region effective at
+// time of query, latest first. Given SKU, both prices of the variant
+// and of the product are queried
+func (repo *PriceSQLRepo) FetchEffective(ctx context.Context, query domain.PriceQuery) ([]domain.Price, error) {
+	at := query.At.UTC()
+	where := "product_id = ? AND region = ? AND sku IN (?, '') AND valid_from <= ? AND (valid_to IS NULL OR valid_to > ?)"
+	args := []interface{}{query.ProductID, query.Region, query.SKU, at, at}
+
+	if query.Currency != "" {
+		where += " AND currency = ?"
+		args = append(args, query.Currency)
+	}
+	return repo.find(ctx, where, args...)
+}
+
+func (repo *PriceSQLRepo) find(ctx context.Context, where string, args ...interface{}) ([]domain.Price, error) {
+	rows, err := repo.db.Conn(ctx).QueryContext(ctx, repo.db.Rebind(
+		"SELECT "+priceColumns+" FROM prices WHERE "+where+" ORDER BY valid_from DESC, price_id",
+	), args...)
+	if err != nil {
+		return nil, sqlHelper.TranslateError(err)
+	}
+	defer rows.Close()
+
+	var prices = make([]domain.Price, 0)
+	for rows.Next() {
+		price, err := scanPrice(rows)
+		if err != nil {
+			return nil, sqlHelper.TranslateError(err)
+		}
+		prices = append(prices, price)
+	}
+	return prices, sqlHelper.TranslateError(rows.Err())
+}
+
+// Get queries a single price
+func (repo *PriceSQLRepo) Get(ctx context.Context, priceID string) (domain.Price, error) {
+	id, err := strconv.ParseInt(priceID, 10, 64)
+	if err != nil {
+		return domain.Price{}, domain.ErrResourceNotFound
+	}
+
+	prices, err := repo.find(ctx, "price_id = ?", id)
+	if err != nil {
+		return domain.Price{}, err
+	}
+
+	if len(prices) == 0 {
+		return domain.Price{}, domain.ErrResourceNotFound
+	}
+	return prices[0], nil
+}
+
+// Create inserts a price and returns its ID. Start of price is stored
+// at millisecond precision, like in mongo, so that prices starting
+// within the same millisecond take effect at the same time
+func (repo *PriceSQLRepo) Create(ctx context.Context, price domain.Price) (string, error) {
+	var validTo interface{}
+	if price.ValidTo != nil {
+		validTo = price.ValidTo.UTC()
+	}
+
+	query := "INSERT INTO prices (product_id, sku, region, amount, currency, valid_from, valid_to) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	args := []interface{}{
+		price.ProductID, price.SKU, price.Region, price.Money.Amount, price.Money.Currency,
+		price.ValidFrom.UTC().Truncate(time.Millisecond), validTo,
+	}
+
+	var (
+		priceID int64
+		err     error
+	)
+	conn := repo.db.Conn(ctx)
+	if repo.db.Dialect == sqlHelper.Postgres {
+		err = conn.QueryRowContext(ctx, repo.db.Rebind(query+" RETURNING price_id"), args...).Scan(&priceID)
+	} else {
+		var result sql.Result
+		if result, err = conn.ExecContext(ctx, query, args...); err == nil {
+			priceID, err = result.LastInsertId()
+		}
+	}
+
+	// prices taking effect at the same time are the
+	// only unique constraint other than the price ID
+	switch {
+	case err == nil:
+		return strconv.FormatInt(priceID, 10), nil
+	case sqlHelper.ViolatedUnique(err) != "":
+		return "", domain.ErrDuplicatePrice
+	}
+	return "", sqlHelper.TranslateError(err)
+}
+
+// Delete removes a single price
+func (repo *PriceSQLRepo) Delete(ctx context.Context, priceID string) error {
+	id, err := strconv.ParseInt(priceID, 10, 64)
+	if err != nil {
+		return domain.ErrResourceNotFound
+	}
+
+	result, err := repo.db.Conn(ctx).ExecContext(ctx, repo.db.Rebind("DELETE FROM prices WHERE price_id = ?"), id)
+	if err != nil {
+		return sqlHelper.TranslateError(err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return sqlHelper.TranslateError(err)
+	}
+
+	if deleted == 0 {
+		return domain.ErrResourceNotFound
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package sql
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sqlHelper "github.com/iqdf/benjerry-service/common/repository/sql"
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriceRepo(t *testing.T) {
+	ctx := context.TODO()
+	dir, _ := ioutil.TempDir("", "benjerry")
+	defer os.RemoveAll(dir)
+
+	db, err := sqlHelper.Open(sqlHelper.SQLite, "file:"+filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.Migrate(ctx)
+	repo := NewPriceRepo(db)
+
+	validFrom := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	price := domain.Price{
+		ProductID: "646",
+		Region:    "uk",
+		Money:     domain.Money{Amount: 499, Currency: "GBP"},
+		ValidFrom: validFrom,
+	}
+
+	priceID, err := repo.Create(ctx, price)
+	assert.NoError(t, err)
+
+	t.Run("Create-duplicate", func(t *testing.T) {
+		_, err := repo.Create(ctx, price)
+		assert.Equal(t, domain.ErrDuplicatePrice, err)
+	})
+
+	t.Run("FetchEffective", func(t *testing.T) {
+		variant := price
+		variant.SKU = "BJ-646-P"
+		variant.ValidFrom = validFrom.Add(24 * time.Hour)
+		repo.Create(ctx, variant)
+
+		prices, err := repo.FetchEffective(ctx, domain.PriceQuery{ProductID: "646", SKU: "BJ-646-P", Region: "uk", At: validFrom.Add(48 * time.Hour)})
+		assert.NoError(t, err)
+		assert.Len(t, prices, 2)
+		assert.Equal(t, "BJ-646-P", prices[0].SKU)
+
+		prices, _ = repo.FetchEffective(ctx, domain.PriceQuery{ProductID: "646", Region: "uk", At: validFrom.Add(-time.Hour)})
+		assert.Empty(t, prices)
+	})
+
+	t.Run("Get-and-delete", func(t *testing.T) {
+		stored, err := repo.Get(ctx, priceID)
+		assert.NoError(t, err)
+		assert.Equal(t, validFrom, stored.ValidFrom)
+
+		assert.NoError(t, repo.Delete(ctx, priceID))
+		assert.Equal(t, domain.ErrResourceNotFound, repo.Delete(ctx, priceID))
+
+		_, err = repo.Get(ctx, "not-an-id")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+const timeout = time.Second * 10
+
+// PriceService ...
+type PriceService struct {
+	appName        string
+	priceRepo      domain.PriceRepository
+	productService domain.ProductService
+}
+
+// NewPriceService creates new service that provides use cases
+// for prices of products. Products are looked up through
+// productService, so that prices of products hidden from
+// the member are hidden too
+func NewPriceService(
+	appName string,
+	priceRepo domain.PriceRepository,
+	productService domain.ProductService,
+) *PriceService {
+	return &PriceService{
+		appName:        appName,
+		priceRepo:      priceRepo,
+		productService: productService,
+	}
+}
+
+// FetchPrices lists all prices of product, whether
+// they are in effect, scheduled or expired
+func (service *PriceService) FetchPrices(ctx context.Context, productID string) ([]domain.Price, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if _, err := service.productService.GetProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+	return service.priceRepo.Fetch(ctx, productID)
+}
+
+// GetPrice ...
+func (service *PriceService) GetPrice(ctx context.Context, priceID string) (domain.Price, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return service.priceRepo.Get(ctx, priceID)
+}
+
+// CreatePrice adds price of product, or of its variant given SKU,
+// taking effect at ValidFrom, or right away when it is not set
+func (service *PriceService) CreatePrice(ctx context.Context, price domain.Price) (domain.Price, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if price.ValidFrom.IsZero() {
+		price.ValidFrom = time.Now()
+	}
+	price.ValidFrom = price.ValidFrom.UTC().Truncate(time.Second)
+
+	if !price.Money.Valid() || !domain.ValidRegion(price.Region) {
+		return domain.Price{}, domain.ErrBadParamInput
+	}
+	if price.ValidTo != nil && !price.ValidTo.After(price.ValidFrom) {
+		return domain.Price{}, domain.ErrBadParamInput
+	}
+
+	product, err := service.productService.GetProduct(ctx, price.ProductID)
+	if err != nil {
+		return domain.Price{}, err
+	}
+	if _, ok := product.Variant(price.SKU); price.SKU != "" && !ok {
+		return domain.Price{}, domain.ErrResourceNotFound
+	}
+
+	price.PriceID, err = service.priceRepo.Create(ctx, price)
+	if err != nil {
+		return domain.Price{}, err
+	}
+	return price, nil
+}
+
+// DeletePrice ...
+func (service *PriceService) DeletePrice(ctx context.Context, priceID string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return service.priceRepo.Delete(ctx, priceID)
+}
+
+// QuotePrices answers the price of product, or of its variant given SKU,
+// in region at time of query, or now when it is not set, in each currency
+// or only in the queried currency. Prices of the variant take precedence
+// over prices of the product, and later prices over earlier ones.
+// Products not sold in the region have no price there
+func (service *PriceService) QuotePrices(ctx context.Context, query domain.PriceQuery) ([]domain.Price, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if !domain.ValidRegion(query.Region) {
+		return nil, domain.ErrBadParamInput
+	}
+	if query.Currency != "" && !domain.ValidCurrency(query.Currency) {
+		return nil, domain.ErrBadParamInput
+	}
+	if query.At.IsZero() {
+		query.At = time.Now()
+	}
+
+	product, err := service.productService.GetProduct(ctx, query.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := product.Variant(query.SKU); query.SKU != "" && !ok {
+		return nil, domain.ErrResourceNotFound
+	}
+	if !product.SoldIn(query.Region) {
+		return nil, domain.ErrResourceNotFound
+	}
+
+	candidates, err := service.priceRepo.FetchEffective(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := pickPrices(candidates, query)
+	if len(prices) == 0 {
+		return nil, domain.ErrResourceNotFound
+	}
+	return prices, nil
+}
+
+// pickPrices picks the price in effect in each currency, ordered by currency
+func pickPrices(candidates []domain.Price, query domain.PriceQuery) []domain.Price {
+	byCurrency := make(map[string]domain.Price)
+
+	for _, candidate := range candidates {
+		if !candidate.EffectiveAt(query.At) || (candidate.SKU != "" && candidate.SKU != query.SKU) {
+			continue
+		}
+
+		picked, ok := byCurrency[candidate.Money.Currency]
+		if !ok || precedes(candidate, picked) {
+			byCurrency[candidate.Money.Currency] = candidate
+		}
+	}
+
+	prices := make([]domain.Price, 0, len(byCurrency))
+	for _, price := range byCurrency {
+		prices = append(prices, price)
+	}
+
+	sort.Slice(prices, func(i, j int) bool {
+		return prices[i].Money.Currency < prices[j].Money.Currency
+	})
+	return prices
+}
+
+// precedes tells whether price takes precedence over other,
+// which is when it is of a variant and other is not, or
+// when it took effect later
+func precedes(price, other domain.Price) bool {
+	if (price.SKU != "") != (other.SKU != "") {
+		return price.SKU != ""
+	}
+	return price.ValidFrom.After(other.ValidFrom)
+}
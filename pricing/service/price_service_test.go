@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/domain/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	appName     = "TestApp"
+	contextType = mock.Anything
+	priceType   = mock.AnythingOfType("domain.Price")
+	queryType   = mock.AnythingOfType("domain.PriceQuery")
+)
+
+func TestCreatePrice(t *testing.T) {
+	// setup mock repository, mock service and mock data
+	mockPriceRepo := new(mocks.PriceRepository)
+	mockProductService := new(mocks.ProductService)
+
+	mockProduct := createMockProduct()
+	validFrom := time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("CreatePrice-success", func(t *testing.T) {
+		price := domain.Price{
+			ProductID: "646",
+			SKU:       "BJ-646-P",
+			Region:    "uk",
+			Money:     domain.Money{Amount: 499, Currency: "GBP"},
+			ValidFrom: validFrom,
+		}
+
+		mockProductService.On("GetProduct", contextType, "646").
+			Return(mockProduct, nil).
+			Once()
+		mockPriceRepo.On("Create", contextType, price).
+			Return("5f0c", nil).
+			Once()
+
+		var priceService = NewPriceService(appName, mockPriceRepo, mockProductService)
+		created, err := priceService.CreatePrice(context.TODO(), price)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "5f0c", created.PriceID)
+		mockPriceRepo.AssertExpectations(t)
+	})
+
+	t.Run("CreatePrice-invalid", func(t *testing.T) {
+		validTo := validFrom.Add(-time.Hour)
+		invalidPrices := map[string]domain.Price{
+			"zero-amount":      {ProductID: "646", Region: "uk", Money: domain.Money{Currency: "GBP"}},
+			"negative-amount":  {ProductID: "646", Region: "uk", Money: domain.Money{Amount: -1, Currency: "GBP"}},
+			"unknown-currency": {ProductID: "646", Region: "uk", Money: domain.Money{Amount: 499, Currency: "XYZ"}},
+			"bad-region":       {ProductID: "646", Region: "UK", Money: domain.Money{Amount: 499, Currency: "GBP"}},
+			"ends-before-start": {
+				ProductID: "646", Region: "uk", Money: domain.Money{Amount: 499, Currency: "GBP"},
+				ValidFrom: validFrom, ValidTo: &validTo,
+			},
+		}
+
+		for name, price := range invalidPrices {
+			t.Run(name, func(t *testing.T) {
+				var priceService = NewPriceService(appName, mockPriceRepo, mockProductService)
+				_, err := priceService.CreatePrice(context.TODO(), price)
+
+				assert.Equal(t, domain.ErrBadParamInput, err)
+			})
+		}
+	})
+
+	t.Run("CreatePrice-unknown-variant", func(t *testing.T) {
+		mockPriceRepo := new(mocks.PriceRepository)
+		mockProductService.On("GetProduct", contextType, "646").
+			Return(mockProduct, nil).
+			Once()
+
+		price := domain.Price{
+			ProductID: "646",
+			SKU:       "BJ-646-X",
+			Region:    "uk",
+			Money:     domain.Money{Amount: 499, Currency: "GBP"},
+		}
+
+		var priceService = NewPriceService(appName, mockPriceRepo, mockProductService)
+		_, err := priceService.CreatePrice(context.TODO(), price)
+
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+		mockPriceRepo.AssertNotCalled(t, "Create", contextType, priceType)
+	})
+}
+
+func TestQuotePrices(t *testing.T) {
+	// setup mock repository, mock service and mock data
+	mockPriceRepo := new(mocks.PriceRepository)
+	mockProductService := new(mocks.ProductService)
+
+	mockProduct := createMockProduct()
+	mockProduct.Regions = []string{"us", "uk"}
+
+	july := time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)
+	august := time.Date(2020, 8, 1, 0, 0, 0, 0, time.UTC)
+	at := time.Date(2020, 8, 15, 0, 0, 0, 0, time.UTC)
+
+	productPrice := domain.Price{
+		PriceID: "1", ProductID: "646", Region: "uk",
+		Money: domain.Money{Amount: 450, Currency: "GBP"}, ValidFrom: july,
+	}
+	raisedPrice := domain.Price{
+		PriceID: "2", ProductID: "646", Region: "uk",
+		Money: domain.Money{Amount: 499, Currency: "GBP"}, ValidFrom: august,
+	}
+	variantPrice := domain.Price{
+		PriceID: "3", ProductID: "646", SKU: "BJ-646-P", Region: "uk",
+		Money: domain.Money{Amount: 525, Currency: "GBP"}, ValidFrom: july,
+	}
+	euroPrice := domain.Price{
+		PriceID: "4", ProductID: "646", Region: "uk",
+		Money: domain.Money{Amount: 549, Currency: "EUR"}, ValidFrom: july,
+	}
+
+	t.Run("QuotePrices-latest", func(t *testing.T) {
+		query := domain.PriceQuery{ProductID: "646", Region: "uk", At: at}
+
+		mockProductService.On("GetProduct", contextType, "646").
+			Return(mockProduct, nil).
+			Once()
+		mockPriceRepo.On("FetchEffective", contextType, query).
+			Return([]domain.Price{raisedPrice, productPrice, euroPrice}, nil).
+			Once()
+
+		var priceService = NewPriceService(appName, mockPriceRepo, mockProductService)
+		prices, err := priceService.QuotePrices(context.TODO(), query)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.Price{euroPrice, raisedPrice}, prices)
+		mockPriceRepo.AssertExpectations(t)
+	})
+
+	t.Run("QuotePrices-variant", func(t *testing.T) {
+		query := domain.PriceQuery{ProductID: "646", SKU: "BJ-646-P", Region: "uk", Currency: "GBP", At: at}
+
+		mockProductService.On("GetProduct", contextType, "646").
+			Return(mockProduct, nil).
+			Once()
+		mockPriceRepo.On("FetchEffective", contextType, query).
+			Return([]domain.Price{raisedPrice, productPrice, variantPrice}, nil).
+			Once()
+
+		var priceService = NewPriceService(appName, mockPriceRepo, mockProductService)
+		prices, err := priceService.QuotePrices(context.TODO(), query)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.Price{variantPrice}, prices)
+	})
+
+	t.Run("QuotePrices-not-sold", func(t *testing.T) {
+		mockPriceRepo := new(mocks.PriceRepository)
+		query := domain.PriceQuery{ProductID: "646", Region: "fr", At: at}
+
+		mockProductService.On("GetProduct", contextType, "646").
+			Return(mockProduct, nil).
+			Once()
+
+		var priceService = NewPriceService(appName, mockPriceRepo, mockProductService)
+		_, err := priceService.QuotePrices(context.TODO(), query)
+
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+		mockPriceRepo.AssertNotCalled(t, "FetchEffective", contextType, queryType)
+	})
+
+	t.Run("QuotePrices-no-price", func(t *testing.T) {
+		query := domain.PriceQuery{ProductID: "646", Region: "us", At: at}
+
+		mockProductService.On("GetProduct", contextType, "646").
+			Return(mockProduct, nil).
+			Once()
+		mockPriceRepo.On("FetchEffective", contextType, query).
+			Return([]domain.Price{}, nil).
+			Once()
+
+		var priceService = NewPriceService(appName, mockPriceRepo, mockProductService)
+		_, err := priceService.QuotePrices(context.TODO(), query)
+
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+}
+
+func TestPickPricesEffective(t *testing.T) {
+	july := time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)
+	august := time.Date(2020, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	expired := domain.Price{PriceID: "1", Money: domain.Money{Amount: 450, Currency: "GBP"}, ValidFrom: july, ValidTo: &august}
+	current := domain.Price{PriceID: "2", Money: domain.Money{Amount: 499, Currency: "GBP"}, ValidFrom: july.Add(-time.Hour)}
+
+	assert.Equal(t, []domain.Price{expired}, pickPrices([]domain.Price{expired, current}, domain.PriceQuery{At: july}))
+	assert.Equal(t, []domain.Price{current}, pickPrices([]domain.Price{expired, current}, domain.PriceQuery{At: august}))
+	assert.Empty(t, pickPrices([]domain.Price{expired}, domain.PriceQuery{At: august}))
+}
+
+func createMockProduct() domain.Product {
+	return domain.Product{
+		ProductID: "646",
+		Name:      "Vanilla Toffee Bar Crunch",
+		Variants: []domain.ProductVariant{
+			{SKU: "BJ-646-P", Format: domain.FormatPint, Size: "465ml"},
+		},
+	}
+}
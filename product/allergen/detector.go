@@ -0,0 +1,97 @@
+package allergen
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// Detector proposes allergens of products by
+// looking up their ingredients in a dictionary
+type Detector struct {
+	dictionary Dictionary
+}
+
+// NewDetector creates detector looking up ingredients in dictionary
+func NewDetector(dictionary Dictionary) *Detector {
+	return &Detector{dictionary: dictionary}
+}
+
+// Detect lists allergens contained in any of the ingredients,
+// in the order of domain.KnownAllergens
+func (detector *Detector) Detect(ingredients []string) []string {
+	var detected []string
+	for _, allergen := range domain.KnownAllergens {
+		entry, ok := detector.dictionary[allergen]
+		if !ok {
+			continue
+		}
+
+		for _, ingredient := range ingredients {
+			if entry.matches(normalize(ingredient)) {
+				detected = append(detected, allergen)
+				break
+			}
+		}
+	}
+	return detected
+}
+
+// Check compares allergens declared as contained
+// with the allergens detected in ingredients
+func (detector *Detector) Check(allergens domain.Allergens, ingredients []string) domain.AllergenCheck {
+	detected := detector.Detect(ingredients)
+
+	var check domain.AllergenCheck
+	for _, allergen := range detected {
+		if !contains(allergens.Contains, allergen) {
+			check.Undeclared = append(check.Undeclared, allergen)
+		}
+	}
+	for _, allergen := range allergens.Contains {
+		if !contains(detected, allergen) {
+			check.Undetected = append(check.Undetected, allergen)
+		}
+	}
+	return check
+}
+
+// matches tells whether normalized ingredient has any of the
+// synonyms of entry once its exceptions are blanked out
+func (entry Entry) matches(ingredient string) bool {
+	for _, exception := range entry.Exceptions {
+		// adjacent exceptions share the space between
+		// them, so they are blanked out one at a time
+		exception = normalize(exception)
+		for strings.Contains(ingredient, exception) {
+			ingredient = strings.Replace(ingredient, exception, " ", -1)
+		}
+	}
+
+	for _, synonym := range entry.Synonyms {
+		if strings.Contains(ingredient, normalize(synonym)) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize lower cases text and separates its words by single
+// spaces, including one before and after, so that words are
+// matched as a whole, e.g. " soy lecithin " in " soy lecithin emulsifier "
+func normalize(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return " " + strings.Join(words, " ") + " "
+}
+
+func contains(values []string, value string) bool {
+	for _, listed := range values {
+		if listed == value {
+			return true
+		}
+	}
+	return false
+}
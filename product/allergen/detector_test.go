@@ -0,0 +1,49 @@
+package allergen
+
+import (
+	"testing"
+
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	detector := NewDetector(DefaultDictionary)
+
+	testCases := []struct {
+		name        string
+		ingredients []string
+		expected    []string
+	}{
+		{name: "synonyms", ingredients: []string{"Skim Milk", "egg yolks", "soy lecithin"}, expected: []string{"milk", "eggs", "soy"}},
+		{name: "vocabulary-order", ingredients: []string{"wheat flour", "almonds", "cream"}, expected: []string{"milk", "tree nuts", "wheat"}},
+		{name: "whole-words", ingredients: []string{"creamy texture", "buttery flavor"}},
+		{name: "exceptions", ingredients: []string{"cocoa butter", "coconut milk", "Cocoa Butter, cocoa butter"}},
+		{name: "exception-next-to-synonym", ingredients: []string{"cocoa butter and butter"}, expected: []string{"milk"}},
+		{name: "peanut-butter", ingredients: []string{"peanut butter"}, expected: []string{"peanuts"}},
+		{name: "nothing", ingredients: nil},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, detector.Detect(testCase.ingredients))
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	detector := NewDetector(DefaultDictionary)
+	ingredients := []string{"cream", "sugar", "egg yolks"}
+
+	t.Run("agrees", func(t *testing.T) {
+		allergens := domain.Allergens{Contains: []string{"milk", "eggs"}, MayContain: []string{"peanuts"}}
+		assert.True(t, detector.Check(allergens, ingredients).Agrees())
+	})
+
+	t.Run("disagrees", func(t *testing.T) {
+		// may contain does not declare an allergen as contained
+		allergens := domain.Allergens{Contains: []string{"eggs", "soy"}, MayContain: []string{"milk"}}
+		expected := domain.AllergenCheck{Undeclared: []string{"milk"}, Undetected: []string{"soy"}}
+		assert.Equal(t, expected, detector.Check(allergens, ingredients))
+	})
+}
@@ -0,0 +1,83 @@
+package allergen
+
+// Entry lists words of ingredients that contain an allergen, and
+// words that mention one of them without containing the allergen,
+// e.g. cocoa butter, which is no butter and has no milk
+type Entry struct {
+	Synonyms   []string
+	Exceptions []string
+}
+
+// Dictionary maps known allergens to their entries. Words are matched
+// as whole words regardless of case, so both singular and plural
+// forms are listed
+type Dictionary map[string]Entry
+
+// DefaultDictionary is the dictionary of ingredients used in the
+// catalog. When a new ingredient is added to the catalog, add it
+// as synonym of every allergen it contains
+var DefaultDictionary = Dictionary{
+	"milk": {
+		Synonyms: []string{
+			"milk", "skim milk", "nonfat milk", "milkfat", "milk fat", "cream", "butter",
+			"buttermilk", "butteroil", "whey", "casein", "caseinate", "caseinates",
+			"lactose", "yogurt", "yoghurt", "cheese", "cream cheese", "ghee", "curd",
+		},
+		Exceptions: []string{
+			"cocoa butter", "peanut butter", "shea butter", "nut butter",
+			"coconut milk", "coconut cream", "almond milk", "oat milk", "soy milk",
+			"rice milk", "cashew milk", "non dairy", "dairy free", "cream of tartar",
+		},
+	},
+	"eggs": {
+		Synonyms: []string{
+			"egg", "eggs", "egg yolk", "egg yolks", "egg white", "egg whites",
+			"albumen", "albumin", "meringue", "ovalbumin", "lysozyme",
+		},
+		Exceptions: []string{"eggplant"},
+	},
+	"peanuts": {
+		Synonyms: []string{
+			"peanut", "peanuts", "peanut butter", "peanut oil", "peanut flour",
+			"groundnut", "groundnuts",
+		},
+	},
+	"tree nuts": {
+		Synonyms: []string{
+			"almond", "almonds", "hazelnut", "hazelnuts", "filbert", "filberts",
+			"walnut", "walnuts", "pecan", "pecans", "cashew", "cashews",
+			"pistachio", "pistachios", "macadamia", "macadamias",
+			"brazil nut", "brazil nuts", "pine nut", "pine nuts",
+			"praline", "pralines", "marzipan", "gianduja", "nut butter",
+		},
+	},
+	"soy": {
+		Synonyms: []string{
+			"soy", "soya", "soybean", "soybeans", "soy lecithin", "soy protein",
+			"soy milk", "tofu", "edamame", "miso",
+		},
+	},
+	"wheat": {
+		Synonyms: []string{
+			"wheat", "wheat flour", "enriched flour", "durum", "semolina", "spelt",
+			"farina", "bulgur", "couscous", "seitan", "graham flour", "einkorn", "emmer",
+		},
+		Exceptions: []string{"buckwheat"},
+	},
+	"fish": {
+		Synonyms: []string{
+			"fish", "anchovy", "anchovies", "cod", "salmon", "tuna", "tilapia",
+			"fish gelatin", "fish oil",
+		},
+		Exceptions: []string{"swedish fish"},
+	},
+	"shellfish": {
+		Synonyms: []string{
+			"shellfish", "shrimp", "shrimps", "prawn", "prawns", "crab", "crabs",
+			"lobster", "lobsters", "crayfish", "crawfish", "krill",
+		},
+	},
+	"sesame": {
+		Synonyms: []string{"sesame", "sesame seed", "sesame seeds", "sesame oil", "tahini", "halva", "halvah"},
+	},
+}
@@ -0,0 +1,195 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	validatorLib "github.com/iqdf/benjerry-service/common/validator"
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// ImportSummary counts imported records by outcome. Skipped
+// records are duplicates in the catalog or unchanged products
+type ImportSummary struct {
+	Created int64
+	Updated int64
+	Skipped int64
+	Failed  int64
+}
+
+// Importer validates catalog records and
+// upserts them through product service
+type Importer struct {
+	service   domain.ProductService
+	batchSize int
+	dryRun    bool
+	log       io.Writer
+}
+
+// pendingRecord is a raw catalog record
+// with its position and productId (if any)
+type pendingRecord struct {
+	index     int
+	productID string
+	raw       json.RawMessage
+}
+
+// NewImporter creates catalog importer. On dry run records
+// are validated and counted but never written to the service
+func NewImporter(service domain.ProductService, batchSize int, dryRun bool, log io.Writer) *Importer {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	return &Importer{
+		service:   service,
+		batchSize: batchSize,
+		dryRun:    dryRun,
+		log:       log,
+	}
+}
+
+// Import reads whole catalog, then validates and upserts records in
+// batches. Records without productId are new products, which the
+// service allocates productIds. Record errors, including products
+// the service fails to write on their own, are logged and counted,
+// other errors abort import
+func (importer *Importer) Import(ctx context.Context, r io.Reader) (ImportSummary, error) {
+	var summary ImportSummary
+
+	records, err := importer.readRecords(r, &summary)
+	if err != nil {
+		return summary, err
+	}
+
+	batch := make([]domain.Product, 0, importer.batchSize)
+	batchRecords := make([]pendingRecord, 0, importer.batchSize)
+	for _, record := range records {
+		catalogRecord, err := DecodeRecord(record.raw)
+		if err != nil {
+			importer.fail(&summary, record, err)
+			continue
+		}
+
+		batch = append(batch, catalogRecord.Product())
+		batchRecords = append(batchRecords, record)
+		if len(batch) == importer.batchSize {
+			if err = importer.flush(ctx, batch, batchRecords, &summary); err != nil {
+				return summary, err
+			}
+			batch, batchRecords = batch[:0], batchRecords[:0]
+		}
+	}
+
+	err = importer.flush(ctx, batch, batchRecords, &summary)
+	return summary, err
+}
+
+// readRecords reads raw records and skips records
+// whose productId is repeated within the catalog
+func (importer *Importer) readRecords(r io.Reader, summary *ImportSummary) ([]pendingRecord, error) {
+	var (
+		records []pendingRecord
+		seenIDs = make(map[string]bool)
+		reader  = NewReader(r)
+	)
+
+	for index := 1; ; index++ {
+		raw, err := reader.Next()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var header struct {
+			ProductID string `json:"productId"`
+		}
+		record := pendingRecord{index: index, raw: raw}
+
+		if err = json.Unmarshal(raw, &header); err != nil {
+			importer.fail(summary, record, validatorLib.NewValidationError(err))
+			continue
+		}
+		record.productID = header.ProductID
+
+		if record.productID != "" {
+			if seenIDs[record.productID] {
+				summary.Skipped++
+				importer.logf(record, "skipped, duplicate productId in catalog")
+				continue
+			}
+			seenIDs[record.productID] = true
+		}
+		records = append(records, record)
+	}
+}
+
+// flush upserts batch of products read from records, or on
+// dry run only counts which products would be created
+func (importer *Importer) flush(
+	ctx context.Context,
+	batch []domain.Product,
+	records []pendingRecord,
+	summary *ImportSummary,
+) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if importer.dryRun {
+		for _, product := range batch {
+			if product.ProductID == "" {
+				summary.Created++
+				continue
+			}
+
+			_, err := importer.service.GetProduct(ctx, product.ProductID)
+			switch err {
+			case nil:
+				summary.Updated++
+			case domain.ErrResourceNotFound:
+				summary.Created++
+			default:
+				return err
+			}
+		}
+		return nil
+	}
+
+	result, err := importer.service.UpsertProducts(ctx, batch)
+	if err != nil {
+		return err
+	}
+
+	summary.Created += result.Created
+	summary.Updated += result.Updated
+	summary.Skipped += result.Unchanged
+	for _, failure := range result.Failures {
+		importer.fail(summary, records[failure.Index], failure.Err)
+	}
+	return nil
+}
+
+func (importer *Importer) fail(summary *ImportSummary, record pendingRecord, err error) {
+	summary.Failed++
+
+	message := err.Error()
+	if verr, ok := err.(*validatorLib.ValidationError); ok {
+		message = verr.Message()
+	}
+	importer.logf(record, message)
+}
+
+func (importer *Importer) logf(record pendingRecord, message string) {
+	if importer.log == nil {
+		return
+	}
+	if record.productID == "" {
+		fmt.Fprintf(importer.log, "record %d: %s\n", record.index, message)
+		return
+	}
+	fmt.Fprintf(importer.log, "record %d (productId %s): %s\n", record.index, record.productID, message)
+}
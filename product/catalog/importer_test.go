@@ -0,0 +1,125 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/domain/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	contextType  = mock.Anything
+	queryType    = mock.AnythingOfType("domain.ProductQuery")
+	productsType = mock.AnythingOfType("[]domain.Product")
+)
+
+func TestImportLeavesMissingIDsToService(t *testing.T) {
+	productService := new(mocks.ProductService)
+	catalogJSON := `[
+		{"name": "Cherry Garcia", "description": "Cherry Ice Cream", "allergy_info": "milk", "dietary_certifications": "Kosher"},
+		{"productId": "646", "name": "Vanilla Toffee Bar Crunch", "description": "Vanilla", "allergy_info": "milk", "dietary_certifications": "Kosher"},
+		{"productId": "646", "name": "Duplicate", "description": "Vanilla", "allergy_info": "milk", "dietary_certifications": "Kosher"},
+		{"productId": "700", "name": "No Description", "allergy_info": "milk", "dietary_certifications": "Kosher"}
+	]`
+
+	var upserted []domain.Product
+	productService.On("UpsertProducts", contextType, productsType).
+		Run(func(args mock.Arguments) { upserted = args.Get(1).([]domain.Product) }).
+		Return(domain.UpsertResult{Created: 1, Unchanged: 1}, nil).
+		Once()
+
+	var log bytes.Buffer
+	importer := NewImporter(productService, 10, false, &log)
+	summary, err := importer.Import(context.TODO(), strings.NewReader(catalogJSON))
+
+	assert.NoError(t, err)
+	assert.Equal(t, ImportSummary{Created: 1, Updated: 0, Skipped: 2, Failed: 1}, summary)
+	assert.Len(t, upserted, 2)
+	assert.Equal(t, "", upserted[0].ProductID)
+	assert.Equal(t, "646", upserted[1].ProductID)
+	assert.Contains(t, log.String(), "record 3 (productId 646): skipped")
+	assert.Contains(t, log.String(), "record 4 (productId 700): Description is a required field")
+	productService.AssertExpectations(t)
+}
+
+func TestImportNDJSONInBatches(t *testing.T) {
+	productService := new(mocks.ProductService)
+	catalogNDJSON := `{"productId": "101", "name": "A", "description": "A", "allergy_info": "milk", "dietary_certifications": "Kosher"}
+{"productId": "102", "name": "B", "description": "B", "allergy_info": "milk", "dietary_certifications": "Kosher"}
+{"productId": "103", "name": "C", "description": "C", "allergy_info": "milk", "dietary_certifications": "Kosher"}
+`
+
+	productService.On("UpsertProducts", contextType, productsType).
+		Return(domain.UpsertResult{Created: 1, Updated: 1}, nil).
+		Once()
+	productService.On("UpsertProducts", contextType, productsType).
+		Return(domain.UpsertResult{Created: 1}, nil).
+		Once()
+
+	importer := NewImporter(productService, 2, false, nil)
+	summary, err := importer.Import(context.TODO(), strings.NewReader(catalogNDJSON))
+
+	assert.NoError(t, err)
+	assert.Equal(t, ImportSummary{Created: 2, Updated: 1}, summary)
+	productService.AssertExpectations(t)
+}
+
+func TestImportFailuresOfBatch(t *testing.T) {
+	productService := new(mocks.ProductService)
+	catalogNDJSON := `{"productId": "101", "name": "A", "description": "A", "allergy_info": "milk", "dietary_certifications": "Kosher"}
+{"productId": "102", "name": "B", "description": "B", "allergy_info": "milk", "dietary_certifications": "Kosher"}
+`
+
+	// products failing on their own are counted and
+	// logged by record, and do not abort the import
+	productService.On("UpsertProducts", contextType, productsType).
+		Return(domain.UpsertResult{
+			Created:  1,
+			Failures: []domain.UpsertFailure{{Index: 1, Err: domain.ErrDuplicateGTIN}},
+		}, nil).
+		Once()
+
+	var log bytes.Buffer
+	importer := NewImporter(productService, 10, false, &log)
+	summary, err := importer.Import(context.TODO(), strings.NewReader(catalogNDJSON))
+
+	assert.NoError(t, err)
+	assert.Equal(t, ImportSummary{Created: 1, Failed: 1}, summary)
+	assert.Contains(t, log.String(), "record 2 (productId 102): "+domain.ErrDuplicateGTIN.Error())
+	productService.AssertExpectations(t)
+}
+
+func TestImportDryRun(t *testing.T) {
+	productService := new(mocks.ProductService)
+	file, err := os.Open("../../icecream.json")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	productService.On("GetProduct", contextType, "646").
+		Return(domain.Product{ProductID: "646"}, nil).
+		Once()
+	productService.On("GetProduct", contextType, mock.AnythingOfType("string")).
+		Return(domain.Product{}, domain.ErrResourceNotFound)
+
+	importer := NewImporter(productService, 100, true, nil)
+	summary, err := importer.Import(context.TODO(), file)
+
+	assert.NoError(t, err)
+	assert.Equal(t, ImportSummary{Created: 3, Updated: 1}, summary)
+	productService.AssertNotCalled(t, "UpsertProducts", contextType, productsType)
+}
+
+func TestImportMalformedCatalog(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	importer := NewImporter(productService, 100, false, nil)
+	_, err := importer.Import(context.TODO(), strings.NewReader(`[{"productId": "101"`))
+
+	assert.Equal(t, ErrMalformedCatalog, err)
+}
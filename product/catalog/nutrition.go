@@ -0,0 +1,130 @@
+package catalog
+
+import (
+	"math"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// NutritionRecord is nutrition facts of a product in catalog record.
+// Amounts are quantities with their unit, e.g. "98 g" or "2/3 cup",
+// nutrients are those of 100 grams of product
+type NutritionRecord struct {
+	ServingSize   string          `json:"serving_size" validate:"required,mass=1kg"`
+	ServingVolume string          `json:"serving_volume,omitempty" validate:"omitempty,volume=1l"`
+	ServingLabel  string          `json:"serving_label,omitempty" validate:"omitempty,max=25"`
+	Per100g       NutrientsRecord `json:"per_100g"`
+}
+
+// NutrientsRecord is nutrients in a quantity of product. Each of them is
+// bounded by its amount in 100 grams of the richest food in nutrient
+type NutrientsRecord struct {
+	Calories          string `json:"calories" validate:"required,energy=900kcal"`
+	TotalFat          string `json:"total_fat,omitempty" validate:"omitempty,mass=100g"`
+	SaturatedFat      string `json:"saturated_fat,omitempty" validate:"omitempty,mass=100g"`
+	TransFat          string `json:"trans_fat,omitempty" validate:"omitempty,mass=100g"`
+	Cholesterol       string `json:"cholesterol,omitempty" validate:"omitempty,mass=5g"`
+	Sodium            string `json:"sodium,omitempty" validate:"omitempty,mass=40g"`
+	TotalCarbohydrate string `json:"total_carbohydrate,omitempty" validate:"omitempty,mass=100g"`
+	DietaryFiber      string `json:"dietary_fiber,omitempty" validate:"omitempty,mass=100g"`
+	TotalSugars       string `json:"total_sugars,omitempty" validate:"omitempty,mass=100g"`
+	AddedSugars       string `json:"added_sugars,omitempty" validate:"omitempty,mass=100g"`
+	Protein           string `json:"protein,omitempty" validate:"omitempty,mass=100g"`
+	VitaminD          string `json:"vitamin_d,omitempty" validate:"omitempty,mass=1mg"`
+	Calcium           string `json:"calcium,omitempty" validate:"omitempty,mass=5g"`
+	Iron              string `json:"iron,omitempty" validate:"omitempty,mass=1g"`
+	Potassium         string `json:"potassium,omitempty" validate:"omitempty,mass=10g"`
+}
+
+// NewNutritionRecord copies nutrition facts into their record
+func NewNutritionRecord(facts domain.NutritionFacts) *NutritionRecord {
+	if facts.IsEmpty() {
+		return nil
+	}
+
+	record := &NutritionRecord{
+		ServingSize:  formatQuantity(facts.ServingSize, "g"),
+		ServingLabel: facts.ServingLabel,
+		Per100g:      NewNutrientsRecord(facts.Per100g),
+	}
+	if facts.ServingVolume > 0 {
+		record.ServingVolume = formatQuantity(facts.ServingVolume, "ml")
+	}
+	return record
+}
+
+// NewNutrientsRecord copies nutrients into their record, each
+// in the unit nutrition labels use for it, e.g. sodium in mg
+func NewNutrientsRecord(nutrients domain.Nutrients) NutrientsRecord {
+	return NutrientsRecord{
+		Calories:          formatQuantity(nutrients.Calories, "kcal"),
+		TotalFat:          formatQuantity(nutrients.TotalFat, "g"),
+		SaturatedFat:      formatQuantity(nutrients.SaturatedFat, "g"),
+		TransFat:          formatQuantity(nutrients.TransFat, "g"),
+		Cholesterol:       formatQuantity(nutrients.Cholesterol*1000, "mg"),
+		Sodium:            formatQuantity(nutrients.Sodium*1000, "mg"),
+		TotalCarbohydrate: formatQuantity(nutrients.TotalCarbohydrate, "g"),
+		DietaryFiber:      formatQuantity(nutrients.DietaryFiber, "g"),
+		TotalSugars:       formatQuantity(nutrients.TotalSugars, "g"),
+		AddedSugars:       formatQuantity(nutrients.AddedSugars, "g"),
+		Protein:           formatQuantity(nutrients.Protein, "g"),
+		VitaminD:          formatQuantity(nutrients.VitaminD*1000000, "mcg"),
+		Calcium:           formatQuantity(nutrients.Calcium*1000, "mg"),
+		Iron:              formatQuantity(nutrients.Iron*1000, "mg"),
+		Potassium:         formatQuantity(nutrients.Potassium*1000, "mg"),
+	}
+}
+
+// NutritionFacts copies nutrition record into product nutrition facts,
+// amounts are converted into grams, millilitres and kilocalories
+func (record *NutritionRecord) NutritionFacts() domain.NutritionFacts {
+	if record == nil {
+		return domain.NutritionFacts{}
+	}
+
+	return domain.NutritionFacts{
+		ServingSize:   baseAmount(record.ServingSize),
+		ServingVolume: baseAmount(record.ServingVolume),
+		ServingLabel:  record.ServingLabel,
+		Per100g:       record.Per100g.Nutrients(),
+	}
+}
+
+// Nutrients copies nutrients record into nutrients,
+// amounts are converted into grams and kilocalories
+func (record NutrientsRecord) Nutrients() domain.Nutrients {
+	return domain.Nutrients{
+		Calories:          baseAmount(record.Calories),
+		TotalFat:          baseAmount(record.TotalFat),
+		SaturatedFat:      baseAmount(record.SaturatedFat),
+		TransFat:          baseAmount(record.TransFat),
+		Cholesterol:       baseAmount(record.Cholesterol),
+		Sodium:            baseAmount(record.Sodium),
+		TotalCarbohydrate: baseAmount(record.TotalCarbohydrate),
+		DietaryFiber:      baseAmount(record.DietaryFiber),
+		TotalSugars:       baseAmount(record.TotalSugars),
+		AddedSugars:       baseAmount(record.AddedSugars),
+		Protein:           baseAmount(record.Protein),
+		VitaminD:          baseAmount(record.VitaminD),
+		Calcium:           baseAmount(record.Calcium),
+		Iron:              baseAmount(record.Iron),
+		Potassium:         baseAmount(record.Potassium),
+	}
+}
+
+// baseAmount converts validated quantity into base unit of its
+// dimension, amounts left out of record are zero
+func baseAmount(text string) float64 {
+	quantity, err := domain.ParseQuantity(text)
+	if err != nil {
+		return 0
+	}
+	return quantity.Base()
+}
+
+// formatQuantity formats amount in unit, rounded to
+// thousandths so that conversions read as entered
+func formatQuantity(amount float64, unit string) string {
+	amount = math.Round(amount*1000) / 1000
+	return domain.Quantity{Amount: amount, Unit: unit}.String()
+}
@@ -0,0 +1,76 @@
+package catalog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// ErrMalformedCatalog is returned when catalog is
+// neither a JSON array nor newline delimited JSON
+var ErrMalformedCatalog = errors.New("catalog: expected JSON array or newline delimited JSON objects")
+
+// Reader reads raw records one at a time from catalog in
+// either JSON array format (icecream.json) or NDJSON format
+type Reader struct {
+	input   *bufio.Reader
+	decoder *json.Decoder
+	isArray bool
+}
+
+// NewReader creates catalog reader, the format
+// is detected from the first non-space character
+func NewReader(r io.Reader) *Reader {
+	return &Reader{input: bufio.NewReader(r)}
+}
+
+// Next returns the next raw record or io.EOF
+// when there are no more records to read
+func (reader *Reader) Next() (json.RawMessage, error) {
+	if reader.decoder == nil {
+		if err := reader.start(); err != nil {
+			return nil, err
+		}
+	}
+
+	if !reader.decoder.More() {
+		if reader.isArray {
+			// consume the closing bracket
+			if _, err := reader.decoder.Token(); err != nil {
+				return nil, ErrMalformedCatalog
+			}
+		}
+		return nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := reader.decoder.Decode(&raw); err != nil {
+		return nil, ErrMalformedCatalog
+	}
+	return raw, nil
+}
+
+// start detects catalog format and consumes the
+// opening bracket if catalog is a JSON array
+func (reader *Reader) start() error {
+	for {
+		first, err := reader.input.Peek(1)
+		if err != nil {
+			return err
+		}
+		if first[0] != ' ' && first[0] != '\t' && first[0] != '\r' && first[0] != '\n' {
+			reader.isArray = first[0] == '['
+			break
+		}
+		reader.input.ReadByte()
+	}
+
+	reader.decoder = json.NewDecoder(reader.input)
+	if reader.isArray {
+		if _, err := reader.decoder.Token(); err != nil {
+			return ErrMalformedCatalog
+		}
+	}
+	return nil
+}
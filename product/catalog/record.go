@@ -0,0 +1,155 @@
+package catalog
+
+import (
+	"bytes"
+
+	validatorLib "github.com/iqdf/benjerry-service/common/validator"
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// Record is a single product in the catalog file format (see icecream.json),
+// which is also the body accepted by [POST] /api/products/. Records
+// without productId are new products, which are allocated one
+type Record struct {
+	ProductID            string           `json:"productId" validate:"omitempty,numeric,min=3"`
+	Name                 string           `json:"name" validate:"required,ascii,max=50"`
+	ImageClosedURL       string           `json:"image_closed" validate:"omitempty,uri"`
+	ImageOpenURL         string           `json:"image_open" validate:"omitempty,uri"`
+	Description          string           `json:"description" validate:"required,max=100"`
+	Story                string           `json:"story" validate:"omitempty,max=300"`
+	SourcingValues       *[]string        `json:"sourcing_values"`
+	Ingredients          *[]string        `json:"ingredients"`
+	AllergyInfo          string           `json:"allergy_info" validate:"required,max=50"`
+	DietaryCertification string           `json:"dietary_certifications" validate:"required,max=25"`
+	Allergens            *AllergensRecord `json:"allergens,omitempty"`
+	Nutrition            *NutritionRecord `json:"nutrition,omitempty"`
+	Regions              []string         `json:"regions,omitempty" validate:"omitempty,dive,region"`
+	GTINs                []string         `json:"gtins,omitempty" validate:"omitempty,dive,gtin"`
+
+	RegionOverrides map[string]OverrideRecord `json:"region_overrides,omitempty" validate:"omitempty,dive,keys,region,endkeys"`
+}
+
+// AllergensRecord is allergens declared by a product in catalog record
+type AllergensRecord struct {
+	Contains   []string `json:"contains" validate:"omitempty,dive,allergen"`
+	MayContain []string `json:"may_contain" validate:"omitempty,dive,allergen"`
+}
+
+// NewAllergensRecord copies declared allergens into their record
+func NewAllergensRecord(allergens domain.Allergens) *AllergensRecord {
+	if allergens.IsEmpty() {
+		return nil
+	}
+	return &AllergensRecord{Contains: allergens.Contains, MayContain: allergens.MayContain}
+}
+
+// Allergens copies allergens record into normalized product allergens
+func (record *AllergensRecord) Allergens() domain.Allergens {
+	if record == nil {
+		return domain.Allergens{}
+	}
+	return domain.Allergens{Contains: record.Contains, MayContain: record.MayContain}.Normalized()
+}
+
+// OverrideRecord is attributes of a product that differ in one
+// region, keyed by region code such as us or uk in catalog record
+type OverrideRecord struct {
+	ImageClosedURL       string `json:"image_closed,omitempty" validate:"omitempty,uri"`
+	ImageOpenURL         string `json:"image_open,omitempty" validate:"omitempty,uri"`
+	AllergyInfo          string `json:"allergy_info,omitempty" validate:"omitempty,max=50"`
+	DietaryCertification string `json:"dietary_certifications,omitempty" validate:"omitempty,max=25"`
+}
+
+// NewOverrideRecords copies region overrides into their records
+func NewOverrideRecords(overrides map[string]domain.RegionOverride) map[string]OverrideRecord {
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	records := make(map[string]OverrideRecord, len(overrides))
+	for region, override := range overrides {
+		records[region] = OverrideRecord{
+			ImageClosedURL:       override.ImageClosedURL,
+			ImageOpenURL:         override.ImageOpenURL,
+			AllergyInfo:          override.AllergyInfo,
+			DietaryCertification: override.DietaryCertification,
+		}
+	}
+	return records
+}
+
+// RegionOverrides copies override records into product region overrides
+func RegionOverrides(records map[string]OverrideRecord) map[string]domain.RegionOverride {
+	if len(records) == 0 {
+		return nil
+	}
+
+	overrides := make(map[string]domain.RegionOverride, len(records))
+	for region, record := range records {
+		overrides[region] = domain.RegionOverride{
+			ImageClosedURL:       record.ImageClosedURL,
+			ImageOpenURL:         record.ImageOpenURL,
+			AllergyInfo:          record.AllergyInfo,
+			DietaryCertification: record.DietaryCertification,
+		}
+	}
+	return overrides
+}
+
+// DecodeRecord unmarshals and validates a single catalog record,
+// including consistency of its nutrition facts
+func DecodeRecord(data []byte) (Record, error) {
+	var record Record
+	if err := validatorLib.DecodeAndValidateJSON(bytes.NewReader(data), &record); err != nil {
+		return record, err
+	}
+
+	if err := record.Nutrition.NutritionFacts().Check(); err != nil {
+		return record, validatorLib.NewValidationError(err)
+	}
+	return record, nil
+}
+
+// NewRecord copies product entity into catalog record
+func NewRecord(product domain.Product) Record {
+	return Record{
+		ProductID:            product.ProductID,
+		Name:                 product.Name,
+		ImageClosedURL:       product.ImageClosedURL,
+		ImageOpenURL:         product.ImageOpenURL,
+		Description:          product.Description,
+		Story:                product.Story,
+		SourcingValues:       product.SourcingValues,
+		Ingredients:          product.Ingredients,
+		AllergyInfo:          product.AllergyInfo,
+		DietaryCertification: product.DietaryCertification,
+		Allergens:            NewAllergensRecord(product.Allergens),
+		Nutrition:            NewNutritionRecord(product.Nutrition),
+		Regions:              product.Regions,
+		GTINs:                product.GTINs,
+		RegionOverrides:      NewOverrideRecords(product.RegionOverrides),
+	}
+}
+
+// Product copies catalog record into product entity,
+// GTINs are normalized into GTIN-14 and allergens
+// are listed in the order of known allergens
+func (record Record) Product() domain.Product {
+	return domain.Product{
+		ProductID:            record.ProductID,
+		Name:                 record.Name,
+		ImageClosedURL:       record.ImageClosedURL,
+		ImageOpenURL:         record.ImageOpenURL,
+		Description:          record.Description,
+		Story:                record.Story,
+		SourcingValues:       record.SourcingValues,
+		Ingredients:          record.Ingredients,
+		AllergyInfo:          record.AllergyInfo,
+		DietaryCertification: record.DietaryCertification,
+		Allergens:            record.Allergens.Allergens(),
+		Nutrition:            record.Nutrition.NutritionFacts(),
+		Regions:              record.Regions,
+		GTINs:                domain.NormalizeGTINs(record.GTINs),
+		RegionOverrides:      RegionOverrides(record.RegionOverrides),
+	}
+}
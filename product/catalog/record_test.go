@@ -0,0 +1,83 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeRecordGTINs(t *testing.T) {
+	valid := []byte(`{"productId": "646", "name": "Vanilla Toffee Bar Crunch", "description": "Vanilla",
+		"allergy_info": "wheat", "dietary_certifications": "Kosher",
+		"gtins": ["96385074", "012345678905", "0012345678905", "4006381333931", "10012345678902"]}`)
+
+	record, err := DecodeRecord(valid)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"00000096385074", "00012345678905", "04006381333931", "10012345678902"}, record.Product().GTINs)
+
+	for _, gtin := range []string{"012345678906", "1234567", "01234567890X"} {
+		t.Run(gtin, func(t *testing.T) {
+			invalid := []byte(`{"productId": "646", "name": "Vanilla Toffee Bar Crunch", "description": "Vanilla",
+				"allergy_info": "wheat", "dietary_certifications": "Kosher", "gtins": ["` + gtin + `"]}`)
+
+			_, err := DecodeRecord(invalid)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDecodeRecordAllergens(t *testing.T) {
+	valid := []byte(`{"productId": "646", "name": "Vanilla Toffee Bar Crunch", "description": "Vanilla",
+		"allergy_info": "wheat", "dietary_certifications": "Kosher",
+		"allergens": {"contains": ["wheat", "milk", "milk"], "may_contain": ["milk", "peanuts"]}}`)
+
+	record, err := DecodeRecord(valid)
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.Allergens{Contains: []string{"milk", "wheat"}, MayContain: []string{"peanuts"}}, record.Product().Allergens)
+
+	invalid := []byte(`{"productId": "646", "name": "Vanilla Toffee Bar Crunch", "description": "Vanilla",
+		"allergy_info": "wheat", "dietary_certifications": "Kosher", "allergens": {"contains": ["Milk"]}}`)
+
+	_, err = DecodeRecord(invalid)
+	assert.Error(t, err)
+}
+
+func TestDecodeRecordNutrition(t *testing.T) {
+	record := func(nutrition string) []byte {
+		return []byte(`{"productId": "646", "name": "Vanilla Toffee Bar Crunch", "description": "Vanilla",
+			"allergy_info": "wheat", "dietary_certifications": "Kosher", "nutrition": ` + nutrition + `}`)
+	}
+
+	valid := record(`{"serving_size": "98 g", "serving_volume": "2/3 cup", "serving_label": "2/3 cup",
+		"per_100g": {"calories": "1046 kJ", "total_fat": "15g", "saturated_fat": "10 g",
+		"sodium": "60 mg", "total_carbohydrate": "28 g", "total_sugars": "25 g", "vitamin_d": "0.5 mcg"}}`)
+
+	decoded, err := DecodeRecord(valid)
+	assert.NoError(t, err)
+
+	facts := decoded.Product().Nutrition
+	assert.Equal(t, 98.0, facts.ServingSize)
+	assert.InDelta(t, 157.73, facts.ServingVolume, 0.01)
+	assert.InDelta(t, 250, facts.Per100g.Calories, 0.01)
+	assert.InDelta(t, 0.06, facts.Per100g.Sodium, 0.000001)
+	assert.Equal(t, "250 kcal", NewNutritionRecord(facts).Per100g.Calories)
+	assert.Equal(t, "60 mg", NewNutritionRecord(facts).Per100g.Sodium)
+	assert.Equal(t, "0.5 mcg", NewNutritionRecord(facts).Per100g.VitaminD)
+
+	invalid := map[string]string{
+		"wrong-unit":       `{"serving_size": "98 ml", "per_100g": {"calories": "250 kcal"}}`,
+		"no-unit":          `{"serving_size": "98", "per_100g": {"calories": "250 kcal"}}`,
+		"out-of-range":     `{"serving_size": "98 g", "per_100g": {"calories": "250 kcal", "sodium": "41 g"}}`,
+		"exceeds-total":    `{"serving_size": "98 g", "per_100g": {"calories": "250 kcal", "total_fat": "5 g", "saturated_fat": "6 g"}}`,
+		"heavier-than-100": `{"serving_size": "98 g", "per_100g": {"calories": "250 kcal", "total_fat": "60 g", "protein": "60 g"}}`,
+	}
+	for name, nutrition := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := DecodeRecord(record(nutrition))
+			assert.Error(t, err)
+		})
+	}
+}
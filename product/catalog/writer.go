@@ -0,0 +1,177 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// Formats of exported catalog
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+
+	// DefaultDelimiter joins list values within a CSV cell
+	DefaultDelimiter = "|"
+)
+
+// ErrUnknownFormat is returned for unsupported export format
+var ErrUnknownFormat = errors.New("catalog: format must be one of json, ndjson or csv")
+
+// csvHeader names CSV columns after catalog record fields
+var csvHeader = []string{
+	"productId",
+	"name",
+	"image_closed",
+	"image_open",
+	"description",
+	"story",
+	"sourcing_values",
+	"ingredients",
+	"allergy_info",
+	"dietary_certifications",
+	"regions",
+	"gtins",
+	"contains",
+	"may_contain",
+}
+
+// Writer writes products into catalog one at a time.
+// Close must be called to complete the catalog
+type Writer interface {
+	Write(product domain.Product) error
+	Close() error
+}
+
+// NewWriter creates catalog writer of the format. Delimiter
+// is only used by CSV format to join list values in a cell
+func NewWriter(w io.Writer, format string, delimiter string) (Writer, error) {
+	switch format {
+	case FormatJSON:
+		return &jsonWriter{output: w}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{encoder: json.NewEncoder(w)}, nil
+	case FormatCSV:
+		if delimiter == "" {
+			delimiter = DefaultDelimiter
+		}
+		return &csvWriter{output: csv.NewWriter(w), delimiter: delimiter}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// ContentType of the catalog format
+func ContentType(format string) string {
+	switch format {
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatCSV:
+		return "text/csv"
+	}
+	return "application/json"
+}
+
+// jsonWriter writes JSON array, the same format accepted by import
+type jsonWriter struct {
+	output io.Writer
+	count  int
+}
+
+func (writer *jsonWriter) Write(product domain.Product) error {
+	value, err := json.Marshal(NewRecord(product))
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n"
+	if writer.count == 0 {
+		separator = "[\n"
+	}
+	writer.count++
+
+	if _, err = io.WriteString(writer.output, separator); err != nil {
+		return err
+	}
+	_, err = writer.output.Write(value)
+	return err
+}
+
+func (writer *jsonWriter) Close() error {
+	closing := "\n]\n"
+	if writer.count == 0 {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(writer.output, closing)
+	return err
+}
+
+// ndjsonWriter writes a record per line
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (writer *ndjsonWriter) Write(product domain.Product) error {
+	return writer.encoder.Encode(NewRecord(product))
+}
+
+func (writer *ndjsonWriter) Close() error { return nil }
+
+// csvWriter writes header followed by a row per product, sourcing
+// values, ingredients, regions, GTINs and allergens are joined by delimiter.
+// Region overrides and nutrition facts are only exported in JSON formats
+type csvWriter struct {
+	output      *csv.Writer
+	delimiter   string
+	wroteHeader bool
+}
+
+func (writer *csvWriter) Write(product domain.Product) error {
+	if err := writer.writeHeader(); err != nil {
+		return err
+	}
+
+	return writer.output.Write([]string{
+		product.ProductID,
+		product.Name,
+		product.ImageClosedURL,
+		product.ImageOpenURL,
+		product.Description,
+		product.Story,
+		writer.join(product.SourcingValues),
+		writer.join(product.Ingredients),
+		product.AllergyInfo,
+		product.DietaryCertification,
+		strings.Join(product.Regions, writer.delimiter),
+		strings.Join(product.GTINs, writer.delimiter),
+		strings.Join(product.Allergens.Contains, writer.delimiter),
+		strings.Join(product.Allergens.MayContain, writer.delimiter),
+	})
+}
+
+func (writer *csvWriter) Close() error {
+	if err := writer.writeHeader(); err != nil {
+		return err
+	}
+	writer.output.Flush()
+	return writer.output.Error()
+}
+
+func (writer *csvWriter) writeHeader() error {
+	if writer.wroteHeader {
+		return nil
+	}
+	writer.wroteHeader = true
+	return writer.output.Write(csvHeader)
+}
+
+func (writer *csvWriter) join(values *[]string) string {
+	if values == nil {
+		return ""
+	}
+	return strings.Join(*values, writer.delimiter)
+}
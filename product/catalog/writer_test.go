@@ -0,0 +1,83 @@
+package catalog
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONExportRoundTrip(t *testing.T) {
+	products := []domain.Product{createMockProduct("646"), createMockProduct("647")}
+
+	for _, format := range []string{FormatJSON, FormatNDJSON} {
+		var output bytes.Buffer
+		writer, err := NewWriter(&output, format, "")
+		assert.NoError(t, err)
+
+		for _, product := range products {
+			assert.NoError(t, writer.Write(product))
+		}
+		assert.NoError(t, writer.Close())
+
+		var imported []domain.Product
+		reader := NewReader(&output)
+		for {
+			raw, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+			assert.NoError(t, err)
+
+			record, err := DecodeRecord(raw)
+			assert.NoError(t, err)
+			imported = append(imported, record.Product())
+		}
+		assert.Equal(t, products, imported, format)
+	}
+}
+
+func TestEmptyJSONExport(t *testing.T) {
+	var output bytes.Buffer
+	writer, _ := NewWriter(&output, FormatJSON, "")
+
+	assert.NoError(t, writer.Close())
+	assert.Equal(t, "[]\n", output.String())
+}
+
+func TestCSVExport(t *testing.T) {
+	var output bytes.Buffer
+	writer, err := NewWriter(&output, FormatCSV, ";")
+	assert.NoError(t, err)
+
+	assert.NoError(t, writer.Write(createMockProduct("646")))
+	assert.NoError(t, writer.Close())
+
+	expected := "productId,name,image_closed,image_open,description,story,sourcing_values,ingredients,allergy_info,dietary_certifications,regions,gtins,contains,may_contain\n" +
+		"646,Vanilla Toffee Bar Crunch,/files/vanilla-toffee-landing.png,/files/vanilla-toffee-landing-open.png," +
+		"Vanilla Ice Cream with Fudge-Covered Toffee Pieces,,Non-GMO;Fairtrade,cream;cocoa (processed with alkali)," +
+		"\"may contain wheat, peanuts\",Kosher,,,milk,peanuts;wheat\n"
+	assert.Equal(t, expected, output.String())
+}
+
+func TestUnknownFormat(t *testing.T) {
+	_, err := NewWriter(&bytes.Buffer{}, "xml", "")
+	assert.Equal(t, ErrUnknownFormat, err)
+}
+
+func createMockProduct(productID string) domain.Product {
+	return domain.Product{
+		ProductID:            productID,
+		Name:                 "Vanilla Toffee Bar Crunch",
+		ImageClosedURL:       "/files/vanilla-toffee-landing.png",
+		ImageOpenURL:         "/files/vanilla-toffee-landing-open.png",
+		Description:          "Vanilla Ice Cream with Fudge-Covered Toffee Pieces",
+		SourcingValues:       &[]string{"Non-GMO", "Fairtrade"},
+		Ingredients:          &[]string{"cream", "cocoa (processed with alkali)"},
+		AllergyInfo:          "may contain wheat, peanuts",
+		DietaryCertification: "Kosher",
+		Allergens:            domain.Allergens{Contains: []string{"milk"}, MayContain: []string{"peanuts", "wheat"}},
+	}
+}
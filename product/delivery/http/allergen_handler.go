@@ -0,0 +1,83 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/product/catalog"
+)
+
+// allergenProposalResponse ...
+type allergenProposalResponse struct {
+	Data allergenProposalData `json:"allergens"`
+}
+
+// allergenProposalData is allergens declared by product next
+// to allergens detected in its ingredients
+type allergenProposalData struct {
+	Declared catalog.AllergensRecord `json:"declared"`
+	Detected []string                `json:"detected"`
+	Check    allergenCheckData       `json:"check"`
+}
+
+type allergenCheckData struct {
+	Undeclared []string `json:"undeclared"`
+	Undetected []string `json:"undetected"`
+}
+
+// newAllergenCheckData copies allergen check into its response,
+// checks without disagreement are left out of product response
+func newAllergenCheckData(check domain.AllergenCheck) *allergenCheckData {
+	if check.Agrees() {
+		return nil
+	}
+	return &allergenCheckData{Undeclared: check.Undeclared, Undetected: check.Undetected}
+}
+
+// newAllergenProposalData copies allergen proposal into its response.
+// Lists are never null, so that clients tell empty lists apart
+func newAllergenProposalData(proposal domain.AllergenProposal) allergenProposalData {
+	return allergenProposalData{
+		Declared: catalog.AllergensRecord{
+			Contains:   nonNil(proposal.Declared.Contains),
+			MayContain: nonNil(proposal.Declared.MayContain),
+		},
+		Detected: nonNil(proposal.Detected),
+		Check: allergenCheckData{
+			Undeclared: nonNil(proposal.Check.Undeclared),
+			Undetected: nonNil(proposal.Check.Undetected),
+		},
+	}
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// handleGetAllergens provides handler func that proposes allergens
+// of product detected in its ingredients, next to the declared ones
+// [GET] /api/products/:product_id/allergens
+func (handler *ProductHandler) handleGetAllergens() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		productID := params["product_id"]
+
+		proposal, err := handler.service.ProposeAllergens(r.Context(), productID)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		json.NewEncoder(w).Encode(allergenProposalResponse{Data: newAllergenProposalData(proposal)})
+	}
+}
@@ -0,0 +1,320 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	validatorLib "github.com/iqdf/benjerry-service/common/validator"
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// batchGetRequest lists productIds of products to get
+type batchGetRequest struct {
+	ProductIDs []string `json:"productIds" validate:"required,min=1,max=100,dive,required"`
+}
+
+// batchRequest lists items of a batch, each of which is
+// decoded and validated on its own
+type batchRequest struct {
+	Products []json.RawMessage `json:"products" validate:"required,min=1,max=100"`
+}
+
+// batchUpdateItem is an update of product identified by productId,
+// pinned to version like If-Match unless zero
+type batchUpdateItem struct {
+	ProductID string `json:"productId" validate:"required"`
+	Version   int64  `json:"version" validate:"min=0"`
+	productUpdateRequest
+}
+
+// batchDeleteItem identifies product to delete, pinned
+// to version like If-Match unless zero
+type batchDeleteItem struct {
+	ProductID string `json:"productId" validate:"required"`
+	Version   int64  `json:"version" validate:"min=0"`
+}
+
+// batchResponse holds results of items in the order of request
+type batchResponse struct {
+	Results []batchResultData `json:"results"`
+}
+
+// batchResultData is the outcome of an item, Status being the
+// HTTP status a single product request would have answered
+type batchResultData struct {
+	ProductID string               `json:"productId,omitempty"`
+	Status    int                  `json:"status"`
+	Error     string               `json:"error,omitempty"`
+	Product   *productResponseData `json:"product,omitempty"`
+}
+
+// batchItems tracks items of a batch request, of which those that
+// failed validation are answered without reaching the service
+type batchItems struct {
+	productIDs []string
+	invalid    map[int]string
+}
+
+func newBatchItems(size int) batchItems {
+	return batchItems{
+		productIDs: make([]string, size),
+		invalid:    make(map[int]string),
+	}
+}
+
+// decode decodes and validates raw item i into item
+func (items batchItems) decode(i int, raw json.RawMessage, item interface{}) bool {
+	err := validatorLib.DecodeAndValidateJSON(bytes.NewReader(raw), item)
+	if err == nil {
+		return true
+	}
+
+	items.invalid[i] = err.Error()
+	if verr, ok := err.(*validatorLib.ValidationError); ok {
+		items.invalid[i] = verr.Message()
+	}
+	return false
+}
+
+// valid lists indexes of items that passed validation
+func (items batchItems) valid() []int {
+	var valid []int
+	for i := range items.productIDs {
+		if _, ok := items.invalid[i]; !ok {
+			valid = append(valid, i)
+		}
+	}
+	return valid
+}
+
+// handleCollectionMethod registers handler of POST to custom method of
+// collection, e.g. /api/products:batchGet. Path templates of routes start
+// with a slash, so they cannot match a path suffixed by the method
+func handleCollectionMethod(router *mux.Router, collection string, method string, handler http.Handler) *mux.Route {
+	target := collection + ":" + method
+	return router.NewRoute().
+		MatcherFunc(func(r *http.Request, match *mux.RouteMatch) bool { return r.URL.Path == target }).
+		Methods("POST").
+		Handler(handler)
+}
+
+// parseAtomic reads atomic query param, false by default
+func parseAtomic(r *http.Request) (bool, error) {
+	atomic := r.URL.Query().Get("atomic")
+	if atomic == "" {
+		return false, nil
+	}
+
+	value, err := strconv.ParseBool(atomic)
+	if err != nil {
+		return false, domain.ErrBadParamInput
+	}
+	return value, nil
+}
+
+// writeBatch runs valid items through run, which is given their indexes,
+// and responds with results of all items in order. Invalid items fail on
+// their own, except in an atomic batch, which is aborted without running
+func writeBatch(
+	w http.ResponseWriter,
+	items batchItems,
+	atomic bool,
+	successStatus int,
+	run func(valid []int) ([]domain.BatchItemResult, error),
+) {
+	valid := items.valid()
+	results := make([]batchResultData, len(items.productIDs))
+
+	for i, message := range items.invalid {
+		results[i] = batchResultData{ProductID: items.productIDs[i], Status: http.StatusBadRequest, Error: message}
+	}
+
+	switch {
+	case len(valid) == 0:
+	case atomic && len(items.invalid) > 0:
+		for _, i := range valid {
+			results[i] = newBatchResultData(domain.BatchItemResult{
+				ProductID: items.productIDs[i],
+				Err:       domain.ErrBatchAborted,
+			}, successStatus)
+		}
+	default:
+		itemResults, err := run(valid)
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		for j, i := range valid {
+			results[i] = newBatchResultData(itemResults[j], successStatus)
+		}
+	}
+
+	json.NewEncoder(w).Encode(batchResponse{Results: results})
+}
+
+func newBatchResultData(result domain.BatchItemResult, successStatus int) batchResultData {
+	if result.Err != nil {
+		return batchResultData{
+			ProductID: result.ProductID,
+			Status:    getResponseStatus(result.Err),
+			Error:     result.Err.Error(),
+		}
+	}
+
+	data := batchResultData{ProductID: result.ProductID, Status: successStatus}
+	if result.Product.ProductID != "" {
+		product := newResponseData(result.Product)
+		data.Product = &product
+	}
+	return data
+}
+
+// handleBatchGetProducts provides handler func that gets products of
+// several productIds, answering each of them like GET of a single product
+// [POST] /api/products:batchGet
+func (handler *ProductHandler) handleBatchGetProducts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var batchGet batchGetRequest
+		if err := validatorLib.DecodeAndValidateJSON(r.Body, &batchGet); err != nil {
+			verr, _ := err.(*validatorLib.ValidationError)
+			writeErrorMessage(w, verr.Message(), http.StatusBadRequest)
+			return
+		}
+
+		items := newBatchItems(len(batchGet.ProductIDs))
+		copy(items.productIDs, batchGet.ProductIDs)
+
+		writeBatch(w, items, false, http.StatusOK, func(valid []int) ([]domain.BatchItemResult, error) {
+			return handler.service.BatchGetProducts(r.Context(), batchGet.ProductIDs)
+		})
+	}
+}
+
+// handleBatchCreateProducts provides handler func that creates products,
+// each validated like POST of a single product. With atomic=true either
+// every product is created or none is
+// [POST] /api/products:batchCreate?atomic=true
+func (handler *ProductHandler) handleBatchCreateProducts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		atomic, batch, ok := decodeBatchRequest(w, r)
+		if !ok {
+			return
+		}
+
+		items := newBatchItems(len(batch.Products))
+		products := make([]domain.Product, len(batch.Products))
+		for i, raw := range batch.Products {
+			var productCreate productCreateRequest
+			valid := items.decode(i, raw, &productCreate)
+			items.productIDs[i] = productCreate.ProductID
+			if valid {
+				products[i] = createToProduct(productCreate)
+			}
+		}
+
+		writeBatch(w, items, atomic, http.StatusCreated, func(valid []int) ([]domain.BatchItemResult, error) {
+			creates := make([]domain.Product, 0, len(valid))
+			for _, i := range valid {
+				creates = append(creates, products[i])
+			}
+			return handler.service.BatchCreateProducts(r.Context(), creates, atomic)
+		})
+	}
+}
+
+// handleBatchUpdateProducts provides handler func that updates products,
+// each identified by its productId and validated like PUT of a single
+// product. With atomic=true either every product is updated or none is
+// [POST] /api/products:batchUpdate?atomic=true
+func (handler *ProductHandler) handleBatchUpdateProducts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		atomic, batch, ok := decodeBatchRequest(w, r)
+		if !ok {
+			return
+		}
+
+		items := newBatchItems(len(batch.Products))
+		products := make([]domain.Product, len(batch.Products))
+		for i, raw := range batch.Products {
+			var update batchUpdateItem
+			valid := items.decode(i, raw, &update)
+			items.productIDs[i] = update.ProductID
+			if valid {
+				products[i] = updateToProduct(update.productUpdateRequest)
+				products[i].ProductID = update.ProductID
+				products[i].Version = update.Version
+			}
+		}
+
+		writeBatch(w, items, atomic, http.StatusOK, func(valid []int) ([]domain.BatchItemResult, error) {
+			updates := make([]domain.Product, 0, len(valid))
+			for _, i := range valid {
+				updates = append(updates, products[i])
+			}
+			return handler.service.BatchUpdateProducts(r.Context(), updates, atomic)
+		})
+	}
+}
+
+// handleBatchDeleteProducts provides handler func that moves products to
+// trash, each identified by its productId. With atomic=true either every
+// product is deleted or none is
+// [POST] /api/products:batchDelete?atomic=true
+func (handler *ProductHandler) handleBatchDeleteProducts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		atomic, batch, ok := decodeBatchRequest(w, r)
+		if !ok {
+			return
+		}
+
+		items := newBatchItems(len(batch.Products))
+		keys := make([]domain.ProductKey, len(batch.Products))
+		for i, raw := range batch.Products {
+			var item batchDeleteItem
+			items.decode(i, raw, &item)
+			items.productIDs[i] = item.ProductID
+			keys[i] = domain.ProductKey{ProductID: item.ProductID, Version: item.Version}
+		}
+
+		writeBatch(w, items, atomic, http.StatusOK, func(valid []int) ([]domain.BatchItemResult, error) {
+			deletes := make([]domain.ProductKey, 0, len(valid))
+			for _, i := range valid {
+				deletes = append(deletes, keys[i])
+			}
+			return handler.service.BatchDeleteProducts(r.Context(), deletes, atomic)
+		})
+	}
+}
+
+// decodeBatchRequest reads atomic query param and items of batch
+// request, responding with an error when either is malformed
+func decodeBatchRequest(w http.ResponseWriter, r *http.Request) (bool, batchRequest, bool) {
+	var batch batchRequest
+
+	atomic, err := parseAtomic(r)
+	if err != nil {
+		writeErrorMessage(w, "atomic must be true or false", http.StatusBadRequest)
+		return false, batch, false
+	}
+
+	if err := validatorLib.DecodeAndValidateJSON(r.Body, &batch); err != nil {
+		verr, _ := err.(*validatorLib.ValidationError)
+		writeErrorMessage(w, verr.Message(), http.StatusBadRequest)
+		return false, batch, false
+	}
+	return atomic, batch, true
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/product/ingredient"
+)
+
+// ingredientData is an ingredient parsed from ingredients of product
+type ingredientData struct {
+	Name        string           `json:"name"`
+	Qualifiers  []string         `json:"qualifiers,omitempty"`
+	Ingredients []ingredientData `json:"ingredients,omitempty"`
+}
+
+func newIngredientsData(ingredients []domain.Ingredient) []ingredientData {
+	if len(ingredients) == 0 {
+		return nil
+	}
+
+	ingredientsData := make([]ingredientData, 0, len(ingredients))
+	for _, ingredient := range ingredients {
+		ingredientsData = append(ingredientsData, ingredientData{
+			Name:        ingredient.Name,
+			Qualifiers:  ingredient.Qualifiers,
+			Ingredients: newIngredientsData(ingredient.Ingredients),
+		})
+	}
+	return ingredientsData
+}
+
+// expandsIngredients tells whether request asks for ingredients
+// to be expanded into their parsed form, e.g. ?expand=ingredients
+func expandsIngredients(r *http.Request) bool {
+	return queryLists(r, "expand", "ingredients")
+}
+
+// withParsedIngredients keeps parsed ingredients of product only if they
+// are expanded. Products stored before their ingredients were parsed on
+// write have them parsed here
+func withParsedIngredients(product domain.Product, expand bool) domain.Product {
+	switch {
+	case !expand:
+		product.ParsedIngredients = nil
+	case product.ParsedIngredients == nil && product.Ingredients != nil:
+		product.ParsedIngredients = ingredient.ParseAll(*product.Ingredients)
+	}
+	return product
+}
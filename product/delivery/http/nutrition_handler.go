@@ -0,0 +1,143 @@
+package http
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/product/catalog"
+	"github.com/iqdf/benjerry-service/product/label"
+)
+
+// nutritionServingResponse ...
+type nutritionServingResponse struct {
+	Data nutritionServingData `json:"nutrition"`
+}
+
+// nutritionServingData is nutrients in a quantity of product, which
+// is Grams of product or Servings of its serving size
+type nutritionServingData struct {
+	Grams     float64                 `json:"grams"`
+	Servings  float64                 `json:"servings"`
+	Nutrients catalog.NutrientsRecord `json:"nutrients"`
+}
+
+func newNutritionServingData(serving domain.NutritionServing) nutritionServingData {
+	return nutritionServingData{
+		Grams:     math.Round(serving.Grams*100) / 100,
+		Servings:  math.Round(serving.Servings*100) / 100,
+		Nutrients: catalog.NewNutrientsRecord(serving.Nutrients),
+	}
+}
+
+// parseServing parses serving query param such as "150g" or "1 cup",
+// requests without it ask for a serving of product
+func parseServing(r *http.Request) (domain.Quantity, error) {
+	serving := r.URL.Query().Get("serving")
+	if serving == "" {
+		return domain.Quantity{}, nil
+	}
+	return domain.ParseQuantity(serving)
+}
+
+// handleGetNutrition provides handler func that computes nutrients in a
+// serving of product, in a quantity of it or in container of its variant
+// [GET] /api/products/:product_id/nutrition?serving=150g&sku=
+func (handler *ProductHandler) handleGetNutrition() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		productID := params["product_id"]
+
+		quantity, err := parseServing(r)
+		if err != nil {
+			writeErrorMessage(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		sku := r.URL.Query().Get("sku")
+		serving, err := handler.service.ComputeNutrition(r.Context(), productID, quantity, sku)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		json.NewEncoder(w).Encode(nutritionServingResponse{Data: newNutritionServingData(serving)})
+	}
+}
+
+// handleGetNutritionLabel provides handler func that renders nutrition
+// label of product as SVG image. Label is of a serving of product, or of
+// serving query param, and states servings per container of variant sku
+// [GET] /api/products/:product_id/nutrition/label.svg?serving=150g&sku=
+func (handler *ProductHandler) handleGetNutritionLabel() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		productID := params["product_id"]
+
+		quantity, err := parseServing(r)
+		if err != nil {
+			writeErrorMessage(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		product, err := handler.service.GetProduct(r.Context(), productID)
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		nutritionLabel, err := newNutritionLabel(product, quantity, r.URL.Query().Get("sku"))
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		w.Header().Set("Content-Type", label.ContentType)
+		label.Render(w, nutritionLabel)
+	}
+}
+
+// newNutritionLabel computes label of quantity of product, which is a
+// serving of it when quantity is zero. Servings per container are
+// those of container of variant sku, if any
+func newNutritionLabel(product domain.Product, quantity domain.Quantity, sku string) (label.Label, error) {
+	serving, err := product.ComputeNutrition(quantity, "")
+	if err != nil {
+		return label.Label{}, err
+	}
+
+	nutritionLabel := label.Label{
+		Title:       product.Name,
+		Serving:     product.Nutrition.ServingLabel,
+		ServingSize: serving.Grams,
+		Nutrients:   serving.Nutrients,
+	}
+
+	// masses are stated by grams of serving size already
+	if quantity != (domain.Quantity{}) {
+		nutritionLabel.Serving = ""
+		if quantity.Dimension() != domain.DimensionMass {
+			nutritionLabel.Serving = quantity.String()
+		}
+	}
+
+	if sku != "" {
+		container, err := product.ComputeNutrition(domain.Quantity{}, sku)
+		if err != nil {
+			return label.Label{}, err
+		}
+		nutritionLabel.ServingsPerContainer = container.Grams / serving.Grams
+	}
+	return nutritionLabel, nil
+}
@@ -1,24 +1,77 @@
 package http
 
 import (
+	"bytes"
 	"encoding/json"
+	"io/ioutil"
+	"log"
+	"mime"
 	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/justinas/alice"
 
+	"github.com/iqdf/benjerry-service/common/jsonpatch"
+	"github.com/iqdf/benjerry-service/common/locale"
 	validatorLib "github.com/iqdf/benjerry-service/common/validator"
 	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/product/catalog"
 )
 
+// Media types accepted by [PATCH] /api/products/:product_id
+const (
+	mergePatchMediaType = "application/merge-patch+json"
+	jsonPatchMediaType  = "application/json-patch+json"
+)
+
+// patchAttempts bounds retries of PATCH without If-Match
+// when product is modified between its read and write
+const patchAttempts = 3
+
 // productSingleResponse ...
 type productSingleResponse struct {
 	Data productResponseData `json:"product"`
 }
 
+// productListResponse ...
+type productListResponse struct {
+	Data       []productResponseData `json:"products"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+	TotalCount int64                 `json:"total_count"`
+	Facets     productFacetsData     `json:"facets"`
+}
+
+type productFacetsData struct {
+	SourcingValues        []facetCountData `json:"sourcing_values"`
+	DietaryCertifications []facetCountData `json:"dietary_certifications"`
+	Allergens             []facetCountData `json:"allergens"`
+}
+
+type facetCountData struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// productSearchResponse ...
+type productSearchResponse struct {
+	Data []productSearchResultData `json:"results"`
+}
+
+type productSearchResultData struct {
+	Product    productResponseData `json:"product"`
+	Score      float64             `json:"score"`
+	Highlights map[string]string   `json:"highlights"`
+}
+
 type productResponseData struct {
 	ProductID            string    `json:"productId"`
 	Name                 string    `json:"name"`
+	Slug                 string    `json:"slug,omitempty"`
+	PreviousSlugs        []string  `json:"previous_slugs,omitempty"`
 	ImageClosedURL       string    `json:"image_closed"`
 	ImageOpenURL         string    `json:"image_open"`
 	Description          string    `json:"description"`
@@ -27,6 +80,20 @@ type productResponseData struct {
 	Ingredients          *[]string `json:"ingredients,omitempty"`
 	AllergyInfo          string    `json:"allergy_info"`
 	DietaryCertification string    `json:"dietary_certifications"`
+	Regions              []string  `json:"regions,omitempty"`
+	GTINs                []string  `json:"gtins,omitempty"`
+	Status               string    `json:"status"`
+	RetiredAt            *int64    `json:"retired_at,omitempty"`
+	Epitaph              string    `json:"epitaph,omitempty"`
+	ScheduledStatus      string    `json:"scheduled_status,omitempty"`
+	ScheduledAt          *int64    `json:"scheduled_at,omitempty"`
+
+	ParsedIngredients []ingredientData                  `json:"parsed_ingredients,omitempty"`
+	Allergens         *catalog.AllergensRecord          `json:"allergens,omitempty"`
+	AllergenCheck     *allergenCheckData                `json:"allergen_check,omitempty"`
+	Nutrition         *catalog.NutritionRecord          `json:"nutrition,omitempty"`
+	RegionOverrides   map[string]catalog.OverrideRecord `json:"region_overrides,omitempty"`
+	Variants          []variantData                     `json:"variants,omitempty"`
 }
 
 // messageError ....
@@ -34,18 +101,9 @@ type messageError struct {
 	Message string `json:"message"`
 }
 
-type productCreateRequest struct {
-	ProductID            string    `json:"productId" validate:"required,numeric,min=3"`
-	Name                 string    `json:"name" validate:"required,ascii,max=50"`
-	ImageClosedURL       string    `json:"image_closed" validate:"omitempty,uri"`
-	ImageOpenURL         string    `json:"image_open" validate:"omitempty,uri"`
-	Description          string    `json:"description" validate:"required,max=100"`
-	Story                string    `json:"story" validate:"omitempty,max=300"`
-	SourcingValues       *[]string `json:"sourcing_values"`
-	Ingredients          *[]string `json:"ingredients"`
-	AllergyInfo          string    `json:"allergy_info" validate:"required,max=50"`
-	DietaryCertification string    `json:"dietary_certifications" validate:"required,max=25"`
-}
+// productCreateRequest shares catalog record format, so that imported
+// catalogs are validated by the same rules as created products
+type productCreateRequest = catalog.Record
 
 type productUpdateRequest struct {
 	Name                 string    `json:"name" validate:"omitempty,ascii,max=50"`
@@ -57,21 +115,26 @@ type productUpdateRequest struct {
 	Ingredients          *[]string `json:"ingredients" validate:"omitempty"`
 	AllergyInfo          string    `json:"allergy_info" validate:"omitempty,max=50"`
 	DietaryCertification string    `json:"dietary_certifications" validate:"omitempty,max=25"`
+	Regions              []string  `json:"regions" validate:"omitempty,dive,region"`
+	GTINs                []string  `json:"gtins" validate:"omitempty,dive,gtin"`
+
+	Allergens       *catalog.AllergensRecord          `json:"allergens" validate:"omitempty"`
+	Nutrition       *catalog.NutritionRecord          `json:"nutrition" validate:"omitempty"`
+	RegionOverrides map[string]catalog.OverrideRecord `json:"region_overrides" validate:"omitempty,dive,keys,region,endkeys"`
+}
+
+// productRequiredFields are fields of patched products that
+// create requires, which patches may not clear, since the
+// patched product replaces the product as a whole
+type productRequiredFields struct {
+	Name                 string `validate:"required"`
+	Description          string `validate:"required"`
+	AllergyInfo          string `validate:"required"`
+	DietaryCertification string `validate:"required"`
 }
 
 func createToProduct(requestData productCreateRequest) domain.Product {
-	return domain.Product{
-		ProductID:            requestData.ProductID,
-		Name:                 requestData.Name,
-		ImageClosedURL:       requestData.ImageClosedURL,
-		ImageOpenURL:         requestData.ImageOpenURL,
-		Description:          requestData.Description,
-		Story:                requestData.Story,
-		SourcingValues:       requestData.SourcingValues,
-		Ingredients:          requestData.Ingredients,
-		AllergyInfo:          requestData.AllergyInfo,
-		DietaryCertification: requestData.DietaryCertification,
-	}
+	return requestData.Product()
 }
 
 func updateToProduct(requestData productUpdateRequest) domain.Product {
@@ -85,56 +148,421 @@ func updateToProduct(requestData productUpdateRequest) domain.Product {
 		Ingredients:          requestData.Ingredients,
 		AllergyInfo:          requestData.AllergyInfo,
 		DietaryCertification: requestData.DietaryCertification,
+		Regions:              requestData.Regions,
+		GTINs:                domain.NormalizeGTINs(requestData.GTINs),
+		Allergens:            requestData.Allergens.Allergens(),
+		Nutrition:            requestData.Nutrition.NutritionFacts(),
+		RegionOverrides:      catalog.RegionOverrides(requestData.RegionOverrides),
+	}
+}
+
+// productToUpdate copies product entity into update request, which is
+// the document patched by PATCH. Lists and maps are never null in the
+// document, so that JSON Patch can add elements to them
+func productToUpdate(product domain.Product) productUpdateRequest {
+	requestData := productUpdateRequest{
+		Name:                 product.Name,
+		ImageClosedURL:       product.ImageClosedURL,
+		ImageOpenURL:         product.ImageOpenURL,
+		Description:          product.Description,
+		Story:                product.Story,
+		SourcingValues:       product.SourcingValues,
+		Ingredients:          product.Ingredients,
+		AllergyInfo:          product.AllergyInfo,
+		DietaryCertification: product.DietaryCertification,
+		Regions:              product.Regions,
+		GTINs:                product.GTINs,
+		Allergens: &catalog.AllergensRecord{
+			Contains:   nonNil(product.Allergens.Contains),
+			MayContain: nonNil(product.Allergens.MayContain),
+		},
+		Nutrition:       catalog.NewNutritionRecord(product.Nutrition),
+		RegionOverrides: catalog.NewOverrideRecords(product.RegionOverrides),
+	}
+
+	if requestData.Regions == nil {
+		requestData.Regions = []string{}
+	}
+
+	if requestData.GTINs == nil {
+		requestData.GTINs = []string{}
+	}
+
+	if requestData.RegionOverrides == nil {
+		requestData.RegionOverrides = map[string]catalog.OverrideRecord{}
+	}
+
+	if requestData.SourcingValues == nil {
+		requestData.SourcingValues = &[]string{}
+	}
+
+	if requestData.Ingredients == nil {
+		requestData.Ingredients = &[]string{}
 	}
+	return requestData
 }
 
 // ProductHandler ...
 type ProductHandler struct {
 	service domain.ProductService
+	locales locale.Negotiator
 }
 
-// NewProductHandler creates new HTTP handler
-// for product related request
-func NewProductHandler(service domain.ProductService) *ProductHandler {
+// NewProductHandler creates new HTTP handler for product
+// related request, responding in locales picked by locales
+func NewProductHandler(service domain.ProductService, locales locale.Negotiator) *ProductHandler {
 	handler := &ProductHandler{
 		service: service,
+		locales: locales,
 	}
 	return handler
 }
 
-func newSingleResponse(product domain.Product) productSingleResponse {
-	productData := productResponseData{
+func newResponseData(product domain.Product) productResponseData {
+	return productResponseData{
 		ProductID:            product.ProductID,
 		Name:                 product.Name,
+		Slug:                 product.Slug,
+		PreviousSlugs:        product.PreviousSlugs,
 		ImageClosedURL:       product.ImageClosedURL,
 		ImageOpenURL:         product.ImageOpenURL,
 		Description:          product.Description,
 		Story:                product.Story,
 		SourcingValues:       product.SourcingValues,
 		Ingredients:          product.Ingredients,
+		ParsedIngredients:    newIngredientsData(product.ParsedIngredients),
 		AllergyInfo:          product.AllergyInfo,
 		DietaryCertification: product.DietaryCertification,
+		Regions:              product.Regions,
+		GTINs:                product.GTINs,
+		Allergens:            catalog.NewAllergensRecord(product.Allergens),
+		AllergenCheck:        newAllergenCheckData(product.AllergenCheck),
+		Nutrition:            catalog.NewNutritionRecord(product.Nutrition),
+		RegionOverrides:      catalog.NewOverrideRecords(product.RegionOverrides),
+		Status:               string(product.Status),
+		RetiredAt:            unixTime(product.RetiredAt),
+		Epitaph:              product.Epitaph,
+		ScheduledStatus:      string(product.ScheduledStatus),
+		ScheduledAt:          unixTime(product.ScheduledAt),
+		Variants:             newVariantsData(product.Variants),
+	}
+}
+
+// unixTime converts optional time into timestamp in seconds
+func unixTime(t *time.Time) *int64 {
+	if t == nil {
+		return nil
+	}
+	seconds := t.Unix()
+	return &seconds
+}
+
+func newSingleResponse(product domain.Product) productSingleResponse {
+	return productSingleResponse{Data: newResponseData(product)}
+}
+
+func newListResponse(page domain.ProductPage) productListResponse {
+	productsData := make([]productResponseData, 0, len(page.Products))
+	for _, product := range page.Products {
+		productsData = append(productsData, newResponseData(product))
+	}
+	return productListResponse{
+		Data:       productsData,
+		NextCursor: page.NextCursor,
+		TotalCount: page.TotalCount,
+		Facets: productFacetsData{
+			SourcingValues:        newFacetCountsData(page.Facets.SourcingValues),
+			DietaryCertifications: newFacetCountsData(page.Facets.DietaryCertifications),
+			Allergens:             newFacetCountsData(page.Facets.Allergens),
+		},
 	}
-	return productSingleResponse{Data: productData}
+}
+
+func newFacetCountsData(counts []domain.FacetCount) []facetCountData {
+	countsData := make([]facetCountData, 0, len(counts))
+	for _, count := range counts {
+		countsData = append(countsData, facetCountData(count))
+	}
+	return countsData
+}
+
+func newSearchResponse(results []domain.ProductSearchResult) productSearchResponse {
+	resultsData := make([]productSearchResultData, 0, len(results))
+	for _, result := range results {
+		resultsData = append(resultsData, productSearchResultData{
+			Product:    newResponseData(result.Product),
+			Score:      result.Score,
+			Highlights: result.Highlights,
+		})
+	}
+	return productSearchResponse{Data: resultsData}
+}
+
+// parseProductQuery reads paging, sorting and filters from url query
+// e.g. ?limit=20&sort=-name&cursor=<next_cursor>&sourcing=Fairtrade&dietary=Kosher&status=draft&region=uk
+func parseProductQuery(r *http.Request) (domain.ProductQuery, error) {
+	var query domain.ProductQuery
+	values := r.URL.Query()
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return domain.ProductQuery{}, domain.ErrBadParamInput
+		}
+		query.Limit = n
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		query.SortOrder = domain.Ascending
+		if strings.HasPrefix(sort, "-") {
+			query.SortOrder = domain.Descending
+		}
+		query.SortBy = strings.TrimLeft(sort, "+-")
+	}
+
+	query.Cursor = values.Get("cursor")
+	query.Filter = domain.ProductFilter{
+		SourcingValues:        values["sourcing"],
+		SourcingMatch:         domain.MatchMode(values.Get("sourcing_match")),
+		DietaryCertifications: values["dietary"],
+		ExcludeAllergens:      lowerAll(values["exclude_allergen"]),
+		Region:                strings.ToLower(values.Get("region")),
+	}
+
+	for _, status := range values["status"] {
+		query.Filter.Statuses = append(query.Filter.Statuses, domain.ProductStatus(status))
+	}
+	return query, nil
+}
+
+func lowerAll(values []string) []string {
+	var lowered []string
+	for _, value := range values {
+		lowered = append(lowered, strings.ToLower(value))
+	}
+	return lowered
+}
+
+// queryLists tells whether query parameter lists value, in
+// comma separated or repeated form, e.g. ?embed=a,b or ?embed=a&embed=b
+func queryLists(r *http.Request, key string, value string) bool {
+	for _, values := range r.URL.Query()[key] {
+		for _, listed := range strings.Split(values, ",") {
+			if strings.TrimSpace(listed) == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withEmbeds keeps variants and parsed ingredients of product only if
+// request asks for them, e.g. ?embed=variants&expand=ingredients
+func withEmbeds(product domain.Product, r *http.Request) domain.Product {
+	if !embedsVariants(r) {
+		product.Variants = nil
+	}
+	return withParsedIngredients(product, expandsIngredients(r))
 }
 
 // Routes register handle func with the path url
 func (handler *ProductHandler) Routes(router *mux.Router, middleware alice.Chain) {
 	// Register middleware here
+	fetchHandler := middleware.Then(handler.handleFetchProducts())
+	searchHandler := middleware.Then(handler.handleSearchProducts())
+	exportHandler := middleware.Then(handler.handleExportProducts())
 	getHandler := middleware.Then(handler.handleGetProduct())
 	updateHandler := middleware.Then(handler.handleUpdateProduct())
+	patchHandler := middleware.Then(handler.handlePatchProduct())
 	deleteHandler := middleware.Then(handler.handleDeleteProduct())
 	createHandler := middleware.Then(handler.handleCreateProduct())
+	fetchRevisionsHandler := middleware.Then(handler.handleFetchRevisions())
+	getRevisionHandler := middleware.Then(handler.handleGetRevision())
+	restoreRevisionHandler := middleware.Then(handler.handleRestoreRevision())
+	fetchTrashHandler := middleware.Then(handler.handleFetchTrash())
+	restoreHandler := middleware.Then(handler.handleRestoreProduct())
+	purgeHandler := middleware.Then(handler.handlePurgeProduct())
+	fetchRetiredHandler := middleware.Then(handler.handleFetchRetiredProducts())
+	changeStatusHandler := middleware.Then(handler.handleChangeProductStatus())
+	fetchTranslationsHandler := middleware.Then(handler.handleFetchTranslations())
+	setTranslationHandler := middleware.Then(handler.handleSetTranslation())
+	deleteTranslationHandler := middleware.Then(handler.handleDeleteTranslation())
+	getBySKUHandler := middleware.Then(handler.handleGetProductBySKU())
+	getByGTINHandler := middleware.Then(handler.handleGetProductByGTIN())
+	getAllergensHandler := middleware.Then(handler.handleGetAllergens())
+	getNutritionHandler := middleware.Then(handler.handleGetNutrition())
+	getNutritionLabelHandler := middleware.Then(handler.handleGetNutritionLabel())
+	fetchVariantsHandler := middleware.Then(handler.handleFetchVariants())
+	getVariantHandler := middleware.Then(handler.handleGetVariant())
+	createVariantHandler := middleware.Then(handler.handleCreateVariant())
+	updateVariantHandler := middleware.Then(handler.handleUpdateVariant())
+	deleteVariantHandler := middleware.Then(handler.handleDeleteVariant())
+	batchGetHandler := middleware.Then(handler.handleBatchGetProducts())
+	batchCreateHandler := middleware.Then(handler.handleBatchCreateProducts())
+	batchUpdateHandler := middleware.Then(handler.handleBatchUpdateProducts())
+	batchDeleteHandler := middleware.Then(handler.handleBatchDeleteProducts())
 
 	// Register handler methods to router here...
+	fetchRoute := router.Handle("/", fetchHandler).Methods("GET").Name("PRODUCT_FETCH")
+	router.Handle("/search", searchHandler).Methods("GET").Name("PRODUCT_SEARCH_FETCH")
+	router.Handle("/export", exportHandler).Methods("GET").Name("PRODUCT_EXPORT_FETCH")
+	router.Handle("/trash", fetchTrashHandler).Methods("GET").Name("PRODUCT_TRASH_FETCH")
+	router.Handle("/graveyard", fetchRetiredHandler).Methods("GET").Name("PRODUCT_GRAVEYARD_FETCH")
+	router.Handle("/trash/{product_id}", purgeHandler).Methods("DELETE").Name("PRODUCT_PURGE_DELETE")
+	router.Handle("/by-sku/{sku}", getBySKUHandler).Methods("GET").Name("PRODUCT_SKU_GET")
+	router.Handle("/by-barcode/{gtin}", getByGTINHandler).Methods("GET").Name("PRODUCT_BARCODE_GET")
 	router.Handle("/{product_id}", getHandler).Methods("GET").Name("PRODUCT_GET")
 	router.Handle("/{product_id}", updateHandler).Methods("PUT").Name("PRODUCT_UPDATE")
+	router.Handle("/{product_id}", patchHandler).Methods("PATCH").Name("PRODUCT_PATCH_UPDATE")
 	router.Handle("/{product_id}", deleteHandler).Methods("DELETE").Name("PRODUCT_DELETE")
 	router.Handle("/", createHandler).Methods("POST").Name("PRODUCT_CREATE")
+
+	// batch methods are custom methods of the collection, e.g. /api/products:batchGet
+	collection, _ := fetchRoute.GetPathTemplate()
+	collection = strings.TrimSuffix(collection, "/")
+	handleCollectionMethod(router, collection, "batchGet", batchGetHandler).Name("PRODUCT_BATCH_GET")
+	handleCollectionMethod(router, collection, "batchCreate", batchCreateHandler).Name("PRODUCT_BATCH_CREATE")
+	handleCollectionMethod(router, collection, "batchUpdate", batchUpdateHandler).Name("PRODUCT_BATCH_UPDATE")
+	handleCollectionMethod(router, collection, "batchDelete", batchDeleteHandler).Name("PRODUCT_BATCH_DELETE")
+	router.Handle("/{product_id}/restore", restoreHandler).Methods("POST").Name("PRODUCT_RESTORE_UPDATE")
+	router.Handle("/{product_id}/status", changeStatusHandler).Methods("POST").Name("PRODUCT_STATUS_UPDATE")
+	router.Handle("/{product_id}/translations", fetchTranslationsHandler).Methods("GET").Name("PRODUCT_TRANSLATION_FETCH")
+	router.Handle("/{product_id}/translations/{locale}", setTranslationHandler).
+		Methods("PUT").Name("PRODUCT_TRANSLATION_UPDATE")
+	router.Handle("/{product_id}/translations/{locale}", deleteTranslationHandler).
+		Methods("DELETE").Name("PRODUCT_TRANSLATION_DELETE")
+	router.Handle("/{product_id}/allergens", getAllergensHandler).Methods("GET").Name("PRODUCT_ALLERGEN_GET")
+	router.Handle("/{product_id}/nutrition", getNutritionHandler).Methods("GET").Name("PRODUCT_NUTRITION_GET")
+	router.Handle("/{product_id}/nutrition/label.svg", getNutritionLabelHandler).
+		Methods("GET").Name("PRODUCT_NUTRITION_LABEL_GET")
+	router.Handle("/{product_id}/variants", fetchVariantsHandler).Methods("GET").Name("PRODUCT_VARIANT_FETCH")
+	router.Handle("/{product_id}/variants", createVariantHandler).Methods("POST").Name("PRODUCT_VARIANT_CREATE")
+	router.Handle("/{product_id}/variants/{sku}", getVariantHandler).Methods("GET").Name("PRODUCT_VARIANT_GET")
+	router.Handle("/{product_id}/variants/{sku}", updateVariantHandler).Methods("PUT").Name("PRODUCT_VARIANT_UPDATE")
+	router.Handle("/{product_id}/variants/{sku}", deleteVariantHandler).Methods("DELETE").Name("PRODUCT_VARIANT_DELETE")
+	router.Handle("/{product_id}/revisions", fetchRevisionsHandler).Methods("GET").Name("PRODUCT_REVISION_FETCH")
+	router.Handle("/{product_id}/revisions/{revision}", getRevisionHandler).Methods("GET").Name("PRODUCT_REVISION_GET")
+	router.Handle("/{product_id}/revisions/{revision}/restore", restoreRevisionHandler).
+		Methods("POST").Name("PRODUCT_REVISION_RESTORE_UPDATE")
+}
+
+// handleFetchProducts provides handler func that lists a page of products
+// [GET] /api/products/?limit=&sort=&cursor=&sourcing=&sourcing_match=&dietary=&exclude_allergen=&status=&region=&embed=variants&expand=ingredients
+func (handler *ProductHandler) handleFetchProducts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		query, err := parseProductQuery(r)
+		if err != nil {
+			writeErrorMessage(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		page, err := handler.service.FetchProducts(r.Context(), query)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		for i := range page.Products {
+			page.Products[i] = withEmbeds(page.Products[i], r)
+		}
+		response := newListResponse(page)
+		json.NewEncoder(w).Encode(response)
+	}
 }
 
-// handleGetProduct provides handler func that gets a product
-// [GET] /api/products/:product_id
+// handleSearchProducts provides handler func that full-text searches products
+// [GET] /api/products/search?q=&limit=&region=&embed=variants&expand=ingredients
+func (handler *ProductHandler) handleSearchProducts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var limit int
+		values := r.URL.Query()
+
+		if value := values.Get("limit"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				writeErrorMessage(w, domain.ErrBadParamInput.Error(), http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		region := strings.ToLower(values.Get("region"))
+		results, err := handler.service.SearchProducts(r.Context(), values.Get("q"), limit, region)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		for i := range results {
+			results[i].Product = withEmbeds(results[i].Product, r)
+		}
+
+		response := newSearchResponse(results)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// handleExportProducts provides handler func that streams whole catalog
+// [GET] /api/products/export?format=json|ndjson|csv&delimiter=&region=
+func (handler *ProductHandler) handleExportProducts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		values := r.URL.Query()
+
+		format := values.Get("format")
+		if format == "" {
+			format = catalog.FormatJSON
+		}
+
+		writer, err := catalog.NewWriter(w, format, values.Get("delimiter"))
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			writeErrorMessage(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", catalog.ContentType(format))
+		w.Header().Set("Content-Disposition", `attachment; filename="products.`+format+`"`)
+
+		var started bool
+		region := strings.ToLower(values.Get("region"))
+		err = handler.service.ExportProducts(r.Context(), region, func(product domain.Product) error {
+			started = true
+			return writer.Write(product)
+		})
+
+		if err != nil && !started {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Del("Content-Disposition")
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		if err == nil {
+			err = writer.Close()
+		}
+
+		if err != nil {
+			// response is already partially sent,
+			// so the export can only be cut short
+			log.Println("export: aborted:", err)
+		}
+	}
+}
+
+// handleGetProduct provides handler func that gets a product, with its text
+// in the locale requested by ?lang= or else by Accept-Language header.
+// Given region, product must be sold there and has its overrides applied.
+// Products are got by productId or by slug, previous slugs redirect
+// [GET] /api/products/:product_id?lang=&region=&embed=variants&expand=ingredients
 func (handler *ProductHandler) handleGetProduct() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Content-Type", "application/json")
@@ -142,20 +570,107 @@ func (handler *ProductHandler) handleGetProduct() http.HandlerFunc {
 		params := mux.Vars(r)
 		productID := params["product_id"]
 
+		region := strings.ToLower(r.URL.Query().Get("region"))
+		if region != "" && !domain.ValidRegion(region) {
+			writeErrorMessage(w, domain.ErrBadParamInput.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requested := locale.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+		if lang := r.URL.Query().Get("lang"); lang != "" {
+			tag, ok := locale.Canonical(lang)
+			if !ok {
+				writeErrorMessage(w, "lang must be a language tag such as en or fr-CA", http.StatusBadRequest)
+				return
+			}
+			requested = []string{tag}
+		}
+
 		product, err := handler.service.GetProduct(r.Context(), productID)
 
+		// product_id is a slug unless it is a productId, slugs are never all digits
+		bySlug := err == domain.ErrResourceNotFound && validatorLib.ValidateVar(productID, "numeric") != nil
+		if bySlug {
+			product, err = handler.service.GetProductBySlug(r.Context(), productID)
+		}
+
 		if err != nil {
 			status := getResponseStatus(err)
 			writeErrorMessage(w, err.Error(), status)
 			return
 		}
 
-		response := newSingleResponse(product)
+		// previous slugs of renamed products redirect to their current slug
+		if bySlug && productID != product.Slug {
+			redirectToSlug(w, r, product.Slug)
+			return
+		}
+
+		if region != "" {
+			if !product.SoldIn(region) {
+				writeErrorMessage(w, domain.ErrResourceNotFound.Error(), http.StatusNotFound)
+				return
+			}
+			product = product.InRegion(region)
+		}
+
+		language := handler.locales.Resolve(requested, func(tag string) bool {
+			_, ok := product.Translations[tag]
+			return ok
+		})
+
+		product = withEmbeds(product, r)
+
+		w.Header().Set("ETag", formatETag(product.Version))
+		w.Header().Set("Content-Language", language)
+		w.Header().Set("Vary", "Accept-Language")
+		response := newSingleResponse(product.Localized(language))
 		json.NewEncoder(w).Encode(response)
 	}
 }
 
-// handleCreateProduct provides handler func that creates a product
+// redirectToSlug permanently redirects request for a product by its
+// previous slug to the same request by its current slug
+func redirectToSlug(w http.ResponseWriter, r *http.Request, slug string) {
+	target := *r.URL
+	target.Path = path.Join(path.Dir(r.URL.Path), slug)
+
+	w.Header().Del("Content-Type")
+	http.Redirect(w, r, target.String(), http.StatusMovedPermanently)
+}
+
+// handleGetProductByGTIN provides handler func that looks product up by its
+// barcode, a GTIN-8, GTIN-12 (UPC-A), GTIN-13 (EAN-13) or GTIN-14
+// [GET] /api/products/by-barcode/:gtin?embed=variants&expand=ingredients
+func (handler *ProductHandler) handleGetProductByGTIN() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		gtin := params["gtin"]
+
+		if err := validatorLib.ValidateVar(gtin, "gtin"); err != nil {
+			writeErrorMessage(w, "gtin must be a GTIN-8, GTIN-12, GTIN-13 or GTIN-14 with valid check digit", http.StatusBadRequest)
+			return
+		}
+
+		product, err := handler.service.GetProductByGTIN(r.Context(), gtin)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		product = withEmbeds(product, r)
+
+		w.Header().Set("ETag", formatETag(product.Version))
+		json.NewEncoder(w).Encode(newSingleResponse(product))
+	}
+}
+
+// handleCreateProduct provides handler func that creates a product, which
+// is allocated a productId unless given one, and responds with it
 // [POST] /api/product/
 func (handler *ProductHandler) handleCreateProduct() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -169,14 +684,18 @@ func (handler *ProductHandler) handleCreateProduct() http.HandlerFunc {
 		}
 
 		var product = createToProduct(productCreate)
-		err := handler.service.CreateProduct(r.Context(), product)
+		product, err := handler.service.CreateProduct(r.Context(), product)
 
 		if err != nil {
 			status := getResponseStatus(err)
 			writeErrorMessage(w, err.Error(), status)
 			return
 		}
+
+		w.Header().Set("Location", path.Join(r.URL.Path, product.ProductID))
+		w.Header().Set("ETag", formatETag(product.Version))
 		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(newSingleResponse(product))
 	}
 }
 
@@ -189,8 +708,14 @@ func (handler *ProductHandler) handleUpdateProduct() http.HandlerFunc {
 		params := mux.Vars(r)
 		productID := params["product_id"]
 
+		version, err := parseIfMatch(r)
+		if err != nil {
+			writeErrorMessage(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+
 		var productUpdate productUpdateRequest
-		if err := validatorLib.ValidateJSON(r.Body, &productUpdate); err != nil {
+		if err := validatorLib.DecodeAndValidateJSON(r.Body, &productUpdate); err != nil {
 			verr, _ := err.(*validatorLib.ValidationError)
 			writeErrorMessage(w, verr.Message(), http.StatusBadRequest)
 			return
@@ -198,8 +723,9 @@ func (handler *ProductHandler) handleUpdateProduct() http.HandlerFunc {
 
 		var product = updateToProduct(productUpdate)
 		product.ProductID = productID
+		product.Version = version
 
-		err := handler.service.UpdateProduct(r.Context(), productID, product)
+		err = handler.service.UpdateProduct(r.Context(), productID, product)
 
 		if err != nil {
 			status := getResponseStatus(err)
@@ -210,7 +736,136 @@ func (handler *ProductHandler) handleUpdateProduct() http.HandlerFunc {
 	}
 }
 
-// handleDeleteProduct provides handler func that deletes a product
+// handlePatchProduct provides handler func that partially updates a product
+// with either JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902) body.
+// Unlike PUT, patch can clear fields, e.g. {"story": null}
+// [PATCH] /api/product/:product_id
+func (handler *ProductHandler) handlePatchProduct() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		productID := params["product_id"]
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "", "application/json", mergePatchMediaType:
+			mediaType = mergePatchMediaType
+		case jsonPatchMediaType:
+		default:
+			w.Header().Set("Accept-Patch", mergePatchMediaType+", "+jsonPatchMediaType)
+			writeErrorMessage(w, "Unsupported patch media type", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		version, err := parseIfMatch(r)
+		if err != nil {
+			writeErrorMessage(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+
+		patch, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			writeErrorMessage(w, domain.ErrBadParamInput.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// the product read is patched and written back only if it is still
+		// at the read version. Without If-Match, the patch is simply
+		// reapplied onto a newer version of the product
+		for attempt := 1; ; attempt++ {
+			status, message := handler.patchProduct(r, productID, version, mediaType, patch)
+
+			if status == http.StatusPreconditionFailed && version == 0 && attempt < patchAttempts {
+				continue
+			}
+
+			if status != http.StatusOK {
+				writeErrorMessage(w, message, status)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+}
+
+// patchProduct reads, patches and replaces product, returning
+// response status and error message if the patch fails
+func (handler *ProductHandler) patchProduct(
+	r *http.Request,
+	productID string,
+	version int64,
+	mediaType string,
+	patch []byte,
+) (int, string) {
+	product, err := handler.service.GetProduct(r.Context(), productID)
+
+	if err != nil {
+		return getResponseStatus(err), err.Error()
+	}
+
+	if version != 0 && version != product.Version {
+		return http.StatusPreconditionFailed, domain.ErrPreconditionFailed.Error()
+	}
+
+	document, _ := json.Marshal(productToUpdate(product))
+	patched, err := applyPatch(mediaType, document, patch)
+
+	if err == jsonpatch.ErrTestFailed {
+		return http.StatusConflict, err.Error()
+	}
+
+	if err != nil {
+		return http.StatusBadRequest, err.Error()
+	}
+
+	// patched document is validated by the same rules as PUT, but
+	// fields outside of it are rejected and required fields are kept
+	var productUpdate productUpdateRequest
+	if err := validatorLib.DecodeStrictAndValidateJSON(bytes.NewReader(patched), &productUpdate); err != nil {
+		verr, _ := err.(*validatorLib.ValidationError)
+		return http.StatusBadRequest, verr.Message()
+	}
+
+	err = validatorLib.ValidateStruct(productRequiredFields{
+		Name:                 productUpdate.Name,
+		Description:          productUpdate.Description,
+		AllergyInfo:          productUpdate.AllergyInfo,
+		DietaryCertification: productUpdate.DietaryCertification,
+	})
+	if err != nil {
+		verr, _ := err.(*validatorLib.ValidationError)
+		return http.StatusBadRequest, verr.Message()
+	}
+
+	readVersion := product.Version
+	product = updateToProduct(productUpdate)
+	product.ProductID = productID
+	product.Version = readVersion
+
+	err = handler.service.ReplaceProduct(r.Context(), productID, product)
+
+	if err != nil {
+		return getResponseStatus(err), err.Error()
+	}
+	return http.StatusOK, ""
+}
+
+// applyPatch patches document with patch of the media type
+func applyPatch(mediaType string, document, patch []byte) ([]byte, error) {
+	if mediaType == mergePatchMediaType {
+		return jsonpatch.MergePatch(document, patch)
+	}
+
+	operations, err := jsonpatch.DecodePatch(patch)
+	if err != nil {
+		return nil, err
+	}
+	return jsonpatch.ApplyPatch(document, operations)
+}
+
+// handleDeleteProduct provides handler func that moves a product to trash
 // [DEL] /api/product/:product_id
 func (handler *ProductHandler) handleDeleteProduct() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -219,7 +874,13 @@ func (handler *ProductHandler) handleDeleteProduct() http.HandlerFunc {
 		params := mux.Vars(r)
 		productID := params["product_id"]
 
-		err := handler.service.DeleteProduct(r.Context(), productID)
+		version, err := parseIfMatch(r)
+		if err != nil {
+			writeErrorMessage(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+
+		err = handler.service.DeleteProduct(r.Context(), productID, version)
 
 		if err != nil {
 			status := getResponseStatus(err)
@@ -230,6 +891,32 @@ func (handler *ProductHandler) handleDeleteProduct() http.HandlerFunc {
 	}
 }
 
+// formatETag formats product version as strong entity tag
+func formatETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseIfMatch reads product version required by If-Match header.
+// Version is zero when header is absent or "*", i.e. any version.
+// Weak or malformed entity tags can never match strongly
+func parseIfMatch(r *http.Request) (int64, error) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+
+	if ifMatch == "" || ifMatch == "*" {
+		return 0, nil
+	}
+
+	if len(ifMatch) < 2 || !strings.HasPrefix(ifMatch, `"`) || !strings.HasSuffix(ifMatch, `"`) {
+		return 0, domain.ErrPreconditionFailed
+	}
+
+	version, err := strconv.ParseInt(ifMatch[1:len(ifMatch)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, domain.ErrPreconditionFailed
+	}
+	return version, nil
+}
+
 // writerErrorMessage is a helper that writes error message to response
 func writeErrorMessage(writer http.ResponseWriter, errMsg string, httpStatus int) {
 	writer.WriteHeader(httpStatus)
@@ -245,12 +932,19 @@ func getResponseStatus(err error) int {
 		return http.StatusOK
 	case domain.ErrAuthFail, domain.ErrExpiredToken:
 		return http.StatusUnauthorized
-	case domain.ErrBadParamInput:
+	case domain.ErrBadParamInput, domain.ErrInvalidNutrition:
 		return http.StatusBadRequest
-	case domain.ErrConflict:
-		return http.StatusOK
 	case domain.ErrResourceNotFound:
 		return http.StatusNotFound
+	case domain.ErrPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case domain.ErrConflict, domain.ErrInvalidTransition, domain.ErrDuplicateSKU,
+		domain.ErrDuplicateGTIN, domain.ErrDuplicateSlug:
+		return http.StatusConflict
+	case domain.ErrBatchAborted:
+		return http.StatusFailedDependency
+	case domain.ErrTransactionUnsupported:
+		return http.StatusNotImplemented
 	default:
 		return http.StatusInternalServerError
 	}
@@ -6,10 +6,17 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/iqdf/benjerry-service/common/auth"
+	"github.com/iqdf/benjerry-service/common/consts/role"
+	"github.com/iqdf/benjerry-service/common/locale"
+	"github.com/iqdf/benjerry-service/common/middleware"
 	"github.com/iqdf/benjerry-service/domain"
 	"github.com/iqdf/benjerry-service/domain/mocks"
+	"github.com/iqdf/benjerry-service/product/service"
+	"github.com/justinas/alice"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -18,8 +25,142 @@ var (
 	contextType   = mock.Anything
 	productIDType = mock.AnythingOfType("string")
 	productType   = mock.AnythingOfType("domain.Product")
+	queryType     = mock.AnythingOfType("domain.ProductQuery")
+
+	testLocales = locale.Negotiator{Default: "en", Fallback: []string{"fr"}}
 )
 
+func TestFetchProductsSuccess(t *testing.T) {
+	productService := new(mocks.ProductService)
+	mockPage := domain.ProductPage{
+		Products:   []domain.Product{createMockProduct()},
+		NextCursor: domain.PageCursor{ProductID: "646"}.Encode(),
+		TotalCount: 2,
+		Facets: domain.ProductFacets{
+			SourcingValues:        []domain.FacetCount{{Value: "Fairtrade", Count: 2}},
+			DietaryCertifications: []domain.FacetCount{{Value: "Kosher", Count: 2}},
+			Allergens:             []domain.FacetCount{{Value: "peanuts", Count: 0}},
+		},
+	}
+	expectedQuery := domain.ProductQuery{
+		Limit:     1,
+		SortBy:    domain.SortByName,
+		SortOrder: domain.Descending,
+		Filter: domain.ProductFilter{
+			SourcingValues:        []string{"Fairtrade", "Non-GMO"},
+			SourcingMatch:         domain.MatchAny,
+			DietaryCertifications: []string{"Kosher"},
+			ExcludeAllergens:      []string{"peanuts"},
+			Region:                "uk",
+		},
+	}
+
+	productService.On("FetchProducts", contextType, expectedQuery).
+		Return(mockPage, nil).
+		Once()
+
+	request, _ := http.NewRequest("GET", "/api/products/?limit=1&sort=-name"+
+		"&sourcing=Fairtrade&sourcing=Non-GMO&sourcing_match=any&dietary=Kosher&exclude_allergen=peanuts&region=UK", strings.NewReader(""))
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	fetchHandle := productHandler.handleFetchProducts()
+
+	var listResponse productListResponse
+
+	fetchHandle(recorder, request)
+	err := json.NewDecoder(recorder.Body).Decode(&listResponse)
+
+	assert.NoError(t, err)
+	assert.Equal(t, recorder.Code, 200)
+	assert.Equal(t, listResponse, newListResponse(mockPage))
+	productService.AssertExpectations(t)
+}
+
+func TestFetchProductsBadLimit(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	request, _ := http.NewRequest("GET", "/api/products/?limit=ten", strings.NewReader(""))
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	fetchHandle := productHandler.handleFetchProducts()
+
+	fetchHandle(recorder, request)
+	assert.Equal(t, recorder.Code, 400)
+	productService.AssertNotCalled(t, "FetchProducts", contextType, queryType)
+}
+
+func TestSearchProductsSuccess(t *testing.T) {
+	productService := new(mocks.ProductService)
+	mockResults := []domain.ProductSearchResult{
+		{
+			Product:    createMockProduct(),
+			Score:      2.5,
+			Highlights: map[string]string{"name": "Vanilla <em>Toffee</em> Bar Crunch"},
+		},
+	}
+
+	productService.On("SearchProducts", contextType, "toffee", 5, "").
+		Return(mockResults, nil).
+		Once()
+
+	request, _ := http.NewRequest("GET", "/api/products/search?q=toffee&limit=5", strings.NewReader(""))
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	searchHandle := productHandler.handleSearchProducts()
+
+	var searchResponse productSearchResponse
+
+	searchHandle(recorder, request)
+	err := json.NewDecoder(recorder.Body).Decode(&searchResponse)
+
+	assert.NoError(t, err)
+	assert.Equal(t, recorder.Code, 200)
+	assert.Equal(t, searchResponse, newSearchResponse(mockResults))
+}
+
+func TestExportProductsCSV(t *testing.T) {
+	productService := new(mocks.ProductService)
+	exportFuncType := mock.AnythingOfType("func(domain.Product) error")
+
+	productService.On("ExportProducts", contextType, "", exportFuncType).
+		Run(func(args mock.Arguments) {
+			fn := args.Get(2).(func(domain.Product) error)
+			fn(createMockProduct())
+		}).
+		Return(nil).
+		Once()
+
+	request, _ := http.NewRequest("GET", "/api/products/export?format=csv", strings.NewReader(""))
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	exportHandle := productHandler.handleExportProducts()
+
+	exportHandle(recorder, request)
+	lines := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n")
+
+	assert.Equal(t, recorder.Code, 200)
+	assert.Equal(t, "text/csv", recorder.Header().Get("Content-Type"))
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[1], "Non-GMO|Cage-Free Eggs|Fairtrade")
+}
+
+func TestExportProductsUnknownFormat(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	request, _ := http.NewRequest("GET", "/api/products/export?format=xml", strings.NewReader(""))
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	exportHandle := productHandler.handleExportProducts()
+
+	exportHandle(recorder, request)
+	assert.Equal(t, recorder.Code, 400)
+}
+
 func TestGetProductSuccess(t *testing.T) {
 	productService := new(mocks.ProductService)
 	mockProduct := createMockProduct()
@@ -36,7 +177,7 @@ func TestGetProductSuccess(t *testing.T) {
 	request = mux.SetURLVars(request, vars)
 	recorder := httptest.NewRecorder()
 
-	productHandler := NewProductHandler(productService)
+	productHandler := NewProductHandler(productService, testLocales)
 	getHandle := productHandler.handleGetProduct()
 
 	var productResponse productSingleResponse
@@ -46,9 +187,115 @@ func TestGetProductSuccess(t *testing.T) {
 
 	assert.NoError(t, err)
 	assert.Equal(t, recorder.Code, 200)
+	assert.Equal(t, `"4"`, recorder.Header().Get("ETag"))
 	assert.Equal(t, productResponse, newSingleResponse(mockProduct))
 }
 
+func TestGetProductLocalized(t *testing.T) {
+	productService := new(mocks.ProductService)
+	mockProduct := createMockProduct()
+	mockProduct.Translations = map[string]domain.ProductTranslation{
+		"fr":    {Name: "Vanille Caramel Croquant"},
+		"de-CH": {Name: "Vanille Karamell", Story: "Eine Geschichte"},
+	}
+
+	productService.On("GetProduct", contextType, "646").
+		Return(mockProduct, nil)
+
+	tests := []struct {
+		name           string
+		url            string
+		acceptLanguage string
+		language       string
+		productName    string
+		story          string
+	}{
+		{"accept-language", "/api/products/646", "de-CH, fr;q=0.5", "de-CH", "Vanille Karamell", "Eine Geschichte"},
+		{"parent-locale", "/api/products/646", "fr-CA", "fr", "Vanille Caramel Croquant", mockProduct.Story},
+		{"default-locale", "/api/products/646", "en-GB, fr;q=0.5", "en", mockProduct.Name, mockProduct.Story},
+		{"fallback", "/api/products/646", "ja", "fr", "Vanille Caramel Croquant", mockProduct.Story},
+		{"lang-query", "/api/products/646?lang=de-ch", "fr", "de-CH", "Vanille Karamell", "Eine Geschichte"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request, _ := http.NewRequest("GET", test.url, nil)
+			request.Header.Set("Accept-Language", test.acceptLanguage)
+			request = mux.SetURLVars(request, map[string]string{"product_id": "646"})
+			recorder := httptest.NewRecorder()
+
+			productHandler := NewProductHandler(productService, testLocales)
+			getHandle := productHandler.handleGetProduct()
+
+			var productResponse productSingleResponse
+
+			getHandle(recorder, request)
+			err := json.NewDecoder(recorder.Body).Decode(&productResponse)
+
+			assert.NoError(t, err)
+			assert.Equal(t, 200, recorder.Code)
+			assert.Equal(t, test.language, recorder.Header().Get("Content-Language"))
+			assert.Equal(t, test.productName, productResponse.Data.Name)
+			assert.Equal(t, test.story, productResponse.Data.Story)
+		})
+	}
+
+	t.Run("bad-lang", func(t *testing.T) {
+		request, _ := http.NewRequest("GET", "/api/products/646?lang=fr_FR", nil)
+		request = mux.SetURLVars(request, map[string]string{"product_id": "646"})
+		recorder := httptest.NewRecorder()
+
+		productHandler := NewProductHandler(productService, testLocales)
+		getHandle := productHandler.handleGetProduct()
+
+		getHandle(recorder, request)
+		assert.Equal(t, 400, recorder.Code)
+	})
+}
+
+func TestGetProductRegion(t *testing.T) {
+	productService := new(mocks.ProductService)
+	mockProduct := createMockProduct()
+	mockProduct.Regions = []string{"us", "uk"}
+	mockProduct.RegionOverrides = map[string]domain.RegionOverride{
+		"uk": {ImageClosedURL: "/files/uk/vanilla-toffee-landing.png"},
+	}
+
+	productService.On("GetProduct", contextType, "646").
+		Return(mockProduct, nil)
+
+	tests := []struct {
+		name     string
+		region   string
+		code     int
+		imageURL string
+	}{
+		{"override", "UK", 200, "/files/uk/vanilla-toffee-landing.png"},
+		{"no-override", "us", 200, mockProduct.ImageClosedURL},
+		{"not-sold", "de", 404, ""},
+		{"bad-region", "gbr", 400, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request, _ := http.NewRequest("GET", "/api/products/646?region="+test.region, nil)
+			request = mux.SetURLVars(request, map[string]string{"product_id": "646"})
+			recorder := httptest.NewRecorder()
+
+			productHandler := NewProductHandler(productService, testLocales)
+			getHandle := productHandler.handleGetProduct()
+
+			var productResponse productSingleResponse
+
+			getHandle(recorder, request)
+			json.NewDecoder(recorder.Body).Decode(&productResponse)
+
+			assert.Equal(t, test.code, recorder.Code)
+			assert.Equal(t, test.imageURL, productResponse.Data.ImageClosedURL)
+		})
+	}
+}
+
 func TestGetProductNotFound(t *testing.T) {
 	productService := new(mocks.ProductService)
 	productID := "978"
@@ -64,18 +311,60 @@ func TestGetProductNotFound(t *testing.T) {
 	request = mux.SetURLVars(request, vars)
 	recorder := httptest.NewRecorder()
 
-	productHandler := NewProductHandler(productService)
+	productHandler := NewProductHandler(productService, testLocales)
 	getHandle := productHandler.handleGetProduct()
 
 	getHandle(recorder, request)
 	assert.Equal(t, recorder.Code, 404)
 }
 
+func TestGetProductBySlug(t *testing.T) {
+	mockProduct := createMockProduct()
+	mockProduct.Slug = "chunky-monkey"
+	mockProduct.PreviousSlugs = []string{"vanilla-toffee-bar-crunch"}
+
+	cases := []struct {
+		name     string
+		slug     string
+		code     int
+		location string
+	}{
+		{name: "current-slug", slug: "chunky-monkey", code: 200},
+		{name: "previous-slug", slug: "vanilla-toffee-bar-crunch", code: 301, location: "/api/products/chunky-monkey?lang=fr"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			productService := new(mocks.ProductService)
+			productService.On("GetProduct", contextType, c.slug).
+				Return(domain.Product{}, domain.ErrResourceNotFound).
+				Once()
+			productService.On("GetProductBySlug", contextType, c.slug).
+				Return(mockProduct, nil).
+				Once()
+
+			request, _ := http.NewRequest("GET", "/api/products/"+c.slug+"?lang=fr", strings.NewReader(""))
+			request = mux.SetURLVars(request, map[string]string{"product_id": c.slug})
+			recorder := httptest.NewRecorder()
+
+			productHandler := NewProductHandler(productService, testLocales)
+			productHandler.handleGetProduct()(recorder, request)
+
+			assert.Equal(t, c.code, recorder.Code)
+			assert.Equal(t, c.location, recorder.Header().Get("Location"))
+			productService.AssertExpectations(t)
+		})
+	}
+}
+
 func TestCreateProductSuccess(t *testing.T) {
 	productService := new(mocks.ProductService)
 
+	mockProduct := createMockProduct()
+	mockProduct.Slug = "vanilla-toffee-bar-crunch"
+	mockProduct.Version = 1
 	productService.On("CreateProduct", contextType, productType).
-		Return(nil).
+		Return(mockProduct, nil).
 		Once()
 
 	createReq := createMockCreateRequest()
@@ -86,18 +375,25 @@ func TestCreateProductSuccess(t *testing.T) {
 	recorder := httptest.NewRecorder()
 
 	assert.NoError(t, err)
-	productHandler := NewProductHandler(productService)
+	productHandler := NewProductHandler(productService, testLocales)
 	createHandle := productHandler.handleCreateProduct()
 
 	createHandle(recorder, request)
 	assert.Equal(t, recorder.Code, 201)
+	assert.Equal(t, "/api/products/"+mockProduct.ProductID, recorder.Header().Get("Location"))
+	assert.Equal(t, `"1"`, recorder.Header().Get("ETag"))
+
+	var productResponse productSingleResponse
+	err = json.NewDecoder(recorder.Body).Decode(&productResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, "vanilla-toffee-bar-crunch", productResponse.Data.Slug)
 }
 
 func TestCreateProductConflict(t *testing.T) {
 	productService := new(mocks.ProductService)
 
 	productService.On("CreateProduct", contextType, productType).
-		Return(domain.ErrConflict).
+		Return(domain.Product{}, domain.ErrConflict).
 		Once()
 
 	createReq := createMockCreateRequest()
@@ -108,11 +404,11 @@ func TestCreateProductConflict(t *testing.T) {
 	recorder := httptest.NewRecorder()
 
 	assert.NoError(t, err)
-	productHandler := NewProductHandler(productService)
+	productHandler := NewProductHandler(productService, testLocales)
 	createHandle := productHandler.handleCreateProduct()
 
 	createHandle(recorder, request)
-	assert.Equal(t, recorder.Code, 200)
+	assert.Equal(t, recorder.Code, 409)
 
 	var msgErr messageError
 	json.NewDecoder(recorder.Body).Decode(&msgErr)
@@ -134,13 +430,113 @@ func TestUpdateSuccess(t *testing.T) {
 	recorder := httptest.NewRecorder()
 
 	assert.NoError(t, err)
-	productHandler := NewProductHandler(productService)
+	productHandler := NewProductHandler(productService, testLocales)
 	updateHandle := productHandler.handleUpdateProduct()
 
 	updateHandle(recorder, request)
 	assert.Equal(t, recorder.Code, 200)
 }
 
+func TestUpdateInvalidRegions(t *testing.T) {
+	testCases := map[string]string{
+		"unknown-region":          `{"regions": ["uk", "mars"]}`,
+		"upper-case-region":       `{"regions": ["UK"]}`,
+		"unknown-override-region": `{"region_overrides": {"mars": {"allergy_info": "milk"}}}`,
+	}
+
+	for name, body := range testCases {
+		t.Run(name, func(t *testing.T) {
+			productService := new(mocks.ProductService)
+
+			request, err := http.NewRequest("PUT", "/api/products/646", strings.NewReader(body))
+			recorder := httptest.NewRecorder()
+
+			assert.NoError(t, err)
+			productHandler := NewProductHandler(productService, testLocales)
+			updateHandle := productHandler.handleUpdateProduct()
+
+			updateHandle(recorder, request)
+			assert.Equal(t, 400, recorder.Code)
+			productService.AssertNotCalled(t, "UpdateProduct", contextType, productIDType, productType)
+		})
+	}
+}
+
+func TestUpdateInvalid(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	// fields are validated as on create,
+	// so that names must be ASCII
+	updateReq := createMockUpdateRequest()
+	updateReq.Name = "Crème Brûlée"
+	productbyte, err := json.Marshal(updateReq)
+	assert.NoError(t, err)
+
+	request, err := http.NewRequest("PUT", "/api/products/646", strings.NewReader(string(productbyte)))
+	recorder := httptest.NewRecorder()
+
+	assert.NoError(t, err)
+	productHandler := NewProductHandler(productService, testLocales)
+	updateHandle := productHandler.handleUpdateProduct()
+
+	updateHandle(recorder, request)
+	assert.Equal(t, 400, recorder.Code)
+	productService.AssertNotCalled(t, "UpdateProduct", contextType, productIDType, productType)
+}
+
+func TestUpdateConflict(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	productService.On("UpdateProduct", contextType, productIDType, productType).
+		Return(domain.ErrDuplicateGTIN).
+		Once()
+
+	updateReq := createMockUpdateRequest()
+	productbyte, err := json.Marshal(updateReq)
+	assert.NoError(t, err)
+
+	request, err := http.NewRequest("PUT", "/api/products/646", strings.NewReader(string(productbyte)))
+	recorder := httptest.NewRecorder()
+
+	assert.NoError(t, err)
+	productHandler := NewProductHandler(productService, testLocales)
+	updateHandle := productHandler.handleUpdateProduct()
+
+	// conflicts are 409 on every endpoint, not only on create
+	updateHandle(recorder, request)
+	assert.Equal(t, 409, recorder.Code)
+
+	var msgErr messageError
+	json.NewDecoder(recorder.Body).Decode(&msgErr)
+	assert.Equal(t, domain.ErrDuplicateGTIN.Error(), msgErr.Message)
+}
+
+func TestUpdateIfMatch(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	var updated domain.Product
+	productService.On("UpdateProduct", contextType, productIDType, productType).
+		Run(func(args mock.Arguments) { updated = args.Get(2).(domain.Product) }).
+		Return(domain.ErrPreconditionFailed).
+		Once()
+
+	updateReq := createMockUpdateRequest()
+	productbyte, err := json.Marshal(updateReq)
+	assert.NoError(t, err)
+
+	request, err := http.NewRequest("PUT", "/api/products/646", strings.NewReader(string(productbyte)))
+	request.Header.Set("If-Match", `"3"`)
+	recorder := httptest.NewRecorder()
+
+	assert.NoError(t, err)
+	productHandler := NewProductHandler(productService, testLocales)
+	updateHandle := productHandler.handleUpdateProduct()
+
+	updateHandle(recorder, request)
+	assert.Equal(t, 412, recorder.Code)
+	assert.Equal(t, int64(3), updated.Version)
+}
+
 func TestUpdateFail(t *testing.T) {
 	productService := new(mocks.ProductService)
 
@@ -156,7 +552,7 @@ func TestUpdateFail(t *testing.T) {
 	recorder := httptest.NewRecorder()
 
 	assert.NoError(t, err)
-	productHandler := NewProductHandler(productService)
+	productHandler := NewProductHandler(productService, testLocales)
 	updateHandle := productHandler.handleUpdateProduct()
 
 	updateHandle(recorder, request)
@@ -167,10 +563,176 @@ func TestUpdateFail(t *testing.T) {
 	assert.Equal(t, msgErr.Message, domain.ErrResourceNotFound.Error())
 }
 
+func TestPatchProductMergePatch(t *testing.T) {
+	productService := new(mocks.ProductService)
+	mockProduct := createMockProduct()
+
+	productService.On("GetProduct", contextType, mockProduct.ProductID).
+		Return(mockProduct, nil).
+		Once()
+
+	var replaced domain.Product
+	productService.On("ReplaceProduct", contextType, mockProduct.ProductID, productType).
+		Run(func(args mock.Arguments) { replaced = args.Get(2).(domain.Product) }).
+		Return(nil).
+		Once()
+
+	patch := `{"story": null, "ingredients": [], "image_open": "", "name": "Vanilla What Bar Crunch"}`
+	request, _ := http.NewRequest("PATCH", "/api/products/646", strings.NewReader(patch))
+	request.Header.Set("Content-Type", "application/merge-patch+json")
+	request = mux.SetURLVars(request, map[string]string{"product_id": mockProduct.ProductID})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	patchHandle := productHandler.handlePatchProduct()
+
+	patchHandle(recorder, request)
+	assert.Equal(t, recorder.Code, 200)
+	assert.Equal(t, "Vanilla What Bar Crunch", replaced.Name)
+	assert.Equal(t, "", replaced.Story)
+	assert.Equal(t, "", replaced.ImageOpenURL)
+	assert.Equal(t, &[]string{}, replaced.Ingredients)
+	assert.Equal(t, mockProduct.SourcingValues, replaced.SourcingValues)
+	assert.Equal(t, mockProduct.Description, replaced.Description)
+}
+
+func TestPatchProductJSONPatch(t *testing.T) {
+	productService := new(mocks.ProductService)
+	mockProduct := createMockProduct()
+
+	productService.On("GetProduct", contextType, mockProduct.ProductID).
+		Return(mockProduct, nil).
+		Once()
+
+	var replaced domain.Product
+	productService.On("ReplaceProduct", contextType, mockProduct.ProductID, productType).
+		Run(func(args mock.Arguments) { replaced = args.Get(2).(domain.Product) }).
+		Return(nil).
+		Once()
+
+	patch := `[
+		{"op": "test", "path": "/ingredients/2", "value": "liquid sugar"},
+		{"op": "remove", "path": "/ingredients/2"},
+		{"op": "add", "path": "/ingredients/-", "value": "toffee"}
+	]`
+	request, _ := http.NewRequest("PATCH", "/api/products/646", strings.NewReader(patch))
+	request.Header.Set("Content-Type", "application/json-patch+json")
+	request = mux.SetURLVars(request, map[string]string{"product_id": mockProduct.ProductID})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	patchHandle := productHandler.handlePatchProduct()
+
+	patchHandle(recorder, request)
+	assert.Equal(t, recorder.Code, 200)
+	assert.Equal(t, &[]string{"cream", "skim milk", "water", "sugar", "toffee"}, replaced.Ingredients)
+}
+
+func TestPatchProductIfMatch(t *testing.T) {
+	productService := new(mocks.ProductService)
+	mockProduct := createMockProduct()
+
+	productService.On("GetProduct", contextType, mockProduct.ProductID).
+		Return(mockProduct, nil).
+		Once()
+
+	request, _ := http.NewRequest("PATCH", "/api/products/646", strings.NewReader(`{"name": "Chunky Monkey"}`))
+	request.Header.Set("Content-Type", mergePatchMediaType)
+	request.Header.Set("If-Match", `"3"`)
+	request = mux.SetURLVars(request, map[string]string{"product_id": mockProduct.ProductID})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	patchHandle := productHandler.handlePatchProduct()
+
+	patchHandle(recorder, request)
+	assert.Equal(t, 412, recorder.Code)
+	productService.AssertNotCalled(t, "ReplaceProduct", contextType, productIDType, productType)
+}
+
+func TestPatchProductRetry(t *testing.T) {
+	productService := new(mocks.ProductService)
+	mockProduct := createMockProduct()
+
+	productService.On("GetProduct", contextType, mockProduct.ProductID).
+		Return(mockProduct, nil).
+		Twice()
+
+	productService.On("ReplaceProduct", contextType, mockProduct.ProductID, productType).
+		Return(domain.ErrPreconditionFailed).
+		Once()
+
+	var replaced domain.Product
+	productService.On("ReplaceProduct", contextType, mockProduct.ProductID, productType).
+		Run(func(args mock.Arguments) { replaced = args.Get(2).(domain.Product) }).
+		Return(nil).
+		Once()
+
+	request, _ := http.NewRequest("PATCH", "/api/products/646", strings.NewReader(`{"name": "Chunky Monkey"}`))
+	request.Header.Set("Content-Type", mergePatchMediaType)
+	request = mux.SetURLVars(request, map[string]string{"product_id": mockProduct.ProductID})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	patchHandle := productHandler.handlePatchProduct()
+
+	patchHandle(recorder, request)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, mockProduct.Version, replaced.Version)
+	productService.AssertExpectations(t)
+}
+
+func TestPatchProductInvalid(t *testing.T) {
+	type patchCase struct {
+		name        string
+		contentType string
+		patch       string
+		status      int
+	}
+	testCases := []patchCase{
+		{"name-too-long", mergePatchMediaType, `{"name": "` + strings.Repeat("a", 51) + `"}`, 400},
+		{"change-product-id", mergePatchMediaType, `{"productId": "999"}`, 400},
+		{"test-failed", jsonPatchMediaType, `[{"op": "test", "path": "/name", "value": "Chunky Monkey"}]`, 409},
+		{"path-not-found", jsonPatchMediaType, `[{"op": "remove", "path": "/ingredients/99"}]`, 400},
+		{"unsupported-media-type", "text/plain", `name=Chunky Monkey`, 415},
+	}
+
+	// patched products replace products, so
+	// fields create requires cannot be cleared
+	for _, field := range []string{"name", "description", "allergy_info", "dietary_certifications"} {
+		testCases = append(testCases,
+			patchCase{"null-" + field, mergePatchMediaType, `{"` + field + `": null}`, 400},
+			patchCase{"remove-" + field, jsonPatchMediaType, `[{"op": "remove", "path": "/` + field + `"}]`, 400},
+		)
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			productService := new(mocks.ProductService)
+			mockProduct := createMockProduct()
+
+			productService.On("GetProduct", contextType, mockProduct.ProductID).
+				Return(mockProduct, nil)
+
+			request, _ := http.NewRequest("PATCH", "/api/products/646", strings.NewReader(testCase.patch))
+			request.Header.Set("Content-Type", testCase.contentType)
+			request = mux.SetURLVars(request, map[string]string{"product_id": mockProduct.ProductID})
+			recorder := httptest.NewRecorder()
+
+			productHandler := NewProductHandler(productService, testLocales)
+			patchHandle := productHandler.handlePatchProduct()
+
+			patchHandle(recorder, request)
+			assert.Equal(t, testCase.status, recorder.Code)
+			productService.AssertNotCalled(t, "ReplaceProduct", contextType, productIDType, productType)
+		})
+	}
+}
+
 func TestDeleteSuccess(t *testing.T) {
 	productService := new(mocks.ProductService)
 
-	productService.On("DeleteProduct", contextType, productIDType).
+	productService.On("DeleteProduct", contextType, productIDType, int64(0)).
 		Return(nil).
 		Once()
 
@@ -182,16 +744,50 @@ func TestDeleteSuccess(t *testing.T) {
 	recorder := httptest.NewRecorder()
 
 	assert.NoError(t, err)
-	productHandler := NewProductHandler(productService)
+	productHandler := NewProductHandler(productService, testLocales)
 	deleteHandle := productHandler.handleDeleteProduct()
 
 	deleteHandle(recorder, request)
 	assert.Equal(t, recorder.Code, 200)
 }
 
+func TestDeleteIfMatch(t *testing.T) {
+	testCases := []struct {
+		name    string
+		ifMatch string
+		err     error
+		status  int
+	}{
+		{"matching-version", `"3"`, nil, 200},
+		{"stale-version", `"3"`, domain.ErrPreconditionFailed, 412},
+		{"weak-etag", `W/"3"`, nil, 412},
+		{"malformed-etag", `"three"`, nil, 412},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			productService := new(mocks.ProductService)
+			productService.On("DeleteProduct", contextType, "646", int64(3)).
+				Return(testCase.err)
+
+			request, _ := http.NewRequest("DELETE", "/api/products/646", nil)
+			request.Header.Set("If-Match", testCase.ifMatch)
+			request = mux.SetURLVars(request, map[string]string{"product_id": "646"})
+			recorder := httptest.NewRecorder()
+
+			productHandler := NewProductHandler(productService, testLocales)
+			deleteHandle := productHandler.handleDeleteProduct()
+
+			deleteHandle(recorder, request)
+			assert.Equal(t, testCase.status, recorder.Code)
+		})
+	}
+}
+
 func createMockProduct() domain.Product {
 	mockProductSuccess := domain.Product{
 		ProductID:      "646",
+		Version:        4,
 		Name:           "Vanilla Toffee Bar Crunch",
 		ImageClosedURL: "/files/vanilla-toffee-landing.png",
 		ImageOpenURL:   "/files/vanilla-toffee-landing-open.png",
@@ -256,3 +852,928 @@ func createMockUpdateRequest() productUpdateRequest {
 		Ingredients: &[]string{},
 	}
 }
+
+func TestFetchRevisionsSuccess(t *testing.T) {
+	productService := new(mocks.ProductService)
+	mockRevisions := []domain.ProductRevision{
+		{
+			ProductID: "646",
+			Revision:  2,
+			Action:    domain.RevisionUpdate,
+			Author:    "jerry",
+			Timestamp: time.Unix(1593561600, 0),
+			Changes: []domain.FieldChange{
+				{Field: "Ingredients", From: []string{"cream"}, To: []string{"cream", "sugar"}},
+			},
+		},
+	}
+
+	productService.On("FetchRevisions", contextType, "646").
+		Return(mockRevisions, nil).
+		Once()
+
+	request, _ := http.NewRequest("GET", "/api/products/646/revisions", nil)
+	request = mux.SetURLVars(request, map[string]string{"product_id": "646"})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	fetchHandle := productHandler.handleFetchRevisions()
+
+	fetchHandle(recorder, request)
+	assert.Equal(t, 200, recorder.Code)
+	assert.JSONEq(t, `{"revisions": [{
+		"revision": 2,
+		"action": "update",
+		"author": "jerry",
+		"timestamp": 1593561600,
+		"changes": [{"field": "ingredients", "from": ["cream"], "to": ["cream", "sugar"]}]
+	}]}`, recorder.Body.String())
+}
+
+func TestGetRevisionBadRevision(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	request, _ := http.NewRequest("GET", "/api/products/646/revisions/latest", nil)
+	request = mux.SetURLVars(request, map[string]string{"product_id": "646", "revision": "latest"})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	getHandle := productHandler.handleGetRevision()
+
+	getHandle(recorder, request)
+	assert.Equal(t, 400, recorder.Code)
+}
+
+func TestRestoreRevisionSuccess(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	productService.On("RestoreRevision", contextType, "646", int64(1), int64(4)).
+		Return(nil).
+		Once()
+
+	request, _ := http.NewRequest("POST", "/api/products/646/revisions/1/restore", nil)
+	request.Header.Set("If-Match", `"4"`)
+	request = mux.SetURLVars(request, map[string]string{"product_id": "646", "revision": "1"})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	restoreHandle := productHandler.handleRestoreRevision()
+
+	restoreHandle(recorder, request)
+	assert.Equal(t, 200, recorder.Code)
+	productService.AssertExpectations(t)
+}
+
+func TestFetchTrashSuccess(t *testing.T) {
+	productService := new(mocks.ProductService)
+	mockProduct := createMockProduct()
+
+	productService.On("FetchTrash", contextType).
+		Return([]domain.TrashedProduct{
+			{Product: mockProduct, DeletedAt: time.Unix(1593561600, 0), DeletedBy: "jerry"},
+		}, nil).
+		Once()
+
+	request, _ := http.NewRequest("GET", "/api/products/trash", nil)
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	fetchHandle := productHandler.handleFetchTrash()
+
+	var trashResponse trashListResponse
+
+	fetchHandle(recorder, request)
+	err := json.NewDecoder(recorder.Body).Decode(&trashResponse)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Len(t, trashResponse.Data, 1)
+	assert.Equal(t, newResponseData(mockProduct), trashResponse.Data[0].Product)
+	assert.Equal(t, int64(1593561600), trashResponse.Data[0].DeletedAt)
+	assert.Equal(t, "jerry", trashResponse.Data[0].DeletedBy)
+}
+
+func TestPurgeProductNotInTrash(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	productService.On("PurgeProduct", contextType, "646").
+		Return(domain.ErrResourceNotFound).
+		Once()
+
+	request, _ := http.NewRequest("DELETE", "/api/products/trash/646", nil)
+	request = mux.SetURLVars(request, map[string]string{"product_id": "646"})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	purgeHandle := productHandler.handlePurgeProduct()
+
+	purgeHandle(recorder, request)
+	assert.Equal(t, 404, recorder.Code)
+}
+
+func TestTrashAndRevisionsReadOnly(t *testing.T) {
+	productRepo := new(mocks.ProductRepository)
+	revisionRepo := new(mocks.ProductRevisionRepository)
+
+	draft := createMockProduct()
+	draft.Status = domain.StatusDraft
+	published := createMockProduct()
+	published.ProductID = "647"
+	published.Status = domain.StatusPublished
+
+	productRepo.On("FetchTrash", contextType).
+		Return([]domain.TrashedProduct{{Product: draft}, {Product: published}}, nil)
+	revisionRepo.On("Fetch", contextType, "646").
+		Return([]domain.ProductRevision{{ProductID: "646", Revision: 1, Product: draft}}, nil)
+	revisionRepo.On("Get", contextType, "646", int64(1)).
+		Return(domain.ProductRevision{ProductID: "646", Revision: 1, Product: draft}, nil)
+
+	// routes are served to a user who may only read
+	reader := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := auth.NewContext(r.Context(), auth.Authentication{
+				ID:             "ben",
+				Authorizations: []auth.Authorization{{AppName: "TestApp", Role: role.ReadPermission}},
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+
+	router := mux.NewRouter()
+	productRouter := router.PathPrefix("/api/products").Subrouter()
+	productService := service.NewProductService("TestApp", productRepo, revisionRepo, nil, nil)
+	NewProductHandler(productService, testLocales).
+		Routes(productRouter, alice.New(reader, middleware.RoleMiddleWare("TestApp")))
+
+	t.Run("FetchTrash-published-only", func(t *testing.T) {
+		request, _ := http.NewRequest("GET", "/api/products/trash", nil)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		var trashResponse trashListResponse
+		err := json.NewDecoder(recorder.Body).Decode(&trashResponse)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 200, recorder.Code)
+		assert.Len(t, trashResponse.Data, 1)
+		assert.Equal(t, "647", trashResponse.Data[0].Product.ProductID)
+	})
+
+	t.Run("FetchRevisions-draft-not-found", func(t *testing.T) {
+		request, _ := http.NewRequest("GET", "/api/products/646/revisions", nil)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		assert.Equal(t, 404, recorder.Code)
+	})
+
+	t.Run("GetRevision-draft-not-found", func(t *testing.T) {
+		request, _ := http.NewRequest("GET", "/api/products/646/revisions/1", nil)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		assert.Equal(t, 404, recorder.Code)
+		assert.NotContains(t, recorder.Body.String(), draft.Name)
+	})
+}
+
+func TestFetchRetiredProductsSuccess(t *testing.T) {
+	productService := new(mocks.ProductService)
+	mockProduct := createMockProduct()
+	retiredAt := time.Unix(1593561600, 0)
+	mockProduct.Status = domain.StatusRetired
+	mockProduct.RetiredAt = &retiredAt
+	mockProduct.Epitaph = "Gone but not forgotten"
+
+	expectedQuery := domain.ProductQuery{
+		Limit:  10,
+		Cursor: "next",
+		Filter: domain.ProductFilter{Region: "uk"},
+	}
+
+	productService.On("FetchRetiredProducts", contextType, expectedQuery).
+		Return(domain.ProductPage{Products: []domain.Product{mockProduct}, NextCursor: "after", TotalCount: 11}, nil).
+		Once()
+
+	request, _ := http.NewRequest("GET", "/api/products/graveyard?limit=10&cursor=next&region=UK", nil)
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	fetchHandle := productHandler.handleFetchRetiredProducts()
+
+	var listResponse productListResponse
+
+	fetchHandle(recorder, request)
+	err := json.NewDecoder(recorder.Body).Decode(&listResponse)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, []productResponseData{newResponseData(mockProduct)}, listResponse.Data)
+	assert.Equal(t, int64(1593561600), *listResponse.Data[0].RetiredAt)
+	assert.Equal(t, "after", listResponse.NextCursor)
+	assert.Equal(t, int64(11), listResponse.TotalCount)
+	productService.AssertExpectations(t)
+}
+
+func TestChangeProductStatusSuccess(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	change := domain.StatusChange{Status: domain.StatusRetired, At: time.Unix(1593561600, 0).UTC(), Epitaph: "RIP"}
+	productService.On("ChangeProductStatus", contextType, "646", change, int64(4)).
+		Return(nil).
+		Once()
+
+	body := strings.NewReader(`{"status": "retired", "at": 1593561600, "epitaph": "RIP"}`)
+	request, _ := http.NewRequest("POST", "/api/products/646/status", body)
+	request.Header.Set("If-Match", `"4"`)
+	request = mux.SetURLVars(request, map[string]string{"product_id": "646"})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	statusHandle := productHandler.handleChangeProductStatus()
+
+	statusHandle(recorder, request)
+	assert.Equal(t, 200, recorder.Code)
+	productService.AssertExpectations(t)
+}
+
+func TestChangeProductStatusInvalid(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	productService.On("ChangeProductStatus", contextType, "646", domain.StatusChange{Status: domain.StatusDraft}, int64(0)).
+		Return(domain.ErrInvalidTransition).
+		Once()
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"unknown-status", `{"status": "archived"}`, 400},
+		{"invalid-transition", `{"status": "draft"}`, 409},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request, _ := http.NewRequest("POST", "/api/products/646/status", strings.NewReader(test.body))
+			request = mux.SetURLVars(request, map[string]string{"product_id": "646"})
+			recorder := httptest.NewRecorder()
+
+			productHandler := NewProductHandler(productService, testLocales)
+			statusHandle := productHandler.handleChangeProductStatus()
+
+			statusHandle(recorder, request)
+			assert.Equal(t, test.code, recorder.Code)
+		})
+	}
+}
+
+func TestSetTranslationSuccess(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	translation := domain.ProductTranslation{Name: "Vanille Caramel Croquant", Story: "Une histoire"}
+	productService.On("SetTranslation", contextType, "646", "fr-CA", translation, int64(4)).
+		Return(nil).
+		Once()
+
+	body := strings.NewReader(`{"name": "Vanille Caramel Croquant", "story": "Une histoire"}`)
+	request, _ := http.NewRequest("PUT", "/api/products/646/translations/fr-ca", body)
+	request.Header.Set("If-Match", `"4"`)
+	request = mux.SetURLVars(request, map[string]string{"product_id": "646", "locale": "fr-ca"})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	setHandle := productHandler.handleSetTranslation()
+
+	setHandle(recorder, request)
+	assert.Equal(t, 200, recorder.Code)
+	productService.AssertExpectations(t)
+}
+
+func TestSetTranslationBadLocale(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	for _, tag := range []string{"en", "fr_FR"} {
+		t.Run(tag, func(t *testing.T) {
+			body := strings.NewReader(`{"name": "Vanilla Toffee Bar Crunch"}`)
+			request, _ := http.NewRequest("PUT", "/api/products/646/translations/"+tag, body)
+			request = mux.SetURLVars(request, map[string]string{"product_id": "646", "locale": tag})
+			recorder := httptest.NewRecorder()
+
+			productHandler := NewProductHandler(productService, testLocales)
+			setHandle := productHandler.handleSetTranslation()
+
+			setHandle(recorder, request)
+			assert.Equal(t, 400, recorder.Code)
+		})
+	}
+}
+
+func TestCreateVariantSuccess(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	variant := domain.ProductVariant{SKU: "BJ-646-P", Format: domain.FormatPint, Size: "465ml"}
+	productService.On("AddVariant", contextType, "646", variant, int64(0)).
+		Return(nil).
+		Once()
+
+	body := strings.NewReader(`{"sku": "BJ-646-P", "format": "pint", "size": "465ml"}`)
+	request, _ := http.NewRequest("POST", "/api/products/646/variants", body)
+	request = mux.SetURLVars(request, map[string]string{"product_id": "646"})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	createHandle := productHandler.handleCreateVariant()
+
+	createHandle(recorder, request)
+	assert.Equal(t, 201, recorder.Code)
+	productService.AssertExpectations(t)
+}
+
+func TestCreateVariantDuplicateSKU(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	productService.On("AddVariant", contextType, "646", mock.AnythingOfType("domain.ProductVariant"), int64(0)).
+		Return(domain.ErrDuplicateSKU).
+		Once()
+
+	body := strings.NewReader(`{"sku": "BJ-646-P", "format": "pint", "size": "465ml"}`)
+	request, _ := http.NewRequest("POST", "/api/products/646/variants", body)
+	request = mux.SetURLVars(request, map[string]string{"product_id": "646"})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	createHandle := productHandler.handleCreateVariant()
+
+	createHandle(recorder, request)
+	assert.Equal(t, 409, recorder.Code)
+	productService.AssertExpectations(t)
+}
+
+func TestCreateVariantInvalid(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	body := strings.NewReader(`{"sku": "BJ-646-T", "format": "tub", "size": "2l"}`)
+	request, _ := http.NewRequest("POST", "/api/products/646/variants", body)
+	request = mux.SetURLVars(request, map[string]string{"product_id": "646"})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	createHandle := productHandler.handleCreateVariant()
+
+	createHandle(recorder, request)
+	assert.Equal(t, 400, recorder.Code)
+	productService.AssertNotCalled(t, "AddVariant")
+}
+
+func TestGetProductEmbedVariants(t *testing.T) {
+	productService := new(mocks.ProductService)
+	mockProduct := createMockProduct()
+	mockProduct.Variants = []domain.ProductVariant{
+		{SKU: "BJ-646-P", Format: domain.FormatPint, Size: "465ml"},
+	}
+
+	productService.On("GetProduct", contextType, "646").
+		Return(mockProduct, nil)
+
+	tests := []struct {
+		name     string
+		url      string
+		variants []variantData
+	}{
+		{"not-embedded", "/api/products/646", nil},
+		{"embedded", "/api/products/646?embed=translations,variants", newVariantsData(mockProduct.Variants)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request, _ := http.NewRequest("GET", test.url, nil)
+			request = mux.SetURLVars(request, map[string]string{"product_id": "646"})
+			recorder := httptest.NewRecorder()
+
+			productHandler := NewProductHandler(productService, testLocales)
+			getHandle := productHandler.handleGetProduct()
+
+			var productResponse productSingleResponse
+
+			getHandle(recorder, request)
+			err := json.NewDecoder(recorder.Body).Decode(&productResponse)
+
+			assert.NoError(t, err)
+			assert.Equal(t, 200, recorder.Code)
+			assert.Equal(t, test.variants, productResponse.Data.Variants)
+		})
+	}
+}
+
+func TestGetProductExpandIngredients(t *testing.T) {
+	productService := new(mocks.ProductService)
+	mockProduct := createMockProduct()
+	mockProduct.Ingredients = &[]string{"cream", "Cocoa (processed with alkali)"}
+
+	// stored before ingredients were parsed on write
+	unparsed := mockProduct
+	mockProduct.ParsedIngredients = []domain.Ingredient{
+		{Name: "cream"},
+		{Name: "cocoa", Qualifiers: []string{"processed with alkali"}},
+	}
+
+	productService.On("GetProduct", contextType, "646").
+		Return(mockProduct, nil).
+		Twice()
+	productService.On("GetProduct", contextType, "647").
+		Return(unparsed, nil).
+		Once()
+
+	expanded := []ingredientData{
+		{Name: "cream"},
+		{Name: "cocoa", Qualifiers: []string{"processed with alkali"}},
+	}
+
+	tests := []struct {
+		name        string
+		productID   string
+		url         string
+		ingredients []ingredientData
+	}{
+		{"not-expanded", "646", "/api/products/646?embed=ingredients", nil},
+		{"expanded", "646", "/api/products/646?expand=ingredients", expanded},
+		{"expanded-unparsed", "647", "/api/products/647?expand=ingredients", expanded},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request, _ := http.NewRequest("GET", test.url, nil)
+			request = mux.SetURLVars(request, map[string]string{"product_id": test.productID})
+			recorder := httptest.NewRecorder()
+
+			productHandler := NewProductHandler(productService, testLocales)
+			getHandle := productHandler.handleGetProduct()
+
+			var productResponse productSingleResponse
+
+			getHandle(recorder, request)
+			err := json.NewDecoder(recorder.Body).Decode(&productResponse)
+
+			assert.NoError(t, err)
+			assert.Equal(t, 200, recorder.Code)
+			assert.Equal(t, test.ingredients, productResponse.Data.ParsedIngredients)
+			assert.Equal(t, mockProduct.Ingredients, productResponse.Data.Ingredients)
+		})
+	}
+	productService.AssertExpectations(t)
+}
+
+func TestGetProductBySKUSuccess(t *testing.T) {
+	productService := new(mocks.ProductService)
+	mockProduct := createMockProduct()
+	mockProduct.Variants = []domain.ProductVariant{
+		{SKU: "BJ-646-P", Format: domain.FormatPint, Size: "465ml"},
+		{SKU: "BJ-646-M", Format: domain.FormatMiniCup, Size: "100ml"},
+	}
+
+	productService.On("GetProductBySKU", contextType, "BJ-646-M").
+		Return(mockProduct, nil).
+		Once()
+
+	request, _ := http.NewRequest("GET", "/api/products/by-sku/BJ-646-M", nil)
+	request = mux.SetURLVars(request, map[string]string{"sku": "BJ-646-M"})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	getHandle := productHandler.handleGetProductBySKU()
+
+	var lookupResponse skuLookupResponse
+
+	getHandle(recorder, request)
+	err := json.NewDecoder(recorder.Body).Decode(&lookupResponse)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "646", lookupResponse.Product.ProductID)
+	assert.Empty(t, lookupResponse.Product.Variants)
+	assert.Equal(t, newVariantData(mockProduct.Variants[1]), lookupResponse.Variant)
+	productService.AssertExpectations(t)
+}
+
+func TestGetProductByGTINSuccess(t *testing.T) {
+	productService := new(mocks.ProductService)
+	mockProduct := createMockProduct()
+	mockProduct.GTINs = []string{"00012345678905"}
+
+	productService.On("GetProductByGTIN", contextType, "012345678905").
+		Return(mockProduct, nil).
+		Once()
+
+	request, _ := http.NewRequest("GET", "/api/products/by-barcode/012345678905", nil)
+	request = mux.SetURLVars(request, map[string]string{"gtin": "012345678905"})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	getHandle := productHandler.handleGetProductByGTIN()
+
+	var productResponse productSingleResponse
+
+	getHandle(recorder, request)
+	err := json.NewDecoder(recorder.Body).Decode(&productResponse)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, newSingleResponse(mockProduct), productResponse)
+	assert.Equal(t, []string{"00012345678905"}, productResponse.Data.GTINs)
+	productService.AssertExpectations(t)
+}
+
+func TestGetProductByGTINBadCheckDigit(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	request, _ := http.NewRequest("GET", "/api/products/by-barcode/012345678906", nil)
+	request = mux.SetURLVars(request, map[string]string{"gtin": "012345678906"})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	getHandle := productHandler.handleGetProductByGTIN()
+
+	getHandle(recorder, request)
+	assert.Equal(t, 400, recorder.Code)
+	productService.AssertNotCalled(t, "GetProductByGTIN", contextType, productIDType)
+}
+
+func TestUpdateProductGTINs(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	expected := domain.Product{ProductID: "646", GTINs: []string{"00012345678905"}}
+	productService.On("UpdateProduct", contextType, "646", expected).
+		Return(nil).
+		Once()
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"normalized", `{"gtins": ["012345678905", "0012345678905"]}`, 200},
+		{"bad-check-digit", `{"gtins": ["012345678906"]}`, 400},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request, _ := http.NewRequest("PUT", "/api/products/646", strings.NewReader(test.body))
+			request = mux.SetURLVars(request, map[string]string{"product_id": "646"})
+			recorder := httptest.NewRecorder()
+
+			productHandler := NewProductHandler(productService, testLocales)
+			updateHandle := productHandler.handleUpdateProduct()
+
+			updateHandle(recorder, request)
+			assert.Equal(t, test.code, recorder.Code)
+		})
+	}
+	productService.AssertExpectations(t)
+}
+
+func TestGetAllergensSuccess(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	proposal := domain.AllergenProposal{
+		Declared: domain.Allergens{MayContain: []string{"peanuts"}},
+		Detected: []string{"milk"},
+		Check:    domain.AllergenCheck{Undeclared: []string{"milk"}},
+	}
+	productService.On("ProposeAllergens", contextType, "646").
+		Return(proposal, nil).
+		Once()
+
+	request, _ := http.NewRequest("GET", "/api/products/646/allergens", nil)
+	request = mux.SetURLVars(request, map[string]string{"product_id": "646"})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	getHandle := productHandler.handleGetAllergens()
+
+	getHandle(recorder, request)
+
+	expected := `{"allergens": {
+		"declared": {"contains": [], "may_contain": ["peanuts"]},
+		"detected": ["milk"],
+		"check": {"undeclared": ["milk"], "undetected": []}
+	}}`
+	assert.Equal(t, 200, recorder.Code)
+	assert.JSONEq(t, expected, recorder.Body.String())
+	productService.AssertExpectations(t)
+}
+
+func TestUpdateProductAllergens(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	expected := domain.Product{
+		ProductID: "646",
+		Allergens: domain.Allergens{Contains: []string{"milk", "peanuts"}, MayContain: []string{"wheat"}},
+	}
+	productService.On("UpdateProduct", contextType, "646", expected).
+		Return(nil).
+		Once()
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"normalized", `{"allergens": {"contains": ["peanuts", "milk"], "may_contain": ["wheat", "milk"]}}`, 200},
+		{"unknown-allergen", `{"allergens": {"contains": ["chocolate"]}}`, 400},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request, _ := http.NewRequest("PUT", "/api/products/646", strings.NewReader(test.body))
+			request = mux.SetURLVars(request, map[string]string{"product_id": "646"})
+			recorder := httptest.NewRecorder()
+
+			productHandler := NewProductHandler(productService, testLocales)
+			updateHandle := productHandler.handleUpdateProduct()
+
+			updateHandle(recorder, request)
+			assert.Equal(t, test.code, recorder.Code)
+		})
+	}
+	productService.AssertExpectations(t)
+}
+
+func TestGetNutritionSuccess(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	serving := domain.NutritionServing{
+		Grams:     150,
+		Servings:  1.530612,
+		Nutrients: domain.Nutrients{Calories: 375, TotalFat: 22.5, Sodium: 0.09},
+	}
+	productService.On("ComputeNutrition", contextType, "646", domain.Quantity{Amount: 150, Unit: "g"}, "").
+		Return(serving, nil).
+		Once()
+
+	request, _ := http.NewRequest("GET", "/api/products/646/nutrition?serving=150g", nil)
+	request = mux.SetURLVars(request, map[string]string{"product_id": "646"})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	getHandle := productHandler.handleGetNutrition()
+
+	getHandle(recorder, request)
+
+	var response map[string]map[string]interface{}
+	json.Unmarshal(recorder.Body.Bytes(), &response)
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, 150.0, response["nutrition"]["grams"])
+	assert.Equal(t, 1.53, response["nutrition"]["servings"])
+	nutrients := response["nutrition"]["nutrients"].(map[string]interface{})
+	assert.Equal(t, "375 kcal", nutrients["calories"])
+	assert.Equal(t, "22.5 g", nutrients["total_fat"])
+	assert.Equal(t, "90 mg", nutrients["sodium"])
+	productService.AssertExpectations(t)
+}
+
+func TestGetNutritionBadServing(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	request, _ := http.NewRequest("GET", "/api/products/646/nutrition?serving=two+scoops", nil)
+	request = mux.SetURLVars(request, map[string]string{"product_id": "646"})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	getHandle := productHandler.handleGetNutrition()
+
+	getHandle(recorder, request)
+	assert.Equal(t, 400, recorder.Code)
+	productService.AssertNotCalled(t, "ComputeNutrition", contextType, "646", mock.Anything, mock.Anything)
+}
+
+func TestGetNutritionLabelSuccess(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	mockProduct := createMockProduct()
+	mockProduct.Nutrition = domain.NutritionFacts{
+		ServingSize:   98,
+		ServingVolume: 150,
+		ServingLabel:  "2/3 cup",
+		Per100g:       domain.Nutrients{Calories: 250, TotalFat: 15, Protein: 4},
+	}
+	mockProduct.Variants = []domain.ProductVariant{{SKU: "646-PINT", Format: domain.FormatPint, Size: "465ml"}}
+	productService.On("GetProduct", contextType, "646").
+		Return(mockProduct, nil).
+		Once()
+
+	request, _ := http.NewRequest("GET", "/api/products/646/nutrition/label.svg?sku=646-PINT", nil)
+	request = mux.SetURLVars(request, map[string]string{"product_id": "646"})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	getHandle := productHandler.handleGetNutritionLabel()
+
+	getHandle(recorder, request)
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "image/svg+xml", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), ">about 3 servings per container</text>")
+	assert.Contains(t, recorder.Body.String(), ">2/3 cup (98g)</text>")
+	productService.AssertExpectations(t)
+}
+
+func TestGetNutritionLabelWithoutNutrition(t *testing.T) {
+	productService := new(mocks.ProductService)
+	productService.On("GetProduct", contextType, "646").
+		Return(createMockProduct(), nil).
+		Once()
+
+	request, _ := http.NewRequest("GET", "/api/products/646/nutrition/label.svg", nil)
+	request = mux.SetURLVars(request, map[string]string{"product_id": "646"})
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	getHandle := productHandler.handleGetNutritionLabel()
+
+	getHandle(recorder, request)
+
+	assert.Equal(t, 404, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+}
+
+func TestBatchRoutes(t *testing.T) {
+	router := mux.NewRouter()
+	productRouter := router.PathPrefix("/api/products").Subrouter()
+	NewProductHandler(new(mocks.ProductService), testLocales).Routes(productRouter, alice.New())
+
+	// route names map to permissions by their suffix
+	routes := map[string]string{
+		"/api/products:batchGet":    "PRODUCT_BATCH_GET",
+		"/api/products:batchCreate": "PRODUCT_BATCH_CREATE",
+		"/api/products:batchUpdate": "PRODUCT_BATCH_UPDATE",
+		"/api/products:batchDelete": "PRODUCT_BATCH_DELETE",
+	}
+
+	for url, name := range routes {
+		request, _ := http.NewRequest("POST", url, nil)
+
+		var match mux.RouteMatch
+		assert.True(t, router.Match(request, &match), url)
+		assert.Equal(t, name, match.Route.GetName())
+	}
+}
+
+func TestBatchGetProducts(t *testing.T) {
+	productService := new(mocks.ProductService)
+	mockProduct := createMockProduct()
+
+	productService.On("BatchGetProducts", contextType, []string{"646", "978"}).
+		Return([]domain.BatchItemResult{
+			{ProductID: "646", Product: mockProduct},
+			{ProductID: "978", Err: domain.ErrResourceNotFound},
+		}, nil).
+		Once()
+
+	body := `{"productIds": ["646", "978"]}`
+	request, _ := http.NewRequest("POST", "/api/products:batchGet", strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	productHandler.handleBatchGetProducts()(recorder, request)
+
+	var response batchResponse
+	err := json.NewDecoder(recorder.Body).Decode(&response)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Len(t, response.Results, 2)
+	assert.Equal(t, 200, response.Results[0].Status)
+	assert.Equal(t, mockProduct.Name, response.Results[0].Product.Name)
+	assert.Equal(t, batchResultData{ProductID: "978", Status: 404, Error: domain.ErrResourceNotFound.Error()}, response.Results[1])
+}
+
+func TestBatchCreateProducts(t *testing.T) {
+	valid, _ := json.Marshal(createMockCreateRequest())
+	invalid := `{"productId": "647", "name": ""}`
+	body := `{"products": [` + string(valid) + `, ` + invalid + `]}`
+
+	t.Run("BatchCreate-invalid-item-fails-alone", func(t *testing.T) {
+		productService := new(mocks.ProductService)
+		created := createMockProduct()
+
+		productService.On("BatchCreateProducts", contextType, mock.MatchedBy(func(products []domain.Product) bool {
+			return len(products) == 1 && products[0].ProductID == "646"
+		}), false).
+			Return([]domain.BatchItemResult{{ProductID: "646", Product: created}}, nil).
+			Once()
+
+		request, _ := http.NewRequest("POST", "/api/products:batchCreate", strings.NewReader(body))
+		recorder := httptest.NewRecorder()
+
+		productHandler := NewProductHandler(productService, testLocales)
+		productHandler.handleBatchCreateProducts()(recorder, request)
+
+		var response batchResponse
+		json.NewDecoder(recorder.Body).Decode(&response)
+
+		assert.Equal(t, 200, recorder.Code)
+		assert.Equal(t, 201, response.Results[0].Status)
+		assert.Equal(t, "647", response.Results[1].ProductID)
+		assert.Equal(t, 400, response.Results[1].Status)
+		productService.AssertExpectations(t)
+	})
+
+	t.Run("BatchCreate-atomic-aborts-on-invalid-item", func(t *testing.T) {
+		productService := new(mocks.ProductService)
+
+		request, _ := http.NewRequest("POST", "/api/products:batchCreate?atomic=true", strings.NewReader(body))
+		recorder := httptest.NewRecorder()
+
+		productHandler := NewProductHandler(productService, testLocales)
+		productHandler.handleBatchCreateProducts()(recorder, request)
+
+		var response batchResponse
+		json.NewDecoder(recorder.Body).Decode(&response)
+
+		assert.Equal(t, 200, recorder.Code)
+		assert.Equal(t, 424, response.Results[0].Status)
+		assert.Equal(t, 400, response.Results[1].Status)
+		productService.AssertNotCalled(t, "BatchCreateProducts", contextType, mock.Anything, true)
+	})
+
+	t.Run("BatchCreate-atomic-unsupported", func(t *testing.T) {
+		productService := new(mocks.ProductService)
+		productService.On("BatchCreateProducts", contextType, mock.Anything, true).
+			Return(nil, domain.ErrTransactionUnsupported).
+			Once()
+
+		body := `{"products": [` + string(valid) + `]}`
+		request, _ := http.NewRequest("POST", "/api/products:batchCreate?atomic=true", strings.NewReader(body))
+		recorder := httptest.NewRecorder()
+
+		productHandler := NewProductHandler(productService, testLocales)
+		productHandler.handleBatchCreateProducts()(recorder, request)
+
+		assert.Equal(t, 501, recorder.Code)
+	})
+
+	t.Run("BatchCreate-too-many-items", func(t *testing.T) {
+		productService := new(mocks.ProductService)
+		items := strings.TrimSuffix(strings.Repeat(string(valid)+",", domain.MaxBatchSize+1), ",")
+
+		request, _ := http.NewRequest("POST", "/api/products:batchCreate", strings.NewReader(`{"products": [`+items+`]}`))
+		recorder := httptest.NewRecorder()
+
+		productHandler := NewProductHandler(productService, testLocales)
+		productHandler.handleBatchCreateProducts()(recorder, request)
+
+		assert.Equal(t, 400, recorder.Code)
+	})
+}
+
+func TestBatchUpdateProducts(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	expected := []domain.Product{{ProductID: "646", Name: "Chunky Monkey", Version: 3}}
+	productService.On("BatchUpdateProducts", contextType, expected, true).
+		Return([]domain.BatchItemResult{{ProductID: "646", Err: domain.ErrPreconditionFailed}}, nil).
+		Once()
+
+	body := `{"products": [{"productId": "646", "version": 3, "name": "Chunky Monkey"}]}`
+	request, _ := http.NewRequest("POST", "/api/products:batchUpdate?atomic=true", strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	productHandler.handleBatchUpdateProducts()(recorder, request)
+
+	var response batchResponse
+	json.NewDecoder(recorder.Body).Decode(&response)
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, 412, response.Results[0].Status)
+	productService.AssertExpectations(t)
+}
+
+func TestBatchDeleteProducts(t *testing.T) {
+	productService := new(mocks.ProductService)
+
+	keys := []domain.ProductKey{{ProductID: "646"}, {ProductID: "647", Version: 2}}
+	productService.On("BatchDeleteProducts", contextType, keys, false).
+		Return([]domain.BatchItemResult{{ProductID: "646"}, {ProductID: "647"}}, nil).
+		Once()
+
+	body := `{"products": [{"productId": "646"}, {"productId": "647", "version": 2}, {"version": 1}]}`
+	request, _ := http.NewRequest("POST", "/api/products:batchDelete", strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+
+	productHandler := NewProductHandler(productService, testLocales)
+	productHandler.handleBatchDeleteProducts()(recorder, request)
+
+	var response batchResponse
+	json.NewDecoder(recorder.Body).Decode(&response)
+
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, []int{200, 200, 400}, []int{
+		response.Results[0].Status,
+		response.Results[1].Status,
+		response.Results[2].Status,
+	})
+	productService.AssertExpectations(t)
+}
@@ -0,0 +1,237 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// revisionFieldNames maps product fields to their
+// names in request and response body of product API
+var revisionFieldNames = map[string]string{
+	"Name":                 "name",
+	"Slug":                 "slug",
+	"ImageClosedURL":       "image_closed",
+	"ImageOpenURL":         "image_open",
+	"Description":          "description",
+	"Story":                "story",
+	"SourcingValues":       "sourcing_values",
+	"Ingredients":          "ingredients",
+	"AllergyInfo":          "allergy_info",
+	"DietaryCertification": "dietary_certifications",
+	"Regions":              "regions",
+	"RegionOverrides":      "region_overrides",
+	"Status":               "status",
+	"RetiredAt":            "retired_at",
+	"Epitaph":              "epitaph",
+	"ScheduledStatus":      "scheduled_status",
+	"ScheduledAt":          "scheduled_at",
+	"Translations":         "translations",
+	"GTINs":                "gtins",
+	"Allergens":            "allergens",
+	"Contains":             "contains",
+	"MayContain":           "may_contain",
+	"Nutrition":            "nutrition",
+	"ServingSize":          "serving_size",
+	"ServingVolume":        "serving_volume",
+	"ServingLabel":         "serving_label",
+	"Per100g":              "per_100g",
+	"Calories":             "calories",
+	"TotalFat":             "total_fat",
+	"SaturatedFat":         "saturated_fat",
+	"TransFat":             "trans_fat",
+	"Cholesterol":          "cholesterol",
+	"Sodium":               "sodium",
+	"TotalCarbohydrate":    "total_carbohydrate",
+	"DietaryFiber":         "dietary_fiber",
+	"TotalSugars":          "total_sugars",
+	"AddedSugars":          "added_sugars",
+	"Protein":              "protein",
+	"VitaminD":             "vitamin_d",
+	"Calcium":              "calcium",
+	"Iron":                 "iron",
+	"Potassium":            "potassium",
+	"Variants":             "variants",
+	"SKU":                  "sku",
+	"Format":               "format",
+	"Size":                 "size",
+}
+
+// revisionListResponse ...
+type revisionListResponse struct {
+	Data []revisionResponseData `json:"revisions"`
+}
+
+// revisionSingleResponse ...
+type revisionSingleResponse struct {
+	Data revisionResponseData `json:"revision"`
+}
+
+type revisionResponseData struct {
+	Revision     int64                `json:"revision"`
+	Action       string               `json:"action"`
+	Author       string               `json:"author"`
+	Timestamp    int64                `json:"timestamp"`
+	Changes      []fieldChangeData    `json:"changes"`
+	RestoredFrom int64                `json:"restored_from,omitempty"`
+	Product      *productResponseData `json:"product,omitempty"`
+}
+
+type fieldChangeData struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// revisionFieldName names changed field as in product API. Translated
+// fields are named after locale, e.g. translations.fr.name
+func revisionFieldName(field string) string {
+	parts := strings.Split(field, ".")
+	for i, part := range parts {
+		if name, ok := revisionFieldNames[part]; ok {
+			parts[i] = name
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func newRevisionData(revision domain.ProductRevision) revisionResponseData {
+	changesData := make([]fieldChangeData, 0, len(revision.Changes))
+	for _, change := range revision.Changes {
+		changesData = append(changesData, fieldChangeData{
+			Field: revisionFieldName(change.Field),
+			From:  changeValueData(change.From),
+			To:    changeValueData(change.To),
+		})
+	}
+
+	return revisionResponseData{
+		Revision:     revision.Revision,
+		Action:       string(revision.Action),
+		Author:       revision.Author,
+		Timestamp:    revision.Timestamp.Unix(),
+		Changes:      changesData,
+		RestoredFrom: revision.RestoredFrom,
+	}
+}
+
+// changeValueData formats times in changed fields as timestamp in seconds
+func changeValueData(value interface{}) interface{} {
+	if t, ok := value.(time.Time); ok {
+		return t.Unix()
+	}
+	return value
+}
+
+func newRevisionListResponse(revisions []domain.ProductRevision) revisionListResponse {
+	revisionsData := make([]revisionResponseData, 0, len(revisions))
+	for _, revision := range revisions {
+		revisionsData = append(revisionsData, newRevisionData(revision))
+	}
+	return revisionListResponse{Data: revisionsData}
+}
+
+func newRevisionSingleResponse(revision domain.ProductRevision) revisionSingleResponse {
+	revisionData := newRevisionData(revision)
+	productData := newResponseData(revision.Product)
+	revisionData.Product = &productData
+
+	return revisionSingleResponse{Data: revisionData}
+}
+
+// parseRevision reads revision number from url path
+func parseRevision(r *http.Request) (int64, error) {
+	revision, err := strconv.ParseInt(mux.Vars(r)["revision"], 10, 64)
+	if err != nil || revision <= 0 {
+		return 0, domain.ErrBadParamInput
+	}
+	return revision, nil
+}
+
+// handleFetchRevisions provides handler func that lists revisions of a product
+// [GET] /api/products/:product_id/revisions
+func (handler *ProductHandler) handleFetchRevisions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		productID := params["product_id"]
+
+		revisions, err := handler.service.FetchRevisions(r.Context(), productID)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		response := newRevisionListResponse(revisions)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// handleGetRevision provides handler func that gets a revision of a product
+// [GET] /api/products/:product_id/revisions/:revision
+func (handler *ProductHandler) handleGetRevision() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		productID := params["product_id"]
+
+		revision, err := parseRevision(r)
+		if err != nil {
+			writeErrorMessage(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		productRevision, err := handler.service.GetRevision(r.Context(), productID, revision)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		response := newRevisionSingleResponse(productRevision)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// handleRestoreRevision provides handler func that rolls a product back to a revision
+// [POST] /api/products/:product_id/revisions/:revision/restore
+func (handler *ProductHandler) handleRestoreRevision() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		productID := params["product_id"]
+
+		revision, err := parseRevision(r)
+		if err != nil {
+			writeErrorMessage(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		version, err := parseIfMatch(r)
+		if err != nil {
+			writeErrorMessage(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+
+		err = handler.service.RestoreRevision(r.Context(), productID, revision, version)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
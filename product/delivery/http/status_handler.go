@@ -0,0 +1,92 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	validatorLib "github.com/iqdf/benjerry-service/common/validator"
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+type statusChangeRequest struct {
+	Status  string `json:"status" validate:"required,oneof=draft published retired"`
+	At      int64  `json:"at" validate:"omitempty,min=0"`
+	Epitaph string `json:"epitaph" validate:"omitempty,max=300"`
+}
+
+func requestToStatusChange(requestData statusChangeRequest) domain.StatusChange {
+	change := domain.StatusChange{
+		Status:  domain.ProductStatus(requestData.Status),
+		Epitaph: requestData.Epitaph,
+	}
+
+	if requestData.At > 0 {
+		change.At = time.Unix(requestData.At, 0).UTC()
+	}
+	return change
+}
+
+// handleFetchRetiredProducts provides handler func that lists a page of retired products
+// [GET] /api/products/graveyard?limit=&sort=&cursor=&sourcing=&sourcing_match=&dietary=&exclude_allergen=&region=&embed=variants&expand=ingredients
+func (handler *ProductHandler) handleFetchRetiredProducts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		query, err := parseProductQuery(r)
+		if err != nil {
+			writeErrorMessage(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		page, err := handler.service.FetchRetiredProducts(r.Context(), query)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		for i := range page.Products {
+			page.Products[i] = withEmbeds(page.Products[i], r)
+		}
+		response := newListResponse(page)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// handleChangeProductStatus provides handler func that publishes or retires a product
+// [POST] /api/products/:product_id/status
+func (handler *ProductHandler) handleChangeProductStatus() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		productID := params["product_id"]
+
+		version, err := parseIfMatch(r)
+		if err != nil {
+			writeErrorMessage(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+
+		var statusChange statusChangeRequest
+		if err := validatorLib.DecodeAndValidateJSON(r.Body, &statusChange); err != nil {
+			verr, _ := err.(*validatorLib.ValidationError)
+			writeErrorMessage(w, verr.Message(), http.StatusBadRequest)
+			return
+		}
+
+		change := requestToStatusChange(statusChange)
+		err = handler.service.ChangeProductStatus(r.Context(), productID, change, version)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
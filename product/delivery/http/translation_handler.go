@@ -0,0 +1,154 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/iqdf/benjerry-service/common/locale"
+	validatorLib "github.com/iqdf/benjerry-service/common/validator"
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// translationListResponse ...
+type translationListResponse struct {
+	Data map[string]translationData `json:"translations"`
+}
+
+// translationData is both request and response body of a translation.
+// Unlike product name, translated name is not limited to ascii
+type translationData struct {
+	Name        string `json:"name,omitempty" validate:"omitempty,max=50"`
+	Description string `json:"description,omitempty" validate:"omitempty,max=100"`
+	Story       string `json:"story,omitempty" validate:"omitempty,max=300"`
+	AllergyInfo string `json:"allergy_info,omitempty" validate:"omitempty,max=50"`
+}
+
+func newTranslationListResponse(translations map[string]domain.ProductTranslation) translationListResponse {
+	data := make(map[string]translationData, len(translations))
+	for tag, translation := range translations {
+		data[tag] = translationData{
+			Name:        translation.Name,
+			Description: translation.Description,
+			Story:       translation.Story,
+			AllergyInfo: translation.AllergyInfo,
+		}
+	}
+	return translationListResponse{Data: data}
+}
+
+func requestToTranslation(requestData translationData) domain.ProductTranslation {
+	return domain.ProductTranslation{
+		Name:        requestData.Name,
+		Description: requestData.Description,
+		Story:       requestData.Story,
+		AllergyInfo: requestData.AllergyInfo,
+	}
+}
+
+// parseTranslationLocale reads canonical locale of translation from
+// path. Text in default locale is the product text itself, which
+// is edited as product rather than as translation
+func (handler *ProductHandler) parseTranslationLocale(r *http.Request) (string, bool) {
+	tag, ok := locale.Canonical(mux.Vars(r)["locale"])
+	if !ok || tag == handler.locales.Default {
+		return "", false
+	}
+	return tag, true
+}
+
+// handleFetchTranslations provides handler func that lists translations of a product
+// [GET] /api/products/:product_id/translations
+func (handler *ProductHandler) handleFetchTranslations() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		productID := params["product_id"]
+
+		product, err := handler.service.GetProduct(r.Context(), productID)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		w.Header().Set("ETag", formatETag(product.Version))
+		response := newTranslationListResponse(product.Translations)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// handleSetTranslation provides handler func that adds or replaces a translation
+// [PUT] /api/products/:product_id/translations/:locale
+func (handler *ProductHandler) handleSetTranslation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		productID := params["product_id"]
+
+		tag, ok := handler.parseTranslationLocale(r)
+		if !ok {
+			writeErrorMessage(w, "locale must be a language tag other than "+handler.locales.Default, http.StatusBadRequest)
+			return
+		}
+
+		version, err := parseIfMatch(r)
+		if err != nil {
+			writeErrorMessage(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+
+		var translationRequest translationData
+		if err := validatorLib.DecodeAndValidateJSON(r.Body, &translationRequest); err != nil {
+			verr, _ := err.(*validatorLib.ValidationError)
+			writeErrorMessage(w, verr.Message(), http.StatusBadRequest)
+			return
+		}
+
+		translation := requestToTranslation(translationRequest)
+		err = handler.service.SetTranslation(r.Context(), productID, tag, translation, version)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// handleDeleteTranslation provides handler func that removes a translation
+// [DELETE] /api/products/:product_id/translations/:locale
+func (handler *ProductHandler) handleDeleteTranslation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		productID := params["product_id"]
+
+		tag, ok := handler.parseTranslationLocale(r)
+		if !ok {
+			writeErrorMessage(w, "locale must be a language tag other than "+handler.locales.Default, http.StatusBadRequest)
+			return
+		}
+
+		version, err := parseIfMatch(r)
+		if err != nil {
+			writeErrorMessage(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+
+		err = handler.service.DeleteTranslation(r.Context(), productID, tag, version)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
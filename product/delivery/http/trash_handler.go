@@ -0,0 +1,92 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// trashListResponse ...
+type trashListResponse struct {
+	Data []trashedProductData `json:"products"`
+}
+
+type trashedProductData struct {
+	Product   productResponseData `json:"product"`
+	DeletedAt int64               `json:"deleted_at"`
+	DeletedBy string              `json:"deleted_by"`
+}
+
+func newTrashListResponse(trash []domain.TrashedProduct) trashListResponse {
+	trashData := make([]trashedProductData, 0, len(trash))
+	for _, trashed := range trash {
+		trashData = append(trashData, trashedProductData{
+			Product:   newResponseData(trashed.Product),
+			DeletedAt: trashed.DeletedAt.Unix(),
+			DeletedBy: trashed.DeletedBy,
+		})
+	}
+	return trashListResponse{Data: trashData}
+}
+
+// handleFetchTrash provides handler func that lists trashed products
+// [GET] /api/products/trash
+func (handler *ProductHandler) handleFetchTrash() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		trash, err := handler.service.FetchTrash(r.Context())
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		response := newTrashListResponse(trash)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// handleRestoreProduct provides handler func that takes a product out of trash
+// [POST] /api/products/:product_id/restore
+func (handler *ProductHandler) handleRestoreProduct() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		productID := params["product_id"]
+
+		err := handler.service.RestoreProduct(r.Context(), productID)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// handlePurgeProduct provides handler func that removes a trashed product for good
+// [DEL] /api/products/trash/:product_id
+func (handler *ProductHandler) handlePurgeProduct() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		productID := params["product_id"]
+
+		err := handler.service.PurgeProduct(r.Context(), productID)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
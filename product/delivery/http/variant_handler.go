@@ -0,0 +1,267 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	validatorLib "github.com/iqdf/benjerry-service/common/validator"
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// variantListResponse ...
+type variantListResponse struct {
+	Data []variantData `json:"variants"`
+}
+
+// variantSingleResponse ...
+type variantSingleResponse struct {
+	Data variantData `json:"variant"`
+}
+
+// skuLookupResponse is product found by SKU of one of its variants
+type skuLookupResponse struct {
+	Product productResponseData `json:"product"`
+	Variant variantData         `json:"variant"`
+}
+
+type variantData struct {
+	SKU            string    `json:"sku"`
+	Format         string    `json:"format"`
+	Size           string    `json:"size"`
+	ImageClosedURL string    `json:"image_closed,omitempty"`
+	ImageOpenURL   string    `json:"image_open,omitempty"`
+	Ingredients    *[]string `json:"ingredients,omitempty"`
+}
+
+// variantUpdateRequest is body of variant, which is identified by SKU in path
+type variantUpdateRequest struct {
+	Format         string    `json:"format" validate:"required,oneof=pint mini_cup scoop_shop non_dairy"`
+	Size           string    `json:"size" validate:"required,max=25"`
+	ImageClosedURL string    `json:"image_closed" validate:"omitempty,uri"`
+	ImageOpenURL   string    `json:"image_open" validate:"omitempty,uri"`
+	Ingredients    *[]string `json:"ingredients" validate:"omitempty"`
+}
+
+type variantCreateRequest struct {
+	SKU string `json:"sku" validate:"required,printascii,max=32"`
+	variantUpdateRequest
+}
+
+func newVariantData(variant domain.ProductVariant) variantData {
+	return variantData{
+		SKU:            variant.SKU,
+		Format:         string(variant.Format),
+		Size:           variant.Size,
+		ImageClosedURL: variant.ImageClosedURL,
+		ImageOpenURL:   variant.ImageOpenURL,
+		Ingredients:    variant.Ingredients,
+	}
+}
+
+func newVariantsData(variants []domain.ProductVariant) []variantData {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	variantsData := make([]variantData, 0, len(variants))
+	for _, variant := range variants {
+		variantsData = append(variantsData, newVariantData(variant))
+	}
+	return variantsData
+}
+
+func requestToVariant(sku string, requestData variantUpdateRequest) domain.ProductVariant {
+	return domain.ProductVariant{
+		SKU:            sku,
+		Format:         domain.VariantFormat(requestData.Format),
+		Size:           requestData.Size,
+		ImageClosedURL: requestData.ImageClosedURL,
+		ImageOpenURL:   requestData.ImageOpenURL,
+		Ingredients:    requestData.Ingredients,
+	}
+}
+
+// embedsVariants tells whether request asks for variants
+// to be embedded in products, e.g. ?embed=variants
+func embedsVariants(r *http.Request) bool {
+	return queryLists(r, "embed", "variants")
+}
+
+// handleFetchVariants provides handler func that lists variants of a product
+// [GET] /api/products/:product_id/variants
+func (handler *ProductHandler) handleFetchVariants() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		productID := params["product_id"]
+
+		product, err := handler.service.GetProduct(r.Context(), productID)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		variantsData := newVariantsData(product.Variants)
+		if variantsData == nil {
+			variantsData = make([]variantData, 0)
+		}
+
+		w.Header().Set("ETag", formatETag(product.Version))
+		json.NewEncoder(w).Encode(variantListResponse{Data: variantsData})
+	}
+}
+
+// handleGetVariant provides handler func that gets a variant of a product
+// [GET] /api/products/:product_id/variants/:sku
+func (handler *ProductHandler) handleGetVariant() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		productID := params["product_id"]
+
+		product, err := handler.service.GetProduct(r.Context(), productID)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		variant, ok := product.Variant(params["sku"])
+		if !ok {
+			writeErrorMessage(w, domain.ErrResourceNotFound.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("ETag", formatETag(product.Version))
+		json.NewEncoder(w).Encode(variantSingleResponse{Data: newVariantData(variant)})
+	}
+}
+
+// handleGetProductBySKU provides handler func that looks product up by SKU of its variant
+// [GET] /api/products/by-sku/:sku?embed=variants&expand=ingredients
+func (handler *ProductHandler) handleGetProductBySKU() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		sku := params["sku"]
+
+		product, err := handler.service.GetProductBySKU(r.Context(), sku)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+
+		variant, _ := product.Variant(sku)
+		product = withEmbeds(product, r)
+
+		w.Header().Set("ETag", formatETag(product.Version))
+		json.NewEncoder(w).Encode(skuLookupResponse{
+			Product: newResponseData(product),
+			Variant: newVariantData(variant),
+		})
+	}
+}
+
+// handleCreateVariant provides handler func that adds a variant to a product
+// [POST] /api/products/:product_id/variants
+func (handler *ProductHandler) handleCreateVariant() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		productID := params["product_id"]
+
+		version, err := parseIfMatch(r)
+		if err != nil {
+			writeErrorMessage(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+
+		var variantCreate variantCreateRequest
+		if err := validatorLib.DecodeAndValidateJSON(r.Body, &variantCreate); err != nil {
+			verr, _ := err.(*validatorLib.ValidationError)
+			writeErrorMessage(w, verr.Message(), http.StatusBadRequest)
+			return
+		}
+
+		variant := requestToVariant(variantCreate.SKU, variantCreate.variantUpdateRequest)
+		err = handler.service.AddVariant(r.Context(), productID, variant, version)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+// handleUpdateVariant provides handler func that replaces a variant of a product
+// [PUT] /api/products/:product_id/variants/:sku
+func (handler *ProductHandler) handleUpdateVariant() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		productID := params["product_id"]
+
+		version, err := parseIfMatch(r)
+		if err != nil {
+			writeErrorMessage(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+
+		var variantUpdate variantUpdateRequest
+		if err := validatorLib.DecodeAndValidateJSON(r.Body, &variantUpdate); err != nil {
+			verr, _ := err.(*validatorLib.ValidationError)
+			writeErrorMessage(w, verr.Message(), http.StatusBadRequest)
+			return
+		}
+
+		variant := requestToVariant(params["sku"], variantUpdate)
+		err = handler.service.UpdateVariant(r.Context(), productID, params["sku"], variant, version)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// handleDeleteVariant provides handler func that removes a variant of a product
+// [DELETE] /api/products/:product_id/variants/:sku
+func (handler *ProductHandler) handleDeleteVariant() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		params := mux.Vars(r)
+		productID := params["product_id"]
+
+		version, err := parseIfMatch(r)
+		if err != nil {
+			writeErrorMessage(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+
+		err = handler.service.DeleteVariant(r.Context(), productID, params["sku"], version)
+
+		if err != nil {
+			status := getResponseStatus(err)
+			writeErrorMessage(w, err.Error(), status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
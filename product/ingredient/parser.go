@@ -0,0 +1,146 @@
+package ingredient
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// closers maps opening brackets of label text to closing ones
+var closers = map[rune]rune{'(': ')', '[': ']', '{': '}'}
+
+// qualifierWords start bracketed text that describes an ingredient
+// rather than listing what it is made of, e.g. "(processed with alkali)",
+// "(for color)" or "(an emulsifier)". Text starting with a digit,
+// e.g. "(2%)", is a qualifier as well
+var qualifierWords = map[string]bool{
+	"a":            true,
+	"an":           true,
+	"added":        true,
+	"as":           true,
+	"color":        true,
+	"colour":       true,
+	"contains":     true,
+	"emulsifier":   true,
+	"for":          true,
+	"from":         true,
+	"made":         true,
+	"preservative": true,
+	"processed":    true,
+	"stabilizer":   true,
+	"to":           true,
+	"used":         true,
+	"with":         true,
+}
+
+// Parse parses label text listing ingredients separated by commas or
+// semicolons. Bracketed text after an ingredient either qualifies it
+// or lists its sub-ingredients, which nest to any depth, e.g.
+// "chocolate chips (sugar, cocoa [processed with alkali])". Names are
+// lower cased with whitespace collapsed. Parsing is lenient, so that
+// any text is accepted: brackets left open are closed at end of text
+// and stray closing brackets are ignored
+func Parse(text string) []domain.Ingredient {
+	parser := &parser{text: []rune(text)}
+	return parser.list(0)
+}
+
+// ParseAll parses every entry of ingredients list,
+// each of which may list several ingredients itself
+func ParseAll(texts []string) []domain.Ingredient {
+	var ingredients []domain.Ingredient
+	for _, text := range texts {
+		ingredients = append(ingredients, Parse(text)...)
+	}
+	return ingredients
+}
+
+type parser struct {
+	text []rune
+	pos  int
+}
+
+// list parses ingredients up to closer, or end of text when closer is 0
+func (parser *parser) list(closer rune) []domain.Ingredient {
+	var ingredients []domain.Ingredient
+	for {
+		ingredient, done := parser.item(closer)
+
+		// bracketed text without a name, e.g. "(sugar, salt)",
+		// lists ingredients of the enclosing list
+		if ingredient.Name == "" {
+			ingredients = append(ingredients, ingredient.Ingredients...)
+		} else {
+			ingredients = append(ingredients, ingredient)
+		}
+
+		if done {
+			return ingredients
+		}
+	}
+}
+
+// item parses a single ingredient up to the next separator, which
+// is consumed. done tells whether the list ended with closer or text
+func (parser *parser) item(closer rune) (ingredient domain.Ingredient, done bool) {
+	var name strings.Builder
+	defer func() { ingredient.Name = normalize(name.String()) }()
+
+	for parser.pos < len(parser.text) {
+		char := parser.text[parser.pos]
+		parser.pos++
+
+		switch {
+		case char == ',' || char == ';':
+			return ingredient, false
+		case char == closer:
+			return ingredient, true
+		case char == ')' || char == ']' || char == '}':
+			continue
+		case closers[char] != 0:
+			ingredient = withGroup(ingredient, parser.list(closers[char]))
+		default:
+			name.WriteRune(char)
+		}
+	}
+	return ingredient, true
+}
+
+// withGroup adds bracketed ingredients to ingredient, either as its
+// qualifiers when all of them are plain qualifier text, or as its
+// sub-ingredients otherwise
+func withGroup(ingredient domain.Ingredient, group []domain.Ingredient) domain.Ingredient {
+	for _, item := range group {
+		if !isQualifier(item) {
+			ingredient.Ingredients = append(ingredient.Ingredients, group...)
+			return ingredient
+		}
+	}
+
+	for _, item := range group {
+		ingredient.Qualifiers = append(ingredient.Qualifiers, item.Name)
+	}
+	return ingredient
+}
+
+func isQualifier(ingredient domain.Ingredient) bool {
+	if len(ingredient.Qualifiers) > 0 || len(ingredient.Ingredients) > 0 {
+		return false
+	}
+
+	words := strings.Fields(ingredient.Name)
+	if len(words) == 0 {
+		return false
+	}
+	return qualifierWords[words[0]] || unicode.IsDigit([]rune(words[0])[0])
+}
+
+// normalize lower cases name, collapses its whitespace and trims
+// punctuation that labels put around names, e.g. "and salt." is "salt"
+func normalize(name string) string {
+	name = strings.Join(strings.Fields(strings.ToLower(name)), " ")
+	name = strings.TrimRight(name, ".*:")
+	name = strings.TrimPrefix(name, "and ")
+	return strings.TrimSpace(name)
+}
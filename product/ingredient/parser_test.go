@@ -0,0 +1,90 @@
+package ingredient
+
+import (
+	"testing"
+
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected []domain.Ingredient
+	}{
+		{
+			name:     "plain",
+			text:     "  Skim   Milk ",
+			expected: []domain.Ingredient{{Name: "skim milk"}},
+		},
+		{
+			name:     "qualifier",
+			text:     "cocoa (processed with alkali)",
+			expected: []domain.Ingredient{{Name: "cocoa", Qualifiers: []string{"processed with alkali"}}},
+		},
+		{
+			name: "list",
+			text: "sugar, cream; and salt.",
+			expected: []domain.Ingredient{
+				{Name: "sugar"},
+				{Name: "cream"},
+				{Name: "salt"},
+			},
+		},
+		{
+			name: "nested",
+			text: "Chocolate Chips (sugar, cocoa [processed with alkali], milk {skim milk, cream}) (2%)",
+			expected: []domain.Ingredient{{
+				Name:       "chocolate chips",
+				Qualifiers: []string{"2%"},
+				Ingredients: []domain.Ingredient{
+					{Name: "sugar"},
+					{Name: "cocoa", Qualifiers: []string{"processed with alkali"}},
+					{Name: "milk", Ingredients: []domain.Ingredient{{Name: "skim milk"}, {Name: "cream"}}},
+				},
+			}},
+		},
+		{
+			name: "unbalanced",
+			text: "caramel (sugar, butter (cream)]), salt)",
+			expected: []domain.Ingredient{
+				{Name: "caramel", Ingredients: []domain.Ingredient{
+					{Name: "sugar"},
+					{Name: "butter", Ingredients: []domain.Ingredient{{Name: "cream"}}},
+				}},
+				{Name: "salt"},
+			},
+		},
+		{
+			name:     "unclosed",
+			text:     "fudge (sugar, cocoa",
+			expected: []domain.Ingredient{{Name: "fudge", Ingredients: []domain.Ingredient{{Name: "sugar"}, {Name: "cocoa"}}}},
+		},
+		{
+			name:     "unnamed-group",
+			text:     "(sugar, salt),,",
+			expected: []domain.Ingredient{{Name: "sugar"}, {Name: "salt"}},
+		},
+		{
+			name: "empty",
+			text: " ",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, Parse(testCase.text))
+		})
+	}
+}
+
+func TestParseAllFormatsBack(t *testing.T) {
+	texts := []string{"cream", "Chocolate Chips (sugar, cocoa [processed with alkali])"}
+	ingredients := ParseAll(texts)
+
+	formatted := domain.FormatIngredients(ingredients)
+
+	assert.Equal(t, "cream, chocolate chips (sugar, cocoa (processed with alkali))", formatted)
+	assert.Equal(t, ingredients, Parse(formatted))
+}
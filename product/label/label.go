@@ -0,0 +1,329 @@
+package label
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// ContentType is media type of rendered labels
+const ContentType = "image/svg+xml"
+
+// Label is a nutrition label of a serving of product
+type Label struct {
+	// Title names product in title of the image, which is not printed
+	Title string
+
+	// Serving is household measure of a serving, e.g. "2/3 cup"
+	Serving string
+
+	// ServingSize is grams of product in a serving
+	ServingSize float64
+
+	// ServingsPerContainer is left out of label when zero
+	ServingsPerContainer float64
+
+	// Nutrients is nutrients in a serving
+	Nutrients domain.Nutrients
+}
+
+// dailyValues are the FDA reference daily values of a 2,000 calorie
+// diet in grams, which are the 100% of % Daily Value
+var dailyValues = map[string]float64{
+	"Total Fat":          78,
+	"Saturated Fat":      20,
+	"Cholesterol":        0.3,
+	"Sodium":             2.3,
+	"Total Carbohydrate": 275,
+	"Dietary Fiber":      28,
+	"Added Sugars":       50,
+	"Vitamin D":          0.00002,
+	"Calcium":            1.3,
+	"Iron":               0.018,
+	"Potassium":          4.7,
+}
+
+// Dimensions of label in SVG user units, which are pixels at 96 dpi
+const (
+	width   = 240
+	margin  = 6
+	content = width - 2*margin
+)
+
+// footnote of % Daily Value, broken into lines that fit the label
+var footnote = []string{
+	"* The % Daily Value (DV) tells you how much a",
+	"nutrient in a serving of food contributes to a daily",
+	"diet. 2,000 calories a day is used for general",
+	"nutrition advice.",
+}
+
+// Render writes label as SVG image in the format of FDA nutrition
+// facts labels. Amounts are rounded by FDA rounding rules and
+// % Daily Values are computed from unrounded amounts
+func Render(w io.Writer, label Label) error {
+	n := label.Nutrients
+	canvas := &canvas{y: margin}
+
+	canvas.text(margin, 26, "Nutrition Facts", "start", 26, true)
+	canvas.rule(1)
+	if label.ServingsPerContainer > 0 {
+		canvas.text(margin, 14, servings(label.ServingsPerContainer)+" per container", "start", 11, false)
+	}
+	canvas.text(margin, 15, "Serving size", "start", 13, true)
+	canvas.overlay(width-margin, servingSize(label), "end", 13, true)
+	canvas.rule(8)
+
+	canvas.text(margin, 11, "Amount per serving", "start", 9, true)
+	canvas.text(margin, 26, "Calories", "start", 22, true)
+	canvas.overlay(width-margin, formatAmount(roundCalories(n.Calories), ""), "end", 26, true)
+	canvas.rule(4)
+	canvas.text(width-margin, 12, "% Daily Value*", "end", 9, true)
+
+	for _, row := range []nutrientRow{
+		{name: "Total Fat", bold: true, amount: formatFat(n.TotalFat), grams: n.TotalFat},
+		{name: "Saturated Fat", level: 1, amount: formatFat(n.SaturatedFat), grams: n.SaturatedFat},
+		{name: "Trans Fat", level: 1, amount: formatFat(n.TransFat), grams: -1},
+		{name: "Cholesterol", bold: true, amount: formatCholesterol(n.Cholesterol), grams: n.Cholesterol},
+		{name: "Sodium", bold: true, amount: formatSodium(n.Sodium), grams: n.Sodium},
+		{name: "Total Carbohydrate", bold: true, amount: formatCarbohydrate(n.TotalCarbohydrate), grams: n.TotalCarbohydrate},
+		{name: "Dietary Fiber", level: 1, amount: formatCarbohydrate(n.DietaryFiber), grams: n.DietaryFiber},
+		{name: "Total Sugars", level: 1, amount: formatCarbohydrate(n.TotalSugars), grams: -1},
+		{name: "Added Sugars", level: 2, grams: n.AddedSugars,
+			text: "Includes " + formatCarbohydrate(n.AddedSugars) + " Added Sugars"},
+		{name: "Protein", bold: true, amount: formatCarbohydrate(n.Protein), grams: -1},
+	} {
+		canvas.nutrient(row)
+	}
+	canvas.rule(8)
+
+	canvas.vitamin("Vitamin D", formatAmount(round(n.VitaminD*1000000, 0.1), "mcg"), n.VitaminD)
+	canvas.vitamin("Calcium", formatAmount(round(n.Calcium*1000, 10), "mg"), n.Calcium)
+	canvas.vitamin("Iron", formatAmount(round(n.Iron*1000, 0.1), "mg"), n.Iron)
+	canvas.vitamin("Potassium", formatAmount(round(n.Potassium*1000, 10), "mg"), n.Potassium)
+	canvas.rule(4)
+
+	for _, line := range footnote {
+		canvas.text(margin, 10, line, "start", 8, false)
+	}
+
+	height := canvas.y + margin
+	var svg bytes.Buffer
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Helvetica, Arial, sans-serif">`,
+		width, height, width, height)
+	svg.WriteString("<title>")
+	xml.EscapeText(&svg, []byte("Nutrition Facts: "+label.Title))
+	svg.WriteString("</title>")
+	fmt.Fprintf(&svg, `<rect x="1" y="1" width="%d" height="%d" fill="white" stroke="black" stroke-width="2"/>`, width-2, height-2)
+	svg.WriteString(canvas.body.String())
+	svg.WriteString("</svg>\n")
+
+	_, err := w.Write(svg.Bytes())
+	return err
+}
+
+// canvas lays label out from top to bottom, y being
+// the top of the next element
+type canvas struct {
+	body     strings.Builder
+	y        int
+	baseline int
+}
+
+// text writes a line of given height at x, which is its
+// start, middle or end depending on anchor
+func (canvas *canvas) text(x, height int, text, anchor string, size int, bold bool) {
+	canvas.y += height
+	canvas.baseline = canvas.y - height/5
+	canvas.overlay(x, text, anchor, size, bold)
+}
+
+// overlay writes text on the baseline of the previous line
+func (canvas *canvas) overlay(x int, text, anchor string, size int, bold bool) {
+	weight := "normal"
+	if bold {
+		weight = "bold"
+	}
+
+	fmt.Fprintf(&canvas.body, `<text x="%d" y="%d" font-size="%d" font-weight="%s" text-anchor="%s">`,
+		x, canvas.baseline, size, weight, anchor)
+	xml.EscapeText(&canvas.body, []byte(text))
+	canvas.body.WriteString("</text>")
+}
+
+// rule draws a horizontal rule of thickness below the previous line
+func (canvas *canvas) rule(thickness int) {
+	canvas.y += 2
+	fmt.Fprintf(&canvas.body, `<rect x="%d" y="%d" width="%d" height="%d" fill="black"/>`,
+		margin, canvas.y, content, thickness)
+	canvas.y += thickness
+}
+
+// nutrientRow is a row of nutrient on label. Its text is its name followed
+// by its rounded amount unless given, and its % Daily Value is left out
+// when grams is negative
+type nutrientRow struct {
+	name   string
+	text   string
+	amount string
+	level  int
+	bold   bool
+	grams  float64
+}
+
+// nutrient writes row of nutrient indented by its level,
+// rows are separated by hairlines
+func (canvas *canvas) nutrient(row nutrientRow) {
+	x := margin + 12*row.level
+	canvas.hairline(row.level)
+	if row.text != "" {
+		canvas.text(x, 15, row.text, "start", 11, row.bold)
+	} else {
+		canvas.text(x, 15, row.name, "start", 11, row.bold)
+		canvas.overlay(x+textWidth(row.name, row.bold), row.amount, "start", 11, false)
+	}
+
+	if row.grams >= 0 {
+		canvas.overlay(width-margin, percent(row.name, row.grams), "end", 11, true)
+	}
+}
+
+// vitamin writes a row of vitamin or mineral with its % Daily Value
+func (canvas *canvas) vitamin(name string, amount string, grams float64) {
+	canvas.hairline(0)
+	canvas.text(margin, 15, name+" "+amount, "start", 11, false)
+	canvas.overlay(width-margin, percent(name, grams), "end", 11, false)
+}
+
+// hairline separates rows, starting at indentation of level
+func (canvas *canvas) hairline(level int) {
+	if level > 1 {
+		level = 1
+	}
+	canvas.y++
+	fmt.Fprintf(&canvas.body, `<rect x="%d" y="%d" width="%d" height="0.5" fill="black"/>`,
+		margin+12*level, canvas.y, content-12*level)
+}
+
+// textWidth estimates width of name followed by a space in font size
+// 11, so that amounts follow names as they do on printed labels
+func textWidth(name string, bold bool) int {
+	perChar := 6.0
+	if bold {
+		perChar = 6.6
+	}
+	return int(math.Ceil(float64(len(name)+1) * perChar))
+}
+
+// percent formats % Daily Value of grams of nutrient
+func percent(name string, grams float64) string {
+	return strconv.Itoa(int(math.Round(grams/dailyValues[name]*100))) + "%"
+}
+
+// servingSize formats household measure of serving followed by its grams
+func servingSize(label Label) string {
+	grams := formatAmount(math.Round(label.ServingSize), "g")
+	if label.Serving == "" {
+		return grams
+	}
+	return label.Serving + " (" + grams + ")"
+}
+
+// servings formats servings per container, rounded
+// to halves and stated as about unless exact
+func servings(count float64) string {
+	rounded := round(count, 0.5)
+	text := formatAmount(rounded, "") + " servings"
+	if rounded == 1 {
+		text = "1 serving"
+	}
+	if math.Abs(rounded-count) > 0.01 {
+		text = "about " + text
+	}
+	return text
+}
+
+// roundCalories rounds calories by FDA rules: below 5 is
+// 0, up to 50 to nearest 5, above 50 to nearest 10
+func roundCalories(calories float64) float64 {
+	switch {
+	case calories < 5:
+		return 0
+	case calories <= 50:
+		return round(calories, 5)
+	default:
+		return round(calories, 10)
+	}
+}
+
+// formatFat formats grams of fat by FDA rules: below 0.5 g is
+// 0 g, below 5 g to nearest 0.5 g, otherwise to nearest gram
+func formatFat(grams float64) string {
+	switch {
+	case grams < 0.5:
+		return "0g"
+	case grams < 5:
+		return formatAmount(round(grams, 0.5), "g")
+	default:
+		return formatAmount(round(grams, 1), "g")
+	}
+}
+
+// formatCholesterol formats grams of cholesterol by FDA rules: below
+// 2 mg is 0 mg, up to 5 mg less than 5 mg, otherwise to nearest 5 mg
+func formatCholesterol(grams float64) string {
+	milligrams := grams * 1000
+	switch {
+	case milligrams < 2:
+		return "0mg"
+	case milligrams <= 5:
+		return "<5mg"
+	default:
+		return formatAmount(round(milligrams, 5), "mg")
+	}
+}
+
+// formatSodium formats grams of sodium by FDA rules: below 5 mg is
+// 0 mg, up to 140 mg to nearest 5 mg, otherwise to nearest 10 mg
+func formatSodium(grams float64) string {
+	milligrams := grams * 1000
+	switch {
+	case milligrams < 5:
+		return "0mg"
+	case milligrams <= 140:
+		return formatAmount(round(milligrams, 5), "mg")
+	default:
+		return formatAmount(round(milligrams, 10), "mg")
+	}
+}
+
+// formatCarbohydrate formats grams of carbohydrates and protein by
+// FDA rules: below 0.5 g is 0 g, below 1 g less than 1 g,
+// otherwise to nearest gram
+func formatCarbohydrate(grams float64) string {
+	switch {
+	case grams < 0.5:
+		return "0g"
+	case grams < 1:
+		return "<1g"
+	default:
+		return formatAmount(round(grams, 1), "g")
+	}
+}
+
+// round rounds amount to nearest multiple of step
+func round(amount, step float64) float64 {
+	return math.Round(amount/step) * step
+}
+
+// formatAmount formats amount followed by unit without space,
+// as labels do, with at most one decimal
+func formatAmount(amount float64, unit string) string {
+	return strconv.FormatFloat(math.Round(amount*10)/10, 'f', -1, 64) + unit
+}
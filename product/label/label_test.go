@@ -0,0 +1,100 @@
+package label
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRounding(t *testing.T) {
+	testCases := []struct {
+		name     string
+		format   func(float64) string
+		amount   float64
+		expected string
+	}{
+		{name: "fat-insignificant", format: formatFat, amount: 0.4, expected: "0g"},
+		{name: "fat-halves", format: formatFat, amount: 2.3, expected: "2.5g"},
+		{name: "fat-grams", format: formatFat, amount: 14.7, expected: "15g"},
+		{name: "cholesterol-insignificant", format: formatCholesterol, amount: 0.0015, expected: "0mg"},
+		{name: "cholesterol-less-than", format: formatCholesterol, amount: 0.004, expected: "<5mg"},
+		{name: "cholesterol-fives", format: formatCholesterol, amount: 0.0588, expected: "60mg"},
+		{name: "sodium-insignificant", format: formatSodium, amount: 0.004, expected: "0mg"},
+		{name: "sodium-fives", format: formatSodium, amount: 0.0622, expected: "60mg"},
+		{name: "sodium-tens", format: formatSodium, amount: 0.1449, expected: "140mg"},
+		{name: "carbohydrate-insignificant", format: formatCarbohydrate, amount: 0.3, expected: "0g"},
+		{name: "carbohydrate-less-than", format: formatCarbohydrate, amount: 0.7, expected: "<1g"},
+		{name: "carbohydrate-grams", format: formatCarbohydrate, amount: 27.6, expected: "28g"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, testCase.format(testCase.amount))
+		})
+	}
+
+	t.Run("calories", func(t *testing.T) {
+		assert.Equal(t, 0.0, roundCalories(4))
+		assert.Equal(t, 35.0, roundCalories(37))
+		assert.Equal(t, 50.0, roundCalories(50))
+		assert.Equal(t, 250.0, roundCalories(245))
+	})
+
+	t.Run("servings", func(t *testing.T) {
+		assert.Equal(t, "1 serving", servings(1))
+		assert.Equal(t, "3 servings", servings(3))
+		assert.Equal(t, "about 3 servings", servings(3.1))
+		assert.Equal(t, "about 4.5 servings", servings(4.4))
+	})
+}
+
+func TestRender(t *testing.T) {
+	label := Label{
+		Title:                "Cookies & Cream",
+		Serving:              "2/3 cup",
+		ServingSize:          98,
+		ServingsPerContainer: 3.1,
+		Nutrients: domain.Nutrients{
+			Calories:          245,
+			TotalFat:          14.7,
+			SaturatedFat:      9.8,
+			Cholesterol:       0.0588,
+			Sodium:            0.0622,
+			TotalCarbohydrate: 27.6,
+			TotalSugars:       24.5,
+			AddedSugars:       17.6,
+			Protein:           3.9,
+			Calcium:           0.1,
+		},
+	}
+
+	var svg bytes.Buffer
+	err := Render(&svg, label)
+	assert.NoError(t, err)
+
+	// label is well-formed XML
+	decoder := xml.NewDecoder(bytes.NewReader(svg.Bytes()))
+	for {
+		_, err := decoder.Token()
+		if err != nil {
+			assert.Equal(t, "EOF", err.Error())
+			break
+		}
+	}
+
+	body := svg.String()
+	assert.Contains(t, body, `<svg xmlns="http://www.w3.org/2000/svg"`)
+	assert.Contains(t, body, "<title>Nutrition Facts: Cookies &amp; Cream</title>")
+	assert.Contains(t, body, ">about 3 servings per container</text>")
+	assert.Contains(t, body, ">2/3 cup (98g)</text>")
+	assert.Contains(t, body, ">250</text>")
+	assert.Contains(t, body, ">15g</text>")
+	assert.Contains(t, body, ">19%</text>")
+	assert.Contains(t, body, ">Includes 18g Added Sugars</text>")
+	assert.Contains(t, body, ">35%</text>")
+	assert.Contains(t, body, ">Calcium 100mg</text>")
+	assert.Contains(t, body, ">8%</text>")
+}
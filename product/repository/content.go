@@ -0,0 +1,118 @@
+// Package repository holds what product repositories share
+// regardless of the database they store products in
+package repository
+
+import "github.com/iqdf/benjerry-service/domain"
+
+// Content clears lifecycle, translations and variants of the
+// product, so that writes of product content leave them as they are
+func Content(product domain.Product) domain.Product {
+	product.Translations = nil
+	product.Variants = nil
+	product.Status = ""
+	product.RetiredAt = nil
+	product.Epitaph = ""
+	product.ScheduledStatus = ""
+	product.ScheduledAt = nil
+	return product
+}
+
+// DefaultLists stores products without sourcing
+// values or ingredients with empty lists of them
+func DefaultLists(product *domain.Product) {
+	if product.Ingredients == nil {
+		product.Ingredients = &[]string{}
+	}
+
+	if product.SourcingValues == nil {
+		product.SourcingValues = &[]string{}
+	}
+}
+
+// SetContent overwrites attributes of stored product that are set in
+// content, like $set of a mongo document whose empty fields are left
+// out. Allergen check is always overwritten, so that it is cleared
+// once allergens agree
+func SetContent(stored *domain.Product, content domain.Product) {
+	setString(&stored.Name, content.Name)
+	setString(&stored.Slug, content.Slug)
+	setString(&stored.ImageClosedURL, content.ImageClosedURL)
+	setString(&stored.ImageOpenURL, content.ImageOpenURL)
+	setString(&stored.Description, content.Description)
+	setString(&stored.Story, content.Story)
+	setString(&stored.AllergyInfo, content.AllergyInfo)
+	setString(&stored.DietaryCertification, content.DietaryCertification)
+
+	if len(content.PreviousSlugs) > 0 {
+		stored.PreviousSlugs = content.PreviousSlugs
+	}
+	if content.SourcingValues != nil {
+		stored.SourcingValues = content.SourcingValues
+	}
+	if content.Ingredients != nil {
+		stored.Ingredients = content.Ingredients
+	}
+	if len(content.ParsedIngredients) > 0 {
+		stored.ParsedIngredients = content.ParsedIngredients
+	}
+	if !content.Allergens.IsEmpty() {
+		stored.Allergens = content.Allergens
+	}
+	if !content.Nutrition.IsEmpty() {
+		stored.Nutrition = content.Nutrition
+	}
+	if len(content.Regions) > 0 {
+		stored.Regions = content.Regions
+	}
+	if len(content.RegionOverrides) > 0 {
+		stored.RegionOverrides = content.RegionOverrides
+	}
+	if len(content.GTINs) > 0 {
+		stored.GTINs = content.GTINs
+	}
+	stored.AllergenCheck = content.AllergenCheck
+}
+
+// ReplaceContent overwrites every attribute of stored product with
+// content, clearing those that are empty in content. Slug is left as
+// it is when empty, which it only is for products stored before slugs
+func ReplaceContent(stored *domain.Product, content domain.Product) {
+	DefaultLists(&content)
+
+	stored.Name = content.Name
+	stored.ImageClosedURL = content.ImageClosedURL
+	stored.ImageOpenURL = content.ImageOpenURL
+	stored.Description = content.Description
+	stored.Story = content.Story
+	stored.SourcingValues = content.SourcingValues
+	stored.Ingredients = content.Ingredients
+	stored.ParsedIngredients = content.ParsedIngredients
+	stored.AllergyInfo = content.AllergyInfo
+	stored.DietaryCertification = content.DietaryCertification
+	stored.Regions = content.Regions
+	stored.Allergens = content.Allergens
+	stored.AllergenCheck = content.AllergenCheck
+	stored.Nutrition = content.Nutrition
+	stored.RegionOverrides = content.RegionOverrides
+	stored.GTINs = content.GTINs
+
+	if content.Slug != "" {
+		stored.Slug = content.Slug
+		stored.PreviousSlugs = content.PreviousSlugs
+	}
+}
+
+// SetLifecycle overwrites lifecycle attributes of stored product
+func SetLifecycle(stored *domain.Product, lifecycle domain.Product) {
+	stored.Status = lifecycle.Status
+	stored.RetiredAt = lifecycle.RetiredAt
+	stored.Epitaph = lifecycle.Epitaph
+	stored.ScheduledStatus = lifecycle.ScheduledStatus
+	stored.ScheduledAt = lifecycle.ScheduledAt
+}
+
+func setString(stored *string, value string) {
+	if value != "" {
+		*stored = value
+	}
+}
@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// copyProduct deep copies product, so that stored products share no
+// slices, maps or pointers with callers. Empty attributes are copied
+// as nil, the way mongo leaves out empty fields of stored documents,
+// except sourcing values and ingredients, which keep empty lists
+func copyProduct(product domain.Product) domain.Product {
+	copied := product
+	copied.PreviousSlugs = copyStrings(product.PreviousSlugs)
+	copied.SourcingValues = copyStringList(product.SourcingValues)
+	copied.Ingredients = copyStringList(product.Ingredients)
+	copied.ParsedIngredients = copyIngredients(product.ParsedIngredients)
+	copied.Allergens = domain.Allergens{
+		Contains:   copyStrings(product.Allergens.Contains),
+		MayContain: copyStrings(product.Allergens.MayContain),
+	}
+	copied.AllergenCheck = domain.AllergenCheck{
+		Undeclared: copyStrings(product.AllergenCheck.Undeclared),
+		Undetected: copyStrings(product.AllergenCheck.Undetected),
+	}
+	if product.Nutrition.IsEmpty() {
+		copied.Nutrition = domain.NutritionFacts{}
+	}
+	copied.Regions = copyStrings(product.Regions)
+	copied.GTINs = copyStrings(product.GTINs)
+	copied.RetiredAt = copyTime(product.RetiredAt)
+	copied.ScheduledAt = copyTime(product.ScheduledAt)
+
+	copied.RegionOverrides = nil
+	if len(product.RegionOverrides) > 0 {
+		copied.RegionOverrides = make(map[string]domain.RegionOverride, len(product.RegionOverrides))
+		for region, override := range product.RegionOverrides {
+			copied.RegionOverrides[region] = override
+		}
+	}
+
+	copied.Translations = nil
+	if len(product.Translations) > 0 {
+		copied.Translations = make(map[string]domain.ProductTranslation, len(product.Translations))
+		for locale, translation := range product.Translations {
+			copied.Translations[locale] = translation
+		}
+	}
+
+	copied.Variants = nil
+	for _, variant := range product.Variants {
+		variant.Ingredients = copyStringList(variant.Ingredients)
+		copied.Variants = append(copied.Variants, variant)
+	}
+	return copied
+}
+
+// readProduct copies stored product for callers. Products
+// stored without status are published
+func readProduct(product domain.Product) domain.Product {
+	copied := copyProduct(product)
+	if copied.Status == "" {
+		copied.Status = domain.StatusPublished
+	}
+	return copied
+}
+
+func copyStrings(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	return append([]string(nil), values...)
+}
+
+func copyStringList(values *[]string) *[]string {
+	if values == nil {
+		return nil
+	}
+	copied := append([]string{}, (*values)...)
+	return &copied
+}
+
+func copyIngredients(ingredients []domain.Ingredient) []domain.Ingredient {
+	if len(ingredients) == 0 {
+		return nil
+	}
+
+	copied := make([]domain.Ingredient, 0, len(ingredients))
+	for _, ingredient := range ingredients {
+		copied = append(copied, domain.Ingredient{
+			Name:        ingredient.Name,
+			Qualifiers:  copyStrings(ingredient.Qualifiers),
+			Ingredients: copyIngredients(ingredient.Ingredients),
+		})
+	}
+	return copied
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	copied := *t
+	return &copied
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func intersects(values []string, others []string) bool {
+	for _, value := range values {
+		if contains(others, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func variantSKUs(variants []domain.ProductVariant) []string {
+	skus := make([]string, 0, len(variants))
+	for _, variant := range variants {
+		skus = append(skus, variant.SKU)
+	}
+	return skus
+}
@@ -0,0 +1,174 @@
+package memory
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/product/repository"
+)
+
+// matchesFilter tells whether product is out of trash
+// and satisfies every condition of the filter
+func matchesFilter(document *productDocument, filter domain.ProductFilter) bool {
+	if document.deletedAt != nil {
+		return false
+	}
+	product := document.product
+
+	var sourcingValues []string
+	if product.SourcingValues != nil {
+		sourcingValues = *product.SourcingValues
+	}
+
+	if len(filter.SourcingValues) > 0 {
+		if filter.SourcingMatch == domain.MatchAny {
+			if !intersects(filter.SourcingValues, sourcingValues) {
+				return false
+			}
+		} else {
+			for _, value := range filter.SourcingValues {
+				if !contains(sourcingValues, value) {
+					return false
+				}
+			}
+		}
+	}
+
+	if len(filter.DietaryCertifications) > 0 && !contains(filter.DietaryCertifications, product.DietaryCertification) {
+		return false
+	}
+
+	if len(filter.ExcludeAllergens) > 0 && hasAllergen(product, filter.ExcludeAllergens...) {
+		return false
+	}
+
+	if len(filter.Statuses) > 0 {
+		// products stored without status are published
+		status := product.Status
+		if status == "" {
+			status = domain.StatusPublished
+		}
+
+		var found bool
+		for _, filtered := range filter.Statuses {
+			found = found || filtered == status
+		}
+		if !found {
+			return false
+		}
+	}
+
+	// products without regions are sold everywhere
+	if filter.Region != "" && len(product.Regions) > 0 && !contains(product.Regions, filter.Region) {
+		return false
+	}
+	return true
+}
+
+// hasAllergen tells whether product contains or may contain any of the
+// allergens, products declaring none by matching allergy info
+func hasAllergen(product domain.Product, allergens ...string) bool {
+	if !product.Allergens.IsEmpty() {
+		return intersects(allergens, product.Allergens.Contains) ||
+			intersects(allergens, product.Allergens.MayContain)
+	}
+	return allergenPattern(allergens...).MatchString(product.AllergyInfo)
+}
+
+// allergenPattern matches any of the allergens as a whole word
+// in allergy info, in either singular or plural form
+func allergenPattern(allergens ...string) *regexp.Regexp {
+	words := repository.AllergenWords(allergens)
+	return regexp.MustCompile(`(?i)\b(` + strings.Join(words, "|") + `)\b`)
+}
+
+// CountFacets counts products matching filter by every sourcing
+// value, dietary certification and known allergen they contain
+// or may contain. Each group is counted without the condition of
+// filter on its own dimension, see repository.FacetFiltersOf
+func (repo *ProductMemoryRepo) CountFacets(ctx context.Context, filter domain.ProductFilter) (domain.ProductFacets, error) {
+	var facets domain.ProductFacets
+	filters := repository.FacetFiltersOf(filter)
+
+	sourcingCounts := make(map[string]int64)
+	certificationCounts := make(map[string]int64)
+	allergenCounts := make([]int64, len(domain.KnownAllergens))
+
+	repo.mu.RLock()
+	for _, document := range repo.products {
+		product := document.product
+
+		if product.SourcingValues != nil && matchesFilter(document, filters.Sourcing) {
+			for _, value := range *product.SourcingValues {
+				sourcingCounts[value]++
+			}
+		}
+		if product.DietaryCertification != "" && matchesFilter(document, filters.Dietary) {
+			certificationCounts[product.DietaryCertification]++
+		}
+		if !matchesFilter(document, filters.Allergens) {
+			continue
+		}
+		for i, allergen := range domain.KnownAllergens {
+			if hasAllergen(product, allergen) {
+				allergenCounts[i]++
+			}
+		}
+	}
+	repo.mu.RUnlock()
+
+	facets.SourcingValues = repository.SortedCounts(sourcingCounts)
+	facets.DietaryCertifications = repository.SortedCounts(certificationCounts)
+
+	// every known allergen is listed, including zero counts
+	for i, allergen := range domain.KnownAllergens {
+		facets.Allergens = append(facets.Allergens, domain.FacetCount{Value: allergen, Count: allergenCounts[i]})
+	}
+	return facets, nil
+}
+
+// Search queries filtered products matching text on name, description,
+// story and ingredients, ordered by relevance score, see repository.SearchScore
+func (repo *ProductMemoryRepo) Search(
+	ctx context.Context,
+	text string,
+	limit int,
+	filter domain.ProductFilter,
+) ([]domain.ProductSearchResult, error) {
+	terms, negated := repository.ParseSearch(text)
+
+	repo.mu.RLock()
+	documents := repo.sorted(func(document *productDocument) bool {
+		return matchesFilter(document, filter)
+	})
+
+	var results []domain.ProductSearchResult
+	for _, document := range documents {
+		if repository.SearchScore(document.product, negated) > 0 {
+			continue
+		}
+
+		if score := repository.SearchScore(document.product, terms); score > 0 {
+			results = append(results, domain.ProductSearchResult{
+				Product: readProduct(document.product),
+				Score:   score,
+			})
+		}
+	}
+	repo.mu.RUnlock()
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	if results == nil {
+		results = make([]domain.ProductSearchResult, 0)
+	}
+	return results, nil
+}
@@ -0,0 +1,564 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/product/repository"
+)
+
+// productDocument is a stored product, in trash when deletedAt is set
+type productDocument struct {
+	product   domain.Product
+	deletedAt *time.Time
+	deletedBy string
+}
+
+// ProductMemoryRepo keeps products in process memory, e.g. to run
+// without mongo. It honors the same constraints and error contract
+// as the mongo repository: productIds, GTINs, slugs and variant SKUs
+// are unique across products, trashed ones included
+type ProductMemoryRepo struct {
+	mu       sync.RWMutex
+	products map[string]*productDocument
+}
+
+// NewProductRepo creates an empty in-memory product repository
+func NewProductRepo() *ProductMemoryRepo {
+	return &ProductMemoryRepo{products: make(map[string]*productDocument)}
+}
+
+// live finds product that is not in trash
+func (repo *ProductMemoryRepo) live(productID string) (*productDocument, bool) {
+	document, ok := repo.products[productID]
+	if !ok || document.deletedAt != nil {
+		return nil, false
+	}
+	return document, true
+}
+
+// writable finds product that is not in trash and, given non-zero
+// version, tells apart a missing product from a product that is no
+// longer at that version, like checkMatched of the mongo repository
+func (repo *ProductMemoryRepo) writable(productID string, version int64) (*productDocument, error) {
+	document, ok := repo.live(productID)
+	if !ok {
+		return nil, domain.ErrResourceNotFound
+	}
+
+	if version != 0 && document.product.Version != version {
+		return nil, domain.ErrPreconditionFailed
+	}
+	return document, nil
+}
+
+// checkUnique tells whether product takes GTIN, slug or variant SKU of
+// another product than itself, which unique indexes reject in mongo
+func (repo *ProductMemoryRepo) checkUnique(product domain.Product) error {
+	for productID, document := range repo.products {
+		if productID == product.ProductID {
+			continue
+		}
+
+		other := document.product
+		switch {
+		case intersects(product.GTINs, other.GTINs):
+			return domain.ErrDuplicateGTIN
+		case product.Slug != "" && product.Slug == other.Slug:
+			return domain.ErrDuplicateSlug
+		case intersects(variantSKUs(product.Variants), variantSKUs(other.Variants)):
+			return domain.ErrConflict
+		}
+	}
+	return nil
+}
+
+// sorted lists products matching match ordered by productId
+func (repo *ProductMemoryRepo) sorted(match func(document *productDocument) bool) []*productDocument {
+	var documents []*productDocument
+	for _, document := range repo.products {
+		if match(document) {
+			documents = append(documents, document)
+		}
+	}
+
+	sort.Slice(documents, func(i, j int) bool {
+		return documents[i].product.ProductID < documents[j].product.ProductID
+	})
+	return documents
+}
+
+// Fetch queries a page of products sorted by the requested field.
+// Pages are chained by the cursor of the last product of a page
+func (repo *ProductMemoryRepo) Fetch(ctx context.Context, query domain.ProductQuery) (domain.ProductPage, error) {
+	var page domain.ProductPage
+
+	var after func(product domain.Product) bool
+	if query.Cursor != "" {
+		cursor, err := domain.DecodePageCursor(query.Cursor)
+		if err != nil {
+			return domain.ProductPage{}, err
+		}
+		after = func(product domain.Product) bool {
+			return compareSorted(product, cursor.SortValue, cursor.ProductID, query) > 0
+		}
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	documents := repo.sorted(func(document *productDocument) bool {
+		return matchesFilter(document, query.Filter)
+	})
+	page.TotalCount = int64(len(documents))
+
+	sort.SliceStable(documents, func(i, j int) bool {
+		other := documents[j].product
+		return compareSorted(documents[i].product, sortValue(other, query.SortBy), other.ProductID, query) < 0
+	})
+
+	page.Products = make([]domain.Product, 0)
+	for _, document := range documents {
+		if after != nil && !after(document.product) {
+			continue
+		}
+
+		if query.Limit > 0 && len(page.Products) == query.Limit {
+			last := page.Products[len(page.Products)-1]
+			page.NextCursor = domain.PageCursor{
+				SortValue: sortValue(last, query.SortBy),
+				ProductID: last.ProductID,
+			}.Encode()
+			break
+		}
+		page.Products = append(page.Products, readProduct(document.product))
+	}
+	return page, nil
+}
+
+// sortValue reads the value of sorted field from the product
+func sortValue(product domain.Product, sortBy string) string {
+	switch sortBy {
+	case domain.SortByName:
+		return product.Name
+	case domain.SortByRetiredAt:
+		return domain.FormatSortTime(product.RetiredAt)
+	}
+	return product.ProductID
+}
+
+// compareSorted compares product to the product having sort value value
+// and productID in the requested sort order, breaking ties by productId
+func compareSorted(product domain.Product, value string, productID string, query domain.ProductQuery) int {
+	compare := 0
+	switch query.SortBy {
+	case domain.SortByName:
+		compare = compareStrings(product.Name, value)
+	case domain.SortByRetiredAt:
+		// formatted times do not sort as strings, as
+		// fractions of seconds drop trailing zeros
+		retiredAt, _ := domain.ParseSortTime(sortValue(product, query.SortBy))
+		other, _ := domain.ParseSortTime(value)
+		compare = compareTimes(retiredAt, other)
+	}
+	if compare == 0 {
+		compare = compareStrings(product.ProductID, productID)
+	}
+
+	if query.SortOrder == domain.Descending {
+		return -compare
+	}
+	return compare
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+func compareStrings(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Get queries a single product identified by productID
+func (repo *ProductMemoryRepo) Get(ctx context.Context, productID string) (domain.Product, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	document, ok := repo.live(productID)
+	if !ok {
+		return domain.Product{}, domain.ErrResourceNotFound
+	}
+	return readProduct(document.product), nil
+}
+
+// GetBySKU queries the single product having variant of sku
+func (repo *ProductMemoryRepo) GetBySKU(ctx context.Context, sku string) (domain.Product, error) {
+	return repo.getLive(func(product domain.Product) bool {
+		return contains(variantSKUs(product.Variants), sku)
+	})
+}
+
+// GetByGTIN queries the single product having gtin
+func (repo *ProductMemoryRepo) GetByGTIN(ctx context.Context, gtin string) (domain.Product, error) {
+	return repo.getLive(func(product domain.Product) bool {
+		return contains(product.GTINs, gtin)
+	})
+}
+
+// getLive queries the first product out of trash matching match
+func (repo *ProductMemoryRepo) getLive(match func(product domain.Product) bool) (domain.Product, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	documents := repo.sorted(func(document *productDocument) bool {
+		return document.deletedAt == nil && match(document.product)
+	})
+
+	if len(documents) == 0 {
+		return domain.Product{}, domain.ErrResourceNotFound
+	}
+	return readProduct(documents[0].product), nil
+}
+
+// GetBySlug queries the single product whose current or previous slug
+// is slug, preferring the current one. Trashed products are included,
+// as their slugs stay reserved until they are purged
+func (repo *ProductMemoryRepo) GetBySlug(ctx context.Context, slug string) (domain.Product, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	documents := repo.sorted(func(document *productDocument) bool {
+		return document.product.Slug == slug || contains(document.product.PreviousSlugs, slug)
+	})
+
+	for _, document := range documents {
+		if document.product.Slug == slug {
+			return readProduct(document.product), nil
+		}
+	}
+
+	if len(documents) == 0 {
+		return domain.Product{}, domain.ErrResourceNotFound
+	}
+	return readProduct(documents[0].product), nil
+}
+
+// Create stores a single product at version 1
+func (repo *ProductMemoryRepo) Create(ctx context.Context, product domain.Product) error {
+	stored := copyProduct(product)
+	stored.Version = 1
+	repository.DefaultLists(&stored)
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.products[product.ProductID]; ok {
+		return domain.ErrConflict
+	}
+
+	if err := repo.checkUnique(stored); err != nil {
+		return err
+	}
+
+	repo.products[product.ProductID] = &productDocument{product: stored}
+	return nil
+}
+
+// Upsert creates published products that do not exist yet and
+// updates attributes of existing products, leaving their lifecycle,
+// translations and variants as they are. Upserting a trashed product
+// takes it out of trash. Products conflicting with others are listed
+// as failures once the rest are written, like an unordered bulk write
+func (repo *ProductMemoryRepo) Upsert(ctx context.Context, products []domain.Product) (domain.UpsertResult, error) {
+	var result domain.UpsertResult
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for i, product := range products {
+		content := repository.Content(copyProduct(product))
+		repository.DefaultLists(&content)
+
+		var stored domain.Product
+		document, exists := repo.products[product.ProductID]
+		if exists {
+			stored = copyProduct(document.product)
+			repository.SetContent(&stored, content)
+			stored.ParsedIngredients = content.ParsedIngredients
+			stored.Version++
+		} else {
+			stored = content
+			stored.Status = domain.StatusPublished
+			stored.Version = 1
+		}
+
+		if err := repo.checkUnique(stored); err != nil {
+			result.Failures = append(result.Failures, domain.UpsertFailure{Index: i, Err: err})
+			continue
+		}
+
+		if exists {
+			result.Updated++
+		} else {
+			result.Created++
+		}
+		repo.products[product.ProductID] = &productDocument{product: stored}
+	}
+	return result, nil
+}
+
+// Stream calls fn on every filtered product ordered by productId.
+// Products are read at once, so that fn may write to the repository.
+// Streaming stops at the first error returned by fn
+func (repo *ProductMemoryRepo) Stream(ctx context.Context, filter domain.ProductFilter, fn func(domain.Product) error) error {
+	repo.mu.RLock()
+	documents := repo.sorted(func(document *productDocument) bool {
+		return matchesFilter(document, filter)
+	})
+
+	products := make([]domain.Product, 0, len(documents))
+	for _, document := range documents {
+		products = append(products, readProduct(document.product))
+	}
+	repo.mu.RUnlock()
+
+	for _, product := range products {
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Update modifies non-empty attributes of a single product. Given
+// non-zero product version, the product must be at that version
+func (repo *ProductMemoryRepo) Update(ctx context.Context, productID string, product domain.Product) error {
+	content := repository.Content(copyProduct(product))
+
+	return repo.write(productID, product.Version, func(stored *domain.Product) error {
+		repository.SetContent(stored, content)
+
+		// parsed ingredients are cleared once
+		// ingredients are updated to none
+		if content.Ingredients != nil && len(content.ParsedIngredients) == 0 {
+			stored.ParsedIngredients = nil
+		}
+		return repo.checkUnique(*stored)
+	})
+}
+
+// Replace overwrites every attribute of a single product, unlike
+// Update, attributes that are empty in product are cleared. Slug
+// is left as it is when empty. Given non-zero product version,
+// the product must be at that version
+func (repo *ProductMemoryRepo) Replace(ctx context.Context, productID string, product domain.Product) error {
+	content := copyProduct(product)
+
+	return repo.write(productID, product.Version, func(stored *domain.Product) error {
+		repository.ReplaceContent(stored, content)
+		return repo.checkUnique(*stored)
+	})
+}
+
+// UpdateStatus overwrites lifecycle attributes of a single product.
+// Given non-zero product version, the product must be at that version
+func (repo *ProductMemoryRepo) UpdateStatus(ctx context.Context, productID string, product domain.Product) error {
+	lifecycle := copyProduct(product)
+
+	return repo.write(productID, product.Version, func(stored *domain.Product) error {
+		repository.SetLifecycle(stored, lifecycle)
+		return nil
+	})
+}
+
+// UpdateTranslations overwrites translations of a single product.
+// Given non-zero product version, the product must be at that version
+func (repo *ProductMemoryRepo) UpdateTranslations(ctx context.Context, productID string, product domain.Product) error {
+	translations := copyProduct(product).Translations
+
+	return repo.write(productID, product.Version, func(stored *domain.Product) error {
+		stored.Translations = translations
+		return nil
+	})
+}
+
+// UpdateVariants overwrites variants of a single product. Given
+// non-zero product version, the product must be at that version.
+// Variant SKU taken by another product is a conflict
+func (repo *ProductMemoryRepo) UpdateVariants(ctx context.Context, productID string, product domain.Product) error {
+	variants := copyProduct(product).Variants
+
+	return repo.write(productID, product.Version, func(stored *domain.Product) error {
+		stored.Variants = variants
+		if err := repo.checkUnique(*stored); err == domain.ErrConflict {
+			return domain.ErrDuplicateSKU
+		} else if err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// write applies modify to copy of a single product out of trash,
+// which replaces the product at the next version unless modify
+// fails. Given non-zero version, the product must be at that version
+func (repo *ProductMemoryRepo) write(productID string, version int64, modify func(stored *domain.Product) error) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	document, err := repo.writable(productID, version)
+	if err != nil {
+		return err
+	}
+
+	stored := copyProduct(document.product)
+	if err = modify(&stored); err != nil {
+		return err
+	}
+
+	stored.Version++
+	document.product = stored
+	return nil
+}
+
+// FetchScheduled queries products with a status change
+// scheduled at or before the given time
+func (repo *ProductMemoryRepo) FetchScheduled(ctx context.Context, before time.Time) ([]domain.Product, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	documents := repo.sorted(func(document *productDocument) bool {
+		scheduledAt := document.product.ScheduledAt
+		return document.deletedAt == nil && scheduledAt != nil && !scheduledAt.After(before)
+	})
+
+	products := make([]domain.Product, 0, len(documents))
+	for _, document := range documents {
+		products = append(products, readProduct(document.product))
+	}
+	return products, nil
+}
+
+// Delete moves a single product to trash, marking it with the time
+// of deletion and who deleted it. Given non-zero version, the
+// product must be at that version
+func (repo *ProductMemoryRepo) Delete(ctx context.Context, productID string, version int64, deletedBy string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	document, err := repo.writable(productID, version)
+	if err != nil {
+		return err
+	}
+
+	deletedAt := time.Now().UTC()
+	document.deletedAt = &deletedAt
+	document.deletedBy = deletedBy
+	document.product.Version++
+	return nil
+}
+
+// FetchTrash queries all trashed products, latest deleted first
+func (repo *ProductMemoryRepo) FetchTrash(ctx context.Context) ([]domain.TrashedProduct, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	documents := repo.sorted(func(document *productDocument) bool {
+		return document.deletedAt != nil
+	})
+
+	sort.SliceStable(documents, func(i, j int) bool {
+		return documents[i].deletedAt.After(*documents[j].deletedAt)
+	})
+	return newTrash(documents), nil
+}
+
+// FetchTrashBefore queries up to limit products trashed
+// before deletedBefore, earliest deleted first
+func (repo *ProductMemoryRepo) FetchTrashBefore(ctx context.Context, deletedBefore time.Time, limit int) ([]domain.TrashedProduct, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	documents := repo.sorted(func(document *productDocument) bool {
+		return document.deletedAt != nil && document.deletedAt.Before(deletedBefore)
+	})
+
+	sort.SliceStable(documents, func(i, j int) bool {
+		return documents[i].deletedAt.Before(*documents[j].deletedAt)
+	})
+
+	if len(documents) > limit {
+		documents = documents[:limit]
+	}
+	return newTrash(documents), nil
+}
+
+// GetTrashed queries a single trashed product
+func (repo *ProductMemoryRepo) GetTrashed(ctx context.Context, productID string) (domain.TrashedProduct, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	document, ok := repo.products[productID]
+	if !ok || document.deletedAt == nil {
+		return domain.TrashedProduct{}, domain.ErrResourceNotFound
+	}
+	return newTrash([]*productDocument{document})[0], nil
+}
+
+// newTrash reads trashed products of documents
+func newTrash(documents []*productDocument) []domain.TrashedProduct {
+	trash := make([]domain.TrashedProduct, 0, len(documents))
+	for _, document := range documents {
+		trash = append(trash, domain.TrashedProduct{
+			Product:   readProduct(document.product),
+			DeletedAt: *document.deletedAt,
+			DeletedBy: document.deletedBy,
+		})
+	}
+	return trash
+}
+
+// Restore takes a single product out of trash
+func (repo *ProductMemoryRepo) Restore(ctx context.Context, productID string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	document, ok := repo.products[productID]
+	if !ok || document.deletedAt == nil {
+		return domain.ErrResourceNotFound
+	}
+
+	document.deletedAt = nil
+	document.deletedBy = ""
+	document.product.Version++
+	return nil
+}
+
+// Purge removes a single trashed product for good
+func (repo *ProductMemoryRepo) Purge(ctx context.Context, productID string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	document, ok := repo.products[productID]
+	if !ok || document.deletedAt == nil {
+		return domain.ErrResourceNotFound
+	}
+
+	delete(repo.products, productID)
+	return nil
+}
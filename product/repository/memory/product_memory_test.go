@@ -0,0 +1,235 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/domain/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+func createMockProduct(productID string, name string) domain.Product {
+	ingredients := []string{"cream", "skim milk", "toffee"}
+	sourcing := []string{"Fairtrade"}
+	return domain.Product{
+		ProductID:      productID,
+		Name:           name,
+		Slug:           "slug-" + productID,
+		Description:    "Buttery toffee in vanilla",
+		Ingredients:    &ingredients,
+		SourcingValues: &sourcing,
+		Status:         domain.StatusPublished,
+	}
+}
+
+func TestCreateProduct(t *testing.T) {
+	ctx := context.TODO()
+	repo := NewProductRepo()
+
+	t.Run("Create-success", func(t *testing.T) {
+		product := createMockProduct("646", "Vanilla Toffee Bar Crunch")
+		assert.NoError(t, repo.Create(ctx, product))
+
+		stored, err := repo.Get(ctx, "646")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), stored.Version)
+		assert.Equal(t, product.Name, stored.Name)
+
+		// stored product shares nothing with callers
+		(*product.Ingredients)[0] = "changed"
+		(*stored.Ingredients)[1] = "changed"
+		stored, _ = repo.Get(ctx, "646")
+		assert.Equal(t, []string{"cream", "skim milk", "toffee"}, *stored.Ingredients)
+	})
+
+	t.Run("Create-conflict", func(t *testing.T) {
+		err := repo.Create(ctx, createMockProduct("646", "Another"))
+		assert.Equal(t, domain.ErrConflict, err)
+	})
+
+	t.Run("Create-duplicate-gtin-and-slug", func(t *testing.T) {
+		product := createMockProduct("647", "Cherry Garcia")
+		product.GTINs = []string{"0076840100477"}
+		assert.NoError(t, repo.Create(ctx, product))
+
+		duplicate := createMockProduct("648", "Chunky Monkey")
+		duplicate.GTINs = []string{"0076840100477"}
+		assert.Equal(t, domain.ErrDuplicateGTIN, repo.Create(ctx, duplicate))
+
+		duplicate = createMockProduct("648", "Chunky Monkey")
+		duplicate.Slug = "slug-647"
+		assert.Equal(t, domain.ErrDuplicateSlug, repo.Create(ctx, duplicate))
+	})
+
+	t.Run("Get-not-found", func(t *testing.T) {
+		_, err := repo.Get(ctx, "999")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+}
+
+func TestUpdateProduct(t *testing.T) {
+	ctx := context.TODO()
+	repo := NewProductRepo()
+	repo.Create(ctx, createMockProduct("646", "Vanilla Toffee Bar Crunch"))
+
+	t.Run("Update-partial", func(t *testing.T) {
+		err := repo.Update(ctx, "646", domain.Product{Story: "Toffee was never so crunchy"})
+		assert.NoError(t, err)
+
+		stored, _ := repo.Get(ctx, "646")
+		assert.Equal(t, int64(2), stored.Version)
+		assert.Equal(t, "Vanilla Toffee Bar Crunch", stored.Name)
+		assert.Equal(t, "Toffee was never so crunchy", stored.Story)
+	})
+
+	t.Run("Update-precondition-failed", func(t *testing.T) {
+		err := repo.Update(ctx, "646", domain.Product{Name: "Stale", Version: 1})
+		assert.Equal(t, domain.ErrPreconditionFailed, err)
+	})
+
+	t.Run("Update-not-found", func(t *testing.T) {
+		err := repo.Update(ctx, "999", domain.Product{Name: "Missing"})
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+
+	t.Run("Replace-clears", func(t *testing.T) {
+		err := repo.Replace(ctx, "646", domain.Product{Name: "Vanilla Toffee", Version: 2})
+		assert.NoError(t, err)
+
+		stored, _ := repo.Get(ctx, "646")
+		assert.Equal(t, int64(3), stored.Version)
+		assert.Empty(t, stored.Story)
+		assert.Empty(t, *stored.Ingredients)
+		assert.Equal(t, "slug-646", stored.Slug)
+	})
+
+	t.Run("UpdateVariants-duplicate-sku", func(t *testing.T) {
+		repo.Create(ctx, createMockProduct("647", "Cherry Garcia"))
+		variants := []domain.ProductVariant{{SKU: "BJ-646-P", Format: "pint"}}
+
+		assert.NoError(t, repo.UpdateVariants(ctx, "646", domain.Product{Variants: variants}))
+		assert.Equal(t, domain.ErrDuplicateSKU, repo.UpdateVariants(ctx, "647", domain.Product{Variants: variants}))
+
+		stored, err := repo.GetBySKU(ctx, "BJ-646-P")
+		assert.NoError(t, err)
+		assert.Equal(t, "646", stored.ProductID)
+	})
+
+	t.Run("Upsert-counts", func(t *testing.T) {
+		result, err := repo.Upsert(ctx, []domain.Product{
+			{ProductID: "646", Name: "Vanilla Toffee Bar Crunch", Slug: "slug-646"},
+			{ProductID: "700", Name: "Phish Food", Slug: "phish-food"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, domain.UpsertResult{Created: 1, Updated: 1}, result)
+
+		stored, _ := repo.Get(ctx, "646")
+		assert.Len(t, stored.Variants, 1)
+	})
+}
+
+func TestTrashProduct(t *testing.T) {
+	ctx := context.TODO()
+	repo := NewProductRepo()
+	repo.Create(ctx, createMockProduct("646", "Vanilla Toffee Bar Crunch"))
+
+	assert.Equal(t, domain.ErrPreconditionFailed, repo.Delete(ctx, "646", 2, "ben"))
+	assert.NoError(t, repo.Delete(ctx, "646", 1, "ben"))
+	assert.Equal(t, domain.ErrResourceNotFound, repo.Delete(ctx, "646", 0, "ben"))
+
+	_, err := repo.Get(ctx, "646")
+	assert.Equal(t, domain.ErrResourceNotFound, err)
+
+	// trashed products keep their productId and slug
+	assert.Equal(t, domain.ErrConflict, repo.Create(ctx, createMockProduct("646", "Again")))
+	bySlug, err := repo.GetBySlug(ctx, "slug-646")
+	assert.NoError(t, err)
+	assert.Equal(t, "646", bySlug.ProductID)
+
+	trash, _ := repo.FetchTrash(ctx)
+	assert.Len(t, trash, 1)
+	assert.Equal(t, "ben", trash[0].DeletedBy)
+
+	assert.NoError(t, repo.Restore(ctx, "646"))
+	assert.Equal(t, domain.ErrResourceNotFound, repo.Restore(ctx, "646"))
+	assert.Equal(t, domain.ErrResourceNotFound, repo.Purge(ctx, "646"))
+
+	restored, _ := repo.Get(ctx, "646")
+	assert.Equal(t, int64(3), restored.Version)
+
+	repo.Delete(ctx, "646", 0, "ben")
+	assert.NoError(t, repo.Purge(ctx, "646"))
+	_, err = repo.GetBySlug(ctx, "slug-646")
+	assert.Equal(t, domain.ErrResourceNotFound, err)
+}
+
+func TestFetchProducts(t *testing.T) {
+	ctx := context.TODO()
+	repo := NewProductRepo()
+	for i, name := range []string{"Cherry Garcia", "Americone Dream", "Phish Food", "Half Baked", "Chunky Monkey"} {
+		repo.Create(ctx, createMockProduct(strconv.Itoa(646+i), name))
+	}
+
+	query := domain.ProductQuery{Limit: 2, SortBy: domain.SortByName, SortOrder: domain.Ascending}
+
+	var names []string
+	for {
+		page, err := repo.Fetch(ctx, query)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), page.TotalCount)
+
+		for _, product := range page.Products {
+			names = append(names, product.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []string{"Americone Dream", "Cherry Garcia", "Chunky Monkey", "Half Baked", "Phish Food"}, names)
+
+	query.Cursor = "not-a-cursor"
+	_, err := repo.Fetch(ctx, query)
+	assert.Equal(t, domain.ErrBadParamInput, err)
+
+	results, err := repo.Search(ctx, "monkey -cherry", 10, domain.ProductFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "Chunky Monkey", results[0].Product.Name)
+}
+
+func TestConcurrentWriters(t *testing.T) {
+	ctx := context.TODO()
+	repo := NewProductRepo()
+	repo.Create(ctx, createMockProduct("646", "Vanilla Toffee Bar Crunch"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			repo.Update(ctx, "646", domain.Product{Story: strconv.Itoa(i)})
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			repo.Create(ctx, createMockProduct(strconv.Itoa(700+i), "Flavour "+strconv.Itoa(i)))
+			repo.Get(ctx, "646")
+		}(i)
+	}
+	wg.Wait()
+
+	stored, _ := repo.Get(ctx, "646")
+	assert.Equal(t, int64(21), stored.Version)
+
+	page, _ := repo.Fetch(ctx, domain.ProductQuery{Limit: 100, SortBy: domain.SortByProductID, SortOrder: domain.Ascending})
+	assert.Equal(t, int64(21), page.TotalCount)
+}
+
+func TestProductRepositorySuite(t *testing.T) {
+	repotest.RunProductRepositorySuite(t, func(t *testing.T) domain.ProductRepository {
+		return NewProductRepo()
+	})
+}
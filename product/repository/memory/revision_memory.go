@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// ProductRevisionMemoryRepo keeps revisions of products in process
+// memory, each product's in the order they were numbered
+type ProductRevisionMemoryRepo struct {
+	mu        sync.RWMutex
+	revisions map[string][]domain.ProductRevision
+}
+
+// NewProductRevisionRepo creates an empty in-memory revision repository
+func NewProductRevisionRepo() *ProductRevisionMemoryRepo {
+	return &ProductRevisionMemoryRepo{revisions: make(map[string][]domain.ProductRevision)}
+}
+
+// Fetch queries all revisions of a product, latest first
+func (repo *ProductRevisionMemoryRepo) Fetch(ctx context.Context, productID string) ([]domain.ProductRevision, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	stored := repo.revisions[productID]
+	revisions := make([]domain.ProductRevision, 0, len(stored))
+	for i := len(stored) - 1; i >= 0; i-- {
+		revisions = append(revisions, copyRevision(stored[i]))
+	}
+	return revisions, nil
+}
+
+// Get queries a single revision of a product
+func (repo *ProductRevisionMemoryRepo) Get(ctx context.Context, productID string, revision int64) (domain.ProductRevision, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	for _, stored := range repo.revisions[productID] {
+		if stored.Revision == revision {
+			return copyRevision(stored), nil
+		}
+	}
+	return domain.ProductRevision{}, domain.ErrResourceNotFound
+}
+
+// Create stores revision numbered right after the latest
+// revision of the product and returns the number taken
+func (repo *ProductRevisionMemoryRepo) Create(ctx context.Context, revision domain.ProductRevision) (int64, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	stored := copyRevision(revision)
+	stored.Revision = int64(len(repo.revisions[revision.ProductID]) + 1)
+	repo.revisions[revision.ProductID] = append(repo.revisions[revision.ProductID], stored)
+	return stored.Revision, nil
+}
+
+// copyRevision deep copies revision and its product snapshot
+func copyRevision(revision domain.ProductRevision) domain.ProductRevision {
+	copied := revision
+	copied.Changes = append([]domain.FieldChange(nil), revision.Changes...)
+	copied.Product = readProduct(revision.Product)
+	return copied
+}
@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// minProductID keeps allocated productIds
+// within the minimum length of 3 digits
+const minProductID = 100
+
+// ProductSequenceMemoryRepo allocates productIds from a counter
+// kept in process memory
+type ProductSequenceMemoryRepo struct {
+	mu    sync.Mutex
+	value int64
+}
+
+// NewProductSequenceRepo creates sequence of productIds, which
+// continues after the greatest numeric productId stored in repo
+func NewProductSequenceRepo(repo *ProductMemoryRepo) *ProductSequenceMemoryRepo {
+	sequence := &ProductSequenceMemoryRepo{value: minProductID - 1}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	for productID := range repo.products {
+		value, err := strconv.ParseInt(productID, 10, 64)
+		if err == nil && value > sequence.value {
+			sequence.value = value
+		}
+	}
+	return sequence
+}
+
+// NextProductID takes the next value of the sequence
+func (sequence *ProductSequenceMemoryRepo) NextProductID(ctx context.Context) (string, error) {
+	sequence.mu.Lock()
+	defer sequence.mu.Unlock()
+
+	sequence.value++
+	return strconv.FormatInt(sequence.value, 10), nil
+}
@@ -0,0 +1,184 @@
+package mongo
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	mongoHelper "github.com/iqdf/benjerry-service/common/repository/mongo"
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/product/repository"
+)
+
+// facetModel is the result of facet counting pipeline
+type facetModel struct {
+	SourcingValues        []facetCountModel  `bson:"sourcing_values"`
+	DietaryCertifications []facetCountModel  `bson:"dietary_certifications"`
+	Allergens             []map[string]int64 `bson:"allergens"`
+}
+
+type facetCountModel struct {
+	Value string `bson:"_id"`
+	Count int64  `bson:"count"`
+}
+
+// productFilter builds query document matching products
+// out of trash that satisfy every condition of the filter
+func productFilter(filter domain.ProductFilter) bson.M {
+	var conditions = bson.A{notDeleted()}
+
+	if len(filter.SourcingValues) > 0 {
+		operator := "$all"
+		if filter.SourcingMatch == domain.MatchAny {
+			operator = "$in"
+		}
+		conditions = append(conditions, bson.M{
+			"sourcing_values": bson.M{operator: filter.SourcingValues},
+		})
+	}
+
+	if len(filter.DietaryCertifications) > 0 {
+		conditions = append(conditions, bson.M{
+			"dietary_certifications": bson.M{"$in": filter.DietaryCertifications},
+		})
+	}
+
+	if len(filter.ExcludeAllergens) > 0 {
+		// products declaring no allergens fall back to allergy info
+		pattern := allergenPattern(filter.ExcludeAllergens...)
+		conditions = append(conditions, bson.M{"$nor": bson.A{
+			bson.M{"allergens.contains": bson.M{"$in": filter.ExcludeAllergens}},
+			bson.M{"allergens.mayContain": bson.M{"$in": filter.ExcludeAllergens}},
+			bson.M{"allergens": nil, "allergy_info": primitive.Regex{Pattern: pattern, Options: "i"}},
+		}})
+	}
+
+	if len(filter.Statuses) > 0 {
+		statuses := make(bson.A, 0, len(filter.Statuses)+1)
+		for _, status := range filter.Statuses {
+			statuses = append(statuses, status)
+			// products stored without status are published
+			if status == domain.StatusPublished {
+				statuses = append(statuses, nil)
+			}
+		}
+		conditions = append(conditions, bson.M{"status": bson.M{"$in": statuses}})
+	}
+
+	if filter.Region != "" {
+		// products stored without regions are sold everywhere
+		conditions = append(conditions, bson.M{"regions": bson.M{"$in": bson.A{filter.Region, nil}}})
+	}
+
+	if len(conditions) == 1 {
+		return notDeleted()
+	}
+	return bson.M{"$and": conditions}
+}
+
+// allergenPattern matches any of the allergens as a whole word
+// in allergy info, in either singular or plural form, see
+// repository.AllergenWords
+func allergenPattern(allergens ...string) string {
+	return `\b(` + strings.Join(repository.AllergenWords(allergens), "|") + `)\b`
+}
+
+// CountFacets counts products matching filter by every sourcing
+// value, dietary certification and known allergen they contain
+// or may contain. Each group is counted without the condition of
+// filter on its own dimension, see repository.FacetFiltersOf
+func (repo *ProductMongoRepo) CountFacets(ctx context.Context, filter domain.ProductFilter) (domain.ProductFacets, error) {
+	var models []facetModel
+	filters := repository.FacetFiltersOf(filter)
+
+	// products are first matched by the conditions every group
+	// shares, then each group by the conditions of its own
+	shared := filters.Sourcing
+	shared.DietaryCertifications = nil
+	shared.ExcludeAllergens = nil
+
+	// products are counted by allergens they contain or may contain,
+	// products declaring none by matching allergens against allergy
+	// info, which is free text
+	allergenCounts := bson.M{"_id": nil}
+	for i, allergen := range domain.KnownAllergens {
+		allergenCounts["a"+strconv.Itoa(i)] = bson.M{"$sum": bson.M{"$cond": bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"$in": bson.A{allergen, bson.M{"$ifNull": bson.A{"$allergens.contains", bson.A{}}}}},
+				bson.M{"$in": bson.A{allergen, bson.M{"$ifNull": bson.A{"$allergens.mayContain", bson.A{}}}}},
+				bson.M{"$and": bson.A{
+					bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$allergens", nil}}, nil}},
+					bson.M{"$regexMatch": bson.M{
+						"input":   bson.M{"$ifNull": bson.A{"$allergy_info", ""}},
+						"regex":   allergenPattern(allergen),
+						"options": "i",
+					}},
+				}},
+			}},
+			1,
+			0,
+		}}}
+	}
+
+	countSorting := bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}
+	pipeline := bson.A{
+		bson.M{"$match": productFilter(shared)},
+		bson.M{"$facet": bson.M{
+			"sourcing_values": bson.A{
+				bson.M{"$match": productFilter(filters.Sourcing)},
+				bson.M{"$unwind": "$sourcing_values"},
+				bson.M{"$group": bson.M{"_id": "$sourcing_values", "count": bson.M{"$sum": 1}}},
+				countSorting,
+			},
+			"dietary_certifications": bson.A{
+				bson.M{"$match": productFilter(filters.Dietary)},
+				bson.M{"$match": bson.M{"dietary_certifications": bson.M{"$nin": bson.A{"", nil}}}},
+				bson.M{"$group": bson.M{"_id": "$dietary_certifications", "count": bson.M{"$sum": 1}}},
+				countSorting,
+			},
+			"allergens": bson.A{
+				bson.M{"$match": productFilter(filters.Allergens)},
+				bson.M{"$group": allergenCounts},
+				bson.M{"$project": bson.M{"_id": 0}},
+			},
+		}},
+	}
+
+	collection := repo.db.Collection(collectionName)
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return domain.ProductFacets{}, mongoHelper.TranslateError(err)
+	}
+
+	if err = cursor.All(ctx, &models); err != nil {
+		return domain.ProductFacets{}, mongoHelper.TranslateError(err)
+	}
+
+	var facets domain.ProductFacets
+	if len(models) == 0 {
+		return facets, nil
+	}
+
+	for _, count := range models[0].SourcingValues {
+		facets.SourcingValues = append(facets.SourcingValues, domain.FacetCount(count))
+	}
+	for _, count := range models[0].DietaryCertifications {
+		facets.DietaryCertifications = append(facets.DietaryCertifications, domain.FacetCount(count))
+	}
+
+	// every known allergen is listed, including zero counts
+	var allergenModel map[string]int64
+	if len(models[0].Allergens) > 0 {
+		allergenModel = models[0].Allergens[0]
+	}
+	for i, allergen := range domain.KnownAllergens {
+		facets.Allergens = append(facets.Allergens, domain.FacetCount{
+			Value: allergen,
+			Count: allergenModel["a"+strconv.Itoa(i)],
+		})
+	}
+	return facets, nil
+}
@@ -2,12 +2,13 @@ package mongo
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"go.mongodb.org/mongo-driver/x/bsonx"
 
 	mongoHelper "github.com/iqdf/benjerry-service/common/repository/mongo"
 	"github.com/iqdf/benjerry-service/domain"
@@ -15,19 +16,133 @@ import (
 
 const collectionName = "IceCream" // products
 
+// gtinIndexName names unique index of GTINs, created by migrations, so that
+// conflicting GTINs are told apart from conflicting productIds
+const gtinIndexName = "product_gtins"
+
+// slugIndexName names unique index of slugs, created by migrations, so that
+// conflicting slugs are told apart from conflicting productIds
+const slugIndexName = "product_slug"
+
+// streamBatchSize is the number of documents
+// fetched per round trip while streaming
+const streamBatchSize = 100
+
 // ProductModel ...
 type ProductModel struct {
-	ID                   primitive.ObjectID `bson:"_id,omitempty"`
-	ProductID            string             `bson:"productId,omitempty"`
-	Name                 string             `bson:"name,omitempty"`
-	ImageClosedURL       string             `bson:"imageclosed_url,omitempty"`
-	ImageOpenURL         string             `bson:"imageopen_url,omitempty"`
-	Description          string             `bson:"description,omitempty"`
-	Story                string             `bson:"story,omitempty"`
-	SourcingValues       *[]string          `bson:"sourcing_values,omitempty"`
-	Ingredients          *[]string          `bson:"ingredients,omitempty"`
-	AllergyInfo          string             `bson:"allergy_info,omitempty"`
-	DietaryCertification string             `bson:"dietary_certifications,omitempty"`
+	ID                   primitive.ObjectID          `bson:"_id,omitempty"`
+	ProductID            string                      `bson:"productId,omitempty"`
+	Version              int64                       `bson:"version,omitempty"`
+	Name                 string                      `bson:"name,omitempty"`
+	Slug                 string                      `bson:"slug,omitempty"`
+	PreviousSlugs        []string                    `bson:"previousSlugs,omitempty"`
+	ImageClosedURL       string                      `bson:"imageclosed_url,omitempty"`
+	ImageOpenURL         string                      `bson:"imageopen_url,omitempty"`
+	Description          string                      `bson:"description,omitempty"`
+	Story                string                      `bson:"story,omitempty"`
+	SourcingValues       *[]string                   `bson:"sourcing_values,omitempty"`
+	Ingredients          *[]string                   `bson:"ingredients,omitempty"`
+	ParsedIngredients    []IngredientModel           `bson:"parsedIngredients,omitempty"`
+	AllergyInfo          string                      `bson:"allergy_info,omitempty"`
+	DietaryCertification string                      `bson:"dietary_certifications,omitempty"`
+	Allergens            *AllergensModel             `bson:"allergens,omitempty"`
+	AllergenCheck        *AllergenCheckModel         `bson:"allergenCheck,omitempty"`
+	Nutrition            *NutritionModel             `bson:"nutrition,omitempty"`
+	Regions              []string                    `bson:"regions,omitempty"`
+	RegionOverrides      map[string]OverrideModel    `bson:"regionOverrides,omitempty"`
+	GTINs                []string                    `bson:"gtins,omitempty"`
+	Status               string                      `bson:"status,omitempty"`
+	RetiredAt            *time.Time                  `bson:"retiredAt,omitempty"`
+	Epitaph              string                      `bson:"epitaph,omitempty"`
+	ScheduledStatus      string                      `bson:"scheduledStatus,omitempty"`
+	ScheduledAt          *time.Time                  `bson:"scheduledAt,omitempty"`
+	Translations         map[string]TranslationModel `bson:"translations,omitempty"`
+	Variants             []VariantModel              `bson:"variants,omitempty"`
+	DeletedAt            *time.Time                  `bson:"deletedAt,omitempty"`
+	DeletedBy            string                      `bson:"deletedBy,omitempty"`
+}
+
+// OverrideModel is attributes of a product that differ in one region
+type OverrideModel struct {
+	ImageClosedURL       string `bson:"imageclosed_url,omitempty"`
+	ImageOpenURL         string `bson:"imageopen_url,omitempty"`
+	AllergyInfo          string `bson:"allergy_info,omitempty"`
+	DietaryCertification string `bson:"dietary_certifications,omitempty"`
+}
+
+// IngredientModel is an ingredient parsed from ingredients
+// of a product, stored alongside them in normalized form
+type IngredientModel struct {
+	Name        string            `bson:"name"`
+	Qualifiers  []string          `bson:"qualifiers,omitempty"`
+	Ingredients []IngredientModel `bson:"ingredients,omitempty"`
+}
+
+// AllergensModel is allergens declared by a product
+type AllergensModel struct {
+	Contains   []string `bson:"contains,omitempty"`
+	MayContain []string `bson:"mayContain,omitempty"`
+}
+
+// AllergenCheckModel is disagreement between allergens
+// declared by a product and detected in its ingredients
+type AllergenCheckModel struct {
+	Undeclared []string `bson:"undeclared,omitempty"`
+	Undetected []string `bson:"undetected,omitempty"`
+}
+
+// NutritionModel is nutrition facts of a product, serving size
+// in grams, serving volume in millilitres
+type NutritionModel struct {
+	ServingSize   float64        `bson:"servingSize"`
+	ServingVolume float64        `bson:"servingVolume,omitempty"`
+	ServingLabel  string         `bson:"servingLabel,omitempty"`
+	Per100g       NutrientsModel `bson:"per100g"`
+}
+
+// NutrientsModel is nutrients in 100 grams of a product,
+// calories in kilocalories and other nutrients in grams
+type NutrientsModel struct {
+	Calories          float64 `bson:"calories"`
+	TotalFat          float64 `bson:"totalFat"`
+	SaturatedFat      float64 `bson:"saturatedFat"`
+	TransFat          float64 `bson:"transFat"`
+	Cholesterol       float64 `bson:"cholesterol"`
+	Sodium            float64 `bson:"sodium"`
+	TotalCarbohydrate float64 `bson:"totalCarbohydrate"`
+	DietaryFiber      float64 `bson:"dietaryFiber"`
+	TotalSugars       float64 `bson:"totalSugars"`
+	AddedSugars       float64 `bson:"addedSugars"`
+	Protein           float64 `bson:"protein"`
+	VitaminD          float64 `bson:"vitaminD"`
+	Calcium           float64 `bson:"calcium"`
+	Iron              float64 `bson:"iron"`
+	Potassium         float64 `bson:"potassium"`
+}
+
+// VariantModel is a product sold in one format and size
+type VariantModel struct {
+	SKU            string    `bson:"sku"`
+	Format         string    `bson:"format"`
+	Size           string    `bson:"size,omitempty"`
+	ImageClosedURL string    `bson:"imageclosed_url,omitempty"`
+	ImageOpenURL   string    `bson:"imageopen_url,omitempty"`
+	Ingredients    *[]string `bson:"ingredients,omitempty"`
+}
+
+// TranslationModel is text of a product in one locale
+type TranslationModel struct {
+	Name        string `bson:"name,omitempty"`
+	Description string `bson:"description,omitempty"`
+	Story       string `bson:"story,omitempty"`
+	AllergyInfo string `bson:"allergy_info,omitempty"`
+}
+
+// scoredProductModel is a product document
+// projected with its full-text search score
+type scoredProductModel struct {
+	ProductModel `bson:",inline"`
+	Score        float64 `bson:"score"`
 }
 
 // ProductMongoRepo ...
@@ -41,60 +156,484 @@ type ProductMongoRepo struct {
 func modelFromProduct(product domain.Product) ProductModel {
 	return ProductModel{
 		ProductID:            product.ProductID,
+		Version:              product.Version,
 		Name:                 product.Name,
+		Slug:                 product.Slug,
+		PreviousSlugs:        product.PreviousSlugs,
 		ImageClosedURL:       product.ImageClosedURL,
 		ImageOpenURL:         product.ImageOpenURL,
 		Description:          product.Description,
 		Story:                product.Story,
 		SourcingValues:       product.SourcingValues,
 		Ingredients:          product.Ingredients,
+		ParsedIngredients:    ingredientModels(product.ParsedIngredients),
 		AllergyInfo:          product.AllergyInfo,
 		DietaryCertification: product.DietaryCertification,
+		Allergens:            allergensModel(product.Allergens),
+		AllergenCheck:        allergenCheckModel(product.AllergenCheck),
+		Nutrition:            nutritionModel(product.Nutrition),
+		Regions:              product.Regions,
+		RegionOverrides:      overrideModels(product.RegionOverrides),
+		GTINs:                product.GTINs,
+		Status:               string(product.Status),
+		RetiredAt:            product.RetiredAt,
+		Epitaph:              product.Epitaph,
+		ScheduledStatus:      string(product.ScheduledStatus),
+		ScheduledAt:          product.ScheduledAt,
+		Translations:         translationModels(product.Translations),
+		Variants:             variantModels(product.Variants),
+	}
+}
+
+// ingredientModels copies parsed ingredients into their DB models
+func ingredientModels(ingredients []domain.Ingredient) []IngredientModel {
+	if len(ingredients) == 0 {
+		return nil
+	}
+
+	models := make([]IngredientModel, 0, len(ingredients))
+	for _, ingredient := range ingredients {
+		models = append(models, IngredientModel{
+			Name:        ingredient.Name,
+			Qualifiers:  ingredient.Qualifiers,
+			Ingredients: ingredientModels(ingredient.Ingredients),
+		})
+	}
+	return models
+}
+
+// parsedIngredients copies ingredient models into parsed ingredients
+func parsedIngredients(models []IngredientModel) []domain.Ingredient {
+	if len(models) == 0 {
+		return nil
+	}
+
+	ingredients := make([]domain.Ingredient, 0, len(models))
+	for _, model := range models {
+		ingredients = append(ingredients, domain.Ingredient{
+			Name:        model.Name,
+			Qualifiers:  model.Qualifiers,
+			Ingredients: parsedIngredients(model.Ingredients),
+		})
+	}
+	return ingredients
+}
+
+// allergensModel copies declared allergens into their DB
+// model, products declaring none are stored without it
+func allergensModel(allergens domain.Allergens) *AllergensModel {
+	if allergens.IsEmpty() {
+		return nil
+	}
+	return &AllergensModel{Contains: allergens.Contains, MayContain: allergens.MayContain}
+}
+
+// allergenCheckModel copies allergen check into its DB model,
+// products whose allergens agree are stored without it
+func allergenCheckModel(check domain.AllergenCheck) *AllergenCheckModel {
+	if check.Agrees() {
+		return nil
+	}
+	return &AllergenCheckModel{Undeclared: check.Undeclared, Undetected: check.Undetected}
+}
+
+// nutritionModel copies nutrition facts into their DB model,
+// products without nutrition facts are stored without it
+func nutritionModel(facts domain.NutritionFacts) *NutritionModel {
+	if facts.IsEmpty() {
+		return nil
 	}
+	return &NutritionModel{
+		ServingSize:   facts.ServingSize,
+		ServingVolume: facts.ServingVolume,
+		ServingLabel:  facts.ServingLabel,
+		Per100g:       NutrientsModel(facts.Per100g),
+	}
+}
+
+// overrideModels copies region overrides into their DB models
+func overrideModels(overrides map[string]domain.RegionOverride) map[string]OverrideModel {
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	models := make(map[string]OverrideModel, len(overrides))
+	for region, override := range overrides {
+		models[region] = OverrideModel{
+			ImageClosedURL:       override.ImageClosedURL,
+			ImageOpenURL:         override.ImageOpenURL,
+			AllergyInfo:          override.AllergyInfo,
+			DietaryCertification: override.DietaryCertification,
+		}
+	}
+	return models
+}
+
+// variantModels copies variants into their DB models
+func variantModels(variants []domain.ProductVariant) []VariantModel {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	models := make([]VariantModel, 0, len(variants))
+	for _, variant := range variants {
+		models = append(models, VariantModel{
+			SKU:            variant.SKU,
+			Format:         string(variant.Format),
+			Size:           variant.Size,
+			ImageClosedURL: variant.ImageClosedURL,
+			ImageOpenURL:   variant.ImageOpenURL,
+			Ingredients:    variant.Ingredients,
+		})
+	}
+	return models
+}
+
+// translationModels copies translations into their DB models
+func translationModels(translations map[string]domain.ProductTranslation) map[string]TranslationModel {
+	if len(translations) == 0 {
+		return nil
+	}
+
+	models := make(map[string]TranslationModel, len(translations))
+	for locale, translation := range translations {
+		models[locale] = TranslationModel{
+			Name:        translation.Name,
+			Description: translation.Description,
+			Story:       translation.Story,
+			AllergyInfo: translation.AllergyInfo,
+		}
+	}
+	return models
 }
 
-// Product creates product entity instance and
-// copies data from model into product entity
+// Product creates product entity instance and copies data from
+// model into product entity. Products stored before lifecycle
+// was introduced have no status and are published
 func (model *ProductModel) Product() domain.Product {
+	status := domain.ProductStatus(model.Status)
+	if status == "" {
+		status = domain.StatusPublished
+	}
+
 	return domain.Product{
 		ProductID:            model.ProductID,
+		Version:              model.Version,
 		Name:                 model.Name,
+		Slug:                 model.Slug,
+		PreviousSlugs:        model.PreviousSlugs,
 		ImageClosedURL:       model.ImageClosedURL,
 		ImageOpenURL:         model.ImageOpenURL,
 		Description:          model.Description,
 		Story:                model.Story,
 		SourcingValues:       model.SourcingValues,
 		Ingredients:          model.Ingredients,
+		ParsedIngredients:    parsedIngredients(model.ParsedIngredients),
 		AllergyInfo:          model.AllergyInfo,
 		DietaryCertification: model.DietaryCertification,
+		Allergens:            model.allergens(),
+		AllergenCheck:        model.allergenCheck(),
+		Nutrition:            model.nutrition(),
+		Regions:              model.Regions,
+		RegionOverrides:      model.regionOverrides(),
+		GTINs:                model.GTINs,
+		Status:               status,
+		RetiredAt:            model.RetiredAt,
+		Epitaph:              model.Epitaph,
+		ScheduledStatus:      domain.ProductStatus(model.ScheduledStatus),
+		ScheduledAt:          model.ScheduledAt,
+		Translations:         model.translations(),
+		Variants:             model.variants(),
+	}
+}
+
+// allergens copies allergens model into product allergens
+func (model *ProductModel) allergens() domain.Allergens {
+	if model.Allergens == nil {
+		return domain.Allergens{}
+	}
+	return domain.Allergens{Contains: model.Allergens.Contains, MayContain: model.Allergens.MayContain}
+}
+
+// allergenCheck copies allergen check model into product allergen check
+func (model *ProductModel) allergenCheck() domain.AllergenCheck {
+	if model.AllergenCheck == nil {
+		return domain.AllergenCheck{}
+	}
+	return domain.AllergenCheck{Undeclared: model.AllergenCheck.Undeclared, Undetected: model.AllergenCheck.Undetected}
+}
+
+// nutrition copies nutrition model into product nutrition facts
+func (model *ProductModel) nutrition() domain.NutritionFacts {
+	if model.Nutrition == nil {
+		return domain.NutritionFacts{}
+	}
+	return domain.NutritionFacts{
+		ServingSize:   model.Nutrition.ServingSize,
+		ServingVolume: model.Nutrition.ServingVolume,
+		ServingLabel:  model.Nutrition.ServingLabel,
+		Per100g:       domain.Nutrients(model.Nutrition.Per100g),
+	}
+}
+
+// regionOverrides copies override models into product region overrides
+func (model *ProductModel) regionOverrides() map[string]domain.RegionOverride {
+	if len(model.RegionOverrides) == 0 {
+		return nil
+	}
+
+	overrides := make(map[string]domain.RegionOverride, len(model.RegionOverrides))
+	for region, override := range model.RegionOverrides {
+		overrides[region] = domain.RegionOverride{
+			ImageClosedURL:       override.ImageClosedURL,
+			ImageOpenURL:         override.ImageOpenURL,
+			AllergyInfo:          override.AllergyInfo,
+			DietaryCertification: override.DietaryCertification,
+		}
+	}
+	return overrides
+}
+
+// variants copies variant models into product variants
+func (model *ProductModel) variants() []domain.ProductVariant {
+	if len(model.Variants) == 0 {
+		return nil
+	}
+
+	variants := make([]domain.ProductVariant, 0, len(model.Variants))
+	for _, variant := range model.Variants {
+		variants = append(variants, domain.ProductVariant{
+			SKU:            variant.SKU,
+			Format:         domain.VariantFormat(variant.Format),
+			Size:           variant.Size,
+			ImageClosedURL: variant.ImageClosedURL,
+			ImageOpenURL:   variant.ImageOpenURL,
+			Ingredients:    variant.Ingredients,
+		})
+	}
+	return variants
+}
+
+// translations copies translation models into product translations
+func (model *ProductModel) translations() map[string]domain.ProductTranslation {
+	if len(model.Translations) == 0 {
+		return nil
+	}
+
+	translations := make(map[string]domain.ProductTranslation, len(model.Translations))
+	for locale, translation := range model.Translations {
+		translations[locale] = domain.ProductTranslation{
+			Name:        translation.Name,
+			Description: translation.Description,
+			Story:       translation.Story,
+			AllergyInfo: translation.AllergyInfo,
+		}
+	}
+	return translations
+}
+
+// contentModel clears lifecycle, translations and variants of the
+// model, so that writes of product content leave them as they are
+func contentModel(model ProductModel) ProductModel {
+	model.Translations = nil
+	model.Variants = nil
+	model.Status = ""
+	model.RetiredAt = nil
+	model.Epitaph = ""
+	model.ScheduledStatus = ""
+	model.ScheduledAt = nil
+	return model
+}
+
+// translateWriteError is like mongoHelper.TranslateError, telling
+// conflicting GTINs and slugs apart from conflicting productIds
+func translateWriteError(err error) error {
+	switch {
+	case err != nil && strings.Contains(err.Error(), "index: "+gtinIndexName+" "):
+		return domain.ErrDuplicateGTIN
+	case err != nil && strings.Contains(err.Error(), "index: "+slugIndexName+" "):
+		return domain.ErrDuplicateSlug
 	}
+	return mongoHelper.TranslateError(err)
+}
+
+// notDeleted matches products that are not in trash. Trashed products
+// keep their productId, which stays reserved by the unique index until
+// the product is purged
+func notDeleted() bson.M {
+	return bson.M{"deletedAt": bson.M{"$exists": false}}
 }
 
-// NewProductRepo ...
+// NewProductRepo creates repository of products in database dbName,
+// whose indexes are created by migrations, see mongoHelper.Migrator
 func NewProductRepo(client *mongo.Client, dbName string) *ProductMongoRepo {
 	repo := &ProductMongoRepo{
 		client: client,
 		db:     client.Database(dbName),
 	}
+	return repo
+}
+
+// Fetch queries a page of products sorted by the requested field.
+// Pages are chained by the cursor of the last product of a page
+func (repo *ProductMongoRepo) Fetch(ctx context.Context, query domain.ProductQuery) (domain.ProductPage, error) {
+	var (
+		page   domain.ProductPage
+		models []ProductModel
+	)
 
 	collection := repo.db.Collection(collectionName)
+	filter := productFilter(query.Filter)
 
-	// create unique index constraint for productId field
-	collection.Indexes().CreateOne(
-		context.Background(),
-		mongo.IndexModel{
-			Keys:    bsonx.Doc{{Key: "productId", Value: bsonx.Int32(1)}},
-			Options: options.Index().SetUnique(true),
-		},
-	)
-	return repo
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return domain.ProductPage{}, mongoHelper.TranslateError(err)
+	}
+
+	pageFilter, err := cursorFilter(filter, query)
+	if err != nil {
+		return domain.ProductPage{}, err
+	}
+
+	// fetch one more document than the limit to
+	// find out whether there is a next page
+	findOptions := options.Find().
+		SetSort(sortDocument(query)).
+		SetLimit(int64(query.Limit + 1))
+
+	cursor, err := collection.Find(ctx, pageFilter, findOptions)
+	if err != nil {
+		return domain.ProductPage{}, mongoHelper.TranslateError(err)
+	}
+
+	if err = cursor.All(ctx, &models); err != nil {
+		return domain.ProductPage{}, mongoHelper.TranslateError(err)
+	}
+
+	if len(models) > query.Limit {
+		models = models[:query.Limit]
+		last := models[len(models)-1]
+		page.NextCursor = domain.PageCursor{
+			SortValue: sortValue(last, query.SortBy),
+			ProductID: last.ProductID,
+		}.Encode()
+	}
+
+	page.TotalCount = total
+	page.Products = make([]domain.Product, 0, len(models))
+	for _, model := range models {
+		page.Products = append(page.Products, model.Product())
+	}
+	return page, nil
+}
+
+// sortDocument sorts by requested field and breaks ties by productId
+func sortDocument(query domain.ProductQuery) bson.D {
+	order := int(query.SortOrder)
+	if query.SortBy == domain.SortByProductID {
+		return bson.D{{Key: "productId", Value: order}}
+	}
+	return bson.D{
+		{Key: query.SortBy, Value: order},
+		{Key: "productId", Value: order},
+	}
+}
+
+// sortValue reads the value of sorted field from the model
+func sortValue(model ProductModel, sortBy string) string {
+	switch sortBy {
+	case domain.SortByName:
+		return model.Name
+	case domain.SortByRetiredAt:
+		return domain.FormatSortTime(model.RetiredAt)
+	}
+	return model.ProductID
+}
+
+// cursorValue reads sort value of cursor as stored in
+// sorted field, retirement times are stored as dates
+func cursorValue(cursor domain.PageCursor, sortBy string) (interface{}, error) {
+	if sortBy == domain.SortByRetiredAt {
+		return domain.ParseSortTime(cursor.SortValue)
+	}
+	return cursor.SortValue, nil
+}
+
+// cursorFilter narrows filter down to products that come
+// after the query cursor in the requested sort order
+func cursorFilter(filter bson.M, query domain.ProductQuery) (bson.M, error) {
+	if query.Cursor == "" {
+		return filter, nil
+	}
+
+	cursor, err := domain.DecodePageCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	compare := "$gt"
+	if query.SortOrder == domain.Descending {
+		compare = "$lt"
+	}
+
+	var after bson.M
+	if query.SortBy == domain.SortByProductID {
+		after = bson.M{"productId": bson.M{compare: cursor.ProductID}}
+	} else {
+		value, err := cursorValue(cursor, query.SortBy)
+		if err != nil {
+			return nil, err
+		}
+
+		after = bson.M{"$or": bson.A{
+			bson.M{query.SortBy: bson.M{compare: value}},
+			bson.M{query.SortBy: value, "productId": bson.M{compare: cursor.ProductID}},
+		}}
+	}
+
+	if len(filter) == 0 {
+		return after, nil
+	}
+	return bson.M{"$and": bson.A{filter, after}}, nil
 }
 
-// Fetch queries paginated products
-// TODO: also return the next page?
-// TODO: also input limit per page?
-func (repo *ProductMongoRepo) Fetch(ctx context.Context) ([]domain.Product, error) {
-	return []domain.Product{}, nil
+// Search queries filtered products matching text on name,
+// description, story and ingredients, ordered by relevance score
+func (repo *ProductMongoRepo) Search(
+	ctx context.Context,
+	text string,
+	limit int,
+	filter domain.ProductFilter,
+) ([]domain.ProductSearchResult, error) {
+	var models []scoredProductModel
+
+	collection := repo.db.Collection(collectionName)
+	textFilter := productFilter(filter)
+	textFilter["$text"] = bson.M{"$search": text}
+
+	score := bson.M{"$meta": "textScore"}
+	findOptions := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.M{"score": score}).
+		SetLimit(int64(limit))
+
+	cursor, err := collection.Find(ctx, textFilter, findOptions)
+	if err != nil {
+		return nil, mongoHelper.TranslateError(err)
+	}
+
+	if err = cursor.All(ctx, &models); err != nil {
+		return nil, mongoHelper.TranslateError(err)
+	}
+
+	results := make([]domain.ProductSearchResult, 0, len(models))
+	for _, model := range models {
+		results = append(results, domain.ProductSearchResult{
+			Product: model.Product(),
+			Score:   model.Score,
+		})
+	}
+	return results, nil
 }
 
 // Get queries a single product identified by productID
@@ -102,7 +641,44 @@ func (repo *ProductMongoRepo) Get(ctx context.Context, productID string) (domain
 	var model ProductModel
 
 	collection := repo.db.Collection(collectionName)
-	err := collection.FindOne(ctx, ProductModel{ProductID: productID}).Decode(&model)
+	err := collection.FindOne(ctx, versionFilter(productID, 0)).Decode(&model)
+
+	return model.Product(), mongoHelper.TranslateError(err)
+}
+
+// GetBySKU queries the single product having variant of sku
+func (repo *ProductMongoRepo) GetBySKU(ctx context.Context, sku string) (domain.Product, error) {
+	var model ProductModel
+
+	collection := repo.db.Collection(collectionName)
+	filter := notDeleted()
+	filter["variants.sku"] = sku
+	err := collection.FindOne(ctx, filter).Decode(&model)
+
+	return model.Product(), mongoHelper.TranslateError(err)
+}
+
+// GetByGTIN queries the single product having gtin
+func (repo *ProductMongoRepo) GetByGTIN(ctx context.Context, gtin string) (domain.Product, error) {
+	var model ProductModel
+
+	collection := repo.db.Collection(collectionName)
+	filter := notDeleted()
+	filter["gtins"] = gtin
+	err := collection.FindOne(ctx, filter).Decode(&model)
+
+	return model.Product(), mongoHelper.TranslateError(err)
+}
+
+// GetBySlug queries the single product whose current or previous slug
+// is slug. Trashed products are included, as their slugs stay reserved
+// by the unique index until they are purged
+func (repo *ProductMongoRepo) GetBySlug(ctx context.Context, slug string) (domain.Product, error) {
+	var model ProductModel
+
+	collection := repo.db.Collection(collectionName)
+	filter := bson.M{"$or": bson.A{bson.M{"slug": slug}, bson.M{"previousSlugs": slug}}}
+	err := collection.FindOne(ctx, filter).Decode(&model)
 
 	return model.Product(), mongoHelper.TranslateError(err)
 }
@@ -110,6 +686,7 @@ func (repo *ProductMongoRepo) Get(ctx context.Context, productID string) (domain
 // Create inserts a single product document into collection
 func (repo *ProductMongoRepo) Create(ctx context.Context, product domain.Product) error {
 	var model = modelFromProduct(product)
+	model.Version = 1
 
 	if model.Ingredients == nil {
 		model.Ingredients = &[]string{}
@@ -122,27 +699,484 @@ func (repo *ProductMongoRepo) Create(ctx context.Context, product domain.Product
 	collection := repo.db.Collection(collectionName)
 	_, err := collection.InsertOne(ctx, model)
 
-	return mongoHelper.TranslateError(err)
+	return translateWriteError(err)
+}
+
+// Upsert creates published products that do not exist yet and
+// updates attributes of existing products, all in a single bulk
+// write, leaving their lifecycle, translations and variants as
+// they are. Upserting a trashed product takes it out of trash.
+// Since every upsert increments version, matched products are
+// always counted as updated rather than unchanged. The bulk write
+// is unordered, so products conflicting with others are listed as
+// failures once the rest are written
+func (repo *ProductMongoRepo) Upsert(ctx context.Context, products []domain.Product) (domain.UpsertResult, error) {
+	if len(products) == 0 {
+		return domain.UpsertResult{}, nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(products))
+	for _, product := range products {
+		var model = contentModel(modelFromProduct(product))
+		model.Version = 0 // incremented below
+
+		if model.Ingredients == nil {
+			model.Ingredients = &[]string{}
+		}
+
+		if model.SourcingValues == nil {
+			model.SourcingValues = &[]string{}
+		}
+
+		// allergen check is cleared once allergens agree
+		unset := bson.M{"deletedAt": "", "deletedBy": ""}
+		if model.AllergenCheck == nil {
+			unset["allergenCheck"] = ""
+		}
+		if len(model.ParsedIngredients) == 0 {
+			unset["parsedIngredients"] = ""
+		}
+
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(ProductModel{ProductID: product.ProductID}).
+			SetUpdate(bson.M{
+				"$set":         model,
+				"$setOnInsert": bson.M{"status": domain.StatusPublished},
+				"$unset":       unset,
+				"$inc":         bson.M{"version": 1},
+			}).
+			SetUpsert(true))
+	}
+
+	collection := repo.db.Collection(collectionName)
+	result, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+
+	failures, err := upsertFailures(err)
+	if err != nil {
+		return domain.UpsertResult{}, err
+	}
+
+	return domain.UpsertResult{
+		Created:   result.UpsertedCount,
+		Updated:   result.ModifiedCount,
+		Unchanged: result.MatchedCount - result.ModifiedCount,
+		Failures:  failures,
+	}, nil
+}
+
+// upsertFailures lists products of an unordered bulk write that
+// conflicted with others. Other errors fail the write as a whole
+func upsertFailures(err error) ([]domain.UpsertFailure, error) {
+	bulkErr, ok := err.(mongo.BulkWriteException)
+	if !ok || bulkErr.WriteConcernError != nil {
+		return nil, translateWriteError(err)
+	}
+
+	failures := make([]domain.UpsertFailure, 0, len(bulkErr.WriteErrors))
+	for _, writeErr := range bulkErr.WriteErrors {
+		failure := domain.UpsertFailure{Index: writeErr.Index, Err: translateWriteError(writeErr)}
+		if !domain.IsConflict(failure.Err) {
+			return nil, failure.Err
+		}
+		failures = append(failures, failure)
+	}
+	return failures, nil
+}
+
+// Stream calls fn on every filtered product ordered by productId, reading
+// documents from cursor in batches rather than all at once.
+// Streaming stops at the first error returned by fn
+func (repo *ProductMongoRepo) Stream(ctx context.Context, filter domain.ProductFilter, fn func(domain.Product) error) error {
+	collection := repo.db.Collection(collectionName)
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "productId", Value: 1}}).
+		SetBatchSize(streamBatchSize)
+
+	cursor, err := collection.Find(ctx, productFilter(filter), findOptions)
+	if err != nil {
+		return mongoHelper.TranslateError(err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var model ProductModel
+		if err = cursor.Decode(&model); err != nil {
+			return mongoHelper.TranslateError(err)
+		}
+		if err = fn(model.Product()); err != nil {
+			return err
+		}
+	}
+	return mongoHelper.TranslateError(cursor.Err())
 }
 
-// Update modifies attribute of a single product document
+// Update modifies attribute of a single product document. Given
+// non-zero product version, the document must be at that version
 func (repo *ProductMongoRepo) Update(ctx context.Context, productID string, product domain.Product) error {
+	var model = contentModel(modelFromProduct(product))
+	model.Version = 0 // incremented below
+
+	collection := repo.db.Collection(collectionName)
+	filter := versionFilter(productID, product.Version)
+
+	// allergen check is cleared once allergens agree, parsed
+	// ingredients once ingredients are updated to none
+	unset := bson.M{}
+	if model.AllergenCheck == nil {
+		unset["allergenCheck"] = ""
+	}
+	if model.Ingredients != nil && len(model.ParsedIngredients) == 0 {
+		unset["parsedIngredients"] = ""
+	}
+
+	update := bson.M{"$set": model, "$inc": bson.M{"version": 1}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	result, err := collection.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return translateWriteError(err)
+	}
+	return repo.checkMatched(ctx, result.MatchedCount, productID, product.Version)
+}
+
+// Replace overwrites every attribute of a single product document,
+// unlike Update, attributes that are empty in product are cleared.
+// Given non-zero product version, the document must be at that version
+func (repo *ProductMongoRepo) Replace(ctx context.Context, productID string, product domain.Product) error {
+	var model = modelFromProduct(product)
+
+	collection := repo.db.Collection(collectionName)
+	filter := versionFilter(productID, product.Version)
+
+	update := bson.M{"$set": contentDocument(model), "$inc": bson.M{"version": 1}}
+	if len(model.GTINs) == 0 {
+		update["$unset"] = bson.M{"gtins": ""}
+	}
+	result, err := collection.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return translateWriteError(err)
+	}
+	return repo.checkMatched(ctx, result.MatchedCount, productID, product.Version)
+}
+
+// contentDocument lists every editable attribute of the model
+// including empty ones, which omitempty would otherwise skip,
+// except GTINs, which Replace unsets when empty
+func contentDocument(model ProductModel) bson.M {
+	if model.Ingredients == nil {
+		model.Ingredients = &[]string{}
+	}
+
+	if model.SourcingValues == nil {
+		model.SourcingValues = &[]string{}
+	}
+
+	// products sold everywhere are stored without regions,
+	// rather than with an empty list, see productFilter
+	var regions interface{}
+	if len(model.Regions) > 0 {
+		regions = model.Regions
+	}
+
+	document := bson.M{
+		"name":                   model.Name,
+		"imageclosed_url":        model.ImageClosedURL,
+		"imageopen_url":          model.ImageOpenURL,
+		"description":            model.Description,
+		"story":                  model.Story,
+		"sourcing_values":        model.SourcingValues,
+		"ingredients":            model.Ingredients,
+		"parsedIngredients":      model.ParsedIngredients,
+		"allergy_info":           model.AllergyInfo,
+		"dietary_certifications": model.DietaryCertification,
+		"regions":                regions,
+		"allergens":              model.Allergens,
+		"allergenCheck":          model.AllergenCheck,
+		"nutrition":              model.Nutrition,
+		"regionOverrides":        model.RegionOverrides,
+	}
+
+	// products without GTINs are stored without them, rather
+	// than with null, which the unique index takes only once.
+	// Likewise slugs, which are only empty for products stored
+	// before slugs and are left as they are
+	if len(model.GTINs) > 0 {
+		document["gtins"] = model.GTINs
+	}
+	if model.Slug != "" {
+		document["slug"] = model.Slug
+		document["previousSlugs"] = model.PreviousSlugs
+	}
+	return document
+}
+
+// UpdateStatus overwrites lifecycle attributes of a single product
+// document. Given non-zero product version, the document must be at
+// that version
+func (repo *ProductMongoRepo) UpdateStatus(ctx context.Context, productID string, product domain.Product) error {
 	var model = modelFromProduct(product)
 
 	collection := repo.db.Collection(collectionName)
-	filter := ProductModel{ProductID: productID}
+	filter := versionFilter(productID, product.Version)
 
-	update := bson.M{"$set": model}
-	_, err := collection.UpdateOne(ctx, filter, update)
+	set := bson.M{"status": model.Status}
+	unset := bson.M{}
 
-	return mongoHelper.TranslateError(err)
+	lifecycle := []struct {
+		key   string
+		value interface{}
+		empty bool
+	}{
+		{"retiredAt", model.RetiredAt, model.RetiredAt == nil},
+		{"epitaph", model.Epitaph, model.Epitaph == ""},
+		{"scheduledStatus", model.ScheduledStatus, model.ScheduledStatus == ""},
+		{"scheduledAt", model.ScheduledAt, model.ScheduledAt == nil},
+	}
+	for _, attribute := range lifecycle {
+		if attribute.empty {
+			unset[attribute.key] = ""
+		} else {
+			set[attribute.key] = attribute.value
+		}
+	}
+
+	update := bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	result, err := collection.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return mongoHelper.TranslateError(err)
+	}
+	return repo.checkMatched(ctx, result.MatchedCount, productID, product.Version)
 }
 
-// Delete removes a single document from collection
-func (repo *ProductMongoRepo) Delete(ctx context.Context, productID string) error {
+// UpdateTranslations overwrites translations of a single product
+// document. Given non-zero product version, the document must be at
+// that version
+func (repo *ProductMongoRepo) UpdateTranslations(ctx context.Context, productID string, product domain.Product) error {
+	var model = modelFromProduct(product)
+
 	collection := repo.db.Collection(collectionName)
-	filter := ProductModel{ProductID: productID}
+	filter := versionFilter(productID, product.Version)
 
-	_, err := collection.DeleteOne(ctx, filter)
-	return mongoHelper.TranslateError(err)
+	update := bson.M{"$set": bson.M{"translations": model.Translations}, "$inc": bson.M{"version": 1}}
+	if len(model.Translations) == 0 {
+		update = bson.M{"$unset": bson.M{"translations": ""}, "$inc": bson.M{"version": 1}}
+	}
+	result, err := collection.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return mongoHelper.TranslateError(err)
+	}
+	return repo.checkMatched(ctx, result.MatchedCount, productID, product.Version)
+}
+
+// UpdateVariants overwrites variants of a single product document.
+// Given non-zero product version, the document must be at that
+// version. Variant SKU taken by another product is a conflict
+func (repo *ProductMongoRepo) UpdateVariants(ctx context.Context, productID string, product domain.Product) error {
+	var model = modelFromProduct(product)
+
+	collection := repo.db.Collection(collectionName)
+	filter := versionFilter(productID, product.Version)
+
+	update := bson.M{"$set": bson.M{"variants": model.Variants}, "$inc": bson.M{"version": 1}}
+	if len(model.Variants) == 0 {
+		update = bson.M{"$unset": bson.M{"variants": ""}, "$inc": bson.M{"version": 1}}
+	}
+	result, err := collection.UpdateOne(ctx, filter, update)
+
+	if err = mongoHelper.TranslateError(err); err == domain.ErrConflict {
+		return domain.ErrDuplicateSKU
+	}
+	if err != nil {
+		return err
+	}
+	return repo.checkMatched(ctx, result.MatchedCount, productID, product.Version)
+}
+
+// FetchScheduled queries products with a status change
+// scheduled at or before the given time
+func (repo *ProductMongoRepo) FetchScheduled(ctx context.Context, before time.Time) ([]domain.Product, error) {
+	var models []ProductModel
+
+	collection := repo.db.Collection(collectionName)
+	filter := notDeleted()
+	filter["scheduledAt"] = bson.M{"$lte": before}
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, mongoHelper.TranslateError(err)
+	}
+
+	if err = cursor.All(ctx, &models); err != nil {
+		return nil, mongoHelper.TranslateError(err)
+	}
+
+	products := make([]domain.Product, 0, len(models))
+	for _, model := range models {
+		products = append(products, model.Product())
+	}
+	return products, nil
+}
+
+// Delete moves a single product to trash, marking it with the time
+// of deletion and who deleted it. Given non-zero version, the
+// product must be at that version
+func (repo *ProductMongoRepo) Delete(ctx context.Context, productID string, version int64, deletedBy string) error {
+	collection := repo.db.Collection(collectionName)
+	filter := versionFilter(productID, version)
+
+	update := bson.M{
+		"$set": bson.M{"deletedAt": time.Now().UTC(), "deletedBy": deletedBy},
+		"$inc": bson.M{"version": 1},
+	}
+	result, err := collection.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return mongoHelper.TranslateError(err)
+	}
+	return repo.checkMatched(ctx, result.MatchedCount, productID, version)
+}
+
+// FetchTrash queries all trashed products, latest deleted first
+func (repo *ProductMongoRepo) FetchTrash(ctx context.Context) ([]domain.TrashedProduct, error) {
+	filter := bson.M{"deletedAt": bson.M{"$exists": true}}
+	findOptions := options.Find().SetSort(bson.D{{Key: "deletedAt", Value: -1}})
+
+	return repo.findTrash(ctx, filter, findOptions)
+}
+
+// FetchTrashBefore queries up to limit products trashed
+// before deletedBefore, earliest deleted first
+func (repo *ProductMongoRepo) FetchTrashBefore(ctx context.Context, deletedBefore time.Time, limit int) ([]domain.TrashedProduct, error) {
+	filter := bson.M{"deletedAt": bson.M{"$lt": deletedBefore}}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "deletedAt", Value: 1}, {Key: "productId", Value: 1}}).
+		SetLimit(int64(limit))
+
+	return repo.findTrash(ctx, filter, findOptions)
+}
+
+// GetTrashed queries a single trashed product
+func (repo *ProductMongoRepo) GetTrashed(ctx context.Context, productID string) (domain.TrashedProduct, error) {
+	var model ProductModel
+
+	collection := repo.db.Collection(collectionName)
+	filter := bson.M{"productId": productID, "deletedAt": bson.M{"$exists": true}}
+	err := collection.FindOne(ctx, filter).Decode(&model)
+
+	if err != nil {
+		return domain.TrashedProduct{}, mongoHelper.TranslateError(err)
+	}
+	return newTrashedProduct(model), nil
+}
+
+// findTrash queries trashed products matching filter
+func (repo *ProductMongoRepo) findTrash(ctx context.Context, filter bson.M, findOptions *options.FindOptions) ([]domain.TrashedProduct, error) {
+	var models []ProductModel
+
+	collection := repo.db.Collection(collectionName)
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, mongoHelper.TranslateError(err)
+	}
+
+	if err = cursor.All(ctx, &models); err != nil {
+		return nil, mongoHelper.TranslateError(err)
+	}
+
+	trash := make([]domain.TrashedProduct, 0, len(models))
+	for _, model := range models {
+		trash = append(trash, newTrashedProduct(model))
+	}
+	return trash, nil
+}
+
+// newTrashedProduct reads trashed product of model
+func newTrashedProduct(model ProductModel) domain.TrashedProduct {
+	return domain.TrashedProduct{
+		Product:   model.Product(),
+		DeletedAt: *model.DeletedAt,
+		DeletedBy: model.DeletedBy,
+	}
+}
+
+// Restore takes a single product out of trash
+func (repo *ProductMongoRepo) Restore(ctx context.Context, productID string) error {
+	collection := repo.db.Collection(collectionName)
+	filter := bson.M{"productId": productID, "deletedAt": bson.M{"$exists": true}}
+
+	update := bson.M{
+		"$unset": bson.M{"deletedAt": "", "deletedBy": ""},
+		"$inc":   bson.M{"version": 1},
+	}
+	result, err := collection.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return mongoHelper.TranslateError(err)
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrResourceNotFound
+	}
+	return nil
+}
+
+// Purge removes a single trashed product document from collection
+func (repo *ProductMongoRepo) Purge(ctx context.Context, productID string) error {
+	collection := repo.db.Collection(collectionName)
+	filter := bson.M{"productId": productID, "deletedAt": bson.M{"$exists": true}}
+
+	result, err := collection.DeleteOne(ctx, filter)
+
+	if err != nil {
+		return mongoHelper.TranslateError(err)
+	}
+
+	if result.DeletedCount == 0 {
+		return domain.ErrResourceNotFound
+	}
+	return nil
+}
+
+// versionFilter matches product document by productId and, when
+// version is non-zero, only if the document is at that version.
+// Matching version in the write filter keeps the check atomic.
+// Trashed products are never matched
+func versionFilter(productID string, version int64) bson.M {
+	filter := bson.M{"productId": productID, "deletedAt": bson.M{"$exists": false}}
+	if version != 0 {
+		filter["version"] = version
+	}
+	return filter
+}
+
+// checkMatched tells apart a missing product from a product that
+// is no longer at the expected version when a write matched nothing
+func (repo *ProductMongoRepo) checkMatched(ctx context.Context, matched int64, productID string, version int64) error {
+	if matched > 0 {
+		return nil
+	}
+
+	if version == 0 {
+		return domain.ErrResourceNotFound
+	}
+
+	collection := repo.db.Collection(collectionName)
+	count, err := collection.CountDocuments(ctx, versionFilter(productID, 0))
+
+	if err != nil {
+		return mongoHelper.TranslateError(err)
+	}
+
+	if count == 0 {
+		return domain.ErrResourceNotFound
+	}
+	return domain.ErrPreconditionFailed
 }
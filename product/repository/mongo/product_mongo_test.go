@@ -0,0 +1,51 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	mongoHelper "github.com/iqdf/benjerry-service/common/repository/mongo"
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/domain/repotest"
+)
+
+// connectTestDB connects to mongo of MONGO_TEST_URI and migrates
+// a database of the test, which is dropped once the test is done.
+// Tests are skipped when no mongo is given
+func connectTestDB(t *testing.T) (*mongo.Client, string) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dbName := fmt.Sprint("benjerry_test_", time.Now().UnixNano())
+	t.Cleanup(func() {
+		client.Database(dbName).Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+
+	if _, err = mongoHelper.NewMigrator(client, dbName).Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return client, dbName
+}
+
+func TestProductRepositorySuite(t *testing.T) {
+	repotest.RunProductRepositorySuite(t, func(t *testing.T) domain.ProductRepository {
+		return NewProductRepo(connectTestDB(t))
+	})
+}
@@ -0,0 +1,169 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	mongoHelper "github.com/iqdf/benjerry-service/common/repository/mongo"
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+const revisionCollectionName = "IceCreamRevision" // product revisions
+
+// revisionAttempts bounds retries of numbering a revision
+// when a concurrent change of the same product took the number
+const revisionAttempts = 5
+
+// FieldChangeModel ...
+type FieldChangeModel struct {
+	Field string      `bson:"field"`
+	From  interface{} `bson:"from,omitempty"`
+	To    interface{} `bson:"to,omitempty"`
+}
+
+// ProductRevisionModel ...
+type ProductRevisionModel struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	ProductID    string             `bson:"productId"`
+	Revision     int64              `bson:"revision"`
+	Action       string             `bson:"action"`
+	Author       string             `bson:"author"`
+	Timestamp    time.Time          `bson:"timestamp"`
+	Changes      []FieldChangeModel `bson:"changes"`
+	Product      ProductModel       `bson:"product"`
+	RestoredFrom int64              `bson:"restored_from,omitempty"`
+}
+
+// ProductRevisionMongoRepo ...
+type ProductRevisionMongoRepo struct {
+	client *mongo.Client
+	db     *mongo.Database
+}
+
+// modelFromRevision creates new ProductRevisionModel and
+// copy data from revision entity to revision DB model
+func modelFromRevision(revision domain.ProductRevision) ProductRevisionModel {
+	changes := make([]FieldChangeModel, len(revision.Changes))
+	for i, change := range revision.Changes {
+		changes[i] = FieldChangeModel{Field: change.Field, From: change.From, To: change.To}
+	}
+
+	return ProductRevisionModel{
+		ProductID:    revision.ProductID,
+		Revision:     revision.Revision,
+		Action:       string(revision.Action),
+		Author:       revision.Author,
+		Timestamp:    revision.Timestamp,
+		Changes:      changes,
+		Product:      modelFromProduct(revision.Product),
+		RestoredFrom: revision.RestoredFrom,
+	}
+}
+
+// ProductRevision creates revision entity instance and
+// copies data from model into revision entity
+func (model *ProductRevisionModel) ProductRevision() domain.ProductRevision {
+	changes := make([]domain.FieldChange, len(model.Changes))
+	for i, change := range model.Changes {
+		changes[i] = domain.FieldChange{Field: change.Field, From: changeValue(change.From), To: changeValue(change.To)}
+	}
+
+	return domain.ProductRevision{
+		ProductID:    model.ProductID,
+		Revision:     model.Revision,
+		Action:       domain.RevisionAction(model.Action),
+		Author:       model.Author,
+		Timestamp:    model.Timestamp,
+		Changes:      changes,
+		Product:      model.Product.Product(),
+		RestoredFrom: model.RestoredFrom,
+	}
+}
+
+// changeValue converts changed field values decoded
+// from BSON documents back into their Go types
+func changeValue(value interface{}) interface{} {
+	if dateTime, ok := value.(primitive.DateTime); ok {
+		return dateTime.Time().UTC()
+	}
+	return value
+}
+
+// NewProductRevisionRepo ...
+func NewProductRevisionRepo(client *mongo.Client, dbName string) *ProductRevisionMongoRepo {
+	repo := &ProductRevisionMongoRepo{
+		client: client,
+		db:     client.Database(dbName),
+	}
+	return repo
+}
+
+// Fetch queries all revisions of a product, latest first
+func (repo *ProductRevisionMongoRepo) Fetch(ctx context.Context, productID string) ([]domain.ProductRevision, error) {
+	collection := repo.db.Collection(revisionCollectionName)
+	findOptions := options.Find().SetSort(bson.D{{Key: "revision", Value: -1}})
+
+	cursor, err := collection.Find(ctx, bson.M{"productId": productID}, findOptions)
+	if err != nil {
+		return nil, mongoHelper.TranslateError(err)
+	}
+	defer cursor.Close(ctx)
+
+	var revisions = make([]domain.ProductRevision, 0)
+	for cursor.Next(ctx) {
+		var model ProductRevisionModel
+		if err = cursor.Decode(&model); err != nil {
+			return nil, mongoHelper.TranslateError(err)
+		}
+		revisions = append(revisions, model.ProductRevision())
+	}
+	return revisions, mongoHelper.TranslateError(cursor.Err())
+}
+
+// Get queries a single revision of a product
+func (repo *ProductRevisionMongoRepo) Get(ctx context.Context, productID string, revision int64) (domain.ProductRevision, error) {
+	var model ProductRevisionModel
+
+	collection := repo.db.Collection(revisionCollectionName)
+	filter := bson.M{"productId": productID, "revision": revision}
+	err := collection.FindOne(ctx, filter).Decode(&model)
+
+	if err != nil {
+		return domain.ProductRevision{}, mongoHelper.TranslateError(err)
+	}
+	return model.ProductRevision(), nil
+}
+
+// Create inserts revision numbered right after the latest
+// revision of the product and returns the number taken
+func (repo *ProductRevisionMongoRepo) Create(ctx context.Context, revision domain.ProductRevision) (int64, error) {
+	collection := repo.db.Collection(revisionCollectionName)
+	model := modelFromRevision(revision)
+
+	for attempt := 1; ; attempt++ {
+		var latest ProductRevisionModel
+		findOptions := options.FindOne().SetSort(bson.D{{Key: "revision", Value: -1}})
+
+		err := collection.FindOne(ctx, bson.M{"productId": model.ProductID}, findOptions).Decode(&latest)
+		if err = mongoHelper.TranslateError(err); err != nil && err != domain.ErrResourceNotFound {
+			return 0, err
+		}
+
+		model.Revision = latest.Revision + 1
+		_, err = collection.InsertOne(ctx, model)
+		err = mongoHelper.TranslateError(err)
+
+		if err == domain.ErrConflict && attempt < revisionAttempts {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		return model.Revision, nil
+	}
+}
@@ -0,0 +1,61 @@
+package mongo
+
+import (
+	"context"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	mongoHelper "github.com/iqdf/benjerry-service/common/repository/mongo"
+)
+
+const counterCollectionName = "Counter" // named sequences
+
+// productIDCounter names the sequence of productIds
+const productIDCounter = "productId"
+
+// CounterModel is the last value taken from a named sequence
+type CounterModel struct {
+	Name  string `bson:"_id"`
+	Value int64  `bson:"value"`
+}
+
+// ProductSequenceMongoRepo allocates productIds from an atomic counter
+type ProductSequenceMongoRepo struct {
+	client *mongo.Client
+	db     *mongo.Database
+}
+
+// NewProductSequenceRepo creates sequence of productIds, which continues
+// after the greatest numeric productId stored before the sequence
+// existed, as seeded by migrations, see mongoHelper.Migrator
+func NewProductSequenceRepo(client *mongo.Client, dbName string) *ProductSequenceMongoRepo {
+	return &ProductSequenceMongoRepo{
+		client: client,
+		db:     client.Database(dbName),
+	}
+}
+
+// NextProductID takes the next value of the sequence
+func (repo *ProductSequenceMongoRepo) NextProductID(ctx context.Context) (string, error) {
+	var counter CounterModel
+
+	collection := repo.db.Collection(counterCollectionName)
+	findOptions := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After)
+
+	err := collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": productIDCounter},
+		bson.M{"$inc": bson.M{"value": 1}},
+		findOptions,
+	).Decode(&counter)
+
+	if err != nil {
+		return "", mongoHelper.TranslateError(err)
+	}
+	return strconv.FormatInt(counter.Value, 10), nil
+}
@@ -0,0 +1,333 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// keyPrefix namespaces cached products among other keys of redis
+const keyPrefix = "product:"
+
+// notFoundEntry is cached for products that are not found, so that
+// repeated reads of missing products do not reach the repository
+const notFoundEntry = "not-found"
+
+// errInvalidEntry tells that a cached entry cannot be decoded
+var errInvalidEntry = errors.New("invalid cache entry")
+
+// ProductCacheStats counts reads of products served from cache,
+// including products cached as not found, and reads that missed
+// the cache and were served by the repository
+type ProductCacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// ProductCacheRepo caches products read by Get in redis for TTL, and
+// products that are not found for a shorter TTL. Reads of a product
+// that missed the cache at once are coalesced into a single read of
+// the repository. Writes invalidate products they touch, every other
+// read passes through to the repository, so that new writes must be
+// added here as they are added to domain.ProductRepository.
+// Cache is a best effort, when redis fails products are read from
+// the repository and failed invalidations expire with TTL
+type ProductCacheRepo struct {
+	domain.ProductRepository
+
+	pool        *redis.Pool
+	ttl         time.Duration
+	notFoundTTL time.Duration
+
+	mu      sync.Mutex
+	flights map[string]*flight
+
+	hits   int64
+	misses int64
+}
+
+// flight is a read of a product from the repository, which readers
+// of the product wait for rather than reading it themselves. What
+// flights invalidated by a write read is dropped from cache, as they
+// may read the product as it was before the write
+type flight struct {
+	done    chan struct{}
+	value   []byte
+	err     error
+	invalid bool
+}
+
+// NewProductCacheRepo caches products of repo in redis of pool
+func NewProductCacheRepo(repo domain.ProductRepository, pool *redis.Pool, ttl, notFoundTTL time.Duration) *ProductCacheRepo {
+	return &ProductCacheRepo{
+		ProductRepository: repo,
+		pool:              pool,
+		ttl:               ttl,
+		notFoundTTL:       notFoundTTL,
+		flights:           make(map[string]*flight),
+	}
+}
+
+// Stats counts cache hits and misses so far
+func (repo *ProductCacheRepo) Stats() ProductCacheStats {
+	return ProductCacheStats{
+		Hits:   atomic.LoadInt64(&repo.hits),
+		Misses: atomic.LoadInt64(&repo.misses),
+	}
+}
+
+// Get queries a single product from cache, or from the repository
+// when it is not cached. Within transactions products are always read
+// from the repository, which may see writes not committed yet
+func (repo *ProductCacheRepo) Get(ctx context.Context, productID string) (domain.Product, error) {
+	if _, ok := transactionFromContext(ctx); ok {
+		return repo.ProductRepository.Get(ctx, productID)
+	}
+
+	if value, ok := repo.cached(productID); ok {
+		if product, err := decodeEntry(value); err != errInvalidEntry {
+			atomic.AddInt64(&repo.hits, 1)
+			return product, err
+		}
+	}
+	atomic.AddInt64(&repo.misses, 1)
+
+	value, err := repo.load(ctx, productID)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	return decodeEntry(value)
+}
+
+// load reads product from the repository and caches it, unless
+// another reader is reading it already, whose read it waits for
+func (repo *ProductCacheRepo) load(ctx context.Context, productID string) ([]byte, error) {
+	repo.mu.Lock()
+	if f, ok := repo.flights[productID]; ok {
+		repo.mu.Unlock()
+
+		select {
+		case <-f.done:
+			return f.value, f.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	f := &flight{done: make(chan struct{})}
+	repo.flights[productID] = f
+	repo.mu.Unlock()
+
+	// readers waiting for the flight are not failed
+	// when the reader that started it goes away
+	loadCtx, cancel := detach(ctx)
+	f.value, f.err = repo.read(loadCtx, productID)
+	cancel()
+
+	switch {
+	case f.err != nil:
+	case string(f.value) == notFoundEntry:
+		repo.store(productID, f.value, repo.notFoundTTL)
+	default:
+		repo.store(productID, f.value, repo.ttl)
+	}
+
+	repo.mu.Lock()
+	if repo.flights[productID] == f {
+		delete(repo.flights, productID)
+	}
+	invalid := f.invalid
+	repo.mu.Unlock()
+	close(f.done)
+
+	// a write invalidated the product while it was read,
+	// what was stored above may be the product before it
+	if invalid {
+		repo.drop(productID)
+	}
+	return f.value, f.err
+}
+
+// read queries product from the repository as cache entry
+func (repo *ProductCacheRepo) read(ctx context.Context, productID string) ([]byte, error) {
+	product, err := repo.ProductRepository.Get(ctx, productID)
+
+	switch {
+	case err == domain.ErrResourceNotFound:
+		return []byte(notFoundEntry), nil
+	case err != nil:
+		return nil, err
+	}
+
+	value, err := json.Marshal(product)
+	if err != nil {
+		return nil, domain.ErrInternalServerError
+	}
+	return value, nil
+}
+
+// decodeEntry decodes cached product, which is not found
+// given notFoundEntry. Invalid entries are cache misses
+func decodeEntry(value []byte) (domain.Product, error) {
+	if string(value) == notFoundEntry {
+		return domain.Product{}, domain.ErrResourceNotFound
+	}
+
+	var product domain.Product
+	if err := json.Unmarshal(value, &product); err != nil {
+		return domain.Product{}, errInvalidEntry
+	}
+	return product, nil
+}
+
+// cached reads cache entry of product, which is not
+// found when either it is not cached or redis fails
+func (repo *ProductCacheRepo) cached(productID string) ([]byte, bool) {
+	conn := repo.pool.Get()
+	defer conn.Close()
+
+	value, err := redis.Bytes(conn.Do("GET", keyPrefix+productID))
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// store caches entry of product for ttl
+func (repo *ProductCacheRepo) store(productID string, value []byte, ttl time.Duration) {
+	conn := repo.pool.Get()
+	defer conn.Close()
+
+	conn.Do("SET", keyPrefix+productID, value, "PX", int64(ttl/time.Millisecond))
+}
+
+// invalidate drops cache entries of products, including those
+// being read by flights. Within transactions, products are
+// invalidated again once the transaction is done, as they may
+// be read and cached before the transaction commits
+func (repo *ProductCacheRepo) invalidate(ctx context.Context, productIDs ...string) {
+	if len(productIDs) == 0 {
+		return
+	}
+
+	if writes, ok := transactionFromContext(ctx); ok {
+		writes.add(productIDs)
+	}
+
+	repo.mu.Lock()
+	for _, productID := range productIDs {
+		if f, ok := repo.flights[productID]; ok {
+			f.invalid = true
+			delete(repo.flights, productID)
+		}
+	}
+	repo.mu.Unlock()
+	repo.drop(productIDs...)
+}
+
+// drop deletes cache entries of products
+func (repo *ProductCacheRepo) drop(productIDs ...string) {
+	keys := make([]interface{}, 0, len(productIDs))
+	for _, productID := range productIDs {
+		keys = append(keys, keyPrefix+productID)
+	}
+
+	conn := repo.pool.Get()
+	defer conn.Close()
+
+	conn.Do("DEL", keys...)
+}
+
+// Create stores a single product, dropping it from
+// cache in case it was cached as not found
+func (repo *ProductCacheRepo) Create(ctx context.Context, product domain.Product) error {
+	err := repo.ProductRepository.Create(ctx, product)
+	repo.invalidate(ctx, product.ProductID)
+	return err
+}
+
+// Upsert creates or updates products and invalidates them
+func (repo *ProductCacheRepo) Upsert(ctx context.Context, products []domain.Product) (domain.UpsertResult, error) {
+	result, err := repo.ProductRepository.Upsert(ctx, products)
+
+	productIDs := make([]string, 0, len(products))
+	for _, product := range products {
+		productIDs = append(productIDs, product.ProductID)
+	}
+	repo.invalidate(ctx, productIDs...)
+	return result, err
+}
+
+// Update modifies attributes of a single product and invalidates it.
+// Products are invalidated even when writes fail, as a write failing
+// on version may tell that the cached product is stale
+func (repo *ProductCacheRepo) Update(ctx context.Context, productID string, product domain.Product) error {
+	err := repo.ProductRepository.Update(ctx, productID, product)
+	repo.invalidate(ctx, productID)
+	return err
+}
+
+// Replace overwrites attributes of a single product and invalidates it
+func (repo *ProductCacheRepo) Replace(ctx context.Context, productID string, product domain.Product) error {
+	err := repo.ProductRepository.Replace(ctx, productID, product)
+	repo.invalidate(ctx, productID)
+	return err
+}
+
+// UpdateStatus overwrites lifecycle of a single product and invalidates it
+func (repo *ProductCacheRepo) UpdateStatus(ctx context.Context, productID string, product domain.Product) error {
+	err := repo.ProductRepository.UpdateStatus(ctx, productID, product)
+	repo.invalidate(ctx, productID)
+	return err
+}
+
+// UpdateTranslations overwrites translations of a single product and invalidates it
+func (repo *ProductCacheRepo) UpdateTranslations(ctx context.Context, productID string, product domain.Product) error {
+	err := repo.ProductRepository.UpdateTranslations(ctx, productID, product)
+	repo.invalidate(ctx, productID)
+	return err
+}
+
+// UpdateVariants overwrites variants of a single product and invalidates it
+func (repo *ProductCacheRepo) UpdateVariants(ctx context.Context, productID string, product domain.Product) error {
+	err := repo.ProductRepository.UpdateVariants(ctx, productID, product)
+	repo.invalidate(ctx, productID)
+	return err
+}
+
+// Delete moves a single product to trash and invalidates it
+func (repo *ProductCacheRepo) Delete(ctx context.Context, productID string, version int64, deletedBy string) error {
+	err := repo.ProductRepository.Delete(ctx, productID, version, deletedBy)
+	repo.invalidate(ctx, productID)
+	return err
+}
+
+// Restore takes a single product out of trash and invalidates it
+func (repo *ProductCacheRepo) Restore(ctx context.Context, productID string) error {
+	err := repo.ProductRepository.Restore(ctx, productID)
+	repo.invalidate(ctx, productID)
+	return err
+}
+
+// Purge removes a single trashed product and invalidates it
+func (repo *ProductCacheRepo) Purge(ctx context.Context, productID string) error {
+	err := repo.ProductRepository.Purge(ctx, productID)
+	repo.invalidate(ctx, productID)
+	return err
+}
+
+// detach derives a context from ctx that has its deadline,
+// but is not canceled when ctx is
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(context.Background(), deadline)
+	}
+	return context.WithCancel(context.Background())
+}
@@ -0,0 +1,283 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/domain/repotest"
+	"github.com/iqdf/benjerry-service/product/repository/memory"
+)
+
+// fakeRedis keeps values of GET, SET with PX and DEL in memory,
+// failing every command while down
+type fakeRedis struct {
+	mu        sync.Mutex
+	values    map[string][]byte
+	expiresAt map[string]time.Time
+	down      bool
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{values: make(map[string][]byte), expiresAt: make(map[string]time.Time)}
+}
+
+func (fake *fakeRedis) do(command string, args ...interface{}) (interface{}, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	if command == "" {
+		return nil, nil
+	}
+	if fake.down {
+		return nil, errors.New("connection refused")
+	}
+
+	switch command {
+	case "GET":
+		key := args[0].(string)
+		if time.Now().After(fake.expiresAt[key]) {
+			delete(fake.values, key)
+		}
+		if value, ok := fake.values[key]; ok {
+			return value, nil
+		}
+		return nil, nil
+
+	case "SET":
+		key := args[0].(string)
+		fake.values[key] = args[1].([]byte)
+		fake.expiresAt[key] = time.Now().Add(time.Duration(args[3].(int64)) * time.Millisecond)
+		return "OK", nil
+
+	case "DEL":
+		for _, key := range args {
+			delete(fake.values, key.(string))
+		}
+		return int64(len(args)), nil
+	}
+	return nil, errors.New("unknown command " + command)
+}
+
+func (fake *fakeRedis) keys() int {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	return len(fake.values)
+}
+
+func (fake *fakeRedis) pool() *redis.Pool {
+	return &redis.Pool{Dial: func() (redis.Conn, error) { return fakeConn{fake}, nil }}
+}
+
+// fakeConn sends commands to fake redis
+type fakeConn struct {
+	fake *fakeRedis
+}
+
+func (conn fakeConn) Close() error { return nil }
+func (conn fakeConn) Err() error   { return nil }
+func (conn fakeConn) Do(command string, args ...interface{}) (interface{}, error) {
+	return conn.fake.do(command, args...)
+}
+func (conn fakeConn) Send(string, ...interface{}) error { return nil }
+func (conn fakeConn) Flush() error                      { return nil }
+func (conn fakeConn) Receive() (interface{}, error)     { return nil, nil }
+
+// countingRepo counts reads of products, which
+// wait for release when it is not nil
+type countingRepo struct {
+	domain.ProductRepository
+	reads   int64
+	release chan struct{}
+}
+
+func (repo *countingRepo) Get(ctx context.Context, productID string) (domain.Product, error) {
+	atomic.AddInt64(&repo.reads, 1)
+	if repo.release != nil {
+		<-repo.release
+	}
+	return repo.ProductRepository.Get(ctx, productID)
+}
+
+// directTransactor runs fn as it is, as memory
+// repositories do not support transactions
+type directTransactor struct{}
+
+func (directTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func createMockProduct(productID string, name string) domain.Product {
+	return domain.Product{
+		ProductID:   productID,
+		Name:        name,
+		Slug:        "slug-" + productID,
+		Description: "Buttery toffee in vanilla",
+		Status:      domain.StatusPublished,
+	}
+}
+
+func TestProductRepositorySuite(t *testing.T) {
+	repotest.RunProductRepositorySuite(t, func(t *testing.T) domain.ProductRepository {
+		return NewProductCacheRepo(memory.NewProductRepo(), newFakeRedis().pool(), time.Minute, time.Minute)
+	})
+}
+
+func TestProductCacheGet(t *testing.T) {
+	ctx := context.TODO()
+	fake := newFakeRedis()
+	counting := &countingRepo{ProductRepository: memory.NewProductRepo()}
+	repo := NewProductCacheRepo(counting, fake.pool(), time.Minute, 50*time.Millisecond)
+
+	assert.NoError(t, repo.Create(ctx, createMockProduct("646", "Vanilla Toffee Bar Crunch")))
+
+	t.Run("Get-hit", func(t *testing.T) {
+		product, err := repo.Get(ctx, "646")
+		assert.NoError(t, err)
+		assert.Equal(t, "Vanilla Toffee Bar Crunch", product.Name)
+
+		cached, err := repo.Get(ctx, "646")
+		assert.NoError(t, err)
+		assert.Equal(t, product, cached)
+
+		assert.Equal(t, int64(1), atomic.LoadInt64(&counting.reads))
+		assert.Equal(t, ProductCacheStats{Hits: 1, Misses: 1}, repo.Stats())
+	})
+
+	t.Run("Get-invalidated-by-update", func(t *testing.T) {
+		assert.NoError(t, repo.Update(ctx, "646", domain.Product{Description: "Vanilla with fudge"}))
+
+		product, err := repo.Get(ctx, "646")
+		assert.NoError(t, err)
+		assert.Equal(t, "Vanilla with fudge", product.Description)
+		assert.Equal(t, int64(2), product.Version)
+		assert.Equal(t, int64(2), atomic.LoadInt64(&counting.reads))
+	})
+
+	t.Run("Get-invalidated-by-failed-write", func(t *testing.T) {
+		// writes failing on version may
+		// tell that the cache is stale
+		repo.Get(ctx, "646")
+		err := repo.Update(ctx, "646", domain.Product{Description: "Stale", Version: 1})
+		assert.Equal(t, domain.ErrPreconditionFailed, err)
+		assert.Equal(t, 0, fake.keys())
+	})
+
+	t.Run("Get-invalidated-by-delete", func(t *testing.T) {
+		repo.Get(ctx, "646")
+		assert.NoError(t, repo.Delete(ctx, "646", 0, "admin"))
+
+		_, err := repo.Get(ctx, "646")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+
+	t.Run("Get-not-found-cached-briefly", func(t *testing.T) {
+		reads := atomic.LoadInt64(&counting.reads)
+
+		for i := 0; i < 3; i++ {
+			_, err := repo.Get(ctx, "404")
+			assert.Equal(t, domain.ErrResourceNotFound, err)
+		}
+		assert.Equal(t, reads+1, atomic.LoadInt64(&counting.reads))
+
+		time.Sleep(60 * time.Millisecond)
+		_, err := repo.Get(ctx, "404")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+		assert.Equal(t, reads+2, atomic.LoadInt64(&counting.reads))
+	})
+
+	t.Run("Get-not-found-invalidated-by-create", func(t *testing.T) {
+		_, err := repo.Get(ctx, "647")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+
+		assert.NoError(t, repo.Create(ctx, createMockProduct("647", "Cherry Garcia")))
+
+		product, err := repo.Get(ctx, "647")
+		assert.NoError(t, err)
+		assert.Equal(t, "Cherry Garcia", product.Name)
+	})
+
+	t.Run("Get-redis-down", func(t *testing.T) {
+		fake.mu.Lock()
+		fake.down = true
+		fake.mu.Unlock()
+		defer func() {
+			fake.mu.Lock()
+			fake.down = false
+			fake.mu.Unlock()
+		}()
+
+		// products are read from the repository
+		product, err := repo.Get(ctx, "647")
+		assert.NoError(t, err)
+		assert.Equal(t, "Cherry Garcia", product.Name)
+		assert.NoError(t, repo.Update(ctx, "647", domain.Product{Story: "From Vermont"}))
+	})
+}
+
+func TestProductCacheCoalescing(t *testing.T) {
+	ctx := context.TODO()
+	counting := &countingRepo{ProductRepository: memory.NewProductRepo(), release: make(chan struct{})}
+	repo := NewProductCacheRepo(counting, newFakeRedis().pool(), time.Minute, time.Minute)
+	counting.ProductRepository.Create(ctx, createMockProduct("646", "Vanilla Toffee Bar Crunch"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			product, err := repo.Get(ctx, "646")
+			assert.NoError(t, err)
+			assert.Equal(t, "646", product.ProductID)
+		}()
+	}
+
+	// readers missing the cache together read the
+	// product from the repository only once
+	time.Sleep(20 * time.Millisecond)
+	close(counting.release)
+	wg.Wait()
+
+	assert.Equal(t, int64(1), atomic.LoadInt64(&counting.reads))
+	stats := repo.Stats()
+	assert.Equal(t, int64(8), stats.Hits+stats.Misses)
+}
+
+func TestProductCacheTransaction(t *testing.T) {
+	ctx := context.TODO()
+	fake := newFakeRedis()
+	repo := NewProductCacheRepo(memory.NewProductRepo(), fake.pool(), time.Minute, time.Minute)
+	transactor := repo.Transactor(directTransactor{})
+
+	assert.NoError(t, repo.Create(ctx, createMockProduct("646", "Vanilla Toffee Bar Crunch")))
+	repo.Get(ctx, "646")
+	stats := repo.Stats()
+
+	err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		// products are neither read from nor stored
+		// into cache within transactions
+		assert.NoError(t, repo.Update(ctx, "646", domain.Product{Description: "Vanilla with fudge"}))
+
+		product, err := repo.Get(ctx, "646")
+		assert.NoError(t, err)
+		assert.Equal(t, "Vanilla with fudge", product.Description)
+		assert.Equal(t, 0, fake.keys())
+
+		// a read outside of the transaction caches the
+		// product, which the transaction invalidates
+		repo.Get(context.TODO(), "646")
+		assert.Equal(t, 1, fake.keys())
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fake.keys())
+	assert.Equal(t, stats.Misses+1, repo.Stats().Misses)
+}
@@ -0,0 +1,63 @@
+package redis
+
+import (
+	"context"
+	"sync"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// transactionKey keys writes of the transaction in context
+type transactionKey struct{}
+
+// transactionWrites lists products written within a transaction
+type transactionWrites struct {
+	mu         sync.Mutex
+	productIDs []string
+}
+
+func (writes *transactionWrites) add(productIDs []string) {
+	writes.mu.Lock()
+	defer writes.mu.Unlock()
+
+	writes.productIDs = append(writes.productIDs, productIDs...)
+}
+
+func (writes *transactionWrites) written() []string {
+	writes.mu.Lock()
+	defer writes.mu.Unlock()
+
+	return append([]string(nil), writes.productIDs...)
+}
+
+// transactionFromContext finds writes of the transaction ctx is within
+func transactionFromContext(ctx context.Context) (*transactionWrites, bool) {
+	writes, ok := ctx.Value(transactionKey{}).(*transactionWrites)
+	return writes, ok
+}
+
+// cacheTransactor runs transactions of which cache keeps track, see Transactor
+type cacheTransactor struct {
+	repo       *ProductCacheRepo
+	transactor domain.Transactor
+}
+
+// Transactor wraps transactor so that products are read from the
+// repository within its transactions, and products written within
+// them are invalidated once they are either committed or aborted
+func (repo *ProductCacheRepo) Transactor(transactor domain.Transactor) domain.Transactor {
+	return cacheTransactor{repo: repo, transactor: transactor}
+}
+
+// WithinTransaction runs fn within a transaction of the wrapped
+// transactor. Transactions nested within it take part in it
+func (transactor cacheTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := transactionFromContext(ctx); ok {
+		return transactor.transactor.WithinTransaction(ctx, fn)
+	}
+
+	writes := &transactionWrites{}
+	err := transactor.transactor.WithinTransaction(context.WithValue(ctx, transactionKey{}, writes), fn)
+	transactor.repo.invalidate(ctx, writes.written()...)
+	return err
+}
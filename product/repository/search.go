@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// searchWeights rank matches in name higher than matches
+// in story, like the weighted text index of mongo
+var searchWeights = map[string]float64{
+	"name":        10,
+	"description": 5,
+	"ingredients": 3,
+	"story":       1,
+}
+
+// ParseSearch splits search text into words to match,
+// and words to exclude when they are negated (e.g. -nuts)
+func ParseSearch(text string) ([]string, []string) {
+	var terms, negated []string
+
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return unicode.IsSpace(r) || r == '"' }) {
+		exclude := strings.HasPrefix(word, "-")
+		for _, term := range searchWords(word) {
+			if exclude {
+				negated = append(negated, term)
+			} else {
+				terms = append(terms, term)
+			}
+		}
+	}
+	return terms, negated
+}
+
+// SearchScore weighs words of searched fields of product matching any
+// of the terms. Unlike the text index of mongo, words are not stemmed
+// beyond plural forms and score is the weighted count of matched words
+func SearchScore(product domain.Product, terms []string) float64 {
+	fields := map[string][]string{
+		"name":        searchWords(product.Name),
+		"description": searchWords(product.Description),
+		"story":       searchWords(product.Story),
+	}
+	if product.Ingredients != nil {
+		fields["ingredients"] = searchWords(strings.Join(*product.Ingredients, " "))
+	}
+
+	var score float64
+	for field, words := range fields {
+		for _, word := range words {
+			for _, term := range terms {
+				if word == term {
+					score += searchWeights[field]
+					break
+				}
+			}
+		}
+	}
+	return score
+}
+
+// searchWords splits text into lowercase words in singular form
+func searchWords(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		if len(word) > 3 {
+			words[i] = strings.TrimSuffix(word, "s")
+		}
+	}
+	return words
+}
+
+// SearchTermWords are regular expressions matching search
+// terms as words in either singular or plural form
+func SearchTermWords(terms []string) []string {
+	words := make([]string, 0, len(terms))
+	for _, term := range terms {
+		words = append(words, regexp.QuoteMeta(term)+"s?")
+	}
+	return words
+}
+
+// AllergenWords are regular expressions matching allergens as
+// words of allergy info in either singular or plural form, by
+// which products declaring no allergens are filtered
+func AllergenWords(allergens []string) []string {
+	words := make([]string, 0, len(allergens))
+	for _, allergen := range allergens {
+		singular := strings.TrimSuffix(strings.ToLower(allergen), "s")
+		words = append(words, regexp.QuoteMeta(singular)+"s?")
+	}
+	return words
+}
+
+// SortedCounts lists counts by count, then by value
+func SortedCounts(counts map[string]int64) []domain.FacetCount {
+	var facetCounts []domain.FacetCount
+	for value, count := range counts {
+		facetCounts = append(facetCounts, domain.FacetCount{Value: value, Count: count})
+	}
+
+	sort.Slice(facetCounts, func(i, j int) bool {
+		if facetCounts[i].Count != facetCounts[j].Count {
+			return facetCounts[i].Count > facetCounts[j].Count
+		}
+		return facetCounts[i].Value < facetCounts[j].Value
+	})
+	return facetCounts
+}
+
+// FacetFilters are the filters by which each group of facets is
+// counted, see FacetFiltersOf
+type FacetFilters struct {
+	Sourcing  domain.ProductFilter
+	Dietary   domain.ProductFilter
+	Allergens domain.ProductFilter
+}
+
+// FacetFiltersOf lifts the condition of filter on the dimension each
+// group of facets counts, so that selecting a value of a group keeps
+// counting the other values of that group rather than hiding them
+func FacetFiltersOf(filter domain.ProductFilter) FacetFilters {
+	filters := FacetFilters{Sourcing: filter, Dietary: filter, Allergens: filter}
+	filters.Sourcing.SourcingValues = nil
+	filters.Dietary.DietaryCertifications = nil
+	filters.Allergens.ExcludeAllergens = nil
+	return filters
+}
@@ -0,0 +1,234 @@
+package sql
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	sqlHelper "github.com/iqdf/benjerry-service/common/repository/sql"
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/product/repository"
+)
+
+// filter is the condition on products aliased p that are out
+// of trash and satisfy every condition of the filter
+func (repo *ProductSQLRepo) filter(filter domain.ProductFilter) (string, []interface{}) {
+	var (
+		conditions = []string{"p.deleted_at IS NULL"}
+		args       []interface{}
+	)
+	in := func(values []string) string {
+		for _, value := range values {
+			args = append(args, value)
+		}
+		return " IN (" + sqlHelper.Placeholders(len(values)) + ")"
+	}
+
+	if len(filter.SourcingValues) > 0 {
+		hasSourcing := "EXISTS (SELECT 1 FROM product_sourcing_values ps " +
+			"JOIN sourcing_values s ON s.sourcing_value_id = ps.sourcing_value_id " +
+			"WHERE ps.product_id = p.product_id AND s.value"
+
+		if filter.SourcingMatch == domain.MatchAny {
+			conditions = append(conditions, hasSourcing+in(filter.SourcingValues)+")")
+		} else {
+			for _, value := range filter.SourcingValues {
+				conditions = append(conditions, hasSourcing+" = ?)")
+				args = append(args, value)
+			}
+		}
+	}
+
+	if len(filter.DietaryCertifications) > 0 {
+		conditions = append(conditions, "p.dietary_certification"+in(filter.DietaryCertifications))
+	}
+
+	if len(filter.ExcludeAllergens) > 0 {
+		condition, allergenArgs := repo.hasAllergen(filter.ExcludeAllergens)
+		conditions = append(conditions, "NOT "+condition)
+		args = append(args, allergenArgs...)
+	}
+
+	if len(filter.Statuses) > 0 {
+		var statuses []string
+		for _, status := range filter.Statuses {
+			statuses = append(statuses, string(status))
+
+			// products stored without status are published
+			if status == domain.StatusPublished {
+				statuses = append(statuses, "")
+			}
+		}
+		conditions = append(conditions, "p.status"+in(statuses))
+	}
+
+	// products without regions are sold everywhere
+	if filter.Region != "" {
+		conditions = append(conditions,
+			"(NOT EXISTS (SELECT 1 FROM product_regions r WHERE r.product_id = p.product_id) "+
+				"OR EXISTS (SELECT 1 FROM product_regions r WHERE r.product_id = p.product_id AND r.region = ?))",
+		)
+		args = append(args, filter.Region)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// hasAllergen is the condition on products aliased p that contain or
+// may contain any of the allergens, products declaring none by
+// matching allergy info
+func (repo *ProductSQLRepo) hasAllergen(allergens []string) (string, []interface{}) {
+	matches, pattern := repo.db.MatchesWords("p.allergy_info", repository.AllergenWords(allergens))
+
+	args := make([]interface{}, 0, len(allergens)+1)
+	for _, allergen := range allergens {
+		args = append(args, allergen)
+	}
+	args = append(args, pattern)
+
+	return "(EXISTS (SELECT 1 FROM product_allergens a WHERE a.product_id = p.product_id " +
+		"AND a.allergen IN (" + sqlHelper.Placeholders(len(allergens)) + ")) " +
+		"OR (NOT EXISTS (SELECT 1 FROM product_allergens a WHERE a.product_id = p.product_id) AND " + matches + "))", args
+}
+
+// CountFacets counts products matching filter by every sourcing
+// value, dietary certification and known allergen they contain
+// or may contain. Each group is counted without the condition of
+// filter on its own dimension, see repository.FacetFiltersOf
+func (repo *ProductSQLRepo) CountFacets(ctx context.Context, filter domain.ProductFilter) (domain.ProductFacets, error) {
+	var facets domain.ProductFacets
+	filters := repository.FacetFiltersOf(filter)
+
+	where, args := repo.filter(filters.Sourcing)
+
+	sourcingCounts, err := repo.countBy(ctx,
+		"SELECT s.value, COUNT(*) FROM products p "+
+			"JOIN product_sourcing_values ps ON ps.product_id = p.product_id "+
+			"JOIN sourcing_values s ON s.sourcing_value_id = ps.sourcing_value_id "+
+			"WHERE "+where+" GROUP BY s.value", args...,
+	)
+	if err != nil {
+		return domain.ProductFacets{}, err
+	}
+
+	where, args = repo.filter(filters.Dietary)
+	certificationCounts, err := repo.countBy(ctx,
+		"SELECT p.dietary_certification, COUNT(*) FROM products p "+
+			"WHERE "+where+" AND p.dietary_certification <> '' GROUP BY p.dietary_certification", args...,
+	)
+	if err != nil {
+		return domain.ProductFacets{}, err
+	}
+
+	facets.SourcingValues = repository.SortedCounts(sourcingCounts)
+	facets.DietaryCertifications = repository.SortedCounts(certificationCounts)
+
+	// every known allergen is listed, including zero
+	// counts, each counted by a column of its own
+	var (
+		columns      []string
+		allergenArgs []interface{}
+	)
+	for _, allergen := range domain.KnownAllergens {
+		condition, conditionArgs := repo.hasAllergen([]string{allergen})
+		columns = append(columns, "COALESCE(SUM(CASE WHEN "+condition+" THEN 1 ELSE 0 END), 0)")
+		allergenArgs = append(allergenArgs, conditionArgs...)
+	}
+
+	where, args = repo.filter(filters.Allergens)
+	counts := make([]int64, len(domain.KnownAllergens))
+	targets := make([]interface{}, len(counts))
+	for i := range counts {
+		targets[i] = &counts[i]
+	}
+
+	row := repo.db.Conn(ctx).QueryRowContext(ctx, repo.db.Rebind(
+		"SELECT "+strings.Join(columns, ", ")+" FROM products p WHERE "+where,
+	), append(allergenArgs, args...)...)
+	if err = row.Scan(targets...); err != nil {
+		return domain.ProductFacets{}, sqlHelper.TranslateError(err)
+	}
+
+	for i, allergen := range domain.KnownAllergens {
+		facets.Allergens = append(facets.Allergens, domain.FacetCount{Value: allergen, Count: counts[i]})
+	}
+	return facets, nil
+}
+
+// countBy runs query grouping counts by value
+func (repo *ProductSQLRepo) countBy(ctx context.Context, query string, args ...interface{}) (map[string]int64, error) {
+	rows, err := repo.db.Conn(ctx).QueryContext(ctx, repo.db.Rebind(query), args...)
+	if err != nil {
+		return nil, sqlHelper.TranslateError(err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var (
+			value string
+			count int64
+		)
+		if err = rows.Scan(&value, &count); err != nil {
+			return nil, sqlHelper.TranslateError(err)
+		}
+		counts[value] = count
+	}
+	return counts, sqlHelper.TranslateError(rows.Err())
+}
+
+// Search queries filtered products matching text on name, description,
+// story and ingredients, ordered by relevance score, see
+// repository.SearchScore. Products having any of the words are
+// queried, which are then scored and ranked
+func (repo *ProductSQLRepo) Search(
+	ctx context.Context,
+	text string,
+	limit int,
+	filter domain.ProductFilter,
+) ([]domain.ProductSearchResult, error) {
+	results := make([]domain.ProductSearchResult, 0)
+
+	terms, negated := repository.ParseSearch(text)
+	if len(terms) == 0 {
+		return results, nil
+	}
+
+	where, args := repo.filter(filter)
+	words := repository.SearchTermWords(terms)
+
+	var matches []string
+	for _, column := range []string{"p.name", "p.description", "p.story", "i.name"} {
+		condition, pattern := repo.db.MatchesWords(column, words)
+		if column == "i.name" {
+			condition = "EXISTS (SELECT 1 FROM product_ingredients pi " +
+				"JOIN ingredients i ON i.ingredient_id = pi.ingredient_id " +
+				"WHERE pi.product_id = p.product_id AND " + condition + ")"
+		}
+		matches = append(matches, condition)
+		args = append(args, pattern)
+	}
+
+	products, err := repo.findProducts(ctx, where+" AND ("+strings.Join(matches, " OR ")+")", "ORDER BY p.product_id", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, product := range products {
+		if repository.SearchScore(product, negated) > 0 {
+			continue
+		}
+
+		if score := repository.SearchScore(product, terms); score > 0 {
+			results = append(results, domain.ProductSearchResult{Product: product, Score: score})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
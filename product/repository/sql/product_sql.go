@@ -0,0 +1,947 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	sqlHelper "github.com/iqdf/benjerry-service/common/repository/sql"
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/product/repository"
+)
+
+// productColumns are the columns of products table, in the
+// order they are scanned by scanProduct and written by write
+var productColumns = []string{
+	"product_id", "version", "name", "slug", "image_closed_url", "image_open_url",
+	"description", "story", "allergy_info", "dietary_certification",
+	"parsed_ingredients", "allergen_check", "nutrition", "region_overrides", "translations",
+	"status", "retired_at", "epitaph", "scheduled_status", "scheduled_at",
+	"deleted_at", "deleted_by",
+}
+
+// selectProducts selects productColumns of products aliased p
+var selectProducts = "SELECT p." + strings.Join(productColumns, ", p.") + " FROM products p"
+
+// loadBatch bounds productIds listed in a single query
+// loading lists of products, and products streamed at once
+const loadBatch = 500
+
+// productRow is a stored product, in trash when deletedAt is set
+type productRow struct {
+	product   domain.Product
+	deletedAt *time.Time
+	deletedBy string
+}
+
+// ProductSQLRepo stores products in a SQL database. Attributes of
+// products are columns of products table, lists are rows of tables
+// of their own, ingredients and sourcing values normalized into tables
+// of distinct values. Nested attributes are JSON columns. It honors the
+// same constraints and error contract as the mongo repository:
+// productIds, GTINs, slugs and variant SKUs are unique across products,
+// trashed ones included
+type ProductSQLRepo struct {
+	db *sqlHelper.DB
+}
+
+// NewProductRepo creates product repository over db, whose
+// schema is migrated by sqlHelper.DB.Migrate
+func NewProductRepo(db *sqlHelper.DB) *ProductSQLRepo {
+	return &ProductSQLRepo{db: db}
+}
+
+// translateWriteError is like sqlHelper.TranslateError, telling
+// apart GTINs and slugs taken by another product
+func translateWriteError(err error) error {
+	constraint := sqlHelper.ViolatedUnique(err)
+	switch {
+	case strings.Contains(constraint, "gtin"):
+		return domain.ErrDuplicateGTIN
+	case strings.Contains(constraint, "slug"):
+		return domain.ErrDuplicateSlug
+	}
+	return sqlHelper.TranslateError(err)
+}
+
+// scanProduct reads product from productColumns, leaving its lists
+// to loadLists. Products stored without status are published
+func scanProduct(rows *sql.Rows) (productRow, error) {
+	var (
+		stored                                                          productRow
+		product                                                         domain.Product
+		slug                                                            sql.NullString
+		parsed, allergenCheck, nutrition, regionOverrides, translations sql.NullString
+		retiredAt, scheduledAt, deletedAt                               sql.NullTime
+		status, scheduledStatus                                         string
+	)
+
+	err := rows.Scan(
+		&product.ProductID, &product.Version, &product.Name, &slug,
+		&product.ImageClosedURL, &product.ImageOpenURL,
+		&product.Description, &product.Story, &product.AllergyInfo, &product.DietaryCertification,
+		&parsed, &allergenCheck, &nutrition, &regionOverrides, &translations,
+		&status, &retiredAt, &product.Epitaph, &scheduledStatus, &scheduledAt,
+		&deletedAt, &stored.deletedBy,
+	)
+	if err != nil {
+		return productRow{}, err
+	}
+
+	product.Slug = slug.String
+	product.Status = domain.ProductStatus(status)
+	if product.Status == "" {
+		product.Status = domain.StatusPublished
+	}
+	product.ScheduledStatus = domain.ProductStatus(scheduledStatus)
+	product.RetiredAt = timeOf(retiredAt)
+	product.ScheduledAt = timeOf(scheduledAt)
+	stored.deletedAt = timeOf(deletedAt)
+
+	for _, column := range []struct {
+		value  sql.NullString
+		target interface{}
+	}{
+		{parsed, &product.ParsedIngredients},
+		{allergenCheck, &product.AllergenCheck},
+		{nutrition, &product.Nutrition},
+		{regionOverrides, &product.RegionOverrides},
+		{translations, &product.Translations},
+	} {
+		if !column.value.Valid {
+			continue
+		}
+		if err = json.Unmarshal([]byte(column.value.String), column.target); err != nil {
+			return productRow{}, err
+		}
+	}
+
+	stored.product = product
+	return stored, nil
+}
+
+// timeOf reads nullable time column in UTC
+func timeOf(column sql.NullTime) *time.Time {
+	if !column.Valid {
+		return nil
+	}
+	utc := column.Time.UTC()
+	return &utc
+}
+
+// timeValue writes time into nullable time column in UTC
+func timeValue(value *time.Time) interface{} {
+	if value == nil {
+		return nil
+	}
+	return value.UTC()
+}
+
+// jsonValue writes value into nullable JSON column,
+// which is null when value is empty
+func jsonValue(value interface{}, empty bool) (interface{}, error) {
+	if empty {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(encoded), nil
+}
+
+// productValues lists values of productColumns for stored product
+func productValues(stored productRow) ([]interface{}, error) {
+	product := stored.product
+
+	var slug interface{}
+	if product.Slug != "" {
+		slug = product.Slug
+	}
+
+	values := []interface{}{
+		product.ProductID, product.Version, product.Name, slug,
+		product.ImageClosedURL, product.ImageOpenURL,
+		product.Description, product.Story, product.AllergyInfo, product.DietaryCertification,
+	}
+
+	for _, column := range []struct {
+		value interface{}
+		empty bool
+	}{
+		{product.ParsedIngredients, len(product.ParsedIngredients) == 0},
+		{product.AllergenCheck, product.AllergenCheck.Agrees()},
+		{product.Nutrition, product.Nutrition.IsEmpty()},
+		{product.RegionOverrides, len(product.RegionOverrides) == 0},
+		{product.Translations, len(product.Translations) == 0},
+	} {
+		value, err := jsonValue(column.value, column.empty)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return append(values,
+		string(product.Status), timeValue(product.RetiredAt), product.Epitaph,
+		string(product.ScheduledStatus), timeValue(product.ScheduledAt),
+		timeValue(stored.deletedAt), stored.deletedBy,
+	), nil
+}
+
+// find queries products matching where, in the order and up
+// to the limit given by tail, together with their lists
+func (repo *ProductSQLRepo) find(ctx context.Context, where string, tail string, args ...interface{}) ([]productRow, error) {
+	rows, err := repo.db.Conn(ctx).QueryContext(ctx, repo.db.Rebind(selectProducts+" WHERE "+where+" "+tail), args...)
+	if err != nil {
+		return nil, sqlHelper.TranslateError(err)
+	}
+
+	var stored []productRow
+	for rows.Next() {
+		row, err := scanProduct(rows)
+		if err != nil {
+			rows.Close()
+			return nil, sqlHelper.TranslateError(err)
+		}
+		stored = append(stored, row)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return nil, sqlHelper.TranslateError(err)
+	}
+
+	for start := 0; start < len(stored); start += loadBatch {
+		end := start + loadBatch
+		if end > len(stored) {
+			end = len(stored)
+		}
+		if err = repo.loadLists(ctx, stored[start:end]); err != nil {
+			return nil, sqlHelper.TranslateError(err)
+		}
+	}
+	return stored, nil
+}
+
+// findProducts queries products matching where like find
+func (repo *ProductSQLRepo) findProducts(ctx context.Context, where string, tail string, args ...interface{}) ([]domain.Product, error) {
+	stored, err := repo.find(ctx, where, tail, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]domain.Product, 0, len(stored))
+	for _, row := range stored {
+		products = append(products, row.product)
+	}
+	return products, nil
+}
+
+// first queries the first product matching where
+func (repo *ProductSQLRepo) first(ctx context.Context, where string, tail string, args ...interface{}) (productRow, error) {
+	stored, err := repo.find(ctx, where, tail+" LIMIT 1", args...)
+	if err != nil {
+		return productRow{}, err
+	}
+
+	if len(stored) == 0 {
+		return productRow{}, domain.ErrResourceNotFound
+	}
+	return stored[0], nil
+}
+
+// loadLists reads lists of stored products from their tables
+func (repo *ProductSQLRepo) loadLists(ctx context.Context, stored []productRow) error {
+	products := make(map[string]*domain.Product, len(stored))
+	args := make([]interface{}, 0, len(stored))
+	for i := range stored {
+		product := &stored[i].product
+		product.SourcingValues = &[]string{}
+		product.Ingredients = &[]string{}
+
+		products[product.ProductID] = product
+		args = append(args, product.ProductID)
+	}
+	in := " IN (" + sqlHelper.Placeholders(len(args)) + ")"
+
+	lists := []struct {
+		query string
+		load  func(rows *sql.Rows) error
+	}{
+		{
+			"SELECT pi.product_id, i.name FROM product_ingredients pi " +
+				"JOIN ingredients i ON i.ingredient_id = pi.ingredient_id " +
+				"WHERE pi.product_id" + in + " ORDER BY pi.product_id, pi.ordinal",
+			appendString(products, func(product *domain.Product) *[]string { return product.Ingredients }),
+		},
+		{
+			"SELECT ps.product_id, s.value FROM product_sourcing_values ps " +
+				"JOIN sourcing_values s ON s.sourcing_value_id = ps.sourcing_value_id " +
+				"WHERE ps.product_id" + in + " ORDER BY ps.product_id, ps.ordinal",
+			appendString(products, func(product *domain.Product) *[]string { return product.SourcingValues }),
+		},
+		{
+			"SELECT product_id, slug FROM product_previous_slugs WHERE product_id" + in + " ORDER BY product_id, ordinal",
+			appendString(products, func(product *domain.Product) *[]string { return &product.PreviousSlugs }),
+		},
+		{
+			"SELECT product_id, region FROM product_regions WHERE product_id" + in + " ORDER BY product_id, ordinal",
+			appendString(products, func(product *domain.Product) *[]string { return &product.Regions }),
+		},
+		{
+			"SELECT product_id, gtin FROM product_gtins WHERE product_id" + in + " ORDER BY product_id, ordinal",
+			appendString(products, func(product *domain.Product) *[]string { return &product.GTINs }),
+		},
+		{
+			"SELECT product_id, allergen, may_contain FROM product_allergens " +
+				"WHERE product_id" + in + " ORDER BY product_id, may_contain, ordinal",
+			func(rows *sql.Rows) error {
+				var (
+					productID, allergen string
+					mayContain          int
+				)
+				if err := rows.Scan(&productID, &allergen, &mayContain); err != nil {
+					return err
+				}
+
+				allergens := &products[productID].Allergens
+				if mayContain != 0 {
+					allergens.MayContain = append(allergens.MayContain, allergen)
+				} else {
+					allergens.Contains = append(allergens.Contains, allergen)
+				}
+				return nil
+			},
+		},
+		{
+			"SELECT product_id, sku, format, size, image_closed_url, image_open_url, ingredients " +
+				"FROM product_variants WHERE product_id" + in + " ORDER BY product_id, ordinal",
+			func(rows *sql.Rows) error {
+				var (
+					productID   string
+					variant     domain.ProductVariant
+					format      string
+					ingredients sql.NullString
+				)
+				err := rows.Scan(&productID, &variant.SKU, &format, &variant.Size,
+					&variant.ImageClosedURL, &variant.ImageOpenURL, &ingredients)
+				if err != nil {
+					return err
+				}
+
+				variant.Format = domain.VariantFormat(format)
+				if ingredients.Valid {
+					if err = json.Unmarshal([]byte(ingredients.String), &variant.Ingredients); err != nil {
+						return err
+					}
+				}
+
+				product := products[productID]
+				product.Variants = append(product.Variants, variant)
+				return nil
+			},
+		},
+	}
+
+	conn := repo.db.Conn(ctx)
+	for _, list := range lists {
+		if err := loadList(ctx, conn, repo.db.Rebind(list.query), args, list.load); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadList runs query of a list and loads every row of it
+func loadList(ctx context.Context, conn sqlHelper.Querier, query string, args []interface{}, load func(rows *sql.Rows) error) error {
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err = load(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// appendString loads rows of productId and value
+// by appending value to the list of product
+func appendString(products map[string]*domain.Product, list func(product *domain.Product) *[]string) func(rows *sql.Rows) error {
+	return func(rows *sql.Rows) error {
+		var productID, value string
+		if err := rows.Scan(&productID, &value); err != nil {
+			return err
+		}
+
+		values := list(products[productID])
+		*values = append(*values, value)
+		return nil
+	}
+}
+
+// insert stores a new product together with its lists
+func (repo *ProductSQLRepo) insert(ctx context.Context, stored productRow) error {
+	values, err := productValues(stored)
+	if err != nil {
+		return sqlHelper.TranslateError(err)
+	}
+
+	_, err = repo.db.Conn(ctx).ExecContext(ctx, repo.db.Rebind(
+		"INSERT INTO products ("+strings.Join(productColumns, ", ")+") VALUES ("+sqlHelper.Placeholders(len(values))+")",
+	), values...)
+	if err != nil {
+		return translateWriteError(err)
+	}
+	return repo.insertLists(ctx, stored.product)
+}
+
+// update overwrites a stored product together with its lists
+func (repo *ProductSQLRepo) update(ctx context.Context, stored productRow) error {
+	values, err := productValues(stored)
+	if err != nil {
+		return sqlHelper.TranslateError(err)
+	}
+
+	// values start with productId, which identifies the product
+	assignments := strings.Join(productColumns[1:], " = ?, ") + " = ?"
+	values = append(values[1:], stored.product.ProductID)
+
+	conn := repo.db.Conn(ctx)
+	_, err = conn.ExecContext(ctx, repo.db.Rebind("UPDATE products SET "+assignments+" WHERE product_id = ?"), values...)
+	if err != nil {
+		return translateWriteError(err)
+	}
+
+	for _, table := range []string{
+		"product_ingredients", "product_sourcing_values", "product_previous_slugs",
+		"product_regions", "product_gtins", "product_allergens", "product_variants",
+	} {
+		_, err = conn.ExecContext(ctx, repo.db.Rebind("DELETE FROM "+table+" WHERE product_id = ?"), stored.product.ProductID)
+		if err != nil {
+			return sqlHelper.TranslateError(err)
+		}
+	}
+	return repo.insertLists(ctx, stored.product)
+}
+
+// insertLists stores lists of product into their tables, adding
+// ingredients and sourcing values that are not stored yet
+func (repo *ProductSQLRepo) insertLists(ctx context.Context, product domain.Product) error {
+	conn := repo.db.Conn(ctx)
+	exec := func(query string, args ...interface{}) error {
+		_, err := conn.ExecContext(ctx, repo.db.Rebind(query), args...)
+		return err
+	}
+
+	var statements []func() error
+	addValues := func(values []string, table, column, list, listColumn string) {
+		for i, value := range values {
+			i, value := i, value
+			statements = append(statements, func() error {
+				if err := exec("INSERT INTO "+table+" ("+column+") VALUES (?) ON CONFLICT ("+column+") DO NOTHING", value); err != nil {
+					return err
+				}
+				return exec(
+					"INSERT INTO "+list+" (product_id, ordinal, "+listColumn+") "+
+						"VALUES (?, ?, (SELECT "+listColumn+" FROM "+table+" WHERE "+column+" = ?))",
+					product.ProductID, i, value,
+				)
+			})
+		}
+	}
+	addList := func(values []string, table, column string) {
+		for i, value := range values {
+			i, value := i, value
+			statements = append(statements, func() error {
+				return exec("INSERT INTO "+table+" (product_id, ordinal, "+column+") VALUES (?, ?, ?)", product.ProductID, i, value)
+			})
+		}
+	}
+	addAllergens := func(allergens []string, mayContain int) {
+		for i, allergen := range allergens {
+			i, allergen := i, allergen
+			statements = append(statements, func() error {
+				return exec(
+					"INSERT INTO product_allergens (product_id, may_contain, ordinal, allergen) VALUES (?, ?, ?, ?)",
+					product.ProductID, mayContain, i, allergen,
+				)
+			})
+		}
+	}
+
+	if product.Ingredients != nil {
+		addValues(*product.Ingredients, "ingredients", "name", "product_ingredients", "ingredient_id")
+	}
+	if product.SourcingValues != nil {
+		addValues(*product.SourcingValues, "sourcing_values", "value", "product_sourcing_values", "sourcing_value_id")
+	}
+	addList(product.PreviousSlugs, "product_previous_slugs", "slug")
+	addList(product.Regions, "product_regions", "region")
+	addList(product.GTINs, "product_gtins", "gtin")
+	addAllergens(product.Allergens.Contains, 0)
+	addAllergens(product.Allergens.MayContain, 1)
+
+	for i, variant := range product.Variants {
+		i, variant := i, variant
+		statements = append(statements, func() error {
+			ingredients, err := jsonValue(variant.Ingredients, variant.Ingredients == nil)
+			if err != nil {
+				return err
+			}
+			return exec(
+				"INSERT INTO product_variants (sku, product_id, ordinal, format, size, image_closed_url, image_open_url, ingredients) "+
+					"VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+				variant.SKU, product.ProductID, i, string(variant.Format), variant.Size,
+				variant.ImageClosedURL, variant.ImageOpenURL, ingredients,
+			)
+		})
+	}
+
+	for _, statement := range statements {
+		if err := statement(); err != nil {
+			return translateWriteError(err)
+		}
+	}
+	return nil
+}
+
+// locked queries a single product, trashed or not, and locks it
+// until the end of the transaction carried by ctx
+func (repo *ProductSQLRepo) locked(ctx context.Context, productID string) (productRow, error) {
+	return repo.first(ctx, "p.product_id = ?", repo.db.ForUpdate(), productID)
+}
+
+// writable locks a single product that is not in trash and, given
+// non-zero version, tells apart a missing product from a product that
+// is no longer at that version, like checkMatched of the mongo repository
+func (repo *ProductSQLRepo) writable(ctx context.Context, productID string, version int64) (productRow, error) {
+	stored, err := repo.locked(ctx, productID)
+	switch {
+	case err != nil:
+		return productRow{}, err
+	case stored.deletedAt != nil:
+		return productRow{}, domain.ErrResourceNotFound
+	case version != 0 && stored.product.Version != version:
+		return productRow{}, domain.ErrPreconditionFailed
+	}
+	return stored, nil
+}
+
+// write applies modify to a single product out of trash, which is
+// stored at the next version unless modify fails. Given non-zero
+// version, the product must be at that version
+func (repo *ProductSQLRepo) write(ctx context.Context, productID string, version int64, modify func(stored *domain.Product) error) error {
+	return repo.db.WithinTransaction(ctx, func(ctx context.Context) error {
+		stored, err := repo.writable(ctx, productID, version)
+		if err != nil {
+			return err
+		}
+
+		if err = modify(&stored.product); err != nil {
+			return err
+		}
+
+		stored.product.Version++
+		return repo.update(ctx, stored)
+	})
+}
+
+// sortColumns are columns of sortable product fields other
+// than productId, which breaks ties of every sort
+var sortColumns = map[string]string{
+	domain.SortByName:      "p.name",
+	domain.SortByRetiredAt: "p.retired_at",
+}
+
+// sortValue reads the value of sorted field from the product
+func sortValue(product domain.Product, sortBy string) string {
+	switch sortBy {
+	case domain.SortByName:
+		return product.Name
+	case domain.SortByRetiredAt:
+		return domain.FormatSortTime(product.RetiredAt)
+	}
+	return product.ProductID
+}
+
+// cursorValue reads sort value of cursor as stored in sorted column
+func cursorValue(cursor domain.PageCursor, sortBy string) (interface{}, error) {
+	if sortBy == domain.SortByRetiredAt {
+		retiredAt, err := domain.ParseSortTime(cursor.SortValue)
+		return retiredAt.UTC(), err
+	}
+	return cursor.SortValue, nil
+}
+
+// Fetch queries a page of products sorted by the requested field.
+// Pages are chained by the cursor of the last product of a page
+func (repo *ProductSQLRepo) Fetch(ctx context.Context, query domain.ProductQuery) (domain.ProductPage, error) {
+	var page domain.ProductPage
+
+	where, args := repo.filter(query.Filter)
+
+	row := repo.db.Conn(ctx).QueryRowContext(ctx, repo.db.Rebind("SELECT COUNT(*) FROM products p WHERE "+where), args...)
+	if err := row.Scan(&page.TotalCount); err != nil {
+		return domain.ProductPage{}, sqlHelper.TranslateError(err)
+	}
+
+	order, after := "ASC", ">"
+	if query.SortOrder == domain.Descending {
+		order, after = "DESC", "<"
+	}
+
+	column := sortColumns[query.SortBy]
+	sorted := "p.product_id " + order
+	if column != "" {
+		sorted = column + " " + order + ", " + sorted
+	}
+
+	if query.Cursor != "" {
+		cursor, err := domain.DecodePageCursor(query.Cursor)
+		if err != nil {
+			return domain.ProductPage{}, err
+		}
+
+		if column != "" {
+			value, err := cursorValue(cursor, query.SortBy)
+			if err != nil {
+				return domain.ProductPage{}, err
+			}
+			where += " AND (" + column + " " + after + " ? OR (" + column + " = ? AND p.product_id " + after + " ?))"
+			args = append(args, value, value, cursor.ProductID)
+		} else {
+			where += " AND p.product_id " + after + " ?"
+			args = append(args, cursor.ProductID)
+		}
+	}
+
+	// fetching a product beyond the limit tells
+	// whether there is another page after this one
+	tail := "ORDER BY " + sorted
+	if query.Limit > 0 {
+		tail += " LIMIT ?"
+		args = append(args, query.Limit+1)
+	}
+
+	products, err := repo.findProducts(ctx, where, tail, args...)
+	if err != nil {
+		return domain.ProductPage{}, err
+	}
+
+	if query.Limit > 0 && len(products) > query.Limit {
+		products = products[:query.Limit]
+		last := products[len(products)-1]
+
+		page.NextCursor = domain.PageCursor{SortValue: sortValue(last, query.SortBy), ProductID: last.ProductID}.Encode()
+	}
+
+	page.Products = products
+	return page, nil
+}
+
+// Get queries a single product identified by productID
+func (repo *ProductSQLRepo) Get(ctx context.Context, productID string) (domain.Product, error) {
+	stored, err := repo.first(ctx, "p.product_id = ? AND p.deleted_at IS NULL", "", productID)
+	return stored.product, err
+}
+
+// GetBySKU queries the single product having variant of sku
+func (repo *ProductSQLRepo) GetBySKU(ctx context.Context, sku string) (domain.Product, error) {
+	stored, err := repo.first(ctx,
+		"p.deleted_at IS NULL AND EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.product_id AND v.sku = ?)",
+		"ORDER BY p.product_id", sku,
+	)
+	return stored.product, err
+}
+
+// GetByGTIN queries the single product having gtin
+func (repo *ProductSQLRepo) GetByGTIN(ctx context.Context, gtin string) (domain.Product, error) {
+	stored, err := repo.first(ctx,
+		"p.deleted_at IS NULL AND EXISTS (SELECT 1 FROM product_gtins g WHERE g.product_id = p.product_id AND g.gtin = ?)",
+		"ORDER BY p.product_id", gtin,
+	)
+	return stored.product, err
+}
+
+// GetBySlug queries the single product whose current or previous slug
+// is slug, preferring the current one. Trashed products are included,
+// as their slugs stay reserved until they are purged
+func (repo *ProductSQLRepo) GetBySlug(ctx context.Context, slug string) (domain.Product, error) {
+	stored, err := repo.first(ctx,
+		"p.slug = ? OR EXISTS (SELECT 1 FROM product_previous_slugs ps WHERE ps.product_id = p.product_id AND ps.slug = ?)",
+		"ORDER BY CASE WHEN p.slug = ? THEN 0 ELSE 1 END, p.product_id", slug, slug, slug,
+	)
+	return stored.product, err
+}
+
+// Create inserts a single product at version 1
+func (repo *ProductSQLRepo) Create(ctx context.Context, product domain.Product) error {
+	product.Version = 1
+	repository.DefaultLists(&product)
+
+	// productId taken is told apart from other conflicts
+	// first, whichever constraint the database checks first
+	return repo.db.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := repo.locked(ctx, product.ProductID); err != domain.ErrResourceNotFound {
+			if err == nil {
+				err = domain.ErrConflict
+			}
+			return err
+		}
+		return repo.insert(ctx, productRow{product: product})
+	})
+}
+
+// Upsert creates published products that do not exist yet and
+// updates attributes of existing products, leaving their lifecycle,
+// translations and variants as they are. Upserting a trashed product
+// takes it out of trash. Products conflicting with others are listed
+// as failures once the rest are written, like an unordered bulk write
+func (repo *ProductSQLRepo) Upsert(ctx context.Context, products []domain.Product) (domain.UpsertResult, error) {
+	var result domain.UpsertResult
+
+	err := repo.db.WithinTransaction(ctx, func(ctx context.Context) error {
+		for i, product := range products {
+			content := repository.Content(product)
+			repository.DefaultLists(&content)
+
+			// every product is written within a savepoint of its
+			// own, which rolls back the product alone on conflict
+			var created bool
+			err := repo.db.WithinTransaction(ctx, func(ctx context.Context) error {
+				stored, err := repo.locked(ctx, product.ProductID)
+				switch {
+				case err == domain.ErrResourceNotFound:
+					created = true
+					content.Status = domain.StatusPublished
+					content.Version = 1
+					return repo.insert(ctx, productRow{product: content})
+
+				case err != nil:
+					return err
+				}
+
+				repository.SetContent(&stored.product, content)
+				stored.product.ParsedIngredients = content.ParsedIngredients
+				stored.product.Version++
+				return repo.update(ctx, productRow{product: stored.product})
+			})
+
+			if domain.IsConflict(err) {
+				result.Failures = append(result.Failures, domain.UpsertFailure{Index: i, Err: err})
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			if created {
+				result.Created++
+			} else {
+				result.Updated++
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return domain.UpsertResult{}, err
+	}
+	return result, nil
+}
+
+// Stream calls fn on every filtered product ordered by productId.
+// Products are read in batches, none of which is being read while
+// fn is called, so that fn may write to the repository. Streaming
+// stops at the first error returned by fn
+func (repo *ProductSQLRepo) Stream(ctx context.Context, filter domain.ProductFilter, fn func(domain.Product) error) error {
+	where, args := repo.filter(filter)
+
+	var after string
+	for {
+		products, err := repo.findProducts(ctx,
+			where+" AND p.product_id > ?", "ORDER BY p.product_id LIMIT ?",
+			append(args[:len(args):len(args)], after, loadBatch)...,
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, product := range products {
+			if err = fn(product); err != nil {
+				return err
+			}
+		}
+
+		if len(products) < loadBatch {
+			return nil
+		}
+		after = products[len(products)-1].ProductID
+	}
+}
+
+// Update modifies non-empty attributes of a single product. Given
+// non-zero product version, the product must be at that version
+func (repo *ProductSQLRepo) Update(ctx context.Context, productID string, product domain.Product) error {
+	content := repository.Content(product)
+
+	return repo.write(ctx, productID, product.Version, func(stored *domain.Product) error {
+		repository.SetContent(stored, content)
+
+		// parsed ingredients are cleared once
+		// ingredients are updated to none
+		if content.Ingredients != nil && len(content.ParsedIngredients) == 0 {
+			stored.ParsedIngredients = nil
+		}
+		return nil
+	})
+}
+
+// Replace overwrites every attribute of a single product, unlike
+// Update, attributes that are empty in product are cleared. Slug
+// is left as it is when empty. Given non-zero product version,
+// the product must be at that version
+func (repo *ProductSQLRepo) Replace(ctx context.Context, productID string, product domain.Product) error {
+	return repo.write(ctx, productID, product.Version, func(stored *domain.Product) error {
+		repository.ReplaceContent(stored, product)
+		return nil
+	})
+}
+
+// UpdateStatus overwrites lifecycle attributes of a single product.
+// Given non-zero product version, the product must be at that version
+func (repo *ProductSQLRepo) UpdateStatus(ctx context.Context, productID string, product domain.Product) error {
+	return repo.write(ctx, productID, product.Version, func(stored *domain.Product) error {
+		repository.SetLifecycle(stored, product)
+		return nil
+	})
+}
+
+// UpdateTranslations overwrites translations of a single product.
+// Given non-zero product version, the product must be at that version
+func (repo *ProductSQLRepo) UpdateTranslations(ctx context.Context, productID string, product domain.Product) error {
+	return repo.write(ctx, productID, product.Version, func(stored *domain.Product) error {
+		stored.Translations = product.Translations
+		return nil
+	})
+}
+
+// UpdateVariants overwrites variants of a single product. Given
+// non-zero product version, the product must be at that version.
+// Variant SKU taken by another product is a conflict
+func (repo *ProductSQLRepo) UpdateVariants(ctx context.Context, productID string, product domain.Product) error {
+	err := repo.write(ctx, productID, product.Version, func(stored *domain.Product) error {
+		stored.Variants = product.Variants
+		return nil
+	})
+
+	if err == domain.ErrConflict {
+		return domain.ErrDuplicateSKU
+	}
+	return err
+}
+
+// FetchScheduled queries products with a status change
+// scheduled at or before the given time
+func (repo *ProductSQLRepo) FetchScheduled(ctx context.Context, before time.Time) ([]domain.Product, error) {
+	return repo.findProducts(ctx,
+		"p.deleted_at IS NULL AND p.scheduled_at <= ?", "ORDER BY p.product_id", before.UTC(),
+	)
+}
+
+// Delete moves a single product to trash, marking it with the time
+// of deletion and who deleted it. Given non-zero version, the
+// product must be at that version
+func (repo *ProductSQLRepo) Delete(ctx context.Context, productID string, version int64, deletedBy string) error {
+	return repo.db.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := repo.writable(ctx, productID, version); err != nil {
+			return err
+		}
+
+		_, err := repo.db.Conn(ctx).ExecContext(ctx, repo.db.Rebind(
+			"UPDATE products SET deleted_at = ?, deleted_by = ?, version = version + 1 WHERE product_id = ?",
+		), time.Now().UTC(), deletedBy, productID)
+		return sqlHelper.TranslateError(err)
+	})
+}
+
+// FetchTrash queries all trashed products, latest deleted first
+func (repo *ProductSQLRepo) FetchTrash(ctx context.Context) ([]domain.TrashedProduct, error) {
+	stored, err := repo.find(ctx, "p.deleted_at IS NOT NULL", "ORDER BY p.deleted_at DESC, p.product_id")
+	if err != nil {
+		return nil, err
+	}
+	return newTrash(stored), nil
+}
+
+// FetchTrashBefore queries up to limit products trashed
+// before deletedBefore, earliest deleted first
+func (repo *ProductSQLRepo) FetchTrashBefore(ctx context.Context, deletedBefore time.Time, limit int) ([]domain.TrashedProduct, error) {
+	stored, err := repo.find(ctx,
+		"p.deleted_at < ?", "ORDER BY p.deleted_at, p.product_id LIMIT ?", deletedBefore.UTC(), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return newTrash(stored), nil
+}
+
+// GetTrashed queries a single trashed product
+func (repo *ProductSQLRepo) GetTrashed(ctx context.Context, productID string) (domain.TrashedProduct, error) {
+	stored, err := repo.first(ctx, "p.product_id = ? AND p.deleted_at IS NOT NULL", "", productID)
+	if err != nil {
+		return domain.TrashedProduct{}, err
+	}
+	return newTrash([]productRow{stored})[0], nil
+}
+
+// newTrash reads trashed products of rows
+func newTrash(stored []productRow) []domain.TrashedProduct {
+	trash := make([]domain.TrashedProduct, 0, len(stored))
+	for _, row := range stored {
+		trash = append(trash, domain.TrashedProduct{
+			Product:   row.product,
+			DeletedAt: *row.deletedAt,
+			DeletedBy: row.deletedBy,
+		})
+	}
+	return trash
+}
+
+// Restore takes a single product out of trash
+func (repo *ProductSQLRepo) Restore(ctx context.Context, productID string) error {
+	return repo.trashed(ctx, productID,
+		"UPDATE products SET deleted_at = NULL, deleted_by = '', version = version + 1 WHERE product_id = ? AND deleted_at IS NOT NULL",
+	)
+}
+
+// Purge removes a single trashed product for good
+func (repo *ProductSQLRepo) Purge(ctx context.Context, productID string) error {
+	return repo.trashed(ctx, productID, "DELETE FROM products WHERE product_id = ? AND deleted_at IS NOT NULL")
+}
+
+// trashed runs statement on a single trashed product
+func (repo *ProductSQLRepo) trashed(ctx context.Context, productID string, statement string) error {
+	result, err := repo.db.Conn(ctx).ExecContext(ctx, repo.db.Rebind(statement), productID)
+	if err != nil {
+		return sqlHelper.TranslateError(err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return sqlHelper.TranslateError(err)
+	}
+
+	if affected == 0 {
+		return domain.ErrResourceNotFound
+	}
+	return nil
+}
@@ -0,0 +1,469 @@
+package sql
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	sqlHelper "github.com/iqdf/benjerry-service/common/repository/sql"
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/domain/repotest"
+	"github.com/stretchr/testify/assert"
+)
+
+// openTestDB opens a migrated sqlite database in a temporary
+// directory, which is removed by the returned function
+func openTestDB(t *testing.T) (*sqlHelper.DB, func()) {
+	dir, err := ioutil.TempDir("", "benjerry")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sqlHelper.Open(sqlHelper.SQLite, "file:"+filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = db.Migrate(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func createMockProduct(productID string, name string) domain.Product {
+	ingredients := []string{"cream", "skim milk", "toffee"}
+	sourcing := []string{"Fairtrade"}
+	return domain.Product{
+		ProductID:      productID,
+		Name:           name,
+		Slug:           "slug-" + productID,
+		Description:    "Buttery toffee in vanilla",
+		Ingredients:    &ingredients,
+		SourcingValues: &sourcing,
+		Status:         domain.StatusPublished,
+	}
+}
+
+func TestCreateProduct(t *testing.T) {
+	ctx := context.TODO()
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+	repo := NewProductRepo(db)
+
+	t.Run("Create-success", func(t *testing.T) {
+		product := createMockProduct("646", "Vanilla Toffee Bar Crunch")
+		product.GTINs = []string{"0076840100446"}
+		product.Regions = []string{"us", "uk"}
+		product.Allergens = domain.Allergens{Contains: []string{"milk"}, MayContain: []string{"peanuts", "soy"}}
+		product.ParsedIngredients = []domain.Ingredient{{Name: "cream"}, {Name: "toffee", Qualifiers: []string{"sugar"}}}
+		product.RegionOverrides = map[string]domain.RegionOverride{"uk": {AllergyInfo: "contains milk"}}
+		assert.NoError(t, repo.Create(ctx, product))
+
+		stored, err := repo.Get(ctx, "646")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), stored.Version)
+		assert.Equal(t, product.Name, stored.Name)
+		assert.Equal(t, *product.Ingredients, *stored.Ingredients)
+		assert.Equal(t, *product.SourcingValues, *stored.SourcingValues)
+		assert.Equal(t, product.GTINs, stored.GTINs)
+		assert.Equal(t, product.Regions, stored.Regions)
+		assert.Equal(t, product.Allergens, stored.Allergens)
+		assert.Equal(t, product.ParsedIngredients, stored.ParsedIngredients)
+		assert.Equal(t, product.RegionOverrides, stored.RegionOverrides)
+	})
+
+	t.Run("Create-conflict", func(t *testing.T) {
+		err := repo.Create(ctx, createMockProduct("646", "Another"))
+		assert.Equal(t, domain.ErrConflict, err)
+	})
+
+	t.Run("Create-duplicate-gtin-and-slug", func(t *testing.T) {
+		duplicate := createMockProduct("648", "Chunky Monkey")
+		duplicate.GTINs = []string{"0076840100446"}
+		assert.Equal(t, domain.ErrDuplicateGTIN, repo.Create(ctx, duplicate))
+
+		duplicate = createMockProduct("648", "Chunky Monkey")
+		duplicate.Slug = "slug-646"
+		assert.Equal(t, domain.ErrDuplicateSlug, repo.Create(ctx, duplicate))
+
+		// rejected products leave nothing behind
+		_, err := repo.Get(ctx, "648")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+
+	t.Run("Get-not-found", func(t *testing.T) {
+		_, err := repo.Get(ctx, "999")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+
+		_, err = repo.GetByGTIN(ctx, "0000000000000")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+}
+
+func TestUpdateProduct(t *testing.T) {
+	ctx := context.TODO()
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+	repo := NewProductRepo(db)
+	repo.Create(ctx, createMockProduct("646", "Vanilla Toffee Bar Crunch"))
+
+	t.Run("Update-partial", func(t *testing.T) {
+		err := repo.Update(ctx, "646", domain.Product{Story: "Toffee was never so crunchy"})
+		assert.NoError(t, err)
+
+		stored, _ := repo.Get(ctx, "646")
+		assert.Equal(t, int64(2), stored.Version)
+		assert.Equal(t, "Vanilla Toffee Bar Crunch", stored.Name)
+		assert.Equal(t, "Toffee was never so crunchy", stored.Story)
+		assert.Equal(t, []string{"cream", "skim milk", "toffee"}, *stored.Ingredients)
+	})
+
+	t.Run("Update-precondition-failed", func(t *testing.T) {
+		err := repo.Update(ctx, "646", domain.Product{Name: "Stale", Version: 1})
+		assert.Equal(t, domain.ErrPreconditionFailed, err)
+	})
+
+	t.Run("Update-not-found", func(t *testing.T) {
+		err := repo.Update(ctx, "999", domain.Product{Name: "Missing"})
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+
+	t.Run("Replace-clears", func(t *testing.T) {
+		err := repo.Replace(ctx, "646", domain.Product{Name: "Vanilla Toffee", Version: 2})
+		assert.NoError(t, err)
+
+		stored, _ := repo.Get(ctx, "646")
+		assert.Equal(t, int64(3), stored.Version)
+		assert.Empty(t, stored.Story)
+		assert.Empty(t, *stored.Ingredients)
+		assert.Equal(t, "slug-646", stored.Slug)
+	})
+
+	t.Run("UpdateStatus-and-translations", func(t *testing.T) {
+		scheduledAt := time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)
+		err := repo.UpdateStatus(ctx, "646", domain.Product{
+			Status:          domain.StatusDraft,
+			ScheduledStatus: domain.StatusPublished,
+			ScheduledAt:     &scheduledAt,
+		})
+		assert.NoError(t, err)
+
+		translations := map[string]domain.ProductTranslation{"fr": {Name: "Caramel Croquant"}}
+		assert.NoError(t, repo.UpdateTranslations(ctx, "646", domain.Product{Translations: translations}))
+
+		stored, _ := repo.Get(ctx, "646")
+		assert.Equal(t, domain.StatusDraft, stored.Status)
+		assert.Equal(t, translations, stored.Translations)
+
+		scheduled, err := repo.FetchScheduled(ctx, scheduledAt)
+		assert.NoError(t, err)
+		assert.Len(t, scheduled, 1)
+		assert.True(t, scheduledAt.Equal(*scheduled[0].ScheduledAt))
+
+		scheduled, _ = repo.FetchScheduled(ctx, scheduledAt.Add(-time.Second))
+		assert.Empty(t, scheduled)
+	})
+
+	t.Run("UpdateVariants-duplicate-sku", func(t *testing.T) {
+		repo.Create(ctx, createMockProduct("647", "Cherry Garcia"))
+		variants := []domain.ProductVariant{{SKU: "BJ-646-P", Format: "pint"}}
+
+		assert.NoError(t, repo.UpdateVariants(ctx, "646", domain.Product{Variants: variants}))
+		assert.Equal(t, domain.ErrDuplicateSKU, repo.UpdateVariants(ctx, "647", domain.Product{Variants: variants}))
+
+		stored, err := repo.GetBySKU(ctx, "BJ-646-P")
+		assert.NoError(t, err)
+		assert.Equal(t, "646", stored.ProductID)
+		assert.Equal(t, variants, stored.Variants)
+	})
+
+	t.Run("Upsert-counts", func(t *testing.T) {
+		result, err := repo.Upsert(ctx, []domain.Product{
+			{ProductID: "646", Name: "Vanilla Toffee Bar Crunch", Slug: "slug-646"},
+			{ProductID: "700", Name: "Phish Food", Slug: "phish-food"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, domain.UpsertResult{Created: 1, Updated: 1}, result)
+
+		stored, _ := repo.Get(ctx, "646")
+		assert.Len(t, stored.Variants, 1)
+	})
+
+	t.Run("Upsert-conflict-writes-the-rest", func(t *testing.T) {
+		result, err := repo.Upsert(ctx, []domain.Product{
+			{ProductID: "701", Name: "Half Baked", Slug: "phish-food"},
+			{ProductID: "702", Name: "Chunky Monkey", Slug: "chunky-monkey"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, domain.UpsertResult{
+			Created:  1,
+			Failures: []domain.UpsertFailure{{Index: 0, Err: domain.ErrDuplicateSlug}},
+		}, result)
+
+		_, err = repo.Get(ctx, "701")
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+		_, err = repo.Get(ctx, "702")
+		assert.NoError(t, err)
+	})
+}
+
+func TestTrashProduct(t *testing.T) {
+	ctx := context.TODO()
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+	repo := NewProductRepo(db)
+	repo.Create(ctx, createMockProduct("646", "Vanilla Toffee Bar Crunch"))
+
+	assert.Equal(t, domain.ErrPreconditionFailed, repo.Delete(ctx, "646", 2, "ben"))
+	assert.NoError(t, repo.Delete(ctx, "646", 1, "ben"))
+	assert.Equal(t, domain.ErrResourceNotFound, repo.Delete(ctx, "646", 0, "ben"))
+
+	_, err := repo.Get(ctx, "646")
+	assert.Equal(t, domain.ErrResourceNotFound, err)
+
+	// trashed products keep their productId and slug
+	assert.Equal(t, domain.ErrConflict, repo.Create(ctx, createMockProduct("646", "Again")))
+	bySlug, err := repo.GetBySlug(ctx, "slug-646")
+	assert.NoError(t, err)
+	assert.Equal(t, "646", bySlug.ProductID)
+
+	trash, _ := repo.FetchTrash(ctx)
+	assert.Len(t, trash, 1)
+	assert.Equal(t, "ben", trash[0].DeletedBy)
+
+	assert.NoError(t, repo.Restore(ctx, "646"))
+	assert.Equal(t, domain.ErrResourceNotFound, repo.Restore(ctx, "646"))
+	assert.Equal(t, domain.ErrResourceNotFound, repo.Purge(ctx, "646"))
+
+	restored, _ := repo.Get(ctx, "646")
+	assert.Equal(t, int64(3), restored.Version)
+
+	repo.Delete(ctx, "646", 0, "ben")
+	assert.NoError(t, repo.Purge(ctx, "646"))
+	_, err = repo.GetBySlug(ctx, "slug-646")
+	assert.Equal(t, domain.ErrResourceNotFound, err)
+}
+
+func TestFetchProducts(t *testing.T) {
+	ctx := context.TODO()
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+	repo := NewProductRepo(db)
+	for i, name := range []string{"Cherry Garcia", "Americone Dream", "Phish Food", "Half Baked", "Chunky Monkey"} {
+		repo.Create(ctx, createMockProduct(strconv.Itoa(646+i), name))
+	}
+
+	query := domain.ProductQuery{Limit: 2, SortBy: domain.SortByName, SortOrder: domain.Ascending}
+
+	var names []string
+	for {
+		page, err := repo.Fetch(ctx, query)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), page.TotalCount)
+
+		for _, product := range page.Products {
+			names = append(names, product.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []string{"Americone Dream", "Cherry Garcia", "Chunky Monkey", "Half Baked", "Phish Food"}, names)
+
+	query.Cursor = "not-a-cursor"
+	_, err := repo.Fetch(ctx, query)
+	assert.Equal(t, domain.ErrBadParamInput, err)
+
+	results, err := repo.Search(ctx, "monkey -cherry", 10, domain.ProductFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "Chunky Monkey", results[0].Product.Name)
+
+	var streamed []string
+	err = repo.Stream(ctx, domain.ProductFilter{}, func(product domain.Product) error {
+		streamed = append(streamed, product.ProductID)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"646", "647", "648", "649", "650"}, streamed)
+}
+
+func TestCountFacets(t *testing.T) {
+	ctx := context.TODO()
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+	repo := NewProductRepo(db)
+
+	declared := createMockProduct("646", "Vanilla Toffee Bar Crunch")
+	declared.DietaryCertification = "Kosher"
+	declared.Allergens = domain.Allergens{Contains: []string{"milk"}}
+	declared.Regions = []string{"uk"}
+	repo.Create(ctx, declared)
+
+	// products declaring no allergens are matched by allergy info
+	undeclared := createMockProduct("647", "Chunky Monkey")
+	undeclared.AllergyInfo = "Contains walnuts and EGGS"
+	*undeclared.SourcingValues = []string{"Fairtrade", "Cage-Free Eggs"}
+	repo.Create(ctx, undeclared)
+
+	facets, err := repo.CountFacets(ctx, domain.ProductFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.FacetCount{{Value: "Fairtrade", Count: 2}, {Value: "Cage-Free Eggs", Count: 1}}, facets.SourcingValues)
+	assert.Equal(t, []domain.FacetCount{{Value: "Kosher", Count: 1}}, facets.DietaryCertifications)
+	assert.Len(t, facets.Allergens, len(domain.KnownAllergens))
+	assert.Equal(t, domain.FacetCount{Value: "milk", Count: 1}, facets.Allergens[0])
+	assert.Equal(t, domain.FacetCount{Value: "eggs", Count: 1}, facets.Allergens[1])
+
+	for _, filter := range []struct {
+		filter   domain.ProductFilter
+		expected []string
+	}{
+		{domain.ProductFilter{ExcludeAllergens: []string{"eggs"}}, []string{"646"}},
+		{domain.ProductFilter{ExcludeAllergens: []string{"milk"}}, []string{"647"}},
+		{domain.ProductFilter{SourcingValues: []string{"Fairtrade", "Cage-Free Eggs"}}, []string{"647"}},
+		{domain.ProductFilter{SourcingValues: []string{"Organic", "Cage-Free Eggs"}, SourcingMatch: domain.MatchAny}, []string{"647"}},
+		{domain.ProductFilter{DietaryCertifications: []string{"Kosher"}}, []string{"646"}},
+		{domain.ProductFilter{Region: "us"}, []string{"647"}},
+		{domain.ProductFilter{Statuses: []domain.ProductStatus{domain.StatusPublished}}, []string{"646", "647"}},
+	} {
+		page, err := repo.Fetch(ctx, domain.ProductQuery{Limit: 10, Filter: filter.filter})
+		assert.NoError(t, err)
+
+		var productIDs []string
+		for _, product := range page.Products {
+			productIDs = append(productIDs, product.ProductID)
+		}
+		assert.Equal(t, filter.expected, productIDs)
+	}
+}
+
+func TestProductRevisions(t *testing.T) {
+	ctx := context.TODO()
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+	repo := NewProductRevisionRepo(db)
+
+	timestamp := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		number, err := repo.Create(ctx, domain.ProductRevision{
+			ProductID: "646",
+			Action:    domain.RevisionUpdate,
+			Author:    "ben",
+			Timestamp: timestamp,
+			Changes:   []domain.FieldChange{{Field: "story", From: "old", To: "new"}},
+			Product:   createMockProduct("646", "Vanilla Toffee Bar Crunch"),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(i+1), number)
+	}
+
+	revisions, err := repo.Fetch(ctx, "646")
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, int64(2), revisions[0].Revision)
+	assert.Equal(t, timestamp, revisions[0].Timestamp)
+	assert.Equal(t, "new", revisions[0].Changes[0].To)
+	assert.Equal(t, "Vanilla Toffee Bar Crunch", revisions[0].Product.Name)
+
+	_, err = repo.Get(ctx, "646", 3)
+	assert.Equal(t, domain.ErrResourceNotFound, err)
+}
+
+func TestProductSequence(t *testing.T) {
+	ctx := context.TODO()
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+	NewProductRepo(db).Create(ctx, createMockProduct("646", "Vanilla Toffee Bar Crunch"))
+
+	sequence, err := NewProductSequenceRepo(ctx, db)
+	assert.NoError(t, err)
+	productID, err := sequence.NextProductID(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "647", productID)
+
+	// sequence never moves back when seeded again
+	sequence, err = NewProductSequenceRepo(ctx, db)
+	assert.NoError(t, err)
+	productID, _ = sequence.NextProductID(ctx)
+	assert.Equal(t, "648", productID)
+}
+
+func TestConcurrentWriters(t *testing.T) {
+	ctx := context.TODO()
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+	repo := NewProductRepo(db)
+	repo.Create(ctx, createMockProduct("646", "Vanilla Toffee Bar Crunch"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			repo.Update(ctx, "646", domain.Product{Story: strconv.Itoa(i)})
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			repo.Create(ctx, createMockProduct(strconv.Itoa(700+i), "Flavour "+strconv.Itoa(i)))
+			repo.Get(ctx, "646")
+		}(i)
+	}
+	wg.Wait()
+
+	stored, _ := repo.Get(ctx, "646")
+	assert.Equal(t, int64(21), stored.Version)
+
+	page, _ := repo.Fetch(ctx, domain.ProductQuery{Limit: 100, SortBy: domain.SortByProductID, SortOrder: domain.Ascending})
+	assert.Equal(t, int64(21), page.TotalCount)
+}
+
+// openPostgresTestDB opens the migrated postgres database of
+// POSTGRES_TEST_URI with no products, skipping the test when
+// no database is given
+func openPostgresTestDB(t *testing.T) *sqlHelper.DB {
+	dsn := os.Getenv("POSTGRES_TEST_URI")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_URI is not set")
+	}
+
+	db, err := sqlHelper.Open(sqlHelper.Postgres, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err = db.Migrate(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Exec("TRUNCATE products, ingredients, sourcing_values, product_revisions, counters CASCADE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestProductRepositorySuite(t *testing.T) {
+	t.Run("SQLite", func(t *testing.T) {
+		repotest.RunProductRepositorySuite(t, func(t *testing.T) domain.ProductRepository {
+			db, closeDB := openTestDB(t)
+			t.Cleanup(closeDB)
+			return NewProductRepo(db)
+		})
+	})
+
+	t.Run("Postgres", func(t *testing.T) {
+		repotest.RunProductRepositorySuite(t, func(t *testing.T) domain.ProductRepository {
+			return NewProductRepo(openPostgresTestDB(t))
+		})
+	})
+}
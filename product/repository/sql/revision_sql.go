@@ -0,0 +1,159 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	sqlHelper "github.com/iqdf/benjerry-service/common/repository/sql"
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// revisionAttempts bounds retries of numbering a revision
+// when a concurrent change of the same product took the number
+const revisionAttempts = 5
+
+// revisionColumns are the columns scanned by scanRevision
+const revisionColumns = "product_id, revision, action, author, changed_at, changes, product, restored_from"
+
+// ProductRevisionSQLRepo stores revisions of products in a SQL
+// database, their changes and product snapshot as JSON columns
+type ProductRevisionSQLRepo struct {
+	db *sqlHelper.DB
+}
+
+// NewProductRevisionRepo creates revision repository over db,
+// whose schema is migrated by sqlHelper.DB.Migrate
+func NewProductRevisionRepo(db *sqlHelper.DB) *ProductRevisionSQLRepo {
+	return &ProductRevisionSQLRepo{db: db}
+}
+
+// scanRevision reads revision from columns listed in revisionColumns
+func scanRevision(rows *sql.Rows) (domain.ProductRevision, error) {
+	var (
+		revision        domain.ProductRevision
+		action          string
+		changes, stored string
+	)
+
+	err := rows.Scan(
+		&revision.ProductID, &revision.Revision, &action, &revision.Author,
+		&revision.Timestamp, &changes, &stored, &revision.RestoredFrom,
+	)
+	if err != nil {
+		return domain.ProductRevision{}, err
+	}
+
+	revision.Action = domain.RevisionAction(action)
+	revision.Timestamp = revision.Timestamp.UTC()
+
+	if err = json.Unmarshal([]byte(changes), &revision.Changes); err != nil {
+		return domain.ProductRevision{}, err
+	}
+	for i, change := range revision.Changes {
+		revision.Changes[i].From = changeValue(change.From)
+		revision.Changes[i].To = changeValue(change.To)
+	}
+
+	if err = json.Unmarshal([]byte(stored), &revision.Product); err != nil {
+		return domain.ProductRevision{}, err
+	}
+	return revision, nil
+}
+
+// changeValue converts changed field values decoded
+// from JSON documents back into their Go types
+func changeValue(value interface{}) interface{} {
+	if text, ok := value.(string); ok {
+		if dateTime, err := time.Parse(time.RFC3339Nano, text); err == nil {
+			return dateTime.UTC()
+		}
+	}
+	return value
+}
+
+// find queries revisions matching where
+func (repo *ProductRevisionSQLRepo) find(ctx context.Context, where string, args ...interface{}) ([]domain.ProductRevision, error) {
+	rows, err := repo.db.Conn(ctx).QueryContext(ctx, repo.db.Rebind(
+		"SELECT "+revisionColumns+" FROM product_revisions WHERE "+where+" ORDER BY revision DESC",
+	), args...)
+	if err != nil {
+		return nil, sqlHelper.TranslateError(err)
+	}
+	defer rows.Close()
+
+	revisions := make([]domain.ProductRevision, 0)
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, sqlHelper.TranslateError(err)
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, sqlHelper.TranslateError(rows.Err())
+}
+
+// Fetch queries all revisions of a product, latest first
+func (repo *ProductRevisionSQLRepo) Fetch(ctx context.Context, productID string) ([]domain.ProductRevision, error) {
+	return repo.find(ctx, "product_id = ?", productID)
+}
+
+// Get queries a single revision of a product
+func (repo *ProductRevisionSQLRepo) Get(ctx context.Context, productID string, revision int64) (domain.ProductRevision, error) {
+	revisions, err := repo.find(ctx, "product_id = ? AND revision = ?", productID, revision)
+	if err != nil {
+		return domain.ProductRevision{}, err
+	}
+
+	if len(revisions) == 0 {
+		return domain.ProductRevision{}, domain.ErrResourceNotFound
+	}
+	return revisions[0], nil
+}
+
+// Create inserts revision numbered right after the latest
+// revision of the product and returns the number taken
+func (repo *ProductRevisionSQLRepo) Create(ctx context.Context, revision domain.ProductRevision) (int64, error) {
+	changes, err := json.Marshal(revision.Changes)
+	if err != nil {
+		return 0, sqlHelper.TranslateError(err)
+	}
+
+	product, err := json.Marshal(revision.Product)
+	if err != nil {
+		return 0, sqlHelper.TranslateError(err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		var number int64
+
+		// numbering and inserting within a savepoint keeps a
+		// number taken concurrently from aborting a transaction
+		err = repo.db.WithinTransaction(ctx, func(ctx context.Context) error {
+			conn := repo.db.Conn(ctx)
+
+			row := conn.QueryRowContext(ctx, repo.db.Rebind(
+				"SELECT COALESCE(MAX(revision), 0) + 1 FROM product_revisions WHERE product_id = ?",
+			), revision.ProductID)
+			if err := row.Scan(&number); err != nil {
+				return sqlHelper.TranslateError(err)
+			}
+
+			_, err := conn.ExecContext(ctx, repo.db.Rebind(
+				"INSERT INTO product_revisions ("+revisionColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			), revision.ProductID, number, string(revision.Action), revision.Author,
+				revision.Timestamp.UTC(), string(changes), string(product), revision.RestoredFrom,
+			)
+			return sqlHelper.TranslateError(err)
+		})
+
+		if err == domain.ErrConflict && attempt < revisionAttempts {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		return number, nil
+	}
+}
@@ -0,0 +1,94 @@
+package sql
+
+import (
+	"context"
+	"strconv"
+
+	sqlHelper "github.com/iqdf/benjerry-service/common/repository/sql"
+)
+
+// productIDCounter names the sequence of productIds
+const productIDCounter = "productId"
+
+// minProductID keeps allocated productIds
+// within the minimum length of 3 digits
+const minProductID = 100
+
+// ProductSequenceSQLRepo allocates productIds from a counter row
+type ProductSequenceSQLRepo struct {
+	db *sqlHelper.DB
+}
+
+// NewProductSequenceRepo creates sequence of productIds, which
+// continues after the greatest numeric productId stored so far,
+// so that ids given by clients before the sequence existed are
+// not allocated again. The counter never moves back once it is
+// ahead. Fails when the counter cannot be seeded
+func NewProductSequenceRepo(ctx context.Context, db *sqlHelper.DB) (*ProductSequenceSQLRepo, error) {
+	repo := &ProductSequenceSQLRepo{db: db}
+
+	maxID, err := repo.maxProductID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxID < minProductID-1 {
+		maxID = minProductID - 1
+	}
+	_, err = db.ExecContext(ctx, db.Rebind(
+		"INSERT INTO counters (name, value) VALUES (?, ?) ON CONFLICT (name) DO UPDATE "+
+			"SET value = CASE WHEN excluded.value > counters.value THEN excluded.value ELSE counters.value END",
+	), productIDCounter, maxID)
+	if err != nil {
+		return nil, sqlHelper.TranslateError(err)
+	}
+	return repo, nil
+}
+
+// NextProductID takes the next value of the sequence
+func (repo *ProductSequenceSQLRepo) NextProductID(ctx context.Context) (string, error) {
+	var value int64
+
+	err := repo.db.WithinTransaction(ctx, func(ctx context.Context) error {
+		conn := repo.db.Conn(ctx)
+
+		_, err := conn.ExecContext(ctx, repo.db.Rebind(
+			"INSERT INTO counters (name, value) VALUES (?, ?) ON CONFLICT (name) DO UPDATE SET value = counters.value + 1",
+		), productIDCounter, minProductID)
+		if err != nil {
+			return sqlHelper.TranslateError(err)
+		}
+
+		row := conn.QueryRowContext(ctx, repo.db.Rebind("SELECT value FROM counters WHERE name = ?"), productIDCounter)
+		return sqlHelper.TranslateError(row.Scan(&value))
+	})
+
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(value, 10), nil
+}
+
+// maxProductID finds the greatest numeric productId,
+// including productIds of products in trash
+func (repo *ProductSequenceSQLRepo) maxProductID(ctx context.Context) (int64, error) {
+	rows, err := repo.db.QueryContext(ctx, "SELECT product_id FROM products")
+	if err != nil {
+		return 0, sqlHelper.TranslateError(err)
+	}
+	defer rows.Close()
+
+	var maxID int64
+	for rows.Next() {
+		var productID string
+		if err = rows.Scan(&productID); err != nil {
+			return 0, sqlHelper.TranslateError(err)
+		}
+
+		value, err := strconv.ParseInt(productID, 10, 64)
+		if err == nil && value > maxID {
+			maxID = value
+		}
+	}
+	return maxID, sqlHelper.TranslateError(rows.Err())
+}
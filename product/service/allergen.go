@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// ProposeAllergens detects allergens contained in ingredients of product
+// and compares them with the allergens it declares as contained
+func (service *ProductService) ProposeAllergens(ctx context.Context, productID string) (domain.AllergenProposal, error) {
+	product, err := service.GetProduct(ctx, productID)
+
+	if err != nil {
+		return domain.AllergenProposal{}, err
+	}
+
+	ingredients := ingredientsOf(product)
+	return domain.AllergenProposal{
+		Declared: product.Allergens,
+		Detected: service.allergens.Detect(ingredients),
+		Check:    service.allergens.Check(product.Allergens, ingredients),
+	}, nil
+}
+
+// withAllergenCheck flags disagreement between allergens declared by
+// product and allergens detected in its ingredients, so that every
+// write of product content is checked. Allergens and ingredients
+// left out of a partial update are taken from current product
+func (service *ProductService) withAllergenCheck(product domain.Product, current domain.Product) domain.Product {
+	allergens := product.Allergens
+	if allergens.IsEmpty() {
+		allergens = current.Allergens
+	}
+
+	ingredients := ingredientsOf(product)
+	if product.Ingredients == nil {
+		ingredients = ingredientsOf(current)
+	}
+
+	product.AllergenCheck = service.allergens.Check(allergens, ingredients)
+	return product
+}
+
+func ingredientsOf(product domain.Product) []string {
+	if product.Ingredients == nil {
+		return nil
+	}
+	return *product.Ingredients
+}
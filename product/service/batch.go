@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// BatchGetProducts gets products of productIDs, each of which is
+// found or not on its own, like GetProduct
+func (service *ProductService) BatchGetProducts(ctx context.Context, productIDs []string) ([]domain.BatchItemResult, error) {
+	return service.runBatch(ctx, productIDs, false, func(ctx context.Context, i int) domain.BatchItemResult {
+		product, err := service.GetProduct(ctx, productIDs[i])
+		return domain.BatchItemResult{ProductID: productIDs[i], Product: product, Err: err}
+	})
+}
+
+// BatchCreateProducts creates products like CreateProduct. Unless
+// atomic, each product is created or fails on its own
+func (service *ProductService) BatchCreateProducts(
+	ctx context.Context,
+	products []domain.Product,
+	atomic bool,
+) ([]domain.BatchItemResult, error) {
+	productIDs := make([]string, len(products))
+	for i, product := range products {
+		productIDs[i] = product.ProductID
+	}
+
+	return service.runBatch(ctx, productIDs, atomic, func(ctx context.Context, i int) domain.BatchItemResult {
+		product, err := service.CreateProduct(ctx, products[i])
+		if err != nil {
+			return domain.BatchItemResult{ProductID: productIDs[i], Err: err}
+		}
+		return domain.BatchItemResult{ProductID: product.ProductID, Product: product}
+	})
+}
+
+// BatchUpdateProducts updates products identified by their productId,
+// pinned to their version unless zero, like UpdateProduct. Unless
+// atomic, each product is updated or fails on its own
+func (service *ProductService) BatchUpdateProducts(
+	ctx context.Context,
+	products []domain.Product,
+	atomic bool,
+) ([]domain.BatchItemResult, error) {
+	productIDs := make([]string, len(products))
+	for i, product := range products {
+		productIDs[i] = product.ProductID
+	}
+
+	return service.runBatch(ctx, productIDs, atomic, func(ctx context.Context, i int) domain.BatchItemResult {
+		err := service.UpdateProduct(ctx, productIDs[i], products[i])
+		return domain.BatchItemResult{ProductID: productIDs[i], Err: err}
+	})
+}
+
+// BatchDeleteProducts moves products of keys to trash like
+// DeleteProduct. Unless atomic, each product is deleted or
+// fails on its own
+func (service *ProductService) BatchDeleteProducts(
+	ctx context.Context,
+	keys []domain.ProductKey,
+	atomic bool,
+) ([]domain.BatchItemResult, error) {
+	productIDs := make([]string, len(keys))
+	for i, key := range keys {
+		productIDs[i] = key.ProductID
+	}
+
+	return service.runBatch(ctx, productIDs, atomic, func(ctx context.Context, i int) domain.BatchItemResult {
+		err := service.DeleteProduct(ctx, keys[i].ProductID, keys[i].Version)
+		return domain.BatchItemResult{ProductID: productIDs[i], Err: err}
+	})
+}
+
+// runBatch runs item for each of the items requested for productIDs in
+// order. Atomic batch runs within a transaction, which stops at the first
+// failed item and rolls back the others, whose results are then
+// ErrBatchAborted. Error is returned only when the batch as a whole fails
+func (service *ProductService) runBatch(
+	ctx context.Context,
+	productIDs []string,
+	atomic bool,
+	item func(ctx context.Context, i int) domain.BatchItemResult,
+) ([]domain.BatchItemResult, error) {
+	if len(productIDs) == 0 || len(productIDs) > domain.MaxBatchSize {
+		return nil, domain.ErrBadParamInput
+	}
+
+	results := make([]domain.BatchItemResult, len(productIDs))
+	if !atomic {
+		for i := range results {
+			results[i] = item(ctx, i)
+		}
+		return results, nil
+	}
+
+	if service.transactor == nil {
+		return nil, domain.ErrTransactionUnsupported
+	}
+
+	failed := -1
+	err := service.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		// transaction may be run again from the start
+		failed = -1
+		for i := range results {
+			results[i] = item(ctx, i)
+			if results[i].Err != nil {
+				failed = i
+				return results[i].Err
+			}
+		}
+		return nil
+	})
+
+	if err == nil {
+		return results, nil
+	}
+
+	if failed < 0 {
+		return nil, err
+	}
+
+	for i, productID := range productIDs {
+		if i != failed {
+			results[i] = domain.BatchItemResult{ProductID: productID, Err: domain.ErrBatchAborted}
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+const (
+	// snippetRadius is the number of characters kept
+	// around the first matched term of a snippet
+	snippetRadius = 40
+
+	highlightOpen  = "<em>"
+	highlightClose = "</em>"
+)
+
+// searchTerms splits search text into lowercase terms to be
+// highlighted. Negated terms (e.g. -nuts) are not highlighted
+func searchTerms(text string) []string {
+	var terms []string
+
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '"'
+	})
+	for _, word := range words {
+		if strings.HasPrefix(word, "-") {
+			continue
+		}
+		word = strings.TrimFunc(strings.ToLower(word), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if word != "" {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// highlightProduct creates snippet for every searched
+// field of the product that contains any of the terms
+func highlightProduct(product domain.Product, terms []string) map[string]string {
+	fields := map[string]string{
+		"name":        product.Name,
+		"description": product.Description,
+		"story":       product.Story,
+	}
+	if product.Ingredients != nil {
+		fields["ingredients"] = strings.Join(*product.Ingredients, ", ")
+	}
+
+	highlights := make(map[string]string)
+	for field, text := range fields {
+		if snippet, ok := highlight(text, terms); ok {
+			highlights[field] = snippet
+		}
+	}
+	return highlights
+}
+
+// highlight cuts a snippet around the first matched term
+// and wraps every matched term within the snippet
+func highlight(text string, terms []string) (string, bool) {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+
+	// lowercasing may change rune count for some scripts,
+	// in which case matched offsets would be meaningless
+	if len(runes) != len(lower) {
+		return "", false
+	}
+
+	matches := findMatches(lower, terms)
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	start := matches[0][0] - snippetRadius
+	end := matches[0][1] + snippetRadius
+	if start < 0 {
+		start = 0
+	}
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	// avoid cutting snippet in the middle of a word
+	for start > 0 && start < matches[0][0] && !unicode.IsSpace(runes[start-1]) {
+		start++
+	}
+	for end < len(runes) && end > matches[0][1] && !unicode.IsSpace(runes[end]) {
+		end--
+	}
+
+	var snippet strings.Builder
+	if start > 0 {
+		snippet.WriteString("...")
+	}
+
+	pos := start
+	for _, match := range matches {
+		if match[0] < pos || match[1] > end {
+			continue
+		}
+		snippet.WriteString(string(runes[pos:match[0]]))
+		snippet.WriteString(highlightOpen)
+		snippet.WriteString(string(runes[match[0]:match[1]]))
+		snippet.WriteString(highlightClose)
+		pos = match[1]
+	}
+	snippet.WriteString(string(runes[pos:end]))
+
+	if end < len(runes) {
+		snippet.WriteString("...")
+	}
+	return snippet.String(), true
+}
+
+// findMatches returns [start, end) rune offsets of words
+// starting with any of the terms, in order of appearance
+func findMatches(lower []rune, terms []string) [][2]int {
+	var matches [][2]int
+
+	for i := 0; i < len(lower); i++ {
+		if i > 0 && isWordRune(lower[i-1]) {
+			continue
+		}
+		for _, term := range terms {
+			termRunes := []rune(term)
+			if !hasRunePrefix(lower[i:], termRunes) {
+				continue
+			}
+			end := i + len(termRunes)
+			for end < len(lower) && isWordRune(lower[end]) {
+				end++
+			}
+			matches = append(matches, [2]int{i, end})
+			i = end - 1
+			break
+		}
+	}
+	return matches
+}
+
+func hasRunePrefix(s, prefix []rune) bool {
+	if len(prefix) > len(s) {
+		return false
+	}
+	for i := range prefix {
+		if s[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// idAttempts bounds productIds allocated for a created product
+// when allocated ones turn out to be taken by other products
+const idAttempts = 5
+
+// minRandomDigits keeps random productIds within
+// the minimum length of 3 digits
+const minRandomDigits = 3
+
+// RandomIDGenerator allocates random numeric productIds, which need no
+// shared state, e.g. when catalogs of several databases are merged.
+// Collisions are retried by the service, so digits should keep them rare
+type RandomIDGenerator struct {
+	digits int
+}
+
+// NewRandomIDGenerator creates generator of random productIds of digits
+// digits, at least 3, none of which starts with zero
+func NewRandomIDGenerator(digits int) *RandomIDGenerator {
+	if digits < minRandomDigits {
+		digits = minRandomDigits
+	}
+	return &RandomIDGenerator{digits: digits}
+}
+
+// NextProductID allocates a random productId
+func (generator *RandomIDGenerator) NextProductID(ctx context.Context) (string, error) {
+	low := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(generator.digits-1)), nil)
+	span := new(big.Int).Mul(low, big.NewInt(9))
+
+	n, err := rand.Int(rand.Reader, span)
+	if err != nil {
+		return "", err
+	}
+	return n.Add(n, low).String(), nil
+}
+
+// allocateID gives product without productId a new one, and
+// tells whether it did, so that conflicts can be retried
+func (service *ProductService) allocateID(ctx context.Context, productID string) (string, bool, error) {
+	if productID != "" {
+		return productID, false, nil
+	}
+
+	if service.ids == nil {
+		return "", false, domain.ErrBadParamInput
+	}
+
+	allocated, err := service.ids.NextProductID(ctx)
+	return allocated, err == nil, err
+}
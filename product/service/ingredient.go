@@ -0,0 +1,22 @@
+package service
+
+import (
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/product/ingredient"
+)
+
+// withDerivedFields derives fields of product from its ingredients
+// on every write of product content, see withAllergenCheck and
+// withParsedIngredients. Fields left out of a partial update are
+// taken from current product
+func (service *ProductService) withDerivedFields(product domain.Product, current domain.Product) domain.Product {
+	product = service.withAllergenCheck(product, current)
+	return withParsedIngredients(product)
+}
+
+// withParsedIngredients parses ingredients of product into their tree,
+// which is left as stored when a partial update leaves ingredients out
+func withParsedIngredients(product domain.Product) domain.Product {
+	product.ParsedIngredients = ingredient.ParseAll(ingredientsOf(product))
+	return product
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// ComputeNutrition computes nutrients in quantity of product, or in
+// the container of its variant identified by sku, see
+// domain.Product.ComputeNutrition
+func (service *ProductService) ComputeNutrition(
+	ctx context.Context, productID string, quantity domain.Quantity, sku string,
+) (domain.NutritionServing, error) {
+	product, err := service.GetProduct(ctx, productID)
+
+	if err != nil {
+		return domain.NutritionServing{}, err
+	}
+
+	return product.ComputeNutrition(quantity, sku)
+}
@@ -2,26 +2,129 @@ package service
 
 import (
 	"context"
+	"sort"
 	"time"
 
+	"github.com/iqdf/benjerry-service/common/auth"
 	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/product/allergen"
 )
 
 const timeout = time.Second * 10
 
+// streamTimeout bounds operations that go through the whole catalog
+const streamTimeout = time.Minute * 5
+
+// writeAttempts bounds retries of a write that the caller did not
+// pin to a version when the product changes between read and write
+const writeAttempts = 3
+
+// purgeBatchSize bounds trashed products read at once by PurgeTrash
+var purgeBatchSize = 100
+
 // ProductService ...
 type ProductService struct {
-	productRepo domain.ProductRepository
+	appName      string
+	productRepo  domain.ProductRepository
+	revisionRepo domain.ProductRevisionRepository
+	ids          domain.ProductIDGenerator
+	transactor   domain.Transactor
+	allergens    *allergen.Detector
 }
 
-// NewProductService creates new service
-// that provides use cases for product resource
-func NewProductService(productRepo domain.ProductRepository) *ProductService {
+// NewProductService creates new service that provides use cases
+// for product resource, allocating productIds of created products
+// by ids and running atomic batches within transactions of
+// transactor. Without ids, products must be given their productId,
+// without transactor, batches cannot be atomic
+func NewProductService(
+	appName string,
+	productRepo domain.ProductRepository,
+	revisionRepo domain.ProductRevisionRepository,
+	ids domain.ProductIDGenerator,
+	transactor domain.Transactor,
+) *ProductService {
 	return &ProductService{
-		productRepo: productRepo,
+		appName:      appName,
+		productRepo:  productRepo,
+		revisionRepo: revisionRepo,
+		ids:          ids,
+		transactor:   transactor,
+		allergens:    allergen.NewDetector(allergen.DefaultDictionary),
 	}
 }
 
+// FetchProducts ...
+func (service *ProductService) FetchProducts(ctx context.Context, query domain.ProductQuery) (domain.ProductPage, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	query, err := normalizeQuery(query)
+	if err != nil {
+		return domain.ProductPage{}, err
+	}
+	query.Filter = service.visibleFilter(ctx, query.Filter)
+
+	page, err := service.productRepo.Fetch(ctx, query)
+
+	if err != nil {
+		return domain.ProductPage{}, err
+	}
+
+	for i := range page.Products {
+		page.Products[i] = inRegion(page.Products[i], query.Filter.Region)
+	}
+
+	page.Facets, err = service.productRepo.CountFacets(ctx, query.Filter)
+
+	if err != nil {
+		return domain.ProductPage{}, err
+	}
+
+	return page, nil
+}
+
+// SearchProducts searches products sold in region, or in every region if empty
+func (service *ProductService) SearchProducts(
+	ctx context.Context,
+	text string,
+	limit int,
+	region string,
+) ([]domain.ProductSearchResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	terms := searchTerms(text)
+	if len(terms) == 0 {
+		return nil, domain.ErrBadParamInput
+	}
+
+	switch {
+	case limit == 0:
+		limit = domain.DefaultPageLimit
+	case limit < 0 || limit > domain.MaxPageLimit:
+		return nil, domain.ErrBadParamInput
+	}
+
+	if region != "" && !domain.ValidRegion(region) {
+		return nil, domain.ErrBadParamInput
+	}
+
+	filter := service.visibleFilter(ctx, domain.ProductFilter{Region: region})
+	results, err := service.productRepo.Search(ctx, text, limit, filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		results[i].Product = inRegion(results[i].Product, region)
+		results[i].Highlights = highlightProduct(results[i].Product, terms)
+	}
+
+	return results, nil
+}
+
 // GetProduct ...
 func (service *ProductService) GetProduct(ctx context.Context, productID string) (domain.Product, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
@@ -33,21 +136,213 @@ func (service *ProductService) GetProduct(ctx context.Context, productID string)
 		return domain.Product{}, err
 	}
 
+	if product.Status != domain.StatusPublished && !service.canSeeUnpublished(ctx) {
+		return domain.Product{}, domain.ErrResourceNotFound
+	}
+
 	return product, nil
 }
 
-// CreateProduct ...
-func (service *ProductService) CreateProduct(ctx context.Context, product domain.Product) error {
+// GetProductBySKU gets product having variant of sku
+func (service *ProductService) GetProductBySKU(ctx context.Context, sku string) (domain.Product, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	err := service.productRepo.Create(ctx, product)
+	product, err := service.productRepo.GetBySKU(ctx, sku)
 
 	if err != nil {
-		return err
+		return domain.Product{}, err
+	}
+
+	if product.Status != domain.StatusPublished && !service.canSeeUnpublished(ctx) {
+		return domain.Product{}, domain.ErrResourceNotFound
+	}
+
+	return product, nil
+}
+
+// GetProductByGTIN gets product identified by gtin, which is
+// looked up in its GTIN-14 form, see domain.NormalizeGTIN
+func (service *ProductService) GetProductByGTIN(ctx context.Context, gtin string) (domain.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	product, err := service.productRepo.GetByGTIN(ctx, domain.NormalizeGTIN(gtin))
+
+	if err != nil {
+		return domain.Product{}, err
+	}
+
+	if product.Status != domain.StatusPublished && !service.canSeeUnpublished(ctx) {
+		return domain.Product{}, domain.ErrResourceNotFound
 	}
 
-	return nil
+	return product, nil
+}
+
+// CreateProduct creates product as draft, which has to be published
+// to be seen by all, and returns the created product. Products without
+// productId are allocated one, which is allocated again when taken
+func (service *ProductService) CreateProduct(ctx context.Context, product domain.Product) (domain.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := product.Nutrition.Check(); err != nil {
+		return domain.Product{}, err
+	}
+
+	product = withLifecycle(product, domain.Product{Status: domain.StatusDraft})
+	product = service.withDerivedFields(product, domain.Product{})
+
+	requestedID := product.ProductID
+	for attempt := 1; ; attempt++ {
+		productID, allocated, err := service.allocateID(ctx, requestedID)
+		if err != nil {
+			return domain.Product{}, err
+		}
+
+		product.ProductID = productID
+		product, err = service.withSlug(ctx, productID, product, domain.Product{}, nil)
+		if err != nil {
+			return domain.Product{}, err
+		}
+
+		err = service.productRepo.Create(ctx, product)
+
+		if err == domain.ErrConflict && allocated && attempt < idAttempts {
+			continue
+		}
+
+		if err != nil {
+			return domain.Product{}, err
+		}
+		break
+	}
+
+	product.Version = 1
+	err := service.recordRevision(ctx, domain.RevisionCreate, domain.Product{}, product, 0)
+	return product, err
+}
+
+// UpsertProducts writes products that are new or differ from
+// stored ones and records a revision for each of them. Products
+// equal to stored ones are left untouched and counted as unchanged.
+// New products are published, lifecycle, translations and
+// variants of stored ones are kept. Products without productId
+// are new, and are allocated one. Products that are invalid or
+// conflict with others are listed as failures by their index,
+// while the rest are written
+func (service *ProductService) UpsertProducts(ctx context.Context, products []domain.Product) (domain.UpsertResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var (
+		changed, previous []domain.Product
+		indexes           []int
+		unchanged         int64
+		failures          []domain.UpsertFailure
+	)
+	taken := make(map[string]string)
+
+	for i, product := range products {
+		if err := product.Nutrition.Check(); err != nil {
+			failures = append(failures, domain.UpsertFailure{Index: i, Err: err})
+			continue
+		}
+
+		productID, allocated, err := service.allocateID(ctx, product.ProductID)
+		if err != nil {
+			return domain.UpsertResult{}, err
+		}
+		product.ProductID = productID
+
+		var before domain.Product
+		exists := false
+		if !allocated {
+			before, err = service.productRepo.Get(ctx, productID)
+			if err != nil && err != domain.ErrResourceNotFound {
+				return domain.UpsertResult{}, err
+			}
+			exists = err == nil
+		}
+
+		if !exists {
+			before = domain.Product{}
+			product = withLifecycle(product, domain.Product{Status: domain.StatusPublished})
+		} else {
+			product = withLifecycle(product, before)
+			product.Translations = before.Translations
+			product.Variants = before.Variants
+		}
+		product = service.withDerivedFields(product, domain.Product{})
+
+		product, err = service.withSlug(ctx, productID, product, before, taken)
+		if err == domain.ErrDuplicateSlug {
+			failures = append(failures, domain.UpsertFailure{Index: i, Err: err})
+			continue
+		}
+		if err != nil {
+			return domain.UpsertResult{}, err
+		}
+
+		if exists && len(diffProducts(before, product)) == 0 {
+			unchanged++
+			continue
+		}
+		changed = append(changed, product)
+		previous = append(previous, before)
+		indexes = append(indexes, i)
+	}
+
+	result, err := service.productRepo.Upsert(ctx, changed)
+
+	if err != nil {
+		return domain.UpsertResult{}, err
+	}
+	result.Unchanged += unchanged
+
+	// failures of the repository are indexed among changed products
+	failed := make(map[int]bool, len(result.Failures))
+	for _, failure := range result.Failures {
+		failed[failure.Index] = true
+		failures = append(failures, domain.UpsertFailure{Index: indexes[failure.Index], Err: failure.Err})
+	}
+	sort.SliceStable(failures, func(i, j int) bool {
+		return failures[i].Index < failures[j].Index
+	})
+	result.Failures = failures
+
+	for i, product := range changed {
+		if failed[i] {
+			continue
+		}
+
+		action := domain.RevisionUpdate
+		if previous[i].ProductID == "" {
+			action = domain.RevisionCreate
+		}
+
+		if err := service.recordRevision(ctx, action, previous[i], product, 0); err != nil {
+			return domain.UpsertResult{}, err
+		}
+	}
+
+	return result, nil
+}
+
+// ExportProducts streams products sold in region, or in every region if empty
+func (service *ProductService) ExportProducts(ctx context.Context, region string, fn func(domain.Product) error) error {
+	ctx, cancel := context.WithTimeout(ctx, streamTimeout)
+	defer cancel()
+
+	if region != "" && !domain.ValidRegion(region) {
+		return domain.ErrBadParamInput
+	}
+
+	filter := service.visibleFilter(ctx, domain.ProductFilter{Region: region})
+	return service.productRepo.Stream(ctx, filter, func(product domain.Product) error {
+		return fn(inRegion(product, region))
+	})
 }
 
 // UpdateProduct ...
@@ -55,25 +350,413 @@ func (service *ProductService) UpdateProduct(ctx context.Context, productID stri
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	err := service.productRepo.Update(ctx, productID, product)
+	if err := product.Nutrition.Check(); err != nil {
+		return err
+	}
+
+	before, err := service.writeProduct(ctx, productID, product.Version, func(current domain.Product) error {
+		product.Version = current.Version
+		product = service.withDerivedFields(product, current)
+
+		slugged, err := service.withSlug(ctx, productID, product, current, nil)
+		if err != nil {
+			return err
+		}
+		return service.productRepo.Update(ctx, productID, slugged)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return service.recordChanges(ctx, domain.RevisionUpdate, productID, before, 0)
+}
+
+// ReplaceProduct ...
+func (service *ProductService) ReplaceProduct(ctx context.Context, productID string, product domain.Product) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := product.Nutrition.Check(); err != nil {
+		return err
+	}
+
+	before, err := service.writeProduct(ctx, productID, product.Version, func(current domain.Product) error {
+		product.Version = current.Version
+		product = service.withDerivedFields(product, domain.Product{})
+
+		slugged, err := service.withSlug(ctx, productID, product, current, nil)
+		if err != nil {
+			return err
+		}
+		return service.productRepo.Replace(ctx, productID, slugged)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return service.recordChanges(ctx, domain.RevisionUpdate, productID, before, 0)
+}
+
+// DeleteProduct moves product to trash, from where
+// it can be restored until it is purged
+func (service *ProductService) DeleteProduct(ctx context.Context, productID string, version int64) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	before, err := service.writeProduct(ctx, productID, version, func(current domain.Product) error {
+		return service.productRepo.Delete(ctx, productID, current.Version, revisionAuthor(ctx))
+	})
 
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return service.recordRevision(ctx, domain.RevisionDelete, before, domain.Product{}, 0)
 }
 
-// DeleteProduct ...
-func (service *ProductService) DeleteProduct(ctx context.Context, productID string) error {
+// FetchTrash lists trashed products, users who may not see
+// unpublished products only see those deleted while published
+func (service *ProductService) FetchTrash(ctx context.Context) ([]domain.TrashedProduct, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	err := service.productRepo.Delete(ctx, productID)
+	trash, err := service.productRepo.FetchTrash(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if service.canSeeUnpublished(ctx) {
+		return trash, nil
+	}
+
+	var visible = make([]domain.TrashedProduct, 0, len(trash))
+	for _, trashed := range trash {
+		if trashed.Product.Status == domain.StatusPublished {
+			visible = append(visible, trashed)
+		}
+	}
+	return visible, nil
+}
+
+// RestoreProduct takes product out of trash
+func (service *ProductService) RestoreProduct(ctx context.Context, productID string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := service.productRepo.Restore(ctx, productID)
 
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return service.recordChanges(ctx, domain.RevisionUndelete, productID, domain.Product{}, 0)
+}
+
+// PurgeProduct removes trashed product for good,
+// its revisions are kept as a record of it
+func (service *ProductService) PurgeProduct(ctx context.Context, productID string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	trashed, err := service.productRepo.GetTrashed(ctx, productID)
+
+	if err != nil {
+		return err
+	}
+
+	return service.purgeTrashed(ctx, trashed.Product)
+}
+
+// PurgeTrash purges every product that was moved to trash before
+// deletedBefore and counts them. Products are purged in batches
+// of purgeBatchSize, earliest deleted first
+func (service *ProductService) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, streamTimeout)
+	defer cancel()
+
+	var purged int
+	for {
+		trash, err := service.productRepo.FetchTrashBefore(ctx, deletedBefore, purgeBatchSize)
+
+		if err != nil {
+			return purged, err
+		}
+
+		for _, trashed := range trash {
+			err := service.purgeTrashed(ctx, trashed.Product)
+
+			// product restored or purged in the meantime
+			if err == domain.ErrResourceNotFound {
+				continue
+			}
+
+			if err != nil {
+				return purged, err
+			}
+			purged++
+		}
+
+		if len(trash) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// purgeTrashed removes trashed product and records it as revision
+func (service *ProductService) purgeTrashed(ctx context.Context, product domain.Product) error {
+	if err := service.productRepo.Purge(ctx, product.ProductID); err != nil {
+		return err
+	}
+
+	return service.recordRevision(ctx, domain.RevisionPurge, product, domain.Product{}, 0)
+}
+
+// FetchRevisions lists revisions of product, users who may not see
+// unpublished products only see revisions whose snapshot is published
+func (service *ProductService) FetchRevisions(ctx context.Context, productID string) ([]domain.ProductRevision, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	revisions, err := service.revisionRepo.Fetch(ctx, productID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if service.canSeeUnpublished(ctx) {
+		return revisions, nil
+	}
+
+	var visible = make([]domain.ProductRevision, 0, len(revisions))
+	for _, revision := range revisions {
+		if revision.Product.Status == domain.StatusPublished {
+			visible = append(visible, revision)
+		}
+	}
+
+	if len(visible) == 0 {
+		return nil, domain.ErrResourceNotFound
+	}
+	return visible, nil
+}
+
+// GetRevision gets a revision of product, which is not found by
+// users who may not see unpublished products unless its snapshot
+// is published
+func (service *ProductService) GetRevision(ctx context.Context, productID string, revision int64) (domain.ProductRevision, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	productRevision, err := service.revisionRepo.Get(ctx, productID, revision)
+
+	if err != nil {
+		return domain.ProductRevision{}, err
+	}
+
+	if productRevision.Product.Status != domain.StatusPublished && !service.canSeeUnpublished(ctx) {
+		return domain.ProductRevision{}, domain.ErrResourceNotFound
+	}
+
+	return productRevision, nil
+}
+
+// RestoreRevision rolls product back to its snapshot at the given
+// revision, recreating it if the product has been deleted since.
+// Rolling back is recorded as a revision of its own
+func (service *ProductService) RestoreRevision(ctx context.Context, productID string, revision int64, version int64) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	target, err := service.revisionRepo.Get(ctx, productID, revision)
+
+	if err != nil {
+		return err
+	}
+
+	// delete and purge revisions hold the product as it
+	// was before deletion, there is nothing to roll back to
+	if target.Action == domain.RevisionDelete || target.Action == domain.RevisionPurge {
+		return domain.ErrBadParamInput
+	}
+
+	product := target.Product
+	product.ProductID = productID
+
+	before, err := service.writeProduct(ctx, productID, version, func(current domain.Product) error {
+		product.Version = current.Version
+		product = service.withDerivedFields(product, domain.Product{})
+
+		slugged, err := service.withSlug(ctx, productID, product, current, nil)
+		if err != nil {
+			return err
+		}
+		return service.productRepo.Replace(ctx, productID, slugged)
+	})
+
+	if err == domain.ErrResourceNotFound && version == 0 {
+		product = service.withDerivedFields(product, domain.Product{})
+		product, err = service.withSlug(ctx, productID, product, domain.Product{}, nil)
+		if err == nil {
+			err = service.productRepo.Create(ctx, product)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return service.recordChanges(ctx, domain.RevisionRestore, productID, before, revision)
+}
+
+// writeProduct runs write against the current version of product
+// so that its state before the write is known, and returns that
+// state. Given zero version, write is retried if product changes
+// in the meantime, otherwise product must be at the given version
+func (service *ProductService) writeProduct(
+	ctx context.Context,
+	productID string,
+	version int64,
+	write func(current domain.Product) error,
+) (domain.Product, error) {
+	for attempt := 1; ; attempt++ {
+		current, err := service.productRepo.Get(ctx, productID)
+
+		if err != nil {
+			return domain.Product{}, err
+		}
+
+		if version != 0 && version != current.Version {
+			return domain.Product{}, domain.ErrPreconditionFailed
+		}
+
+		err = write(current)
+
+		if err == domain.ErrPreconditionFailed && version == 0 && attempt < writeAttempts {
+			continue
+		}
+
+		if err != nil {
+			return domain.Product{}, err
+		}
+
+		return current, nil
+	}
+}
+
+// recordChanges records a revision from before to the stored
+// product, unless the write changed nothing in the product
+func (service *ProductService) recordChanges(
+	ctx context.Context,
+	action domain.RevisionAction,
+	productID string,
+	before domain.Product,
+	restoredFrom int64,
+) error {
+	after, err := service.productRepo.Get(ctx, productID)
+
+	if err != nil {
+		return err
+	}
+
+	if action == domain.RevisionUpdate && len(diffProducts(before, after)) == 0 {
+		return nil
+	}
+
+	return service.recordRevision(ctx, action, before, after, restoredFrom)
+}
+
+// recordRevision stores revision of a product changing from before to after
+func (service *ProductService) recordRevision(
+	ctx context.Context,
+	action domain.RevisionAction,
+	before domain.Product,
+	after domain.Product,
+	restoredFrom int64,
+) error {
+	revision := domain.ProductRevision{
+		ProductID:    after.ProductID,
+		Action:       action,
+		Author:       revisionAuthor(ctx),
+		Timestamp:    time.Now().UTC(),
+		Changes:      diffProducts(before, after),
+		Product:      after,
+		RestoredFrom: restoredFrom,
+	}
+
+	if action == domain.RevisionDelete || action == domain.RevisionPurge {
+		revision.ProductID = before.ProductID
+		revision.Product = before
+	}
+
+	_, err := service.revisionRepo.Create(ctx, revision)
+
+	return err
+}
+
+// inRegion applies overrides of region to product,
+// product is left as it is when there is no region
+func inRegion(product domain.Product, region string) domain.Product {
+	if region == "" {
+		return product
+	}
+	return product.InRegion(region)
+}
+
+// revisionAuthor names the user making request
+// or system if there is no user authenticated
+func revisionAuthor(ctx context.Context) string {
+	if authentication, ok := auth.FromContext(ctx); ok {
+		return authentication.ID
+	}
+	return domain.SystemAuthor
+}
+
+// normalizeQuery fills in default paging and sorting
+// and rejects query that cannot be served
+func normalizeQuery(query domain.ProductQuery) (domain.ProductQuery, error) {
+	switch {
+	case query.Limit == 0:
+		query.Limit = domain.DefaultPageLimit
+	case query.Limit < 0 || query.Limit > domain.MaxPageLimit:
+		return domain.ProductQuery{}, domain.ErrBadParamInput
+	}
+
+	switch query.SortBy {
+	case "":
+		query.SortBy = domain.SortByProductID
+	case domain.SortByProductID, domain.SortByName:
+	default:
+		return domain.ProductQuery{}, domain.ErrBadParamInput
+	}
+
+	switch query.SortOrder {
+	case 0:
+		query.SortOrder = domain.Ascending
+	case domain.Ascending, domain.Descending:
+	default:
+		return domain.ProductQuery{}, domain.ErrBadParamInput
+	}
+
+	switch query.Filter.SourcingMatch {
+	case "":
+		query.Filter.SourcingMatch = domain.MatchAll
+	case domain.MatchAll, domain.MatchAny:
+	default:
+		return domain.ProductQuery{}, domain.ErrBadParamInput
+	}
+
+	for _, status := range query.Filter.Statuses {
+		if !status.Valid() {
+			return domain.ProductQuery{}, domain.ErrBadParamInput
+		}
+	}
+
+	if query.Filter.Region != "" && !domain.ValidRegion(query.Filter.Region) {
+		return domain.ProductQuery{}, domain.ErrBadParamInput
+	}
+
+	return query, nil
 }
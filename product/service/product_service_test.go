@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/iqdf/benjerry-service/common/auth"
+	"github.com/iqdf/benjerry-service/common/consts/role"
 	"github.com/iqdf/benjerry-service/domain"
 	"github.com/iqdf/benjerry-service/domain/mocks"
 	"github.com/stretchr/testify/assert"
@@ -11,14 +15,188 @@ import (
 )
 
 var (
+	appName       = "TestApp"
 	contextType   = mock.Anything
 	productType   = mock.AnythingOfType("domain.Product")
 	productIDType = mock.AnythingOfType("string")
+	queryType     = mock.AnythingOfType("domain.ProductQuery")
+	filterType    = mock.AnythingOfType("domain.ProductFilter")
+	revisionType  = mock.AnythingOfType("domain.ProductRevision")
 )
 
+func TestFetchProducts(t *testing.T) {
+	// setup mock repository and mock page
+	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+	t.Run("FetchProducts-success-defaults", func(t *testing.T) {
+		mockPage := domain.ProductPage{
+			Products:   []domain.Product{createMockProduct()},
+			TotalCount: 1,
+		}
+		mockFacets := domain.ProductFacets{
+			SourcingValues: []domain.FacetCount{{Value: "Fairtrade", Count: 1}},
+		}
+		expectedQuery := domain.ProductQuery{
+			Limit:     domain.DefaultPageLimit,
+			SortBy:    domain.SortByProductID,
+			SortOrder: domain.Ascending,
+			Filter:    domain.ProductFilter{SourcingMatch: domain.MatchAll},
+		}
+		mockProductRepo.On("Fetch", contextType, expectedQuery).
+			Return(mockPage, nil).
+			Once()
+		mockProductRepo.On("CountFacets", contextType, expectedQuery.Filter).
+			Return(mockFacets, nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		page, err := productService.FetchProducts(context.TODO(), domain.ProductQuery{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockPage.Products, page.Products)
+		assert.Equal(t, mockFacets, page.Facets)
+	})
+
+	t.Run("FetchProducts-success-filtered", func(t *testing.T) {
+		filter := domain.ProductFilter{
+			SourcingValues:   []string{"Fairtrade", "Non-GMO"},
+			SourcingMatch:    domain.MatchAny,
+			ExcludeAllergens: []string{"peanuts"},
+		}
+		mockProductRepo.On("Fetch", contextType, queryType).
+			Return(domain.ProductPage{}, nil).
+			Once()
+		mockProductRepo.On("CountFacets", contextType, filter).
+			Return(domain.ProductFacets{}, nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		_, err := productService.FetchProducts(context.TODO(), domain.ProductQuery{Filter: filter})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("FetchProducts-bad-match-mode", func(t *testing.T) {
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		filter := domain.ProductFilter{SourcingMatch: "some"}
+		_, err := productService.FetchProducts(context.TODO(), domain.ProductQuery{Filter: filter})
+
+		assert.Equal(t, domain.ErrBadParamInput, err)
+	})
+
+	t.Run("FetchProducts-bad-limit", func(t *testing.T) {
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		_, err := productService.FetchProducts(context.TODO(), domain.ProductQuery{Limit: domain.MaxPageLimit + 1})
+
+		assert.Equal(t, domain.ErrBadParamInput, err)
+	})
+
+	t.Run("FetchProducts-bad-sort", func(t *testing.T) {
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		_, err := productService.FetchProducts(context.TODO(), domain.ProductQuery{SortBy: "story"})
+
+		assert.Equal(t, domain.ErrBadParamInput, err)
+	})
+
+	t.Run("FetchProducts-region-overrides", func(t *testing.T) {
+		mockProduct := createMockProduct()
+		mockProduct.Regions = []string{"us", "uk"}
+		mockProduct.RegionOverrides = map[string]domain.RegionOverride{
+			"uk": {ImageClosedURL: "/files/uk/vanilla-toffee-landing.png", DietaryCertification: "Halal"},
+		}
+		filter := domain.ProductFilter{SourcingMatch: domain.MatchAll, Region: "uk"}
+
+		mockProductRepo.On("Fetch", contextType, queryType).
+			Return(domain.ProductPage{Products: []domain.Product{mockProduct}}, nil).
+			Once()
+		mockProductRepo.On("CountFacets", contextType, filter).
+			Return(domain.ProductFacets{}, nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		page, err := productService.FetchProducts(context.TODO(), domain.ProductQuery{Filter: domain.ProductFilter{Region: "uk"}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "/files/uk/vanilla-toffee-landing.png", page.Products[0].ImageClosedURL)
+		assert.Equal(t, mockProduct.ImageOpenURL, page.Products[0].ImageOpenURL)
+		assert.Equal(t, "Halal", page.Products[0].DietaryCertification)
+	})
+
+	t.Run("FetchProducts-bad-region", func(t *testing.T) {
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		filter := domain.ProductFilter{Region: "u.k."}
+		_, err := productService.FetchProducts(context.TODO(), domain.ProductQuery{Filter: filter})
+
+		assert.Equal(t, domain.ErrBadParamInput, err)
+	})
+
+	t.Run("FetchProducts-on-db-error", func(t *testing.T) {
+		var dberr error = domain.ErrInternalServerError
+		mockProductRepo.On("Fetch", contextType, queryType).
+			Return(domain.ProductPage{}, dberr).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		_, err := productService.FetchProducts(context.TODO(), domain.ProductQuery{})
+
+		assert.Equal(t, dberr, err)
+	})
+
+	mockProductRepo.AssertExpectations(t)
+}
+
+func TestSearchProducts(t *testing.T) {
+	// setup mock repository and mock results
+	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+	t.Run("SearchProducts-success-highlighted", func(t *testing.T) {
+		mockResults := []domain.ProductSearchResult{
+			{Product: createMockProduct(), Score: 1.5},
+		}
+		mockProductRepo.On("Search", contextType, "toffee", domain.DefaultPageLimit, domain.ProductFilter{}).
+			Return(mockResults, nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		results, err := productService.SearchProducts(context.TODO(), "toffee", 0, "")
+
+		assert.NoError(t, err)
+		assert.Len(t, results, 1)
+		assert.Equal(t, "Vanilla <em>Toffee</em> Bar Crunch", results[0].Highlights["name"])
+		assert.Equal(t, "Vanilla Ice Cream with Fudge-Covered <em>Toffee</em> Pieces", results[0].Highlights["description"])
+		assert.NotContains(t, results[0].Highlights, "ingredients")
+	})
+
+	t.Run("SearchProducts-empty-text", func(t *testing.T) {
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		_, err := productService.SearchProducts(context.TODO(), "  \"\" ", 0, "")
+
+		assert.Equal(t, domain.ErrBadParamInput, err)
+	})
+
+	mockProductRepo.AssertExpectations(t)
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	text := "Vanilla What Bar Crunch? We gave this flavor a new name to go with the new toffee bars we're using"
+	snippet, ok := highlight(text, searchTerms("Toffee -nuts"))
+
+	assert.True(t, ok)
+	assert.Equal(t, "...flavor a new name to go with the new <em>toffee</em> bars we're using", snippet)
+
+	_, ok = highlight(text, searchTerms("peanut"))
+	assert.False(t, ok)
+}
+
 func TestGetByProductID(t *testing.T) {
 	// setup mock repository and mock item
 	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
 
 	t.Run("GetProduct-success", func(t *testing.T) {
 		mockProductSuccess := createMockProduct()
@@ -26,7 +204,7 @@ func TestGetByProductID(t *testing.T) {
 			Return(mockProductSuccess, nil).
 			Once()
 
-		var productService = NewProductService(mockProductRepo)
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
 		product, err := productService.GetProduct(context.TODO(), mockProductSuccess.ProductID)
 
 		assert.NoError(t, err)
@@ -42,7 +220,7 @@ func TestGetByProductID(t *testing.T) {
 			Return(mockProductFail, dberr).
 			Once()
 
-		var productService = NewProductService(mockProductRepo)
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
 		product, err := productService.GetProduct(context.TODO(), mockProductID)
 
 		assert.Error(t, err)
@@ -51,9 +229,33 @@ func TestGetByProductID(t *testing.T) {
 	})
 }
 
+func TestGetProductByGTIN(t *testing.T) {
+	// setup mock repository and mock item
+	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+	mockProduct := createMockProduct()
+	mockProduct.Status = domain.StatusPublished
+	mockProduct.GTINs = []string{"00012345678905"}
+
+	mockProductRepo.On("GetByGTIN", contextType, "00012345678905").
+		Return(mockProduct, nil).
+		Once()
+
+	var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+	product, err := productService.GetProductByGTIN(context.TODO(), "012345678905")
+
+	assert.NoError(t, err)
+	assert.Equal(t, mockProduct, product)
+	mockProductRepo.AssertExpectations(t)
+}
+
 func TestCreateProduct(t *testing.T) {
 	// setup mock repository and mock data
 	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
 
 	t.Run("CreateProduct-success", func(t *testing.T) {
 		mockProductSuccess := createMockProduct()
@@ -61,118 +263,1589 @@ func TestCreateProduct(t *testing.T) {
 			Return(nil).
 			Once()
 
-		var productService = NewProductService(mockProductRepo)
-		err := productService.CreateProduct(context.TODO(), mockProductSuccess)
+		var revision domain.ProductRevision
+		mockRevisionRepo.On("Create", contextType, revisionType).
+			Run(func(args mock.Arguments) { revision = args.Get(1).(domain.ProductRevision) }).
+			Return(int64(1), nil).
+			Once()
+
+		ctx := auth.NewContext(context.TODO(), auth.Authentication{ID: "jerry"})
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		product, err := productService.CreateProduct(ctx, mockProductSuccess)
 
 		assert.NoError(t, err)
+		assert.Equal(t, "vanilla-toffee-bar-crunch", product.Slug)
+		assert.Equal(t, int64(1), product.Version)
+		assert.Equal(t, domain.RevisionCreate, revision.Action)
+		assert.Equal(t, "jerry", revision.Author)
+		assert.Equal(t, mockProductSuccess.ProductID, revision.ProductID)
+		assert.Equal(t, domain.StatusDraft, revision.Product.Status)
+		assert.Len(t, revision.Changes, 13)
 	})
 
 	t.Run("CreateProduct-on-db-error", func(t *testing.T) {
 		var dberr error = domain.ErrConflict
-		mockProductFail := domain.Product{}
+		mockProductFail := domain.Product{ProductID: "2190"}
 
 		mockProductRepo.On("Create", contextType, productType).
 			Return(dberr).
 			Once()
 
-		var productService = NewProductService(mockProductRepo)
-		err := productService.CreateProduct(context.TODO(), mockProductFail)
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		_, err := productService.CreateProduct(context.TODO(), mockProductFail)
 
 		assert.Error(t, err)
 		assert.Equal(t, dberr, err)
+		mockRevisionRepo.AssertNumberOfCalls(t, "Create", 1)
+	})
+}
+
+func TestUpsertProducts(t *testing.T) {
+	// setup mock repository with one stored product
+	mockProductRepo := new(mocks.ProductRepository)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+	stored := createMockProduct()
+	changed := stored
+	changed.Story = "A brand new story"
+	created := createMockProduct()
+	created.ProductID = "647"
+
+	mockProductRepo.On("Get", contextType, "646").
+		Return(stored, nil)
+	mockProductRepo.On("Get", contextType, "647").
+		Return(domain.Product{}, domain.ErrResourceNotFound)
+	mockProductRepo.On("GetBySlug", contextType, "vanilla-toffee-bar-crunch").
+		Return(stored, nil)
+	mockProductRepo.On("GetBySlug", contextType, "vanilla-toffee-bar-crunch-2").
+		Return(domain.Product{}, domain.ErrResourceNotFound)
+	// new products are published, stored ones keep their status
+	published := created
+	published.Status = domain.StatusPublished
+	published.Slug = "vanilla-toffee-bar-crunch-2"
+
+	mockProductRepo.On("Upsert", contextType, []domain.Product{changed, published}).
+		Return(domain.UpsertResult{Created: 1, Updated: 1}, nil).
+		Once()
+
+	var revisions []domain.ProductRevision
+	mockRevisionRepo.On("Create", contextType, revisionType).
+		Run(func(args mock.Arguments) { revisions = append(revisions, args.Get(1).(domain.ProductRevision)) }).
+		Return(int64(1), nil)
+
+	var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+	result, err := productService.UpsertProducts(context.TODO(), []domain.Product{stored, changed, created})
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.UpsertResult{Created: 1, Updated: 1, Unchanged: 1}, result)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, domain.RevisionUpdate, revisions[0].Action)
+	assert.Equal(t, domain.SystemAuthor, revisions[0].Author)
+	assert.Equal(t, []domain.FieldChange{{Field: "Story", From: stored.Story, To: changed.Story}}, revisions[0].Changes)
+	assert.Equal(t, domain.RevisionCreate, revisions[1].Action)
+}
+
+func TestUpsertProductsFailures(t *testing.T) {
+	mockProductRepo := new(mocks.ProductRepository)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+	invalid := createMockProduct()
+	invalid.ProductID = "645"
+	invalid.Nutrition = domain.NutritionFacts{ServingSize: 100, Per100g: domain.Nutrients{TotalFat: -1}}
+	conflicting := createMockProduct()
+	conflicting.ProductID = "646"
+	created := createMockProduct()
+	created.ProductID = "647"
+
+	mockProductRepo.On("Get", contextType, productIDType).
+		Return(domain.Product{}, domain.ErrResourceNotFound)
+	mockProductRepo.On("GetBySlug", contextType, "vanilla-toffee-bar-crunch").
+		Return(domain.Product{}, domain.ErrResourceNotFound)
+	mockProductRepo.On("GetBySlug", contextType, "vanilla-toffee-bar-crunch-2").
+		Return(domain.Product{}, domain.ErrResourceNotFound)
+
+	// failures of the repository are indexed among products it is given
+	mockProductRepo.On("Upsert", contextType, mock.AnythingOfType("[]domain.Product")).
+		Return(domain.UpsertResult{
+			Created:  1,
+			Failures: []domain.UpsertFailure{{Index: 0, Err: domain.ErrDuplicateGTIN}},
+		}, nil).
+		Once()
+
+	var revisions []domain.ProductRevision
+	mockRevisionRepo.On("Create", contextType, revisionType).
+		Run(func(args mock.Arguments) { revisions = append(revisions, args.Get(1).(domain.ProductRevision)) }).
+		Return(int64(1), nil)
+
+	var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+	result, err := productService.UpsertProducts(context.TODO(), []domain.Product{invalid, conflicting, created})
+
+	// written products are recorded, failed ones are not
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), result.Created)
+	assert.Equal(t, []domain.UpsertFailure{
+		{Index: 0, Err: domain.ErrInvalidNutrition},
+		{Index: 1, Err: domain.ErrDuplicateGTIN},
+	}, result.Failures)
+	assert.Len(t, revisions, 1)
+	assert.Equal(t, "647", revisions[0].ProductID)
+}
+
+func TestExportProducts(t *testing.T) {
+	// setup mock repository streaming two products
+	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+	streamFuncType := mock.AnythingOfType("func(domain.Product) error")
+
+	mockProductRepo.On("Stream", contextType, domain.ProductFilter{}, streamFuncType).
+		Run(func(args mock.Arguments) {
+			fn := args.Get(2).(func(domain.Product) error)
+			fn(createMockProduct())
+			fn(createMockProduct())
+		}).
+		Return(nil).
+		Once()
+
+	var exported int
+	var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+	err := productService.ExportProducts(context.TODO(), "", func(product domain.Product) error {
+		exported++
+		return nil
 	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, exported)
 }
 
 func TestUpdateProduct(t *testing.T) {
 	// setup mock repository and mock data
 	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
 
 	t.Run("UpdateProduct-success", func(t *testing.T) {
 		mockProductSuccess := createMockProduct()
+		mockProductSuccess.Version = 4
 		mockProductID := mockProductSuccess.ProductID
+		updated := mockProductSuccess
+		updated.Name = "Chunky Monkey"
+		updated.Slug = "chunky-monkey"
+		updated.PreviousSlugs = []string{"vanilla-toffee-bar-crunch"}
+		updated.Version = 5
 
-		mockProductRepo.On("Update", contextType, productIDType, productType).
+		// update is pinned to version of product read, renamed
+		// product keeps its slug among its previous slugs
+		mockProductRepo.On("Get", contextType, mockProductID).
+			Return(mockProductSuccess, nil).
+			Once()
+		mockProductRepo.On("Update", contextType, mockProductID, domain.Product{
+			Name:          "Chunky Monkey",
+			Slug:          "chunky-monkey",
+			PreviousSlugs: []string{"vanilla-toffee-bar-crunch"},
+			Version:       4,
+		}).
 			Return(nil).
 			Once()
+		mockProductRepo.On("Get", contextType, mockProductID).
+			Return(updated, nil).
+			Once()
+
+		var revision domain.ProductRevision
+		mockRevisionRepo.On("Create", contextType, revisionType).
+			Run(func(args mock.Arguments) { revision = args.Get(1).(domain.ProductRevision) }).
+			Return(int64(2), nil).
+			Once()
 
-		var productService = NewProductService(mockProductRepo)
-		err := productService.UpdateProduct(context.TODO(), mockProductID, mockProductSuccess)
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.UpdateProduct(context.TODO(), mockProductID, domain.Product{Name: "Chunky Monkey"})
 
 		assert.NoError(t, err)
+		assert.Equal(t, domain.RevisionUpdate, revision.Action)
+		assert.Equal(t, []domain.FieldChange{
+			{Field: "Name", From: mockProductSuccess.Name, To: "Chunky Monkey"},
+			{Field: "Slug", From: "vanilla-toffee-bar-crunch", To: "chunky-monkey"},
+		}, revision.Changes)
+		mockProductRepo.AssertExpectations(t)
+	})
+
+	t.Run("UpdateProduct-retry-on-concurrent-write", func(t *testing.T) {
+		mockProduct := createMockProduct()
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(mockProduct, nil)
+		mockProductRepo.On("Update", contextType, mockProduct.ProductID, productType).
+			Return(domain.ErrPreconditionFailed)
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.UpdateProduct(context.TODO(), mockProduct.ProductID, domain.Product{Name: "Chunky Monkey"})
+
+		assert.Equal(t, domain.ErrPreconditionFailed, err)
+		mockProductRepo.AssertNumberOfCalls(t, "Update", 1+writeAttempts)
 	})
 
 	t.Run("UpdateProduct-on-db-error", func(t *testing.T) {
 		var dberr error = domain.ErrResourceNotFound
 		mockProductFail := domain.Product{}
 
-		mockProductRepo.On("Update", contextType, productIDType, productType).
-			Return(dberr).
+		mockProductRepo.On("Get", contextType, "647").
+			Return(domain.Product{}, dberr).
 			Once()
 
-		var productService = NewProductService(mockProductRepo)
-		err := productService.UpdateProduct(context.TODO(), "646", mockProductFail)
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.UpdateProduct(context.TODO(), "647", mockProductFail)
 
 		assert.Error(t, err)
 		assert.Equal(t, dberr, err)
 	})
 }
 
+func TestReplaceProduct(t *testing.T) {
+	// setup mock repository and mock data
+	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+	t.Run("ReplaceProduct-success", func(t *testing.T) {
+		mockProduct := createMockProduct()
+		mockProduct.Story = ""
+
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(createMockProduct(), nil).
+			Twice()
+		mockProductRepo.On("Replace", contextType, mockProduct.ProductID, mockProduct).
+			Return(nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.ReplaceProduct(context.TODO(), mockProduct.ProductID, mockProduct)
+
+		// stored product is unchanged, so no revision is recorded
+		assert.NoError(t, err)
+		mockRevisionRepo.AssertNotCalled(t, "Create", contextType, revisionType)
+	})
+
+	t.Run("ReplaceProduct-on-version-mismatch", func(t *testing.T) {
+		mockProduct := createMockProduct()
+		mockProduct.Version = 3
+
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(createMockProduct(), nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.ReplaceProduct(context.TODO(), mockProduct.ProductID, mockProduct)
+
+		assert.Equal(t, domain.ErrPreconditionFailed, err)
+		mockProductRepo.AssertNumberOfCalls(t, "Replace", 1)
+	})
+}
+
 func TestDeleteProduct(t *testing.T) {
 	// setup mock repository and mock data
 	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
 
 	t.Run("DeleteProduct-success", func(t *testing.T) {
-		mockProductID := "646"
-		mockProductRepo.On("Delete", contextType, productIDType).
+		mockProduct := createMockProduct()
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(mockProduct, nil).
+			Once()
+		mockProductRepo.On("Delete", contextType, productIDType, mockProduct.Version, "jerry").
 			Return(nil).
 			Once()
 
-		var productService = NewProductService(mockProductRepo)
-		err := productService.DeleteProduct(context.TODO(), mockProductID)
+		var revision domain.ProductRevision
+		mockRevisionRepo.On("Create", contextType, revisionType).
+			Run(func(args mock.Arguments) { revision = args.Get(1).(domain.ProductRevision) }).
+			Return(int64(3), nil).
+			Once()
+
+		ctx := auth.NewContext(context.TODO(), auth.Authentication{ID: "jerry"})
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.DeleteProduct(ctx, mockProduct.ProductID, mockProduct.Version)
 
 		assert.NoError(t, err)
+		assert.Equal(t, domain.RevisionDelete, revision.Action)
+		assert.Equal(t, "jerry", revision.Author)
+		assert.Equal(t, mockProduct, revision.Product)
 	})
 
 	t.Run("DeleteProduct-on-db-error", func(t *testing.T) {
 		var dberr error = domain.ErrResourceNotFound
-		mockProductID := "646"
+		mockProductID := "647"
 
-		mockProductRepo.On("Delete", contextType, productIDType).
-			Return(dberr).
+		mockProductRepo.On("Get", contextType, mockProductID).
+			Return(domain.Product{}, dberr).
 			Once()
 
-		var productService = NewProductService(mockProductRepo)
-		err := productService.DeleteProduct(context.TODO(), mockProductID)
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.DeleteProduct(context.TODO(), mockProductID, 0)
 
 		assert.Error(t, err)
 		assert.Equal(t, dberr, err)
 	})
+
+	t.Run("DeleteProduct-on-version-mismatch", func(t *testing.T) {
+		mockProduct := createMockProduct()
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(mockProduct, nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.DeleteProduct(context.TODO(), mockProduct.ProductID, 2)
+
+		assert.Equal(t, domain.ErrPreconditionFailed, err)
+		mockProductRepo.AssertNumberOfCalls(t, "Delete", 1)
+	})
 }
 
-func createMockProduct() domain.Product {
-	mockProductSuccess := domain.Product{
-		ProductID:      "646",
-		Name:           "Vanilla Toffee Bar Crunch",
-		ImageClosedURL: "/files/vanilla-toffee-landing.png",
-		ImageOpenURL:   "/files/vanilla-toffee-landing-open.png",
-		Description:    "Vanilla Ice Cream with Fudge-Covered Toffee Pieces",
-		Story:          "Vanilla What Bar Crunch? The new ice cream with toffee bars...",
-		SourcingValues: &[]string{
-			"Non-GMO",
-			"Cage-Free Eggs",
-			"Fairtrade",
-			"Responsibly Sourced Packaging",
-			"Caring Dairy",
-		},
-		Ingredients: &[]string{
-			"cream",
-			"skim milk",
-			"liquid sugar",
-			"water",
-			"sugar",
+func TestRestoreProduct(t *testing.T) {
+	// setup mock repository and mock data
+	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+	t.Run("RestoreProduct-success", func(t *testing.T) {
+		mockProduct := createMockProduct()
+		mockProductRepo.On("Restore", contextType, mockProduct.ProductID).
+			Return(nil).
+			Once()
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(mockProduct, nil).
+			Once()
+
+		var revision domain.ProductRevision
+		mockRevisionRepo.On("Create", contextType, revisionType).
+			Run(func(args mock.Arguments) { revision = args.Get(1).(domain.ProductRevision) }).
+			Return(int64(4), nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.RestoreProduct(context.TODO(), mockProduct.ProductID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.RevisionUndelete, revision.Action)
+		assert.Equal(t, mockProduct, revision.Product)
+	})
+
+	t.Run("RestoreProduct-not-in-trash", func(t *testing.T) {
+		mockProductRepo.On("Restore", contextType, "647").
+			Return(domain.ErrResourceNotFound).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.RestoreProduct(context.TODO(), "647")
+
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+}
+
+func TestPurgeTrash(t *testing.T) {
+	// setup mock repository with expired trashed products read in batches
+	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+	defaultBatchSize := purgeBatchSize
+	purgeBatchSize = 2
+	defer func() { purgeBatchSize = defaultBatchSize }()
+
+	now := time.Now()
+	deletedBefore := now.Add(-24 * time.Hour)
+	expired := createMockProduct()
+	restored := createMockProduct()
+	restored.ProductID = "647"
+	latest := createMockProduct()
+	latest.ProductID = "648"
+
+	mockProductRepo.On("FetchTrashBefore", contextType, deletedBefore, 2).
+		Return([]domain.TrashedProduct{
+			{Product: expired, DeletedAt: now.Add(-72 * time.Hour), DeletedBy: "jerry"},
+			{Product: restored, DeletedAt: now.Add(-60 * time.Hour), DeletedBy: "jerry"},
+		}, nil).
+		Once()
+	mockProductRepo.On("FetchTrashBefore", contextType, deletedBefore, 2).
+		Return([]domain.TrashedProduct{
+			{Product: latest, DeletedAt: now.Add(-48 * time.Hour), DeletedBy: "jerry"},
+		}, nil).
+		Once()
+	mockProductRepo.On("Purge", contextType, expired.ProductID).
+		Return(nil).
+		Once()
+	mockProductRepo.On("Purge", contextType, restored.ProductID).
+		Return(domain.ErrResourceNotFound).
+		Once()
+	mockProductRepo.On("Purge", contextType, latest.ProductID).
+		Return(nil).
+		Once()
+
+	var revision domain.ProductRevision
+	mockRevisionRepo.On("Create", contextType, revisionType).
+		Run(func(args mock.Arguments) { revision = args.Get(1).(domain.ProductRevision) }).
+		Return(int64(5), nil).
+		Twice()
+
+	var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+	purged, err := productService.PurgeTrash(context.TODO(), deletedBefore)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, purged)
+	assert.Equal(t, domain.RevisionPurge, revision.Action)
+	assert.Equal(t, domain.SystemAuthor, revision.Author)
+	assert.Equal(t, latest, revision.Product)
+	mockProductRepo.AssertExpectations(t)
+	mockProductRepo.AssertNotCalled(t, "FetchTrash", contextType)
+}
+
+func TestPurgeProduct(t *testing.T) {
+	// setup mock repository and mock data
+	mockProductRepo := new(mocks.ProductRepository)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+	trashed := createMockProduct()
+
+	t.Run("PurgeProduct-success", func(t *testing.T) {
+		mockProductRepo.On("GetTrashed", contextType, trashed.ProductID).
+			Return(domain.TrashedProduct{Product: trashed, DeletedAt: time.Now()}, nil).
+			Once()
+		mockProductRepo.On("Purge", contextType, trashed.ProductID).
+			Return(nil).
+			Once()
+
+		var revision domain.ProductRevision
+		mockRevisionRepo.On("Create", contextType, revisionType).
+			Run(func(args mock.Arguments) { revision = args.Get(1).(domain.ProductRevision) }).
+			Return(int64(3), nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.PurgeProduct(context.TODO(), trashed.ProductID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.RevisionPurge, revision.Action)
+		assert.Equal(t, trashed, revision.Product)
+	})
+
+	t.Run("PurgeProduct-not-in-trash", func(t *testing.T) {
+		mockProductRepo.On("GetTrashed", contextType, "647").
+			Return(domain.TrashedProduct{}, domain.ErrResourceNotFound).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.PurgeProduct(context.TODO(), "647")
+
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+		mockProductRepo.AssertNotCalled(t, "Purge", contextType, "647")
+	})
+}
+
+func TestRestoreRevision(t *testing.T) {
+	// setup mock repository and mock data
+	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+	t.Run("RestoreRevision-deleted-product", func(t *testing.T) {
+		mockProduct := createMockProduct()
+		mockRevisionRepo.On("Get", contextType, mockProduct.ProductID, int64(1)).
+			Return(domain.ProductRevision{Revision: 1, Action: domain.RevisionCreate, Product: mockProduct}, nil).
+			Once()
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(domain.Product{}, domain.ErrResourceNotFound).
+			Once()
+		mockProductRepo.On("Create", contextType, mockProduct).
+			Return(nil).
+			Once()
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(mockProduct, nil).
+			Once()
+
+		var revision domain.ProductRevision
+		mockRevisionRepo.On("Create", contextType, revisionType).
+			Run(func(args mock.Arguments) { revision = args.Get(1).(domain.ProductRevision) }).
+			Return(int64(3), nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.RestoreRevision(context.TODO(), mockProduct.ProductID, 1, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.RevisionRestore, revision.Action)
+		assert.Equal(t, int64(1), revision.RestoredFrom)
+	})
+
+	t.Run("RestoreRevision-delete-revision", func(t *testing.T) {
+		mockRevisionRepo.On("Get", contextType, "646", int64(2)).
+			Return(domain.ProductRevision{Revision: 2, Action: domain.RevisionDelete}, nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.RestoreRevision(context.TODO(), "646", 2, 0)
+
+		assert.Equal(t, domain.ErrBadParamInput, err)
+	})
+}
+
+func TestProductVisibility(t *testing.T) {
+	// setup mock repository and mock data
+	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+	reader := auth.NewContext(context.TODO(), auth.Authentication{
+		ID:             "ben",
+		Authorizations: []auth.Authorization{{AppName: appName, Role: role.ReadPermission}},
+	})
+	writer := auth.NewContext(context.TODO(), auth.Authentication{
+		ID:             "jerry",
+		Authorizations: []auth.Authorization{{AppName: appName, Role: role.WritePermission}},
+	})
+
+	draft := createMockProduct()
+	draft.Status = domain.StatusDraft
+	mockProductRepo.On("Get", contextType, draft.ProductID).
+		Return(draft, nil)
+
+	t.Run("GetProduct-draft-hidden-from-reader", func(t *testing.T) {
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		_, err := productService.GetProduct(reader, draft.ProductID)
+
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+
+	t.Run("GetProduct-draft-shown-to-writer", func(t *testing.T) {
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		product, err := productService.GetProduct(writer, draft.ProductID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, draft, product)
+	})
+
+	t.Run("FetchProducts-reader-sees-published-only", func(t *testing.T) {
+		published := []domain.ProductStatus{domain.StatusPublished}
+		mockProductRepo.On("Fetch", contextType, queryType).
+			Return(domain.ProductPage{}, nil).
+			Once()
+		mockProductRepo.On("CountFacets", contextType, filterType).
+			Return(domain.ProductFacets{}, nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		query := domain.ProductQuery{Filter: domain.ProductFilter{Statuses: []domain.ProductStatus{domain.StatusDraft}}}
+		_, err := productService.FetchProducts(reader, query)
+
+		assert.NoError(t, err)
+		fetched := mockProductRepo.Calls[len(mockProductRepo.Calls)-2].Arguments.Get(1).(domain.ProductQuery)
+		assert.Equal(t, published, fetched.Filter.Statuses)
+	})
+
+	published := createMockProduct()
+	published.Status = domain.StatusPublished
+	mockProductRepo.On("FetchTrash", contextType).
+		Return([]domain.TrashedProduct{{Product: draft}, {Product: published}}, nil)
+	mockRevisionRepo.On("Fetch", contextType, draft.ProductID).
+		Return([]domain.ProductRevision{
+			{ProductID: draft.ProductID, Revision: 1, Product: draft},
+			{ProductID: draft.ProductID, Revision: 2, Product: published},
+		}, nil)
+	mockRevisionRepo.On("Get", contextType, draft.ProductID, int64(1)).
+		Return(domain.ProductRevision{ProductID: draft.ProductID, Revision: 1, Product: draft}, nil)
+
+	t.Run("FetchTrash-reader-sees-published-only", func(t *testing.T) {
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		trash, err := productService.FetchTrash(reader)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.TrashedProduct{{Product: published}}, trash)
+
+		trash, err = productService.FetchTrash(writer)
+		assert.NoError(t, err)
+		assert.Len(t, trash, 2)
+	})
+
+	t.Run("FetchRevisions-reader-sees-published-only", func(t *testing.T) {
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		revisions, err := productService.FetchRevisions(reader, draft.ProductID)
+
+		assert.NoError(t, err)
+		assert.Len(t, revisions, 1)
+		assert.Equal(t, int64(2), revisions[0].Revision)
+
+		revisions, err = productService.FetchRevisions(writer, draft.ProductID)
+		assert.NoError(t, err)
+		assert.Len(t, revisions, 2)
+	})
+
+	t.Run("FetchRevisions-draft-only-hidden-from-reader", func(t *testing.T) {
+		mockRevisionRepo.On("Fetch", contextType, "647").
+			Return([]domain.ProductRevision{{ProductID: "647", Revision: 1, Product: draft}}, nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		_, err := productService.FetchRevisions(reader, "647")
+
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+
+	t.Run("GetRevision-draft-hidden-from-reader", func(t *testing.T) {
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		_, err := productService.GetRevision(reader, draft.ProductID, 1)
+
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+
+		revision, err := productService.GetRevision(writer, draft.ProductID, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, draft, revision.Product)
+	})
+}
+
+func TestFetchRetiredProducts(t *testing.T) {
+	// setup mock repository and mock data
+	mockProductRepo := new(mocks.ProductRepository)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+	retired := createMockProduct()
+	retired.Status = domain.StatusRetired
+	retired.Regions = []string{"uk"}
+	retired.RegionOverrides = map[string]domain.RegionOverride{"uk": {AllergyInfo: "Contains milk"}}
+
+	t.Run("FetchRetiredProducts-latest-retired-first", func(t *testing.T) {
+		expectedQuery := domain.ProductQuery{
+			Cursor:    "next",
+			Limit:     domain.DefaultPageLimit,
+			SortBy:    domain.SortByRetiredAt,
+			SortOrder: domain.Descending,
+			Filter: domain.ProductFilter{
+				SourcingMatch: domain.MatchAll,
+				Statuses:      []domain.ProductStatus{domain.StatusRetired},
+				Region:        "uk",
+			},
+		}
+		mockProductRepo.On("Fetch", contextType, expectedQuery).
+			Return(domain.ProductPage{Products: []domain.Product{retired}, NextCursor: "after", TotalCount: 21}, nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		query := domain.ProductQuery{
+			Cursor: "next",
+			Filter: domain.ProductFilter{Statuses: []domain.ProductStatus{domain.StatusDraft}, Region: "uk"},
+		}
+		page, err := productService.FetchRetiredProducts(context.TODO(), query)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "after", page.NextCursor)
+		assert.Equal(t, "Contains milk", page.Products[0].AllergyInfo)
+		mockProductRepo.AssertExpectations(t)
+	})
+
+	t.Run("FetchRetiredProducts-other-sort", func(t *testing.T) {
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		_, err := productService.FetchRetiredProducts(context.TODO(), domain.ProductQuery{SortBy: domain.SortByName})
+
+		assert.Equal(t, domain.ErrBadParamInput, err)
+	})
+
+	t.Run("FetchRetiredProducts-limit-too-large", func(t *testing.T) {
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		_, err := productService.FetchRetiredProducts(context.TODO(), domain.ProductQuery{Limit: domain.MaxPageLimit + 1})
+
+		assert.Equal(t, domain.ErrBadParamInput, err)
+	})
+}
+
+func TestChangeProductStatus(t *testing.T) {
+	// setup mock repository and mock data
+	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+	draft := createMockProduct()
+	draft.Status = domain.StatusDraft
+	draft.Version = 2
+
+	t.Run("ChangeProductStatus-publish", func(t *testing.T) {
+		published := draft
+		published.Status = domain.StatusPublished
+
+		mockProductRepo.On("Get", contextType, draft.ProductID).
+			Return(draft, nil).
+			Once()
+		mockProductRepo.On("UpdateStatus", contextType, draft.ProductID, published).
+			Return(nil).
+			Once()
+		mockProductRepo.On("Get", contextType, draft.ProductID).
+			Return(published, nil).
+			Once()
+
+		var revision domain.ProductRevision
+		mockRevisionRepo.On("Create", contextType, revisionType).
+			Run(func(args mock.Arguments) { revision = args.Get(1).(domain.ProductRevision) }).
+			Return(int64(2), nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		change := domain.StatusChange{Status: domain.StatusPublished}
+		err := productService.ChangeProductStatus(context.TODO(), draft.ProductID, change, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.FieldChange{{Field: "Status", From: domain.StatusDraft, To: domain.StatusPublished}}, revision.Changes)
+	})
+
+	t.Run("ChangeProductStatus-invalid-transition", func(t *testing.T) {
+		mockProductRepo.On("Get", contextType, draft.ProductID).
+			Return(draft, nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		change := domain.StatusChange{Status: domain.StatusRetired}
+		err := productService.ChangeProductStatus(context.TODO(), draft.ProductID, change, 0)
+
+		assert.Equal(t, domain.ErrInvalidTransition, err)
+	})
+
+	t.Run("ChangeProductStatus-epitaph-without-retire", func(t *testing.T) {
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		change := domain.StatusChange{Status: domain.StatusPublished, Epitaph: "Gone too soon"}
+		err := productService.ChangeProductStatus(context.TODO(), draft.ProductID, change, 0)
+
+		assert.Equal(t, domain.ErrBadParamInput, err)
+	})
+}
+
+func TestNextLifecycle(t *testing.T) {
+	now := time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)
+	later := now.Add(24 * time.Hour)
+	published := domain.Product{ProductID: "646", Status: domain.StatusPublished}
+
+	t.Run("retire-now", func(t *testing.T) {
+		next, err := nextLifecycle(published, domain.StatusChange{Status: domain.StatusRetired, Epitaph: "RIP"}, now)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.StatusRetired, next.Status)
+		assert.Equal(t, now, *next.RetiredAt)
+		assert.Equal(t, "RIP", next.Epitaph)
+	})
+
+	t.Run("retire-later", func(t *testing.T) {
+		next, err := nextLifecycle(published, domain.StatusChange{Status: domain.StatusRetired, At: later}, now)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.StatusPublished, next.Status)
+		assert.Equal(t, domain.StatusRetired, next.ScheduledStatus)
+		assert.Equal(t, later, *next.ScheduledAt)
+	})
+
+	t.Run("comeback", func(t *testing.T) {
+		retired := published
+		retired.Status = domain.StatusRetired
+		retired.RetiredAt = &now
+
+		next, err := nextLifecycle(retired, domain.StatusChange{Status: domain.StatusPublished}, later)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.StatusPublished, next.Status)
+		assert.Nil(t, next.RetiredAt)
+	})
+
+	t.Run("back-to-draft", func(t *testing.T) {
+		_, err := nextLifecycle(published, domain.StatusChange{Status: domain.StatusDraft}, now)
+
+		assert.Equal(t, domain.ErrInvalidTransition, err)
+	})
+}
+
+func TestApplyScheduledStatuses(t *testing.T) {
+	// setup mock repository with a due retirement and a stale publish
+	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+	now := time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)
+	due := now.Add(-time.Minute)
+
+	retiring := createMockProduct()
+	retiring.Status = domain.StatusPublished
+	retiring.ScheduledStatus = domain.StatusRetired
+	retiring.ScheduledAt = &due
+
+	stale := createMockProduct()
+	stale.ProductID = "647"
+	stale.Status = domain.StatusPublished
+	stale.ScheduledStatus = domain.StatusPublished
+	stale.ScheduledAt = &due
+
+	retired := retiring
+	retired.Status = domain.StatusRetired
+	retired.RetiredAt = &now
+	retired.ScheduledStatus = ""
+	retired.ScheduledAt = nil
+
+	unscheduled := stale
+	unscheduled.ScheduledStatus = ""
+	unscheduled.ScheduledAt = nil
+
+	mockProductRepo.On("FetchScheduled", contextType, now).
+		Return([]domain.Product{retiring, stale}, nil).
+		Once()
+	mockProductRepo.On("UpdateStatus", contextType, retiring.ProductID, retired).
+		Return(nil).
+		Once()
+	mockProductRepo.On("UpdateStatus", contextType, stale.ProductID, unscheduled).
+		Return(nil).
+		Once()
+	mockProductRepo.On("Get", contextType, retiring.ProductID).
+		Return(retired, nil).
+		Once()
+	mockProductRepo.On("Get", contextType, stale.ProductID).
+		Return(unscheduled, nil).
+		Once()
+	mockRevisionRepo.On("Create", contextType, revisionType).
+		Return(int64(1), nil)
+
+	var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+	applied, err := productService.ApplyScheduledStatuses(context.TODO(), now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, applied)
+	mockProductRepo.AssertExpectations(t)
+}
+
+func TestDiffProducts(t *testing.T) {
+	before := createMockProduct()
+	after := createMockProduct()
+	after.Version = 9
+	after.Ingredients = &[]string{"cream"}
+	after.SourcingValues = nil
+
+	changes := diffProducts(before, after)
+
+	assert.Equal(t, []domain.FieldChange{
+		{Field: "SourcingValues", From: *before.SourcingValues, To: nil},
+		{Field: "Ingredients", From: *before.Ingredients, To: []string{"cream"}},
+	}, changes)
+	assert.Empty(t, diffProducts(domain.Product{SourcingValues: &[]string{}}, domain.Product{}))
+}
+
+func TestDiffTranslations(t *testing.T) {
+	before := createMockProduct()
+	before.Translations = map[string]domain.ProductTranslation{
+		"fr": {Name: "Vanille Caramel", Story: "Une histoire"},
+		"de": {Name: "Vanille Karamell"},
+	}
+	after := createMockProduct()
+	after.Translations = map[string]domain.ProductTranslation{
+		"fr": {Name: "Vanille Caramel Croquant", Story: "Une histoire"},
+		"nl": {Name: "Vanille Karamel"},
+	}
+
+	assert.Equal(t, []domain.FieldChange{
+		{Field: "Translations.de.Name", From: "Vanille Karamell", To: nil},
+		{Field: "Translations.fr.Name", From: "Vanille Caramel", To: "Vanille Caramel Croquant"},
+		{Field: "Translations.nl.Name", From: nil, To: "Vanille Karamel"},
+	}, diffProducts(before, after))
+}
+
+func TestSetTranslation(t *testing.T) {
+	// setup mock repository and mock data
+	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+	mockProduct := createMockProduct()
+	mockProduct.Version = 3
+	translation := domain.ProductTranslation{Name: "Vanille Caramel"}
+
+	translated := mockProduct
+	translated.Translations = map[string]domain.ProductTranslation{"fr": translation}
+
+	t.Run("SetTranslation-success", func(t *testing.T) {
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(mockProduct, nil).
+			Once()
+		mockProductRepo.On("UpdateTranslations", contextType, mockProduct.ProductID, translated).
+			Return(nil).
+			Once()
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(translated, nil).
+			Once()
+
+		var revision domain.ProductRevision
+		mockRevisionRepo.On("Create", contextType, revisionType).
+			Run(func(args mock.Arguments) { revision = args.Get(1).(domain.ProductRevision) }).
+			Return(int64(2), nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.SetTranslation(context.TODO(), mockProduct.ProductID, "fr", translation, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.FieldChange{{Field: "Translations.fr.Name", From: nil, To: "Vanille Caramel"}}, revision.Changes)
+		mockProductRepo.AssertExpectations(t)
+	})
+
+	t.Run("SetTranslation-empty", func(t *testing.T) {
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.SetTranslation(context.TODO(), mockProduct.ProductID, "fr", domain.ProductTranslation{}, 0)
+
+		assert.Equal(t, domain.ErrBadParamInput, err)
+	})
+
+	t.Run("DeleteTranslation-missing", func(t *testing.T) {
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(translated, nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.DeleteTranslation(context.TODO(), mockProduct.ProductID, "de", 0)
+
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+}
+
+func TestAddVariant(t *testing.T) {
+	// setup mock repository and mock data
+	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+	mockProduct := createMockProduct()
+	mockProduct.Version = 3
+	variant := domain.ProductVariant{SKU: "BJ-646-P", Format: domain.FormatPint, Size: "465ml"}
+
+	withVariant := mockProduct
+	withVariant.Variants = []domain.ProductVariant{variant}
+
+	t.Run("AddVariant-success", func(t *testing.T) {
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(mockProduct, nil).
+			Once()
+		mockProductRepo.On("UpdateVariants", contextType, mockProduct.ProductID, withVariant).
+			Return(nil).
+			Once()
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(withVariant, nil).
+			Once()
+
+		var revision domain.ProductRevision
+		mockRevisionRepo.On("Create", contextType, revisionType).
+			Run(func(args mock.Arguments) { revision = args.Get(1).(domain.ProductRevision) }).
+			Return(int64(2), nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.AddVariant(context.TODO(), mockProduct.ProductID, variant, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.FieldChange{
+			{Field: "Variants.BJ-646-P.SKU", From: nil, To: "BJ-646-P"},
+			{Field: "Variants.BJ-646-P.Format", From: nil, To: domain.FormatPint},
+			{Field: "Variants.BJ-646-P.Size", From: nil, To: "465ml"},
+		}, revision.Changes)
+		mockProductRepo.AssertExpectations(t)
+	})
+
+	t.Run("AddVariant-duplicate", func(t *testing.T) {
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(withVariant, nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.AddVariant(context.TODO(), mockProduct.ProductID, variant, 0)
+
+		assert.Equal(t, domain.ErrDuplicateSKU, err)
+	})
+
+	t.Run("AddVariant-no-sku", func(t *testing.T) {
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.AddVariant(context.TODO(), mockProduct.ProductID, domain.ProductVariant{Size: "465ml"}, 0)
+
+		assert.Equal(t, domain.ErrBadParamInput, err)
+	})
+
+	t.Run("UpdateVariant-missing", func(t *testing.T) {
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(withVariant, nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.UpdateVariant(context.TODO(), mockProduct.ProductID, "BJ-646-M", variant, 0)
+
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+}
+
+func TestAllergenCheck(t *testing.T) {
+	// setup mock repository and mock data
+	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+	mockProduct := createMockProduct()
+	mockProduct.Status = domain.StatusPublished
+	mockProduct.Allergens = domain.Allergens{Contains: []string{"eggs"}, MayContain: []string{"milk"}}
+
+	t.Run("CreateProduct-flags-disagreement", func(t *testing.T) {
+		var created domain.Product
+		mockProductRepo.On("Create", contextType, productType).
+			Run(func(args mock.Arguments) { created = args.Get(1).(domain.Product) }).
+			Return(nil).
+			Once()
+		mockRevisionRepo.On("Create", contextType, revisionType).
+			Return(int64(1), nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		_, err := productService.CreateProduct(context.TODO(), mockProduct)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.AllergenCheck{Undeclared: []string{"milk"}, Undetected: []string{"eggs"}}, created.AllergenCheck)
+	})
+
+	t.Run("UpdateProduct-checks-stored-ingredients", func(t *testing.T) {
+		// allergens are checked against ingredients left out of update
+		fixed := domain.Product{Allergens: domain.Allergens{Contains: []string{"milk"}}}
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(mockProduct, nil).
+			Once()
+		mockProductRepo.On("Update", contextType, mockProduct.ProductID, fixed).
+			Return(nil).
+			Once()
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(mockProduct, nil).
+			Once()
+		mockRevisionRepo.On("Create", contextType, revisionType).
+			Return(int64(2), nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.UpdateProduct(context.TODO(), mockProduct.ProductID, fixed)
+
+		assert.NoError(t, err)
+		mockProductRepo.AssertExpectations(t)
+	})
+
+	t.Run("ProposeAllergens", func(t *testing.T) {
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(mockProduct, nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		proposal, err := productService.ProposeAllergens(context.TODO(), mockProduct.ProductID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockProduct.Allergens, proposal.Declared)
+		assert.Equal(t, []string{"milk"}, proposal.Detected)
+		assert.Equal(t, domain.AllergenCheck{Undeclared: []string{"milk"}, Undetected: []string{"eggs"}}, proposal.Check)
+	})
+}
+
+func TestParsedIngredientsOnWrite(t *testing.T) {
+	// setup mock repository and mock data
+	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+	mockProduct := createMockProduct()
+	mockProduct.Ingredients = &[]string{"cream", "fudge (sugar, cocoa (processed with alkali))"}
+
+	var created domain.Product
+	mockProductRepo.On("Create", contextType, productType).
+		Run(func(args mock.Arguments) { created = args.Get(1).(domain.Product) }).
+		Return(nil).
+		Once()
+	mockRevisionRepo.On("Create", contextType, revisionType).
+		Return(int64(1), nil).
+		Once()
+
+	var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+	_, err := productService.CreateProduct(context.TODO(), mockProduct)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Ingredient{
+		{Name: "cream"},
+		{Name: "fudge", Ingredients: []domain.Ingredient{
+			{Name: "sugar"},
+			{Name: "cocoa", Qualifiers: []string{"processed with alkali"}},
+		}},
+	}, created.ParsedIngredients)
+}
+
+func TestComputeNutrition(t *testing.T) {
+	// setup mock repository and mock data
+	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+	mockProduct := createMockProduct()
+	mockProduct.Nutrition = domain.NutritionFacts{
+		ServingSize:   98,
+		ServingVolume: 150,
+		ServingLabel:  "2/3 cup",
+		Per100g:       domain.Nutrients{Calories: 250, TotalFat: 15, Protein: 4},
+	}
+	mockProduct.Variants = []domain.ProductVariant{{SKU: "646-PINT", Format: domain.FormatPint, Size: "465ml"}}
+
+	t.Run("success-serving", func(t *testing.T) {
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(mockProduct, nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		serving, err := productService.ComputeNutrition(context.TODO(), mockProduct.ProductID, domain.Quantity{}, "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 98.0, serving.Grams)
+		assert.Equal(t, 1.0, serving.Servings)
+		assert.InDelta(t, 245, serving.Nutrients.Calories, 0.001)
+		assert.InDelta(t, 14.7, serving.Nutrients.TotalFat, 0.001)
+	})
+
+	t.Run("success-quantity", func(t *testing.T) {
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(mockProduct, nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		serving, err := productService.ComputeNutrition(
+			context.TODO(), mockProduct.ProductID, domain.Quantity{Amount: 200, Unit: "g"}, "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 200.0, serving.Grams)
+		assert.InDelta(t, 500, serving.Nutrients.Calories, 0.001)
+	})
+
+	t.Run("success-container", func(t *testing.T) {
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(mockProduct, nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		serving, err := productService.ComputeNutrition(context.TODO(), mockProduct.ProductID, domain.Quantity{}, "646-PINT")
+
+		// 465ml is 3.1 servings of 150ml, each of which weighs 98g
+		assert.NoError(t, err)
+		assert.InDelta(t, 303.8, serving.Grams, 0.001)
+		assert.InDelta(t, 3.1, serving.Servings, 0.001)
+	})
+
+	t.Run("error-volume-without-serving-volume", func(t *testing.T) {
+		withoutVolume := mockProduct
+		withoutVolume.Nutrition.ServingVolume = 0
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(withoutVolume, nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		_, err := productService.ComputeNutrition(
+			context.TODO(), mockProduct.ProductID, domain.Quantity{Amount: 1, Unit: "cup"}, "")
+
+		assert.Equal(t, domain.ErrBadParamInput, err)
+	})
+
+	t.Run("error-no-nutrition", func(t *testing.T) {
+		mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+			Return(createMockProduct(), nil).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		_, err := productService.ComputeNutrition(context.TODO(), mockProduct.ProductID, domain.Quantity{}, "")
+
+		assert.Equal(t, domain.ErrResourceNotFound, err)
+	})
+}
+
+func TestCreateProductInvalidNutrition(t *testing.T) {
+	mockProductRepo := new(mocks.ProductRepository)
+	mockFreeSlugs(mockProductRepo)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+	// saturated fat is part of total fat, so it cannot exceed it
+	mockProduct := createMockProduct()
+	mockProduct.Nutrition = domain.NutritionFacts{
+		ServingSize: 98,
+		Per100g:     domain.Nutrients{Calories: 250, TotalFat: 5, SaturatedFat: 10},
+	}
+
+	var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+	_, err := productService.CreateProduct(context.TODO(), mockProduct)
+
+	assert.Equal(t, domain.ErrInvalidNutrition, err)
+	mockProductRepo.AssertNotCalled(t, "Create", contextType, productType)
+}
+
+func TestCreateProductSlug(t *testing.T) {
+	t.Run("CreateProduct-numbers-taken-slug", func(t *testing.T) {
+		mockProductRepo := new(mocks.ProductRepository)
+		mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+		// plain slug is taken by another product, first numbered one
+		// was its slug before it was renamed
+		mockProductRepo.On("GetBySlug", contextType, "vanilla-toffee-bar-crunch").
+			Return(domain.Product{ProductID: "100"}, nil)
+		mockProductRepo.On("GetBySlug", contextType, "vanilla-toffee-bar-crunch-2").
+			Return(domain.Product{ProductID: "101"}, nil)
+		mockProductRepo.On("GetBySlug", contextType, "vanilla-toffee-bar-crunch-3").
+			Return(domain.Product{}, domain.ErrResourceNotFound)
+		mockProductRepo.On("Create", contextType, productType).
+			Return(nil).
+			Once()
+		mockRevisionRepo.On("Create", contextType, revisionType).
+			Return(int64(1), nil)
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		product, err := productService.CreateProduct(context.TODO(), createMockProduct())
+
+		assert.NoError(t, err)
+		assert.Equal(t, "vanilla-toffee-bar-crunch-3", product.Slug)
+		assert.Empty(t, product.PreviousSlugs)
+	})
+
+	t.Run("CreateProduct-numbers-reserved-slug", func(t *testing.T) {
+		mockProductRepo := new(mocks.ProductRepository)
+		mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+		// api/products/trash lists the trash, so
+		// products named Trash are slugged "trash-2"
+		mockProductRepo.On("GetBySlug", contextType, "trash-2").
+			Return(domain.Product{}, domain.ErrResourceNotFound)
+		mockProductRepo.On("Create", contextType, productType).
+			Return(nil).
+			Once()
+		mockRevisionRepo.On("Create", contextType, revisionType).
+			Return(int64(1), nil)
+
+		mockProduct := createMockProduct()
+		mockProduct.Name = "Trash"
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		product, err := productService.CreateProduct(context.TODO(), mockProduct)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "trash-2", product.Slug)
+		mockProductRepo.AssertNotCalled(t, "GetBySlug", contextType, "trash")
+	})
+
+	t.Run("ReplaceProduct-reclaims-previous-slug", func(t *testing.T) {
+		mockProductRepo := new(mocks.ProductRepository)
+		mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+		stored := createMockProduct()
+		stored.Name = "Chunky Monkey"
+		stored.Slug = "chunky-monkey"
+		stored.PreviousSlugs = []string{"vanilla-toffee-bar-crunch", "phish-food"}
+
+		// old slug of product is still its own to take back
+		mockProductRepo.On("GetBySlug", contextType, "vanilla-toffee-bar-crunch").
+			Return(stored, nil)
+		mockProductRepo.On("Get", contextType, stored.ProductID).
+			Return(stored, nil)
+
+		var replaced domain.Product
+		mockProductRepo.On("Replace", contextType, stored.ProductID, productType).
+			Run(func(args mock.Arguments) { replaced = args.Get(2).(domain.Product) }).
+			Return(nil).
+			Once()
+		mockRevisionRepo.On("Create", contextType, revisionType).
+			Return(int64(2), nil)
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		err := productService.ReplaceProduct(context.TODO(), stored.ProductID, createMockProduct())
+
+		assert.NoError(t, err)
+		assert.Equal(t, "vanilla-toffee-bar-crunch", replaced.Slug)
+		assert.Equal(t, []string{"phish-food", "chunky-monkey"}, replaced.PreviousSlugs)
+	})
+
+	t.Run("CreateProduct-on-slugs-exhausted", func(t *testing.T) {
+		mockProductRepo := new(mocks.ProductRepository)
+		mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+		mockProductRepo.On("GetBySlug", contextType, productIDType).
+			Return(domain.Product{ProductID: "100"}, nil)
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		_, err := productService.CreateProduct(context.TODO(), createMockProduct())
+
+		assert.Equal(t, domain.ErrDuplicateSlug, err)
+		mockProductRepo.AssertNumberOfCalls(t, "GetBySlug", slugAttempts)
+		mockProductRepo.AssertNotCalled(t, "Create", contextType, productType)
+	})
+}
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Vanilla Toffee Bar Crunch":   "vanilla-toffee-bar-crunch",
+		"Cherry Garcia®":              "cherry-garcia",
+		"  Phish Food -- Pint  ":      "phish-food-pint",
+		"Half Baked 2.0":              "half-baked-2-0",
+		"1984":                        "product-1984",
+		"!!!":                         "product",
+		strings.Repeat("chunky ", 12): strings.TrimSuffix(strings.Repeat("chunky-", 8), "-"),
+	}
+
+	for name, slug := range cases {
+		assert.Equal(t, slug, domain.Slugify(name), name)
+	}
+	assert.Equal(t, "vanilla", domain.SlugCandidate("vanilla", 1))
+	assert.Equal(t, "vanilla-3", domain.SlugCandidate("vanilla", 3))
+
+	for _, slug := range []string{"search", "export", "trash", "graveyard"} {
+		assert.True(t, domain.IsReservedSlug(slug), slug)
+	}
+	assert.False(t, domain.IsReservedSlug("trash-2"))
+}
+
+func TestGetProductBySlug(t *testing.T) {
+	mockProductRepo := new(mocks.ProductRepository)
+	mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+	mockProduct := createMockProduct()
+	mockProduct.PreviousSlugs = []string{"vanilla"}
+	mockProduct.Status = domain.StatusPublished
+
+	mockProductRepo.On("GetBySlug", contextType, "vanilla").
+		Return(mockProduct, nil).
+		Once()
+	mockProductRepo.On("Get", contextType, mockProduct.ProductID).
+		Return(mockProduct, nil).
+		Once()
+
+	var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+	product, err := productService.GetProductBySlug(context.TODO(), "vanilla")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "vanilla-toffee-bar-crunch", product.Slug)
+	mockProductRepo.AssertExpectations(t)
+}
+
+func TestAllocateProductID(t *testing.T) {
+	t.Run("CreateProduct-retries-taken-id", func(t *testing.T) {
+		mockProductRepo := new(mocks.ProductRepository)
+		mockFreeSlugs(mockProductRepo)
+		mockRevisionRepo := new(mocks.ProductRevisionRepository)
+		mockIDs := new(mocks.ProductIDGenerator)
+
+		mockIDs.On("NextProductID", contextType).Return("2190", nil).Once()
+		mockIDs.On("NextProductID", contextType).Return("2191", nil).Once()
+
+		isProduct := func(productID string) interface{} {
+			return mock.MatchedBy(func(product domain.Product) bool { return product.ProductID == productID })
+		}
+		mockProductRepo.On("Create", contextType, isProduct("2190")).
+			Return(domain.ErrConflict).
+			Once()
+		mockProductRepo.On("Create", contextType, isProduct("2191")).
+			Return(nil).
+			Once()
+		mockRevisionRepo.On("Create", contextType, revisionType).
+			Return(int64(1), nil)
+
+		mockProduct := createMockProduct()
+		mockProduct.ProductID = ""
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, mockIDs, nil)
+		product, err := productService.CreateProduct(context.TODO(), mockProduct)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "2191", product.ProductID)
+		mockIDs.AssertExpectations(t)
+		mockProductRepo.AssertExpectations(t)
+	})
+
+	t.Run("CreateProduct-keeps-given-id", func(t *testing.T) {
+		mockProductRepo := new(mocks.ProductRepository)
+		mockFreeSlugs(mockProductRepo)
+		mockRevisionRepo := new(mocks.ProductRevisionRepository)
+		mockIDs := new(mocks.ProductIDGenerator)
+
+		// given productId taken by another product is not retried
+		mockProductRepo.On("Create", contextType, productType).
+			Return(domain.ErrConflict).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, mockIDs, nil)
+		_, err := productService.CreateProduct(context.TODO(), createMockProduct())
+
+		assert.Equal(t, domain.ErrConflict, err)
+		mockIDs.AssertNotCalled(t, "NextProductID", contextType)
+		mockProductRepo.AssertNumberOfCalls(t, "Create", 1)
+	})
+
+	t.Run("CreateProduct-without-generator", func(t *testing.T) {
+		mockProductRepo := new(mocks.ProductRepository)
+		mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		_, err := productService.CreateProduct(context.TODO(), domain.Product{Name: "Phish Food"})
+
+		assert.Equal(t, domain.ErrBadParamInput, err)
+	})
+
+	t.Run("RandomIDGenerator", func(t *testing.T) {
+		generator := NewRandomIDGenerator(4)
+
+		for i := 0; i < 100; i++ {
+			productID, err := generator.NextProductID(context.TODO())
+
+			assert.NoError(t, err)
+			assert.Regexp(t, "^[1-9][0-9]{3}$", productID)
+		}
+	})
+}
+
+func TestBatchProducts(t *testing.T) {
+	fnType := mock.AnythingOfType("func(context.Context) error")
+	runWithin := func(args mock.Arguments) {
+		fn := args.Get(1).(func(context.Context) error)
+		fn(args.Get(0).(context.Context))
+	}
+
+	t.Run("BatchDeleteProducts-items-fail-alone", func(t *testing.T) {
+		mockProductRepo := new(mocks.ProductRepository)
+		mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+		mockProductRepo.On("Get", contextType, "646").
+			Return(createMockProduct(), nil)
+		mockProductRepo.On("Get", contextType, "978").
+			Return(domain.Product{}, domain.ErrResourceNotFound)
+		mockProductRepo.On("Delete", contextType, "646", int64(0), domain.SystemAuthor).
+			Return(nil).
+			Once()
+		mockRevisionRepo.On("Create", contextType, revisionType).
+			Return(int64(2), nil)
+
+		keys := []domain.ProductKey{{ProductID: "978"}, {ProductID: "646"}}
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		results, err := productService.BatchDeleteProducts(context.TODO(), keys, false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.BatchItemResult{
+			{ProductID: "978", Err: domain.ErrResourceNotFound},
+			{ProductID: "646"},
+		}, results)
+	})
+
+	t.Run("BatchCreateProducts-atomic-aborts-on-failed-item", func(t *testing.T) {
+		mockProductRepo := new(mocks.ProductRepository)
+		mockFreeSlugs(mockProductRepo)
+		mockRevisionRepo := new(mocks.ProductRevisionRepository)
+		mockTransactor := new(mocks.Transactor)
+
+		first := createMockProduct()
+		second := createMockProduct()
+		second.ProductID = "647"
+		third := createMockProduct()
+		third.ProductID = "648"
+
+		isProduct := func(productID string) interface{} {
+			return mock.MatchedBy(func(product domain.Product) bool { return product.ProductID == productID })
+		}
+		mockProductRepo.On("Create", contextType, isProduct("646")).
+			Return(nil).
+			Once()
+		mockProductRepo.On("Create", contextType, isProduct("647")).
+			Return(domain.ErrConflict).
+			Once()
+		mockRevisionRepo.On("Create", contextType, revisionType).
+			Return(int64(1), nil)
+		mockTransactor.On("WithinTransaction", contextType, fnType).
+			Run(runWithin).
+			Return(domain.ErrConflict).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, mockTransactor)
+		results, err := productService.BatchCreateProducts(context.TODO(), []domain.Product{first, second, third}, true)
+
+		// items after the failed one are never run
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.BatchItemResult{
+			{ProductID: "646", Err: domain.ErrBatchAborted},
+			{ProductID: "647", Err: domain.ErrConflict},
+			{ProductID: "648", Err: domain.ErrBatchAborted},
+		}, results)
+		mockProductRepo.AssertNumberOfCalls(t, "Create", 2)
+	})
+
+	t.Run("BatchUpdateProducts-atomic-commit-fails", func(t *testing.T) {
+		mockProductRepo := new(mocks.ProductRepository)
+		mockRevisionRepo := new(mocks.ProductRevisionRepository)
+		mockTransactor := new(mocks.Transactor)
+
+		mockTransactor.On("WithinTransaction", contextType, fnType).
+			Return(domain.ErrInternalServerError).
+			Once()
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, mockTransactor)
+		_, err := productService.BatchUpdateProducts(context.TODO(), []domain.Product{{ProductID: "646"}}, true)
+
+		assert.Equal(t, domain.ErrInternalServerError, err)
+	})
+
+	t.Run("BatchUpdateProducts-atomic-without-transactor", func(t *testing.T) {
+		mockProductRepo := new(mocks.ProductRepository)
+		mockRevisionRepo := new(mocks.ProductRevisionRepository)
+
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+		_, err := productService.BatchUpdateProducts(context.TODO(), []domain.Product{{ProductID: "646"}}, true)
+
+		assert.Equal(t, domain.ErrTransactionUnsupported, err)
+	})
+
+	t.Run("BatchGetProducts-size", func(t *testing.T) {
+		mockProductRepo := new(mocks.ProductRepository)
+		mockRevisionRepo := new(mocks.ProductRevisionRepository)
+		var productService = NewProductService(appName, mockProductRepo, mockRevisionRepo, nil, nil)
+
+		_, err := productService.BatchGetProducts(context.TODO(), nil)
+		assert.Equal(t, domain.ErrBadParamInput, err)
+
+		_, err = productService.BatchGetProducts(context.TODO(), make([]string, domain.MaxBatchSize+1))
+		assert.Equal(t, domain.ErrBadParamInput, err)
+	})
+}
+
+// mockFreeSlugs makes every slug free to take
+func mockFreeSlugs(mockProductRepo *mocks.ProductRepository) {
+	mockProductRepo.On("GetBySlug", contextType, productIDType).
+		Return(domain.Product{}, domain.ErrResourceNotFound).
+		Maybe()
+}
+
+func createMockProduct() domain.Product {
+	mockProductSuccess := domain.Product{
+		ProductID:      "646",
+		Name:           "Vanilla Toffee Bar Crunch",
+		Slug:           "vanilla-toffee-bar-crunch",
+		ImageClosedURL: "/files/vanilla-toffee-landing.png",
+		ImageOpenURL:   "/files/vanilla-toffee-landing-open.png",
+		Description:    "Vanilla Ice Cream with Fudge-Covered Toffee Pieces",
+		Story:          "Vanilla What Bar Crunch? The new ice cream with toffee bars...",
+		SourcingValues: &[]string{
+			"Non-GMO",
+			"Cage-Free Eggs",
+			"Fairtrade",
+			"Responsibly Sourced Packaging",
+			"Caring Dairy",
+		},
+		Ingredients: &[]string{
+			"cream",
+			"skim milk",
+			"liquid sugar",
+			"water",
+			"sugar",
+		},
+		ParsedIngredients: []domain.Ingredient{
+			{Name: "cream"},
+			{Name: "skim milk"},
+			{Name: "liquid sugar"},
+			{Name: "water"},
+			{Name: "sugar"},
 		},
 		AllergyInfo:          "May contain wheat, peanuts",
 		DietaryCertification: "Singapore Food Ministry",
+		Allergens:            domain.Allergens{Contains: []string{"milk"}, MayContain: []string{"peanuts", "wheat"}},
 	}
 	return mockProductSuccess
 }
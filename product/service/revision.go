@@ -0,0 +1,150 @@
+package service
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// untrackedFields are product fields that are not part
+// of its content, or are derived from its content
+var untrackedFields = map[string]bool{
+	"ProductID":         true,
+	"Version":           true,
+	"ParsedIngredients": true,
+	"AllergenCheck":     true,
+	"PreviousSlugs":     true,
+}
+
+// diffProducts lists fields whose values differ between
+// before and after, in the order fields are declared in
+// product. Empty values such as nil and empty lists are equal.
+// Structs, such as allergens, and maps of structs, such as translations,
+// are compared field by field and so are variants, by their SKU
+func diffProducts(before, after domain.Product) []domain.FieldChange {
+	var changes = make([]domain.FieldChange, 0)
+
+	beforeValue := reflect.ValueOf(before)
+	afterValue := reflect.ValueOf(after)
+	productType := beforeValue.Type()
+
+	for i := 0; i < productType.NumField(); i++ {
+		field := productType.Field(i)
+		if untrackedFields[field.Name] {
+			continue
+		}
+
+		if field.Name == "Variants" {
+			beforeVariants := reflect.ValueOf(variantsBySKU(before.Variants))
+			afterVariants := reflect.ValueOf(variantsBySKU(after.Variants))
+			changes = append(changes, diffStructMap(field.Name, beforeVariants, afterVariants)...)
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			changes = append(changes, diffStruct(field.Name, beforeValue.Field(i), afterValue.Field(i))...)
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Map && field.Type.Elem().Kind() == reflect.Struct {
+			changes = append(changes, diffStructMap(field.Name, beforeValue.Field(i), afterValue.Field(i))...)
+			continue
+		}
+
+		from := fieldValue(beforeValue.Field(i))
+		to := fieldValue(afterValue.Field(i))
+
+		if !reflect.DeepEqual(from, to) {
+			changes = append(changes, domain.FieldChange{Field: field.Name, From: from, To: to})
+		}
+	}
+	return changes
+}
+
+// diffStructMap lists fields whose values differ between structs
+// of before and after maps, ordered by key. Changed fields are named
+// after map field, key and struct field, e.g. Translations.fr.Name
+func diffStructMap(name string, before, after reflect.Value) []domain.FieldChange {
+	var keys []string
+	for _, key := range before.MapKeys() {
+		keys = append(keys, key.String())
+	}
+	for _, key := range after.MapKeys() {
+		if !before.MapIndex(key).IsValid() {
+			keys = append(keys, key.String())
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []domain.FieldChange
+	for _, key := range keys {
+		changes = append(changes, diffStruct(name+"."+key, mapValue(before, key), mapValue(after, key))...)
+	}
+	return changes
+}
+
+// diffStruct lists fields whose values differ between structs before
+// and after, named after name and struct field, e.g. Allergens.Contains.
+// Nested structs are compared field by field, e.g. Nutrition.Per100g.Protein
+func diffStruct(name string, before, after reflect.Value) []domain.FieldChange {
+	var changes []domain.FieldChange
+	structType := before.Type()
+
+	for i := 0; i < structType.NumField(); i++ {
+		if structType.Field(i).Type.Kind() == reflect.Struct {
+			field := name + "." + structType.Field(i).Name
+			changes = append(changes, diffStruct(field, before.Field(i), after.Field(i))...)
+			continue
+		}
+
+		from := fieldValue(before.Field(i))
+		to := fieldValue(after.Field(i))
+
+		if !reflect.DeepEqual(from, to) {
+			field := name + "." + structType.Field(i).Name
+			changes = append(changes, domain.FieldChange{Field: field, From: from, To: to})
+		}
+	}
+	return changes
+}
+
+// variantsBySKU maps variants by their SKU
+func variantsBySKU(variants []domain.ProductVariant) map[string]domain.ProductVariant {
+	bySKU := make(map[string]domain.ProductVariant, len(variants))
+	for _, variant := range variants {
+		bySKU[variant.SKU] = variant
+	}
+	return bySKU
+}
+
+// mapValue reads struct of m at key, or empty struct if m has no key
+func mapValue(m reflect.Value, key string) reflect.Value {
+	value := m.MapIndex(reflect.ValueOf(key).Convert(m.Type().Key()))
+	if !value.IsValid() {
+		return reflect.Zero(m.Type().Elem())
+	}
+	return value
+}
+
+// fieldValue dereferences pointer fields and returns nil for empty values
+func fieldValue(value reflect.Value) interface{} {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map:
+		if value.Len() == 0 {
+			return nil
+		}
+	default:
+		if value.IsZero() {
+			return nil
+		}
+	}
+	return value.Interface()
+}
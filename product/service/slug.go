@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// slugAttempts bounds numbered candidates tried for a slug
+// whose plain form is taken by other products
+const slugAttempts = 100
+
+// GetProductBySlug gets product by its slug, or by a slug it had before
+// being renamed, in which case the product tells its current slug
+func (service *ProductService) GetProductBySlug(ctx context.Context, slug string) (domain.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	owner, err := service.productRepo.GetBySlug(ctx, slug)
+
+	if err != nil {
+		return domain.Product{}, err
+	}
+
+	// slugs of trashed products stay reserved, but do not resolve
+	return service.GetProduct(ctx, owner.ProductID)
+}
+
+// withSlug derives slug of product identified by productID from its name,
+// keeping the slug of current product among its previous slugs. Slugs are
+// unique across current and previous slugs of all products, including
+// slugs taken in the same write (mapped to productIds taking them),
+// so a slug taken by another product is numbered, e.g.
+// "vanilla-2", as are slugs reserved by product routes, e.g.
+// "trash-2". Product keeps its slug when its name slugs the same, or
+// when a partial update leaves name out
+func (service *ProductService) withSlug(
+	ctx context.Context,
+	productID string,
+	product domain.Product,
+	current domain.Product,
+	taken map[string]string,
+) (domain.Product, error) {
+	// partial update leaving name out leaves slugs out as well
+	if product.Name == "" {
+		product.Slug, product.PreviousSlugs = "", nil
+		return product, nil
+	}
+
+	product.Slug = current.Slug
+	product.PreviousSlugs = current.PreviousSlugs
+
+	base := domain.Slugify(product.Name)
+	if current.Slug != "" && base == domain.Slugify(current.Name) {
+		return product, nil
+	}
+
+	for n := 1; n <= slugAttempts; n++ {
+		slug := domain.SlugCandidate(base, n)
+		if domain.IsReservedSlug(slug) {
+			continue
+		}
+
+		if owner, ok := taken[slug]; ok && owner != productID {
+			continue
+		}
+
+		owner, err := service.productRepo.GetBySlug(ctx, slug)
+		if err != nil && err != domain.ErrResourceNotFound {
+			return domain.Product{}, err
+		}
+
+		if err == nil && owner.ProductID != productID {
+			continue
+		}
+
+		product.Slug = slug
+		product.PreviousSlugs = previousSlugs(current, slug)
+		if taken != nil {
+			taken[slug] = productID
+		}
+		return product, nil
+	}
+	return domain.Product{}, domain.ErrDuplicateSlug
+}
+
+// previousSlugs adds slug of current product to its previous slugs
+// when product changes slug, reclaimed slug is no longer previous
+func previousSlugs(current domain.Product, slug string) []string {
+	var previous []string
+	for _, previousSlug := range current.PreviousSlugs {
+		if previousSlug != slug {
+			previous = append(previous, previousSlug)
+		}
+	}
+
+	if current.Slug != "" && current.Slug != slug {
+		previous = append(previous, current.Slug)
+	}
+	return previous
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/iqdf/benjerry-service/common/auth"
+	"github.com/iqdf/benjerry-service/common/consts/role"
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// FetchRetiredProducts lists a page of retired products, latest
+// retired first unless query sorts them in ascending order. Products
+// are always sorted by when they were retired, filtered like
+// FetchProducts and shown with their overrides of the filtered region
+func (service *ProductService) FetchRetiredProducts(ctx context.Context, query domain.ProductQuery) (domain.ProductPage, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if query.SortBy != "" && query.SortBy != domain.SortByRetiredAt {
+		return domain.ProductPage{}, domain.ErrBadParamInput
+	}
+
+	if query.SortOrder == 0 {
+		query.SortOrder = domain.Descending
+	}
+
+	query.SortBy = ""
+	query, err := normalizeQuery(query)
+	if err != nil {
+		return domain.ProductPage{}, err
+	}
+
+	query.SortBy = domain.SortByRetiredAt
+	query.Filter.Statuses = []domain.ProductStatus{domain.StatusRetired}
+
+	page, err := service.productRepo.Fetch(ctx, query)
+	if err != nil {
+		return domain.ProductPage{}, err
+	}
+
+	for i := range page.Products {
+		page.Products[i] = inRegion(page.Products[i], query.Filter.Region)
+	}
+	return page, nil
+}
+
+// ChangeProductStatus moves product to another status of its lifecycle,
+// or schedules the move if the change is set at a future time
+func (service *ProductService) ChangeProductStatus(
+	ctx context.Context,
+	productID string,
+	change domain.StatusChange,
+	version int64,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if !change.Status.Valid() {
+		return domain.ErrBadParamInput
+	}
+
+	if change.Epitaph != "" && change.Status != domain.StatusRetired {
+		return domain.ErrBadParamInput
+	}
+
+	before, err := service.writeProduct(ctx, productID, version, func(current domain.Product) error {
+		next, err := nextLifecycle(current, change, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		return service.productRepo.UpdateStatus(ctx, productID, next)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return service.recordChanges(ctx, domain.RevisionUpdate, productID, before, 0)
+}
+
+// ApplyScheduledStatuses moves products whose scheduled status change
+// is due by now and counts them. Schedules that are no longer valid
+// transitions, e.g. the product was retired by hand, are dropped
+func (service *ProductService) ApplyScheduledStatuses(ctx context.Context, now time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, streamTimeout)
+	defer cancel()
+
+	products, err := service.productRepo.FetchScheduled(ctx, now)
+
+	if err != nil {
+		return 0, err
+	}
+
+	var applied int
+	for _, product := range products {
+		next, err := nextLifecycle(product, domain.StatusChange{Status: product.ScheduledStatus}, now)
+
+		if err == domain.ErrInvalidTransition {
+			next = product
+			next.ScheduledStatus = ""
+			next.ScheduledAt = nil
+		} else if err != nil {
+			return applied, err
+		}
+
+		err = service.productRepo.UpdateStatus(ctx, product.ProductID, next)
+
+		// product changed in the meantime, picked up on next run if still due
+		if err == domain.ErrPreconditionFailed || err == domain.ErrResourceNotFound {
+			continue
+		}
+
+		if err != nil {
+			return applied, err
+		}
+
+		if err := service.recordChanges(ctx, domain.RevisionUpdate, product.ProductID, product, 0); err != nil {
+			return applied, err
+		}
+		applied++
+	}
+
+	return applied, nil
+}
+
+// nextLifecycle returns current product with its lifecycle changed,
+// at the version of current product. A change set after now is only
+// scheduled, an epitaph is kept until retired product is retired again
+func nextLifecycle(current domain.Product, change domain.StatusChange, now time.Time) (domain.Product, error) {
+	if !current.Status.CanTransitionTo(change.Status) {
+		return domain.Product{}, domain.ErrInvalidTransition
+	}
+
+	next := current
+	if change.Epitaph != "" {
+		next.Epitaph = change.Epitaph
+	}
+
+	if change.At.After(now) {
+		at := change.At.UTC()
+		next.ScheduledStatus = change.Status
+		next.ScheduledAt = &at
+		return next, nil
+	}
+
+	next.Status = change.Status
+	next.ScheduledStatus = ""
+	next.ScheduledAt = nil
+
+	switch change.Status {
+	case domain.StatusRetired:
+		next.RetiredAt = &now
+	case domain.StatusPublished:
+		next.RetiredAt = nil
+	}
+	return next, nil
+}
+
+// withLifecycle copies lifecycle of from into product
+func withLifecycle(product domain.Product, from domain.Product) domain.Product {
+	product.Status = from.Status
+	product.RetiredAt = from.RetiredAt
+	product.Epitaph = from.Epitaph
+	product.ScheduledStatus = from.ScheduledStatus
+	product.ScheduledAt = from.ScheduledAt
+	return product
+}
+
+// canSeeUnpublished tells whether user making request may see products
+// that are not published, which takes write permission. Requests made
+// outside of the API, e.g. catalog import, see every product
+func (service *ProductService) canSeeUnpublished(ctx context.Context) bool {
+	authentication, ok := auth.FromContext(ctx)
+	if !ok {
+		return true
+	}
+
+	for _, authorization := range authentication.Authorizations {
+		if authorization.AppName == service.appName && authorization.Role == role.WritePermission {
+			return true
+		}
+	}
+	return false
+}
+
+// visibleFilter narrows filter down to published products
+// for users who may not see unpublished ones
+func (service *ProductService) visibleFilter(ctx context.Context, filter domain.ProductFilter) domain.ProductFilter {
+	if !service.canSeeUnpublished(ctx) {
+		filter.Statuses = []domain.ProductStatus{domain.StatusPublished}
+	}
+	return filter
+}
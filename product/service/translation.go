@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// SetTranslation adds or replaces translation of product into locale
+func (service *ProductService) SetTranslation(
+	ctx context.Context,
+	productID string,
+	locale string,
+	translation domain.ProductTranslation,
+	version int64,
+) error {
+	if locale == "" || translation.IsEmpty() {
+		return domain.ErrBadParamInput
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	before, err := service.writeProduct(ctx, productID, version, func(current domain.Product) error {
+		translations := copyTranslations(current.Translations)
+		translations[locale] = translation
+
+		current.Translations = translations
+		return service.productRepo.UpdateTranslations(ctx, productID, current)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return service.recordChanges(ctx, domain.RevisionUpdate, productID, before, 0)
+}
+
+// DeleteTranslation removes translation of product into locale
+func (service *ProductService) DeleteTranslation(ctx context.Context, productID string, locale string, version int64) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	before, err := service.writeProduct(ctx, productID, version, func(current domain.Product) error {
+		if _, ok := current.Translations[locale]; !ok {
+			return domain.ErrResourceNotFound
+		}
+
+		translations := copyTranslations(current.Translations)
+		delete(translations, locale)
+
+		current.Translations = translations
+		return service.productRepo.UpdateTranslations(ctx, productID, current)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return service.recordChanges(ctx, domain.RevisionUpdate, productID, before, 0)
+}
+
+// copyTranslations copies translations so that
+// product read from repository is left untouched
+func copyTranslations(translations map[string]domain.ProductTranslation) map[string]domain.ProductTranslation {
+	copied := make(map[string]domain.ProductTranslation, len(translations)+1)
+	for locale, translation := range translations {
+		copied[locale] = translation
+	}
+	return copied
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// AddVariant adds variant to product. Its SKU must
+// not be taken by any variant across the catalog
+func (service *ProductService) AddVariant(
+	ctx context.Context,
+	productID string,
+	variant domain.ProductVariant,
+	version int64,
+) error {
+	if variant.SKU == "" {
+		return domain.ErrBadParamInput
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	before, err := service.writeProduct(ctx, productID, version, func(current domain.Product) error {
+		if _, ok := current.Variant(variant.SKU); ok {
+			return domain.ErrDuplicateSKU
+		}
+
+		variants := make([]domain.ProductVariant, 0, len(current.Variants)+1)
+		current.Variants = append(append(variants, current.Variants...), variant)
+		return service.productRepo.UpdateVariants(ctx, productID, current)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return service.recordChanges(ctx, domain.RevisionUpdate, productID, before, 0)
+}
+
+// UpdateVariant replaces variant of product identified by sku,
+// the variant keeps its SKU
+func (service *ProductService) UpdateVariant(
+	ctx context.Context,
+	productID string,
+	sku string,
+	variant domain.ProductVariant,
+	version int64,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	variant.SKU = sku
+	before, err := service.writeProduct(ctx, productID, version, func(current domain.Product) error {
+		if _, ok := current.Variant(sku); !ok {
+			return domain.ErrResourceNotFound
+		}
+
+		variants := make([]domain.ProductVariant, 0, len(current.Variants))
+		for _, stored := range current.Variants {
+			if stored.SKU == sku {
+				stored = variant
+			}
+			variants = append(variants, stored)
+		}
+
+		current.Variants = variants
+		return service.productRepo.UpdateVariants(ctx, productID, current)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return service.recordChanges(ctx, domain.RevisionUpdate, productID, before, 0)
+}
+
+// DeleteVariant removes variant of product identified by sku,
+// after which its SKU may be taken by another variant
+func (service *ProductService) DeleteVariant(ctx context.Context, productID string, sku string, version int64) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	before, err := service.writeProduct(ctx, productID, version, func(current domain.Product) error {
+		if _, ok := current.Variant(sku); !ok {
+			return domain.ErrResourceNotFound
+		}
+
+		variants := make([]domain.ProductVariant, 0, len(current.Variants))
+		for _, stored := range current.Variants {
+			if stored.SKU != sku {
+				variants = append(variants, stored)
+			}
+		}
+
+		current.Variants = variants
+		return service.productRepo.UpdateVariants(ctx, productID, current)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return service.recordChanges(ctx, domain.RevisionUpdate, productID, before, 0)
+}
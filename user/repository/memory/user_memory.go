@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/iqdf/benjerry-service/common/auth"
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// UserMemoryRepo keeps users in process memory, e.g. to run
+// without mongo. Usernames are unique like in mongo
+type UserMemoryRepo struct {
+	mu    sync.RWMutex
+	users map[string]domain.User
+}
+
+// NewUserRepo creates an empty in-memory user repository
+func NewUserRepo() *UserMemoryRepo {
+	return &UserMemoryRepo{users: make(map[string]domain.User)}
+}
+
+// copyUser copies user, so that stored users
+// share no authorizations with callers
+func copyUser(user domain.User) domain.User {
+	user.Authorizations = append([]auth.Authorization(nil), user.Authorizations...)
+	return user
+}
+
+// Get queries a single user identified by username
+func (repo *UserMemoryRepo) Get(ctx context.Context, username string) (domain.User, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	user, ok := repo.users[username]
+	if !ok {
+		return domain.User{}, domain.ErrResourceNotFound
+	}
+	return copyUser(user), nil
+}
+
+// Create stores a single user, whose username must not be taken
+func (repo *UserMemoryRepo) Create(ctx context.Context, user domain.User) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.users[user.Username]; ok {
+		return domain.ErrConflict
+	}
+	repo.users[user.Username] = copyUser(user)
+	return nil
+}
@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/domain/repotest"
+)
+
+func TestUserRepositorySuite(t *testing.T) {
+	repotest.RunUserRepositorySuite(t, func(t *testing.T) domain.UserRepository {
+		return NewUserRepo()
+	})
+}
@@ -5,8 +5,6 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
-	"go.mongodb.org/mongo-driver/x/bsonx"
 
 	"github.com/iqdf/benjerry-service/common/auth"
 	mongoHelper "github.com/iqdf/benjerry-service/common/repository/mongo"
@@ -49,24 +47,13 @@ func (model *UserModel) User() domain.User {
 	}
 }
 
-// NewUserRepo ...
+// NewUserRepo creates repository of users in database dbName,
+// whose indexes are created by migrations, see mongoHelper.Migrator
 func NewUserRepo(client *mongo.Client, dbName string) *UserMongoRepo {
 	repo := &UserMongoRepo{
 		client: client,
 		db:     client.Database(dbName),
 	}
-
-	collection := repo.db.Collection(collectionName)
-
-	// create unique index constraint for username field
-	collection.Indexes().CreateOne(
-		context.Background(),
-		mongo.IndexModel{
-			Keys:    bsonx.Doc{{Key: "username", Value: bsonx.Int32(1)}},
-			Options: options.Index().SetUnique(true),
-		},
-	)
-
 	return repo
 }
 
@@ -0,0 +1,75 @@
+package sql
+
+import (
+	"context"
+
+	"github.com/iqdf/benjerry-service/common/auth"
+	sqlHelper "github.com/iqdf/benjerry-service/common/repository/sql"
+	"github.com/iqdf/benjerry-service/domain"
+)
+
+// UserSQLRepo stores users in a SQL database, their authorizations
+// in a table of their own. Usernames are unique like in mongo
+type UserSQLRepo struct {
+	db *sqlHelper.DB
+}
+
+// NewUserRepo creates user repository over db, whose
+// schema is migrated by sqlHelper.DB.Migrate
+func NewUserRepo(db *sqlHelper.DB) *UserSQLRepo {
+	return &UserSQLRepo{db: db}
+}
+
+// Get queries a single user identified by username
+func (repo *UserSQLRepo) Get(ctx context.Context, username string) (domain.User, error) {
+	conn := repo.db.Conn(ctx)
+	user := domain.User{Username: username}
+
+	row := conn.QueryRowContext(ctx, repo.db.Rebind("SELECT hash_password FROM users WHERE username = ?"), username)
+	if err := row.Scan(&user.HashPassword); err != nil {
+		return domain.User{}, sqlHelper.TranslateError(err)
+	}
+
+	rows, err := conn.QueryContext(ctx, repo.db.Rebind(
+		"SELECT app_name, role FROM user_authorizations WHERE username = ? ORDER BY ordinal",
+	), username)
+	if err != nil {
+		return domain.User{}, sqlHelper.TranslateError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var authorization auth.Authorization
+		if err = rows.Scan(&authorization.AppName, &authorization.Role); err != nil {
+			return domain.User{}, sqlHelper.TranslateError(err)
+		}
+		user.Authorizations = append(user.Authorizations, authorization)
+	}
+	return user, sqlHelper.TranslateError(rows.Err())
+}
+
+// Create inserts a single user and its authorizations
+func (repo *UserSQLRepo) Create(ctx context.Context, user domain.User) error {
+	return repo.db.WithinTransaction(ctx, func(ctx context.Context) error {
+		conn := repo.db.Conn(ctx)
+
+		_, err := conn.ExecContext(ctx,
+			repo.db.Rebind("INSERT INTO users (username, hash_password) VALUES (?, ?)"),
+			user.Username, user.HashPassword,
+		)
+		if err != nil {
+			return sqlHelper.TranslateError(err)
+		}
+
+		for i, authorization := range user.Authorizations {
+			_, err = conn.ExecContext(ctx,
+				repo.db.Rebind("INSERT INTO user_authorizations (username, ordinal, app_name, role) VALUES (?, ?, ?, ?)"),
+				user.Username, i, authorization.AppName, authorization.Role,
+			)
+			if err != nil {
+				return sqlHelper.TranslateError(err)
+			}
+		}
+		return nil
+	})
+}
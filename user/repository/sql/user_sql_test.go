@@ -0,0 +1,56 @@
+package sql
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	sqlHelper "github.com/iqdf/benjerry-service/common/repository/sql"
+	"github.com/iqdf/benjerry-service/domain"
+	"github.com/iqdf/benjerry-service/domain/repotest"
+)
+
+// openTestDB opens a migrated database, either sqlite in a
+// temporary directory or, given dialect postgres, the database
+// of POSTGRES_TEST_URI with no users
+func openTestDB(t *testing.T, dialect sqlHelper.Dialect) *sqlHelper.DB {
+	dsn := os.Getenv("POSTGRES_TEST_URI")
+	if dialect == sqlHelper.SQLite {
+		dir, err := ioutil.TempDir("", "benjerry")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.RemoveAll(dir) })
+		dsn = "file:" + filepath.Join(dir, "test.db")
+	} else if dsn == "" {
+		t.Skip("POSTGRES_TEST_URI is not set")
+	}
+
+	db, err := sqlHelper.Open(dialect, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err = db.Migrate(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = db.Exec("DELETE FROM users"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestUserRepositorySuite(t *testing.T) {
+	for name, dialect := range map[string]sqlHelper.Dialect{"SQLite": sqlHelper.SQLite, "Postgres": sqlHelper.Postgres} {
+		dialect := dialect
+		t.Run(name, func(t *testing.T) {
+			repotest.RunUserRepositorySuite(t, func(t *testing.T) domain.UserRepository {
+				return NewUserRepo(openTestDB(t, dialect))
+			})
+		})
+	}
+}